@@ -0,0 +1,154 @@
+package apperror
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+)
+
+// Code: Código estable que se expone en las respuestas de error
+type Code string
+
+const (
+	BAD_REQUEST  Code = "BAD_REQUEST"
+	VALIDATION   Code = "VALIDATION"
+	UNAUTHORIZED Code = "UNAUTHORIZED"
+	FORBIDDEN    Code = "FORBIDDEN"
+	NOT_FOUND    Code = "NOT_FOUND"
+	CONFLICT     Code = "CONFLICT"
+	PRECONDITION Code = "PRECONDITION_FAILED"
+	TOO_LARGE    Code = "TOO_LARGE"
+	INTERNAL     Code = "INTERNAL"
+	UNAVAILABLE  Code = "UNAVAILABLE"
+	RATE_LIMITED Code = "RATE_LIMITED"
+
+	// PRECONDITION_REQUIRED: Falta el header If-Match en una actualización
+	PRECONDITION_REQUIRED Code = "PRECONDITION_REQUIRED"
+)
+
+var statusByCode = map[Code]int{
+	BAD_REQUEST:  http.StatusBadRequest,
+	VALIDATION:   http.StatusUnprocessableEntity,
+	UNAUTHORIZED: http.StatusUnauthorized,
+	FORBIDDEN:    http.StatusForbidden,
+	NOT_FOUND:    http.StatusNotFound,
+	CONFLICT:     http.StatusConflict,
+	PRECONDITION: http.StatusPreconditionFailed,
+	TOO_LARGE:    http.StatusRequestEntityTooLarge,
+	INTERNAL:     http.StatusInternalServerError,
+	UNAVAILABLE:  http.StatusServiceUnavailable,
+	RATE_LIMITED: http.StatusTooManyRequests,
+
+	PRECONDITION_REQUIRED: http.StatusPreconditionRequired,
+}
+
+// Status: Código HTTP que corresponde al código de error
+func (c Code) Status() int {
+	if status, ok := statusByCode[c]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// CodeFromStatus: Código de error por defecto para un código HTTP
+func CodeFromStatus(status int) Code {
+	for code, s := range statusByCode {
+		if s == status {
+			return code
+		}
+	}
+	if status >= http.StatusInternalServerError {
+		return INTERNAL
+	}
+	return BAD_REQUEST
+}
+
+// FieldError: Detalle de validación de un campo, Message es el id del mensaje en el catálogo
+type FieldError struct {
+	Field   string                 `json:"field"`
+	Message string                 `json:"message"`
+	Params  map[string]interface{} `json:"-"`
+}
+
+// Error: Error de dominio con código, mensaje para el cliente y la causa original.
+// Message es el id del mensaje en el catálogo de i18n y Params sus parámetros
+type Error struct {
+	Code    Code
+	Message string
+	Params  map[string]interface{}
+	Fields  []FieldError
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// WithParams: Agrega los parámetros con los que se formatea el mensaje
+func (e *Error) WithParams(params map[string]interface{}) *Error {
+	e.Params = params
+	return e
+}
+
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Wrap: Crea un error de dominio conservando la causa original
+func Wrap(code Code, message string, err error) *Error {
+	return &Error{Code: code, Message: message, Err: err}
+}
+
+func NotFound(message string) *Error {
+	return New(NOT_FOUND, message)
+}
+
+func Conflict(message string) *Error {
+	return New(CONFLICT, message)
+}
+
+func Forbidden(message string) *Error {
+	return New(FORBIDDEN, message)
+}
+
+func Unauthorized(message string) *Error {
+	return New(UNAUTHORIZED, message)
+}
+
+func BadRequest(message string) *Error {
+	return New(BAD_REQUEST, message)
+}
+
+// Validation: Error de validación con el detalle de cada campo inválido
+func Validation(message string, fields ...FieldError) *Error {
+	return &Error{Code: VALIDATION, Message: message, Fields: fields}
+}
+
+// Unavailable: El servicio no puede atender la solicitud por ahora (ej: base de datos caída o apagado en curso)
+func Unavailable(message string, err error) *Error {
+	return Wrap(UNAVAILABLE, message, err)
+}
+
+func Internal(err error) *Error {
+	return Wrap(INTERNAL, "error.internal", err)
+}
+
+// From: Convierte cualquier error a un error de dominio.
+// sql.ErrNoRows se traduce a NOT_FOUND y el resto de errores desconocidos a INTERNAL
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return Wrap(NOT_FOUND, "error.not_found", err)
+	}
+	return Internal(err)
+}
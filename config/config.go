@@ -0,0 +1,608 @@
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/joho/godotenv"
+	"go.yaml.in/yaml/v3"
+
+	"github.com/mbarolo/test_back/lock"
+	"github.com/mbarolo/test_back/logging"
+	"github.com/mbarolo/test_back/notify"
+	"github.com/mbarolo/test_back/ratelimit"
+	"github.com/mbarolo/test_back/tracing"
+	"github.com/mbarolo/test_back/utils"
+)
+
+// Config: Configuración de la aplicación. Cada campo se carga, de menor a mayor precedencia, desde los valores
+// por defecto, el archivo YAML (flag -config o CONFIG_FILE), las variables de entorno (y el archivo .env) y los flags.
+// Tags: env son las variables de entorno (la primera definida gana), flag el nombre del flag, secret redacta el valor
+// al imprimirlo y reload permite cambiarlo en caliente con SIGHUP
+type Config struct {
+	Server      ServerConfig      `yaml:"server"`
+	Database    DatabaseConfig    `yaml:"database"`
+	Auth        AuthConfig        `yaml:"auth"`
+	Log         LogConfig         `yaml:"log"`
+	Tracing     TracingConfig     `yaml:"tracing"`
+	Swagger     SwaggerConfig     `yaml:"swagger"`
+	RateLimit   RateLimitConfig   `yaml:"rate_limit"`
+	Webhooks    WebhooksConfig    `yaml:"webhooks"`
+	Locks       LocksConfig       `yaml:"locks"`
+	Telemetry   TelemetryConfig   `yaml:"telemetry"`
+	Trips       TripsConfig       `yaml:"trips"`
+	Rentals     RentalsConfig     `yaml:"rentals"`
+	Maintenance MaintenanceConfig `yaml:"maintenance"`
+	Stations    StationsConfig    `yaml:"stations"`
+	Rebalancing RebalancingConfig `yaml:"rebalancing"`
+	Incidents   IncidentsConfig   `yaml:"incidents"`
+}
+
+type ServerConfig struct {
+	Addr              string        `yaml:"addr" env:"ADDR" flag:"addr" help:"dirección en la que escucha el servidor HTTP"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" env:"SERVER_READ_HEADER_TIMEOUT" flag:"read-header-timeout" help:"tiempo máximo para leer los headers de la request"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" env:"SERVER_READ_TIMEOUT" flag:"read-timeout" help:"tiempo máximo para leer la request completa"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" env:"SERVER_WRITE_TIMEOUT" flag:"write-timeout" help:"tiempo máximo para escribir la respuesta"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" env:"SERVER_IDLE_TIMEOUT" flag:"idle-timeout" help:"tiempo máximo de una conexión keep-alive inactiva"`
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout" env:"SERVER_SHUTDOWN_TIMEOUT" flag:"shutdown-timeout" help:"tiempo máximo para completar las requests en curso al apagar" reload:"true"`
+	TrustProxy        bool          `yaml:"trust_proxy" env:"SERVER_TRUST_PROXY" flag:"trust-proxy" help:"tomar la IP del cliente de X-Forwarded-For o X-Real-IP (solo detrás de un proxy)"`
+}
+
+type DatabaseConfig struct {
+	Path string `yaml:"path" env:"SQLITE_PATH" flag:"db" help:"ruta al archivo de la base de datos SQLite"`
+}
+
+// AuthConfig: Los secretos no tienen flag para que no queden expuestos en la lista de procesos.
+// JWT_KEY se mantiene como alias de JWT_SECRET por compatibilidad
+type AuthConfig struct {
+	JWTSecret        string        `yaml:"jwt_secret" env:"JWT_SECRET,JWT_KEY" secret:"true"`
+	TokenTTL         time.Duration `yaml:"token_ttl" env:"TOKEN_TTL" flag:"token-ttl" help:"vigencia de los tokens JWT" reload:"true"`
+	AdminCredentials string        `yaml:"admin_credentials" env:"ADMIN_CREDENTIALS" secret:"true"`
+}
+
+type LogConfig struct {
+	Level string `yaml:"level" env:"LOG_LEVEL" flag:"log-level" help:"nivel de log (debug, info, warn, error)" reload:"true"`
+}
+
+type TracingConfig struct {
+	Exporter    string `yaml:"exporter" env:"OTEL_TRACES_EXPORTER" flag:"traces-exporter" help:"exportador de trazas (none, otlp, stdout)"`
+	ServiceName string `yaml:"service_name" env:"OTEL_SERVICE_NAME" flag:"service-name" help:"nombre del servicio en las trazas"`
+}
+
+type SwaggerConfig struct {
+	URL string `yaml:"url" env:"SWAGGER_URL" flag:"swagger-url" help:"URL del doc.json que carga Swagger UI"`
+}
+
+// RateLimitConfig: Límites por grupo de rutas con el formato requests/ventana (ej: 10/1m)
+type RateLimitConfig struct {
+	Enabled bool   `yaml:"enabled" env:"RATE_LIMIT_ENABLED" flag:"rate-limit" help:"activar el rate limiting" reload:"true"`
+	Auth    string `yaml:"auth" env:"RATE_LIMIT_AUTH" flag:"rate-limit-auth" help:"límite de login y registro, por IP" reload:"true"`
+	API     string `yaml:"api" env:"RATE_LIMIT_API" flag:"rate-limit-api" help:"límite de las rutas de usuario, por usuario" reload:"true"`
+	Admin   string `yaml:"admin" env:"RATE_LIMIT_ADMIN" flag:"rate-limit-admin" help:"límite de las rutas de administración" reload:"true"`
+	Devices string `yaml:"devices" env:"RATE_LIMIT_DEVICES" flag:"rate-limit-devices" help:"límite de la telemetría, por dispositivo" reload:"true"`
+}
+
+// WebhooksConfig: Entrega de los eventos de dominio a los webhooks. Los reintentos esperan backoff_base
+// multiplicado por 2 en cada intento fallido, hasta backoff_max
+type WebhooksConfig struct {
+	Enabled      bool          `yaml:"enabled" env:"WEBHOOKS_ENABLED" flag:"webhooks" help:"entregar los eventos a los webhooks"`
+	PollInterval time.Duration `yaml:"poll_interval" env:"WEBHOOKS_POLL_INTERVAL" flag:"webhooks-poll-interval" help:"intervalo entre búsquedas de eventos y entregas pendientes"`
+	Timeout      time.Duration `yaml:"timeout" env:"WEBHOOKS_TIMEOUT" flag:"webhooks-timeout" help:"tiempo máximo de cada intento de entrega" reload:"true"`
+	MaxAttempts  int           `yaml:"max_attempts" env:"WEBHOOKS_MAX_ATTEMPTS" flag:"webhooks-max-attempts" help:"intentos antes de marcar la entrega como dead" reload:"true"`
+	BackoffBase  time.Duration `yaml:"backoff_base" env:"WEBHOOKS_BACKOFF_BASE" flag:"webhooks-backoff-base" help:"espera antes del primer reintento" reload:"true"`
+	BackoffMax   time.Duration `yaml:"backoff_max" env:"WEBHOOKS_BACKOFF_MAX" flag:"webhooks-backoff-max" help:"espera máxima entre reintentos" reload:"true"`
+}
+
+// LocksConfig: Candados de las bicicletas. El driver simulator los simula en memoria, mqtt envía los comandos
+// al broker. Los alquileres esperan la confirmación del candado fuera de la transacción, así un candado lento
+// no bloquea al resto de las escrituras
+type LocksConfig struct {
+	Driver           string          `yaml:"driver" env:"LOCKS_DRIVER" flag:"locks-driver" help:"controlador de los candados (simulator, mqtt)"`
+	Timeout          time.Duration   `yaml:"timeout" env:"LOCKS_TIMEOUT" flag:"locks-timeout" help:"tiempo máximo para que el candado confirme un comando" reload:"true"`
+	SimulatorLatency time.Duration   `yaml:"simulator_latency" env:"LOCKS_SIMULATOR_LATENCY" flag:"locks-simulator-latency" help:"tiempo que tarda un candado simulado en ejecutar un comando"`
+	MQTT             LocksMQTTConfig `yaml:"mqtt"`
+}
+
+// LocksMQTTConfig: Conexión al broker MQTT. Con embedded_broker se inicia un broker en esa dirección
+// con la flota de candados simulada conectada, solo para desarrollo
+type LocksMQTTConfig struct {
+	Broker         string        `yaml:"broker" env:"LOCKS_MQTT_BROKER" flag:"locks-mqtt-broker" help:"URL del broker MQTT (ej: tcp://localhost:1883)"`
+	ClientId       string        `yaml:"client_id" env:"LOCKS_MQTT_CLIENT_ID" flag:"locks-mqtt-client-id" help:"client id de la conexión MQTT"`
+	Username       string        `yaml:"username" env:"LOCKS_MQTT_USERNAME" flag:"locks-mqtt-username" help:"usuario del broker MQTT"`
+	Password       string        `yaml:"password" env:"LOCKS_MQTT_PASSWORD" secret:"true"`
+	TopicPrefix    string        `yaml:"topic_prefix" env:"LOCKS_MQTT_TOPIC_PREFIX" flag:"locks-mqtt-topic-prefix" help:"prefijo de los topics de los candados"`
+	ConnectTimeout time.Duration `yaml:"connect_timeout" env:"LOCKS_MQTT_CONNECT_TIMEOUT" flag:"locks-mqtt-connect-timeout" help:"tiempo máximo para conectarse al broker"`
+	EmbeddedBroker string        `yaml:"embedded_broker" env:"LOCKS_MQTT_EMBEDDED_BROKER" flag:"locks-mqtt-embedded-broker" help:"host:puerto de un broker embebido con candados simulados (desarrollo)"`
+}
+
+// TelemetryConfig: Historial de telemetría de los dispositivos, guardado en una tabla por mes
+type TelemetryConfig struct {
+	Retention time.Duration `yaml:"retention" env:"TELEMETRY_RETENTION" flag:"telemetry-retention" help:"antigüedad a partir de la cual se eliminan los meses de telemetría" reload:"true"`
+}
+
+// TripsConfig: Recorrido de los alquileres
+type TripsConfig struct {
+	SimplifyTolerance int `yaml:"simplify_tolerance" env:"TRIPS_SIMPLIFY_TOLERANCE" flag:"trips-simplify-tolerance" help:"metros que un punto se puede apartar del recorrido simplificado (Douglas-Peucker), 0 guarda todos los puntos" reload:"true"`
+}
+
+// RentalsConfig: Reglas de inicio de los alquileres y cierre de los abandonados
+type RentalsConfig struct {
+	MinBattery int                  `yaml:"min_battery" env:"RENTALS_MIN_BATTERY" flag:"rentals-min-battery" help:"porcentaje de batería mínimo para alquilar un vehículo eléctrico" reload:"true"`
+	AutoEnd    RentalsAutoEndConfig `yaml:"auto_end"`
+}
+
+// RentalsAutoEndConfig: Cierre automático de los alquileres que superan la duración máxima o quedan inactivos.
+// Se avisa al usuario y, si sigue igual al terminar el plazo de gracia, se finaliza con el motivo y la multa
+type RentalsAutoEndConfig struct {
+	Enabled     bool          `yaml:"enabled" env:"RENTALS_AUTO_END_ENABLED" flag:"rentals-auto-end" help:"cierra periódicamente los alquileres abandonados"`
+	Interval    time.Duration `yaml:"interval" env:"RENTALS_AUTO_END_INTERVAL" flag:"rentals-auto-end-interval" help:"cada cuánto se buscan los alquileres abandonados"`
+	MaxDuration time.Duration `yaml:"max_duration" env:"RENTALS_AUTO_END_MAX_DURATION" flag:"rentals-auto-end-max-duration" help:"duración a partir de la cual se avisa y se cierra el alquiler" reload:"true"`
+	IdleAfter   time.Duration `yaml:"idle_after" env:"RENTALS_AUTO_END_IDLE_AFTER" flag:"rentals-auto-end-idle-after" help:"tiempo sin puntos de recorrido a partir del cual el alquiler se considera inactivo" reload:"true"`
+	GracePeriod time.Duration `yaml:"grace_period" env:"RENTALS_AUTO_END_GRACE_PERIOD" flag:"rentals-auto-end-grace-period" help:"plazo entre el aviso al usuario y el cierre" reload:"true"`
+	PenaltyFee  int           `yaml:"penalty_fee" env:"RENTALS_AUTO_END_PENALTY_FEE" flag:"rentals-auto-end-penalty-fee" help:"multa que se suma al costo de un alquiler cerrado automáticamente, 0 sin multa" reload:"true"`
+}
+
+// MaintenanceConfig: Reportes de daño y órdenes de trabajo
+type MaintenanceConfig struct {
+	PhotoDir string `yaml:"photo_dir" env:"MAINTENANCE_PHOTO_DIR" flag:"maintenance-photo-dir" help:"directorio donde se guardan las fotos de los reportes de daño"`
+}
+
+// StationsConfig: Devolución de las bicicletas en las estaciones de anclaje
+type StationsConfig struct {
+	ReturnRequired bool `yaml:"return_required" env:"STATIONS_RETURN_REQUIRED" flag:"stations-return-required" help:"solo se pueden devolver las bicicletas en una estación con anclajes libres" reload:"true"`
+	SnapTolerance  int  `yaml:"snap_tolerance" env:"STATIONS_SNAP_TOLERANCE" flag:"stations-snap-tolerance" help:"metros alrededor de una estación dentro de los que la devolución se ancla en ella" reload:"true"`
+}
+
+// RebalancingConfig: Pronóstico de oferta y demanda por celda y tareas de traslado de bicicletas
+type RebalancingConfig struct {
+	Enabled     bool          `yaml:"enabled" env:"REBALANCING_ENABLED" flag:"rebalancing" help:"planifica las tareas de traslado periódicamente"`
+	Interval    time.Duration `yaml:"interval" env:"REBALANCING_INTERVAL" flag:"rebalancing-interval" help:"cada cuánto se planifican las tareas de traslado"`
+	CellSize    int           `yaml:"cell_size" env:"REBALANCING_CELL_SIZE" flag:"rebalancing-cell-size" help:"metros de lado de las celdas de la grilla" reload:"true"`
+	HistoryDays int           `yaml:"history_days" env:"REBALANCING_HISTORY_DAYS" flag:"rebalancing-history-days" help:"días de alquileres con los que se pronostica la demanda" reload:"true"`
+	MinBikes    int           `yaml:"min_bikes" env:"REBALANCING_MIN_BIKES" flag:"rebalancing-min-bikes" help:"bicicletas mínimas para que un traslado valga la pena" reload:"true"`
+	MaxDistance int           `yaml:"max_distance" env:"REBALANCING_MAX_DISTANCE" flag:"rebalancing-max-distance" help:"metros máximos entre las celdas de un traslado" reload:"true"`
+}
+
+// IncidentsConfig: Reglas de detección de bicicletas perdidas o robadas y envío de los avisos
+type IncidentsConfig struct {
+	Enabled           bool          `yaml:"enabled" env:"INCIDENTS_ENABLED" flag:"incidents" help:"evalúa periódicamente las reglas sobre toda la flota"`
+	Interval          time.Duration `yaml:"interval" env:"INCIDENTS_INTERVAL" flag:"incidents-interval" help:"cada cuánto se evalúan las reglas sobre toda la flota"`
+	Notifier          string        `yaml:"notifier" env:"INCIDENTS_NOTIFIER" flag:"incidents-notifier" help:"driver de los avisos de incidentes: log o http"`
+	NotifierURL       string        `yaml:"notifier_url" env:"INCIDENTS_NOTIFIER_URL" flag:"incidents-notifier-url" help:"URL a la que el driver http envía los avisos" secret:"true"`
+	NotifierTimeout   time.Duration `yaml:"notifier_timeout" env:"INCIDENTS_NOTIFIER_TIMEOUT" flag:"incidents-notifier-timeout" help:"tiempo máximo para enviar un aviso"`
+	MovementThreshold int           `yaml:"movement_threshold" env:"INCIDENTS_MOVEMENT_THRESHOLD" flag:"incidents-movement-threshold" help:"metros que se puede mover una bicicleta sin alquiler antes de abrir un incidente (ruido del GPS)" reload:"true"`
+	NoSignalAfter     time.Duration `yaml:"no_signal_after" env:"INCIDENTS_NO_SIGNAL_AFTER" flag:"incidents-no-signal-after" help:"tiempo sin telemetría a partir del cual se abre un incidente" reload:"true"`
+	MaxRental         time.Duration `yaml:"max_rental" env:"INCIDENTS_MAX_RENTAL" flag:"incidents-max-rental" help:"duración de un alquiler a partir de la cual se abre un incidente" reload:"true"`
+	ServiceArea       string        `yaml:"service_area" env:"INCIDENTS_SERVICE_AREA" flag:"incidents-service-area" help:"polígono del área de servicio (lat,lng;lat,lng;...), vacío no controla el área" reload:"true"`
+}
+
+// Default: Configuración por defecto
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Addr:              "localhost:8080",
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       15 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       60 * time.Second,
+			ShutdownTimeout:   20 * time.Second,
+		},
+		Database: DatabaseConfig{Path: "./app.db"},
+		Auth:     AuthConfig{TokenTTL: 30 * 24 * time.Hour},
+		Log:      LogConfig{Level: "info"},
+		Tracing:  TracingConfig{Exporter: tracing.ExporterNone, ServiceName: "test_back"},
+		Swagger:  SwaggerConfig{URL: "/swagger/doc.json"},
+		RateLimit: RateLimitConfig{
+			Enabled: true,
+			Auth:    "10/1m",
+			API:     "120/1m",
+			Admin:   "600/1m",
+			Devices: "60/1m",
+		},
+		Webhooks: WebhooksConfig{
+			Enabled:      true,
+			PollInterval: 5 * time.Second,
+			Timeout:      10 * time.Second,
+			MaxAttempts:  8,
+			BackoffBase:  30 * time.Second,
+			BackoffMax:   time.Hour,
+		},
+		Locks: LocksConfig{
+			Driver:           lock.DriverSimulator,
+			Timeout:          3 * time.Second,
+			SimulatorLatency: 200 * time.Millisecond,
+			MQTT: LocksMQTTConfig{
+				ClientId:       "test_back",
+				TopicPrefix:    "bikes",
+				ConnectTimeout: 5 * time.Second,
+			},
+		},
+		Telemetry: TelemetryConfig{Retention: 90 * 24 * time.Hour},
+		Trips:     TripsConfig{SimplifyTolerance: 5},
+		Rentals: RentalsConfig{
+			MinBattery: 20,
+			AutoEnd: RentalsAutoEndConfig{
+				Enabled:     true,
+				Interval:    5 * time.Minute,
+				MaxDuration: 24 * time.Hour,
+				IdleAfter:   2 * time.Hour,
+				GracePeriod: 30 * time.Minute,
+			},
+		},
+		Maintenance: MaintenanceConfig{PhotoDir: "./photos"},
+		Stations:    StationsConfig{SnapTolerance: 50},
+		Rebalancing: RebalancingConfig{
+			Enabled:     true,
+			Interval:    time.Hour,
+			CellSize:    500,
+			HistoryDays: 28,
+			MinBikes:    2,
+			MaxDistance: 3000,
+		},
+		Incidents: IncidentsConfig{
+			Enabled:           true,
+			Interval:          5 * time.Minute,
+			Notifier:          notify.DriverLog,
+			NotifierTimeout:   5 * time.Second,
+			MovementThreshold: 50,
+			NoSignalAfter:     6 * time.Hour,
+			MaxRental:         12 * time.Hour,
+		},
+	}
+}
+
+// Validate: Valida la configuración completa y retorna todos los errores juntos
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(key string, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+	}
+
+	if _, _, err := net.SplitHostPort(c.Server.Addr); err != nil {
+		fail("server.addr", "dirección inválida %q, se espera host:puerto", c.Server.Addr)
+	}
+	for _, f := range c.fields() {
+		if d, ok := f.value.Interface().(time.Duration); ok && d <= 0 {
+			fail(f.key, "debe ser una duración positiva")
+		}
+	}
+
+	if c.Database.Path == "" {
+		fail("database.path", "requerido")
+	}
+
+	if c.Auth.JWTSecret == "" {
+		fail("auth.jwt_secret", "requerido")
+	}
+	if c.Auth.AdminCredentials == "" {
+		fail("auth.admin_credentials", "requerido")
+	} else if _, err := base64.StdEncoding.DecodeString(c.Auth.AdminCredentials); err != nil {
+		fail("auth.admin_credentials", "no es un base64 válido")
+	}
+
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(c.Log.Level)); err != nil {
+		fail("log.level", "nivel inválido %q, debe ser uno de: debug, info, warn, error", c.Log.Level)
+	}
+
+	switch c.Tracing.Exporter {
+	case tracing.ExporterNone, tracing.ExporterOTLP, tracing.ExporterStdout:
+	default:
+		fail("tracing.exporter", "exportador inválido %q, debe ser uno de: none, otlp, stdout", c.Tracing.Exporter)
+	}
+	if c.Tracing.ServiceName == "" {
+		fail("tracing.service_name", "requerido")
+	}
+
+	if c.Swagger.URL == "" {
+		fail("swagger.url", "requerido")
+	}
+
+	for _, f := range c.fields() {
+		if strings.HasPrefix(f.key, "rate_limit.") && f.value.Kind() == reflect.String {
+			if _, err := ratelimit.ParseLimit(f.value.String()); err != nil {
+				fail(f.key, "%v", err)
+			}
+		}
+	}
+
+	if c.Webhooks.MaxAttempts < 1 {
+		fail("webhooks.max_attempts", "debe ser mayor a 0")
+	}
+	if c.Webhooks.BackoffMax < c.Webhooks.BackoffBase {
+		fail("webhooks.backoff_max", "debe ser mayor o igual a webhooks.backoff_base")
+	}
+
+	switch c.Locks.Driver {
+	case lock.DriverSimulator:
+	case lock.DriverMQTT:
+		if c.Locks.MQTT.Broker == "" && c.Locks.MQTT.EmbeddedBroker == "" {
+			fail("locks.mqtt.broker", "requerido con el driver mqtt, salvo que se use locks.mqtt.embedded_broker")
+		}
+		if c.Locks.MQTT.EmbeddedBroker != "" {
+			if _, _, err := net.SplitHostPort(c.Locks.MQTT.EmbeddedBroker); err != nil {
+				fail("locks.mqtt.embedded_broker", "dirección inválida %q, se espera host:puerto", c.Locks.MQTT.EmbeddedBroker)
+			}
+		}
+		if c.Locks.MQTT.ClientId == "" {
+			fail("locks.mqtt.client_id", "requerido")
+		}
+		if c.Locks.MQTT.TopicPrefix == "" {
+			fail("locks.mqtt.topic_prefix", "requerido")
+		}
+	default:
+		fail("locks.driver", "driver inválido %q, debe ser uno de: simulator, mqtt", c.Locks.Driver)
+	}
+
+	if c.Trips.SimplifyTolerance < 0 {
+		fail("trips.simplify_tolerance", "debe ser mayor o igual a 0")
+	}
+	if c.Rentals.MinBattery < 0 || c.Rentals.MinBattery > 100 {
+		fail("rentals.min_battery", "debe estar entre 0 y 100")
+	}
+	if c.Maintenance.PhotoDir == "" {
+		fail("maintenance.photo_dir", "requerido")
+	}
+	if c.Stations.SnapTolerance < 0 {
+		fail("stations.snap_tolerance", "debe ser mayor o igual a 0")
+	}
+	if c.Rebalancing.CellSize < 1 {
+		fail("rebalancing.cell_size", "debe ser mayor a 0")
+	}
+	if c.Rebalancing.HistoryDays < 1 {
+		fail("rebalancing.history_days", "debe ser mayor a 0")
+	}
+	if c.Rebalancing.MinBikes < 1 {
+		fail("rebalancing.min_bikes", "debe ser mayor a 0")
+	}
+	if c.Rebalancing.MaxDistance < 1 {
+		fail("rebalancing.max_distance", "debe ser mayor a 0")
+	}
+
+	switch c.Incidents.Notifier {
+	case notify.DriverLog:
+	case notify.DriverHTTP:
+		if c.Incidents.NotifierURL == "" {
+			fail("incidents.notifier_url", "requerido con el driver http")
+		}
+	default:
+		fail("incidents.notifier", "driver inválido %q, debe ser uno de: log, http", c.Incidents.Notifier)
+	}
+	if c.Rentals.AutoEnd.PenaltyFee < 0 {
+		fail("rentals.auto_end.penalty_fee", "debe ser mayor o igual a 0")
+	}
+	if c.Incidents.MovementThreshold < 0 {
+		fail("incidents.movement_threshold", "debe ser mayor o igual a 0")
+	}
+	if c.Incidents.ServiceArea != "" {
+		if _, err := utils.ParsePolygon(c.Incidents.ServiceArea); err != nil {
+			fail("incidents.service_area", "%v", err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Load: Carga la configuración desde el archivo, el entorno y los flags (args sin el nombre del programa).
+// Si la configuración se pudo leer pero es inválida retorna la configuración junto con los errores de validación
+func Load(args []string) (*Config, error) {
+	cfg := Default()
+	fields := cfg.fields()
+
+	fs := flag.NewFlagSet("test_back", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "archivo de configuración YAML")
+	flagValues := map[string]string{}
+	for _, f := range fields {
+		if f.flag == "" {
+			continue
+		}
+		name := f.flag
+		if f.value.Kind() == reflect.Bool {
+			fs.BoolFunc(name, f.help, func(s string) error {
+				flagValues[name] = s
+				return nil
+			})
+			continue
+		}
+		fs.Func(name, f.help, func(s string) error {
+			flagValues[name] = s
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("argumentos inesperados: %v", fs.Args())
+	}
+
+	// las variables del .env no pisan las que ya están definidas en el entorno
+	godotenv.Load(".env")
+
+	if *configFile != "" {
+		if err := loadFile(cfg, *configFile); err != nil {
+			return nil, err
+		}
+	}
+
+	var errs []error
+	for _, f := range fields {
+		for _, name := range f.env {
+			if value, ok := os.LookupEnv(name); ok {
+				if err := setValue(f.value, value); err != nil {
+					errs = append(errs, fmt.Errorf("%s: variable %s: %w", f.key, name, err))
+				}
+				break
+			}
+		}
+		if value, ok := flagValues[f.flag]; ok && f.flag != "" {
+			if err := setValue(f.value, value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: flag -%s: %w", f.key, f.flag, err))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return cfg, cfg.Validate()
+}
+
+// loadFile: Carga el archivo YAML sobre la configuración, las claves desconocidas son un error
+func loadFile(cfg *Config, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("archivo de configuración: %w", err)
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("archivo de configuración %s: %w", path, err)
+	}
+	return nil
+}
+
+// Print: Escribe la configuración efectiva en YAML, con los secretos redactados
+func Print(w io.Writer, cfg *Config) error {
+	redacted := *cfg
+	for _, f := range redacted.fields() {
+		if f.secret && !f.value.IsZero() {
+			f.value.SetString(logging.Redacted)
+		}
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&redacted); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+var current atomic.Pointer[Config]
+
+// Current: Configuración vigente, definida con Set al iniciar y actualizada por Reload
+func Current() *Config {
+	return current.Load()
+}
+
+// Set: Define la configuración vigente
+func Set(cfg *Config) {
+	current.Store(cfg)
+}
+
+// Reload: Vuelve a cargar la configuración y aplica solo los campos recargables (tag reload).
+// Los cambios en el resto de los campos se ignoran y se informan en restart, ya que requieren reiniciar.
+// Si la nueva configuración es inválida se mantiene la vigente
+func Reload(args []string) (cfg *Config, applied []string, restart []string, err error) {
+	loaded, err := Load(args)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	next := *Current()
+	nextFields := next.fields()
+	for i, f := range loaded.fields() {
+		target := nextFields[i]
+		if reflect.DeepEqual(target.value.Interface(), f.value.Interface()) {
+			continue
+		}
+		if !f.reload {
+			restart = append(restart, f.key)
+			continue
+		}
+		target.value.Set(f.value)
+		applied = append(applied, f.key)
+	}
+
+	Set(&next)
+	return &next, applied, restart, nil
+}
+
+// field: Campo hoja de la configuración, con su clave en el YAML (ej: server.addr) y sus tags
+type field struct {
+	key    string
+	env    []string
+	flag   string
+	help   string
+	secret bool
+	reload bool
+	value  reflect.Value
+}
+
+// fields: Campos hoja de la configuración, en el orden en que se declaran
+func (c *Config) fields() []field {
+	var fields []field
+	var walk func(v reflect.Value, prefix string)
+	walk = func(v reflect.Value, prefix string) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			key := prefix + sf.Tag.Get("yaml")
+			if sf.Type.Kind() == reflect.Struct {
+				walk(v.Field(i), key+".")
+				continue
+			}
+
+			f := field{
+				key:    key,
+				flag:   sf.Tag.Get("flag"),
+				help:   sf.Tag.Get("help"),
+				secret: sf.Tag.Get("secret") == "true",
+				reload: sf.Tag.Get("reload") == "true",
+				value:  v.Field(i),
+			}
+			if env := sf.Tag.Get("env"); env != "" {
+				f.env = strings.Split(env, ",")
+			}
+			fields = append(fields, f)
+		}
+	}
+	walk(reflect.ValueOf(c).Elem(), "")
+	return fields
+}
+
+// setValue: Asigna al campo el valor en texto de una variable de entorno o un flag
+func setValue(v reflect.Value, s string) error {
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("duración inválida %q", s)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("entero inválido %q", s)
+		}
+		v.SetInt(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("booleano inválido %q", s)
+		}
+		v.SetBool(b)
+	default:
+		return fmt.Errorf("tipo no soportado %s", v.Type())
+	}
+	return nil
+}
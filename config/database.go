@@ -1,10 +1,14 @@
 package config
 
 import (
+	"context"
 	"database/sql"
-	"log"
+	"log/slog"
 	"os"
+	"strings"
 
+	"github.com/XSAM/otelsql"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
 	_ "modernc.org/sqlite"
 )
 
@@ -15,29 +19,36 @@ type SQLiteConnection struct {
 var DB *sql.DB
 
 // NewSQLiteConnection: Crea y retorna una nueva conexión a SQLite
-func NewSQLiteConnection() *SQLiteConnection {
-	if err := InitDB(); err != nil {
-		log.Fatal("Error al conectar a la base de datos: ", err.Error())
+func NewSQLiteConnection(cfg DatabaseConfig) *SQLiteConnection {
+	if err := InitDB(cfg.Path); err != nil {
+		slog.Error("database connection failed", "error", err)
+		os.Exit(1)
 	}
 	return &SQLiteConnection{DB: DB}
 }
 
-// InitDB: Función para incilaizar la base de datos
-func InitDB() error {
-	// ruta al archivo .db
-	path := os.Getenv("SQLITE_PATH")
-	if path == "" {
-		path = "./app.db"
+// InitDB: Función para incilaizar la base de datos en la ruta al archivo .db
+func InitDB(path string) error {
+	// las escrituras concurrentes esperan a que se libere el lock en lugar de fallar con SQLITE_BUSY.
+	// Las transacciones toman el lock de escritura al comenzar (BEGIN IMMEDIATE), así dos transacciones
+	// que leen y luego escriben no se bloquean mutuamente
+	dsn := path
+	if !strings.Contains(dsn, "?") {
+		dsn += "?_pragma=busy_timeout(5000)&_txlock=immediate"
 	}
 
+	// cada consulta queda registrada como span hijo de la request que la originó
 	var err error
-	DB, err = sql.Open("sqlite", path)
+	DB, err = otelsql.Open("sqlite", dsn,
+		otelsql.WithAttributes(semconv.DBSystemNameSQLite),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{OmitConnResetSession: true, OmitRows: true}),
+	)
 	if err != nil {
 		return err
 	}
 
 	if err = DB.Ping(); err != nil {
-		log.Println("Error al conectarse a la base de datos:", err.Error())
+		slog.Error("database ping failed", "path", path, "error", err)
 		return err
 	}
 
@@ -45,6 +56,15 @@ func InitDB() error {
 		return err
 	}
 
+	if err = migrate(); err != nil {
+		return err
+	}
+
+	if _, err = DB.Exec(migratedIndexes); err != nil {
+		slog.Error("create indexes failed", "error", err)
+		return err
+	}
+
 	return nil
 }
 
@@ -64,19 +84,64 @@ func createTables() error {
         hashed_password TEXT NOT NULL,
         first_name TEXT NOT NULL,
         last_name TEXT NOT NULL,
+        language TEXT NOT NULL DEFAULT '',
 		deleted INTEGER NOT NULL DEFAULT 0,
+        version INTEGER NOT NULL DEFAULT 1,
         created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
         updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
     );
 
+    CREATE TABLE IF NOT EXISTS vehicle_types (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        code TEXT NOT NULL UNIQUE,
+        name TEXT NOT NULL,
+        electric INTEGER NOT NULL DEFAULT 0,
+        cost_per_minute INTEGER NOT NULL,
+        unlock_fee INTEGER NOT NULL DEFAULT 0,
+        range_km INTEGER,
+        max_speed_kmh INTEGER,
+        max_load_kg INTEGER,
+        version INTEGER NOT NULL DEFAULT 1
+    );
+
+    CREATE TABLE IF NOT EXISTS stations (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        name TEXT NOT NULL,
+        latitude REAL NOT NULL,
+        longitude REAL NOT NULL,
+        capacity INTEGER NOT NULL CHECK (capacity > 0),
+        active INTEGER NOT NULL DEFAULT 1,
+        created_at DATETIME NOT NULL,
+        updated_at DATETIME NOT NULL,
+        version INTEGER NOT NULL DEFAULT 1
+    );
+
     CREATE TABLE IF NOT EXISTS bikes (
         id INTEGER PRIMARY KEY AUTOINCREMENT,
-        is_available INTEGER NOT NULL DEFAULT 1,
         latitude REAL NOT NULL,
         longitude REAL NOT NULL,
         created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
         updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		cost_per_minute INTEGER NOT NULL
+        cost_per_minute_override INTEGER,
+        version INTEGER NOT NULL DEFAULT 1,
+        battery_level INTEGER,
+        lock_state TEXT,
+        last_seen_at DATETIME,
+        vehicle_type TEXT NOT NULL DEFAULT 'classic',
+        status TEXT NOT NULL DEFAULT 'available' CHECK (status IN ('available', 'reserved', 'rented', 'maintenance', 'missing', 'retired')),
+        station_id INTEGER REFERENCES stations(id),
+        dock INTEGER
+    );
+
+    CREATE TABLE IF NOT EXISTS bike_status_history (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        bike_id INTEGER NOT NULL,
+        from_status TEXT,
+        to_status TEXT NOT NULL,
+        reason TEXT NOT NULL,
+        actor TEXT NOT NULL,
+        created_at DATETIME NOT NULL,
+        FOREIGN KEY (bike_id) REFERENCES bikes(id) ON DELETE CASCADE
     );
 
     CREATE TABLE IF NOT EXISTS rentals (
@@ -92,22 +157,367 @@ func createTables() error {
         end_longitude REAL,
 		duration INTEGER,
 		cost INTEGER,
+        distance_m INTEGER,
+        version INTEGER NOT NULL DEFAULT 1,
+        start_station_id INTEGER REFERENCES stations(id),
+        end_station_id INTEGER REFERENCES stations(id),
+        end_reason TEXT CHECK (end_reason IN ('rider', 'max_duration', 'idle')),
+        penalty_fee INTEGER,
+        warned_at DATETIME,
+        auto_end_at DATETIME,
         FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
         FOREIGN KEY (bike_id) REFERENCES bikes(id) ON DELETE CASCADE,
         CHECK (rental_status IN ('running', 'ended'))
     );
 
-    CREATE INDEX IF NOT EXISTS idx_bikes_available ON bikes(is_available);
+    CREATE TABLE IF NOT EXISTS rental_route_points (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        rental_id INTEGER NOT NULL,
+        recorded_at DATETIME NOT NULL,
+        latitude REAL NOT NULL,
+        longitude REAL NOT NULL,
+        accuracy REAL,
+        source TEXT NOT NULL,
+        FOREIGN KEY (rental_id) REFERENCES rentals(id) ON DELETE CASCADE,
+        CHECK (source IN ('device', 'app'))
+    );
+
+    CREATE TABLE IF NOT EXISTS work_orders (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        bike_id INTEGER NOT NULL,
+        category TEXT NOT NULL,
+        priority TEXT NOT NULL DEFAULT 'normal',
+        status TEXT NOT NULL DEFAULT 'open',
+        description TEXT NOT NULL DEFAULT '',
+        mechanic TEXT,
+        resolution TEXT,
+        created_at DATETIME NOT NULL,
+        updated_at DATETIME NOT NULL,
+        assigned_at DATETIME,
+        resolved_at DATETIME,
+        version INTEGER NOT NULL DEFAULT 1,
+        FOREIGN KEY (bike_id) REFERENCES bikes(id) ON DELETE CASCADE,
+        CHECK (priority IN ('normal', 'critical')),
+        CHECK (status IN ('open', 'assigned', 'in_progress', 'resolved'))
+    );
+
+    CREATE TABLE IF NOT EXISTS damage_reports (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        bike_id INTEGER NOT NULL,
+        user_id INTEGER NOT NULL,
+        work_order_id INTEGER NOT NULL,
+        category TEXT NOT NULL,
+        description TEXT NOT NULL,
+        photo TEXT,
+        photo_type TEXT,
+        created_at DATETIME NOT NULL,
+        FOREIGN KEY (bike_id) REFERENCES bikes(id) ON DELETE CASCADE,
+        FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+        FOREIGN KEY (work_order_id) REFERENCES work_orders(id) ON DELETE CASCADE
+    );
+
+    CREATE TABLE IF NOT EXISTS idempotency_keys (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        user_id INTEGER NOT NULL,
+        idempotency_key TEXT NOT NULL,
+        fingerprint TEXT NOT NULL,
+        status_code INTEGER,
+        content_type TEXT NOT NULL DEFAULT '',
+        response_body TEXT NOT NULL DEFAULT '',
+        created_at DATETIME NOT NULL,
+        expires_at DATETIME NOT NULL,
+        UNIQUE (user_id, idempotency_key)
+    );
+
+    CREATE TABLE IF NOT EXISTS audit_log (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        actor TEXT NOT NULL,
+        action TEXT NOT NULL,
+        entity_type TEXT NOT NULL,
+        entity_id INTEGER NOT NULL,
+        changes TEXT NOT NULL,
+        request_id TEXT NOT NULL DEFAULT '',
+        ip TEXT NOT NULL DEFAULT '',
+        created_at DATETIME NOT NULL,
+        prev_hash TEXT NOT NULL,
+        hash TEXT NOT NULL
+    );
+
+    CREATE TABLE IF NOT EXISTS outbox_events (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        event_type TEXT NOT NULL,
+        entity_type TEXT NOT NULL,
+        entity_id INTEGER NOT NULL,
+        payload TEXT NOT NULL,
+        request_id TEXT NOT NULL DEFAULT '',
+        created_at DATETIME NOT NULL,
+        dispatched_at DATETIME
+    );
+
+    CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        url TEXT NOT NULL,
+        description TEXT NOT NULL DEFAULT '',
+        event_types TEXT NOT NULL,
+        secret TEXT NOT NULL,
+        is_active INTEGER NOT NULL DEFAULT 1,
+        version INTEGER NOT NULL DEFAULT 1,
+        created_at DATETIME NOT NULL,
+        updated_at DATETIME NOT NULL
+    );
+
+    CREATE TABLE IF NOT EXISTS webhook_deliveries (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        subscription_id INTEGER NOT NULL,
+        event_id INTEGER NOT NULL,
+        event_type TEXT NOT NULL,
+        status TEXT NOT NULL DEFAULT 'pending',
+        attempts INTEGER NOT NULL DEFAULT 0,
+        next_attempt_at DATETIME NOT NULL,
+        last_status_code INTEGER,
+        last_error TEXT NOT NULL DEFAULT '',
+        last_duration_ms INTEGER,
+        created_at DATETIME NOT NULL,
+        updated_at DATETIME NOT NULL,
+        delivered_at DATETIME,
+        FOREIGN KEY (subscription_id) REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+        FOREIGN KEY (event_id) REFERENCES outbox_events(id),
+        UNIQUE (subscription_id, event_id),
+        CHECK (status IN ('pending', 'succeeded', 'dead'))
+    );
+
+    CREATE TABLE IF NOT EXISTS devices (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        bike_id INTEGER NOT NULL UNIQUE,
+        token_hash TEXT NOT NULL UNIQUE,
+        created_at DATETIME NOT NULL,
+        last_seen_at DATETIME,
+        FOREIGN KEY (bike_id) REFERENCES bikes(id) ON DELETE CASCADE
+    );
+
+    CREATE TABLE IF NOT EXISTS rebalancing_tasks (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        from_cell TEXT NOT NULL,
+        from_latitude REAL NOT NULL,
+        from_longitude REAL NOT NULL,
+        to_cell TEXT NOT NULL,
+        to_latitude REAL NOT NULL,
+        to_longitude REAL NOT NULL,
+        bikes INTEGER NOT NULL CHECK (bikes > 0),
+        distance_m INTEGER NOT NULL,
+        status TEXT NOT NULL DEFAULT 'open',
+        forecast_hour DATETIME NOT NULL,
+        claimed_by TEXT,
+        claimed_at DATETIME,
+        completed_at DATETIME,
+        moved INTEGER,
+        created_at DATETIME NOT NULL,
+        updated_at DATETIME NOT NULL,
+        version INTEGER NOT NULL DEFAULT 1,
+        CHECK (status IN ('open', 'claimed', 'completed', 'cancelled'))
+    );
+
+    CREATE TABLE IF NOT EXISTS incidents (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        bike_id INTEGER NOT NULL,
+        rental_id INTEGER,
+        rule TEXT NOT NULL,
+        severity TEXT NOT NULL,
+        status TEXT NOT NULL DEFAULT 'open',
+        details TEXT NOT NULL DEFAULT '{}',
+        latitude REAL NOT NULL,
+        longitude REAL NOT NULL,
+        occurrences INTEGER NOT NULL DEFAULT 1,
+        first_seen_at DATETIME NOT NULL,
+        last_seen_at DATETIME NOT NULL,
+        acknowledged_by TEXT,
+        acknowledged_at DATETIME,
+        resolved_by TEXT,
+        resolved_at DATETIME,
+        resolution TEXT,
+        updated_at DATETIME NOT NULL,
+        version INTEGER NOT NULL DEFAULT 1,
+        FOREIGN KEY (bike_id) REFERENCES bikes(id) ON DELETE CASCADE,
+        FOREIGN KEY (rental_id) REFERENCES rentals(id),
+        CHECK (status IN ('open', 'acknowledged', 'resolved')),
+        CHECK (severity IN ('low', 'medium', 'high', 'critical'))
+    );
+
+    -- catálogo inicial de tipos de vehículo, los cambios del admin se conservan
+    INSERT OR IGNORE INTO vehicle_types (code, name, electric, cost_per_minute, unlock_fee, range_km, max_speed_kmh, max_load_kg) VALUES
+        ('classic', 'Bicicleta clásica', 0, 10, 0, NULL, NULL, NULL),
+        ('e-bike', 'Bicicleta eléctrica', 1, 15, 50, 60, 25, NULL),
+        ('cargo', 'Bicicleta de carga', 0, 20, 0, NULL, NULL, 100),
+        ('scooter', 'Monopatín eléctrico', 1, 18, 50, 35, 25, NULL);
+
+    -- el log de auditoría es solo de inserción
+    CREATE TRIGGER IF NOT EXISTS audit_log_no_update BEFORE UPDATE ON audit_log
+    BEGIN
+        SELECT RAISE(ABORT, 'audit_log es solo de inserción');
+    END;
+    CREATE TRIGGER IF NOT EXISTS audit_log_no_delete BEFORE DELETE ON audit_log
+    BEGIN
+        SELECT RAISE(ABORT, 'audit_log es solo de inserción');
+    END;
+
+    CREATE INDEX IF NOT EXISTS idx_bike_status_history ON bike_status_history(bike_id, id);
     CREATE INDEX IF NOT EXISTS idx_rentals_user ON rentals(user_id);
     CREATE INDEX IF NOT EXISTS idx_rentals_bike ON rentals(bike_id);
     CREATE INDEX IF NOT EXISTS idx_rentals_status ON rentals(rental_status);
+    CREATE INDEX IF NOT EXISTS idx_route_points_rental ON rental_route_points(rental_id, recorded_at);
+    CREATE INDEX IF NOT EXISTS idx_work_orders_bike ON work_orders(bike_id, status);
+    CREATE INDEX IF NOT EXISTS idx_damage_reports_order ON damage_reports(work_order_id);
+    CREATE INDEX IF NOT EXISTS idx_audit_entity ON audit_log(entity_type, entity_id);
+    CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox_events(id) WHERE dispatched_at IS NULL;
+    CREATE INDEX IF NOT EXISTS idx_deliveries_due ON webhook_deliveries(status, next_attempt_at);
+    CREATE INDEX IF NOT EXISTS idx_rebalancing_status ON rebalancing_tasks(status);
+    -- un solo incidente sin resolver por regla y bicicleta, las coincidencias nuevas se suman a ese
+    CREATE UNIQUE INDEX IF NOT EXISTS idx_incidents_unresolved ON incidents(bike_id, rule) WHERE status != 'resolved';
+    CREATE INDEX IF NOT EXISTS idx_incidents_status ON incidents(status, last_seen_at);
     `
 
 	_, err := DB.Exec(schema)
 	if err != nil {
-		log.Println("Error al inicializar la base de datos: ", err.Error())
+		slog.Error("create tables failed", "error", err)
 		return err
 	}
 
 	return nil
 }
+
+// columnas agregadas después de la creación inicial de las tablas, para bases ya existentes
+var migrations = []struct {
+	table      string
+	column     string
+	definition string
+}{
+	{"users", "language", "TEXT NOT NULL DEFAULT ''"},
+	{"users", "version", "INTEGER NOT NULL DEFAULT 1"},
+	{"bikes", "version", "INTEGER NOT NULL DEFAULT 1"},
+	{"rentals", "version", "INTEGER NOT NULL DEFAULT 1"},
+	{"bikes", "battery_level", "INTEGER"},
+	{"bikes", "lock_state", "TEXT"},
+	{"bikes", "last_seen_at", "DATETIME"},
+	{"rentals", "distance_m", "INTEGER"},
+	{"bikes", "vehicle_type", "TEXT NOT NULL DEFAULT 'classic'"},
+	{"bikes", "status", "TEXT NOT NULL DEFAULT 'available' CHECK (status IN ('available', 'reserved', 'rented', 'maintenance', 'missing', 'retired'))"},
+	{"bikes", "station_id", "INTEGER REFERENCES stations(id)"},
+	{"bikes", "dock", "INTEGER"},
+	{"rentals", "start_station_id", "INTEGER REFERENCES stations(id)"},
+	{"rentals", "end_station_id", "INTEGER REFERENCES stations(id)"},
+	{"rentals", "end_reason", "TEXT CHECK (end_reason IN ('rider', 'max_duration', 'idle'))"},
+	{"rentals", "penalty_fee", "INTEGER"},
+	{"rentals", "warned_at", "DATETIME"},
+	{"rentals", "auto_end_at", "DATETIME"},
+	{"bikes", "cost_per_minute_override", "INTEGER"},
+}
+
+// backfills: Completan con los datos existentes una columna recién agregada (tabla.columna),
+// corren en la misma transacción que la agrega
+var backfills = map[string]string{
+	// el estado reemplaza a is_available: las que tienen un alquiler en curso quedan alquiladas
+	// y las que no estaban disponibles pasan a mantenimiento
+	"bikes.status": `
+    UPDATE bikes SET status = CASE
+        WHEN EXISTS (SELECT 1 FROM rentals r WHERE r.bike_id = bikes.id AND r.rental_status = 'running') THEN 'rented'
+        WHEN is_available = 1 THEN 'available'
+        ELSE 'maintenance'
+    END;
+    INSERT INTO bike_status_history (bike_id, from_status, to_status, reason, actor, created_at)
+        SELECT id, NULL, status, 'migration', 'system', CURRENT_TIMESTAMP FROM bikes;
+    DROP INDEX IF EXISTS idx_bikes_available;
+    ALTER TABLE bikes DROP COLUMN is_available;
+    `,
+	// los alquileres finalizados antes del cierre automático los finalizó el usuario
+	"rentals.end_reason": `UPDATE rentals SET end_reason = 'rider' WHERE rental_status = 'ended'`,
+	// la tarifa pasa a ser la del tipo de vehículo: solo las bicicletas con una tarifa distinta a la de su tipo
+	// conservan la suya
+	"bikes.cost_per_minute_override": `
+    UPDATE bikes SET cost_per_minute_override = cost_per_minute
+        WHERE cost_per_minute IS NOT (SELECT t.cost_per_minute FROM vehicle_types t WHERE t.code = bikes.vehicle_type);
+    ALTER TABLE bikes DROP COLUMN cost_per_minute;
+    `,
+}
+
+// migratedIndexes: Índices sobre columnas que pueden venir de una migración, se crean después de migrar
+const migratedIndexes = `
+    CREATE INDEX IF NOT EXISTS idx_bikes_status ON bikes(status);
+    CREATE UNIQUE INDEX IF NOT EXISTS idx_bikes_dock ON bikes(station_id, dock) WHERE station_id IS NOT NULL;
+    `
+
+// migrate: Agrega a las tablas existentes las columnas que les falten
+func migrate() error {
+	for _, m := range migrations {
+		exists, err := columnExists(context.Background(), m.table, m.column)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+
+		if err := addColumn(m.table, m.column, m.definition); err != nil {
+			slog.Error("add column failed", "table", m.table, "column", m.column, "error", err)
+			return err
+		}
+		slog.Info("column added", "table", m.table, "column", m.column)
+	}
+
+	return nil
+}
+
+// addColumn: Agrega la columna y corre su backfill, si tiene, en una sola transacción
+func addColumn(table string, column string, definition string) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("ALTER TABLE " + table + " ADD COLUMN " + column + " " + definition); err != nil {
+		return err
+	}
+	if backfill, ok := backfills[table+"."+column]; ok {
+		if _, err := tx.Exec(backfill); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// PendingMigrations: Columnas (tabla.columna) de las migraciones que todavía no existen en la base de datos
+func PendingMigrations(ctx context.Context) ([]string, error) {
+	pending := []string{}
+	for _, m := range migrations {
+		exists, err := columnExists(ctx, m.table, m.column)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			pending = append(pending, m.table+"."+m.column)
+		}
+	}
+
+	return pending, nil
+}
+
+// columnExists: Revisa si la columna existe en la tabla
+func columnExists(ctx context.Context, table string, column string) (bool, error) {
+	rows, err := DB.QueryContext(ctx, "SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+
+	return false, rows.Err()
+}
@@ -0,0 +1,114 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// bikesWithCost: Tabla bikes anterior a la tarifa por tipo de vehículo, con la tarifa copiada en cada bicicleta
+const bikesWithCost = `
+    CREATE TABLE bikes (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        latitude REAL NOT NULL,
+        longitude REAL NOT NULL,
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        cost_per_minute INTEGER NOT NULL,
+        version INTEGER NOT NULL DEFAULT 1,
+        battery_level INTEGER,
+        lock_state TEXT,
+        last_seen_at DATETIME,
+        vehicle_type TEXT NOT NULL DEFAULT 'classic',
+        status TEXT NOT NULL DEFAULT 'available',
+        station_id INTEGER,
+        dock INTEGER
+    );
+    INSERT INTO bikes (latitude, longitude, cost_per_minute, vehicle_type) VALUES
+        (0, 0, 10, 'classic'),
+        (0, 0, 7, 'classic'),
+        (0, 0, 15, 'e-bike');
+    `
+
+func TestMigrateCostPerMinuteOverride(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	old, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := old.Exec(bikesWithCost); err != nil {
+		t.Fatal(err)
+	}
+	old.Close()
+
+	if err := InitDB(path); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { CloseDB() })
+
+	// solo la bicicleta con una tarifa distinta a la de su tipo conserva la suya
+	rows, err := DB.Query("SELECT id, cost_per_minute_override FROM bikes ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	want := map[int64]sql.NullInt64{1: {}, 2: {Int64: 7, Valid: true}, 3: {}}
+	for rows.Next() {
+		var id int64
+		var override sql.NullInt64
+		if err := rows.Scan(&id, &override); err != nil {
+			t.Fatal(err)
+		}
+		if override != want[id] {
+			t.Errorf("bicicleta %d: override = %v, se esperaba %v", id, override, want[id])
+		}
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if exists, err := columnExists(context.Background(), "bikes", "cost_per_minute"); err != nil || exists {
+		t.Errorf("la columna cost_per_minute sigue en bikes (%v)", err)
+	}
+	pending, err := PendingMigrations(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) > 0 {
+		t.Errorf("migraciones pendientes: %v", pending)
+	}
+}
+
+func TestAuditLogAppendOnly(t *testing.T) {
+	if err := InitDB(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { CloseDB() })
+
+	_, err := DB.Exec("INSERT INTO audit_log (actor, action, entity_type, entity_id, changes, created_at, prev_hash, hash) VALUES ('admin', 'bike.create', 'bike', 1, '{}', CURRENT_TIMESTAMP, '', 'h1')")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"update": "UPDATE audit_log SET changes = '{\"cost\":1}' WHERE id = 1",
+		"delete": "DELETE FROM audit_log WHERE id = 1",
+	}
+	for name, query := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := DB.Exec(query); err == nil || !strings.Contains(err.Error(), "audit_log es solo de inserción") {
+				t.Errorf("error = %v, se esperaba que el trigger rechace la modificación", err)
+			}
+		})
+	}
+
+	var changes, hash string
+	if err := DB.QueryRow("SELECT changes, hash FROM audit_log WHERE id = 1").Scan(&changes, &hash); err != nil {
+		t.Fatal(err)
+	}
+	if changes != "{}" || hash != "h1" {
+		t.Errorf("registro = %s %s, se esperaba sin cambios", changes, hash)
+	}
+}
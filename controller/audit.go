@@ -0,0 +1,163 @@
+package controller
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mbarolo/test_back/apperror"
+	"github.com/mbarolo/test_back/forms"
+	"github.com/mbarolo/test_back/i18n"
+	"github.com/mbarolo/test_back/models"
+	"github.com/mbarolo/test_back/services"
+	"github.com/mbarolo/test_back/utils"
+)
+
+// GetAuditLog godoc
+// @Summary      Log de auditoría
+// @Description  Listar las acciones que modificaron el estado del sistema, con el diff de cada cambio (admin)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BasicAuth
+// @Param        limit        query     int     false  "Cantidad de resultados por página"
+// @Param        cursor       query     string  false  "Cursor de la página siguiente"
+// @Param        sort         query     string  false  "Campo de ordenamiento, con - para descendente (default: -id)"
+// @Param        actor        query     string  false  "Filtrar por actor (ej: admin, user:3)"
+// @Param        action       query     string  false  "Filtrar por acción (ej: bike.update)"
+// @Param        entity_type  query     string  false  "Filtrar por tipo de entidad (user, bike, rental)"
+// @Param        entity_id    query     int     false  "Filtrar por id de la entidad"
+// @Param        request_id   query     string  false  "Filtrar por request id"
+// @Param        from         query     string  false  "Fecha desde (RFC3339 o 2006-01-02)"
+// @Param        to           query     string  false  "Fecha hasta (RFC3339 o 2006-01-02)"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /admin/audit [get]
+func GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	spec, err := utils.ParseQuerySpec(r.URL.Query(), forms.AuditQuery)
+	if err != nil {
+		utils.ErrorResponse(w, r, "request.query_error", err)
+		return
+	}
+
+	entries, err := services.GetAuditLog(r.Context(), spec)
+	if err != nil {
+		utils.ErrorResponse(w, r, "audit.list_error", err)
+		return
+	}
+
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "audit.list_ok", i18n.Params{"count": entries.TotalCount}), entries)
+}
+
+// formatos de exportación del log de auditoría
+var auditExporters = map[string]struct {
+	contentType string
+	encode      func(w io.Writer, entry *models.AuditEntry) error
+}{
+	"ndjson": {"application/x-ndjson", encodeAuditNDJSON},
+	"csv":    {"text/csv", encodeAuditCSV},
+}
+
+var auditCSVHeader = []string{"id", "created_at", "actor", "action", "entity_type", "entity_id", "request_id", "ip", "changes", "prev_hash", "hash"}
+
+// ExportAuditLog godoc
+// @Summary      Exportar log de auditoría
+// @Description  Descargar los registros de auditoría que cumplen los filtros, en orden cronológico, como NDJSON o CSV (admin)
+// @Tags         admin
+// @Produce      application/x-ndjson
+// @Produce      text/csv
+// @Security     BasicAuth
+// @Param        format       query     string  false  "Formato: ndjson (default) o csv"
+// @Param        actor        query     string  false  "Filtrar por actor (ej: admin, user:3)"
+// @Param        action       query     string  false  "Filtrar por acción (ej: bike.update)"
+// @Param        entity_type  query     string  false  "Filtrar por tipo de entidad (user, bike, rental)"
+// @Param        entity_id    query     int     false  "Filtrar por id de la entidad"
+// @Param        request_id   query     string  false  "Filtrar por request id"
+// @Param        from         query     string  false  "Fecha desde (RFC3339 o 2006-01-02)"
+// @Param        to           query     string  false  "Fecha hasta (RFC3339 o 2006-01-02)"
+// @Success      200  {string}  string
+// @Failure      400  {object}  map[string]interface{}
+// @Router       /admin/audit/export [get]
+func ExportAuditLog(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "ndjson"
+	}
+	exporter, ok := auditExporters[format]
+	if !ok {
+		utils.ErrorResponse(w, r, "request.query_error", apperror.BadRequest("query.invalid_param").WithParams(i18n.Params{"param": "format"}))
+		return
+	}
+
+	spec, err := utils.ParseQuerySpec(r.URL.Query(), forms.AuditExportQuery)
+	if err != nil {
+		utils.ErrorResponse(w, r, "request.query_error", err)
+		return
+	}
+
+	filename := "audit-" + time.Now().Format("20060102-150405") + "." + format
+	w.Header().Set("Content-Type", exporter.contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	if format == "csv" {
+		if err := encodeCSVRow(w, auditCSVHeader); err != nil {
+			return
+		}
+	}
+
+	// la respuesta ya comenzó, un error a mitad de la exportación solo se registra en el log
+	services.ExportAuditLog(r.Context(), spec, w, exporter.encode)
+}
+
+// VerifyAuditLog godoc
+// @Summary      Verificar log de auditoría
+// @Description  Recorrer la cadena de hashes del log de auditoría y detectar registros alterados o eliminados (admin)
+// @Tags         admin
+// @Produce      json
+// @Security     BasicAuth
+// @Success      200  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /admin/audit/verify [get]
+func VerifyAuditLog(w http.ResponseWriter, r *http.Request) {
+	result, err := services.VerifyAuditLog(r.Context())
+	if err != nil {
+		utils.ErrorResponse(w, r, "audit.verify_error", err)
+		return
+	}
+
+	message := "audit.chain_valid"
+	if !result.Valid {
+		message = "audit.chain_broken"
+	}
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, message, i18n.Params{"count": result.Checked}), result)
+}
+
+func encodeAuditNDJSON(w io.Writer, entry *models.AuditEntry) error {
+	return json.NewEncoder(w).Encode(entry)
+}
+
+func encodeAuditCSV(w io.Writer, entry *models.AuditEntry) error {
+	return encodeCSVRow(w, []string{
+		strconv.FormatInt(entry.Id, 10),
+		entry.CreatedAt.Format(time.RFC3339Nano),
+		entry.Actor,
+		entry.Action,
+		entry.EntityType,
+		strconv.FormatInt(entry.EntityId, 10),
+		entry.RequestId,
+		entry.Ip,
+		string(entry.Changes),
+		entry.PrevHash,
+		entry.Hash,
+	})
+}
+
+func encodeCSVRow(w io.Writer, row []string) error {
+	writer := csv.NewWriter(w)
+	writer.Write(row)
+	writer.Flush()
+	return writer.Error()
+}
@@ -1,28 +1,17 @@
 package controller
 
 import (
-	"encoding/json"
 	"net/http"
 
 	"github.com/mbarolo/test_back/forms"
+	"github.com/mbarolo/test_back/i18n"
+	"github.com/mbarolo/test_back/metrics"
 	"github.com/mbarolo/test_back/middleware"
 	"github.com/mbarolo/test_back/models"
 	"github.com/mbarolo/test_back/services"
 	"github.com/mbarolo/test_back/utils"
-	"golang.org/x/crypto/bcrypt"
 )
 
-func comparePasswords(hash string, password string) error {
-	byteHash := []byte(hash)
-	byteLogin := []byte(password)
-	err := bcrypt.CompareHashAndPassword(byteHash, byteLogin)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
 // Login godoc
 // @Summary      Iniciar sesión
 // @Description  Autenticar usuario y obtener token JWT
@@ -33,36 +22,35 @@ func comparePasswords(hash string, password string) error {
 // @Success      200          {object}  map[string]interface{}
 // @Failure      400          {object}  map[string]interface{}
 // @Failure      401          {object}  map[string]interface{}
+// @Failure      429          {object}  map[string]interface{}
 // @Router       /auth/login [post]
 func Login(w http.ResponseWriter, r *http.Request) {
 	var loginData models.Login
-	if err := json.NewDecoder(r.Body).Decode(&loginData); err != nil {
-		utils.JsonResponse(w, http.StatusBadRequest, "Error al decodificar cuerpo de la solicitud: "+err.Error(), nil)
+	if err := decodeBody(w, r, &loginData, false); err != nil {
+		utils.ErrorResponse(w, r, "auth.login_error", err)
 		return
 	}
 
-	if loginData.Email == "" || loginData.Password == "" {
-		utils.JsonResponse(w, http.StatusBadRequest, "Email o contraseña vacíos", nil)
-		return
-	}
-
-	user, err := services.GetUserByEmail(loginData.Email)
+	user, err := services.GetUserByEmail(r.Context(), loginData.Email)
 	if err != nil {
-		utils.JsonResponse(w, http.StatusUnauthorized, "La información de inicio de sesión es incorrecta", nil)
+		metrics.LoginFailures.WithLabelValues(metrics.LoginUnknownUser).Inc()
+		utils.JsonResponse(w, http.StatusUnauthorized, i18n.T(r, "auth.login_invalid"), nil)
 		return
 	}
 
-	if err = comparePasswords(user.HashedPassword, loginData.Password); err != nil {
-		utils.JsonResponse(w, http.StatusUnauthorized, "La información de inicio de sesión es incorrecta", nil)
+	if err = services.ComparePassword(r.Context(), user.HashedPassword, loginData.Password); err != nil {
+		metrics.LoginFailures.WithLabelValues(metrics.LoginWrongPassword).Inc()
+		utils.JsonResponse(w, http.StatusUnauthorized, i18n.T(r, "auth.login_invalid"), nil)
 		return
 	}
 
 	token, exp, err := middleware.GenerateToken(*user)
 	if err != nil {
-		utils.JsonResponse(w, http.StatusUnauthorized, "Error al iniciar sesión: ", err.Error())
+		utils.ErrorResponse(w, r, "auth.login_error", err)
+		return
 	}
 
-	utils.JsonResponse(w, http.StatusOK, "Sesión iniciada correctamente", models.LoginResponse{Token: token, Expire: exp.String()})
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "auth.login_ok"), models.LoginResponse{Token: token, Expire: exp.String()})
 }
 
 // Register godoc
@@ -74,29 +62,30 @@ func Login(w http.ResponseWriter, r *http.Request) {
 // @Param        user  body      forms.UserForm  true  "Datos del nuevo usuario"
 // @Success      201   {object}  map[string]interface{}
 // @Failure      400   {object}  map[string]interface{}
+// @Failure      429   {object}  map[string]interface{}
 // @Failure      500   {object}  map[string]interface{}
 // @Router       /auth/register [post]
 func Register(w http.ResponseWriter, r *http.Request) {
-	var userForm *forms.UserForm
-	if err := json.NewDecoder(r.Body).Decode(&userForm); err != nil {
-		utils.JsonResponse(w, http.StatusBadRequest, "Error al decodificar el cuerpo de la solicitud: "+err.Error(), nil)
+	var userForm forms.UserForm
+	if err := decodeBody(w, r, &userForm, false); err != nil {
+		utils.ErrorResponse(w, r, "user.create_error", err)
 		return
 	}
-	defer r.Body.Close()
 
 	// Se hashea la contraseña del usuario
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(userForm.HashedPassword), bcrypt.DefaultCost)
+	hashedPassword, err := services.HashPassword(r.Context(), userForm.Password)
 	if err != nil {
-		utils.JsonResponse(w, http.StatusInternalServerError, "Error al hashear la contraseña: "+err.Error(), nil)
+		utils.ErrorResponse(w, r, "auth.hash_error", err)
 		return
 	}
-	userForm.HashedPassword = string(hashedPassword)
+	newUser := userForm.ToUser()
+	newUser.HashedPassword = hashedPassword
 
-	user, err := services.CreateUser(userForm.ToUser())
+	user, err := services.CreateUser(r.Context(), newUser)
 	if err != nil {
-		utils.JsonResponse(w, http.StatusInternalServerError, "Error al crear el usuario: "+err.Error(), nil)
+		utils.ErrorResponse(w, r, "user.create_error", err)
 		return
 	}
 
-	utils.JsonResponse(w, http.StatusCreated, "Usuario creado correctamente", user)
+	utils.JsonResponse(w, http.StatusCreated, i18n.T(r, "user.created"), user)
 }
@@ -1,34 +1,43 @@
 package controller
 
 import (
-	"encoding/json"
 	"net/http"
-	"strconv"
 
-	"github.com/go-chi/chi/v5"
 	"github.com/mbarolo/test_back/forms"
+	"github.com/mbarolo/test_back/i18n"
 	"github.com/mbarolo/test_back/services"
 	"github.com/mbarolo/test_back/utils"
 )
 
 // GetAvailableBikes godoc
 // @Summary      Obtener bicicletas disponibles
-// @Description  Retorna las bicicletas disponibles para alquilar
+// @Description  Retorna las bicicletas disponibles para alquilar. Con near retorna las que están dentro del radio, de la más cercana a la más lejana
 // @Tags         bikes
 // @Accept       json
 // @Produce      json
 // @Security     BearerAuth
+// @Param        type         query     string  false  "Filtrar por tipo de vehículo (classic, e-bike, cargo, scooter)"
+// @Param        min_battery  query     int     false  "Batería mínima en porcentaje, excluye los vehículos sin batería conocida"
+// @Param        near         query     string  false  "Buscar cerca de esta ubicación: lat,lng"
+// @Param        radius       query     number  false  "Radio de búsqueda en metros, con near (default: 1000, máximo: 20000)"
 // @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
 // @Failure      500  {object}  map[string]interface{}
 // @Router       /bikes/available [get]
 func GetAvailableBikes(w http.ResponseWriter, r *http.Request) {
-	bikes, err := services.GetAvailableBikes()
+	query, err := forms.ParseAvailableBikesQuery(r.URL.Query())
 	if err != nil {
-		utils.JsonResponse(w, http.StatusInternalServerError, "Error al obtener las bicicletas:"+err.Error(), nil)
+		utils.ErrorResponse(w, r, "request.query_error", err)
 		return
 	}
 
-	utils.JsonResponse(w, http.StatusOK, "Bicicletas obtenidas", bikes)
+	bikes, err := services.GetAvailableBikes(r.Context(), query)
+	if err != nil {
+		utils.ErrorResponse(w, r, "bike.list_error", err)
+		return
+	}
+
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "bike.list_ok", i18n.Params{"count": len(bikes)}), bikes)
 }
 
 // GetAllBikes godoc
@@ -38,22 +47,36 @@ func GetAvailableBikes(w http.ResponseWriter, r *http.Request) {
 // @Accept       json
 // @Produce      json
 // @Security     BasicAuth
+// @Param        limit         query     int     false  "Cantidad de resultados por página"
+// @Param        cursor        query     string  false  "Cursor de la página siguiente"
+// @Param        sort          query     string  false  "Campo de ordenamiento, con - para descendente (ej: -created_at)"
+// @Param        status        query     string  false  "Filtrar por estado (available, reserved, rented, maintenance, missing, retired)"
+// @Param        vehicle_type  query     string  false  "Filtrar por tipo de vehículo"
+// @Param        from          query     string  false  "Fecha de creación desde (RFC3339 o 2006-01-02)"
+// @Param        to            query     string  false  "Fecha de creación hasta (RFC3339 o 2006-01-02)"
 // @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
 // @Failure      500  {object}  map[string]interface{}
 // @Router       /admin/bikes [get]
 func GetAllBikes(w http.ResponseWriter, r *http.Request) {
-	bikes, err := services.GetAllBikes()
+	spec, err := utils.ParseQuerySpec(r.URL.Query(), forms.BikeQuery)
 	if err != nil {
-		utils.JsonResponse(w, http.StatusInternalServerError, "Error al obtener las bicicletas: "+err.Error(), nil)
+		utils.ErrorResponse(w, r, "request.query_error", err)
 		return
 	}
 
-	utils.JsonResponse(w, http.StatusOK, "Bicicletas obtenidas", bikes)
+	bikes, err := services.GetAllBikes(r.Context(), spec)
+	if err != nil {
+		utils.ErrorResponse(w, r, "bike.list_error", err)
+		return
+	}
+
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "bike.list_ok", i18n.Params{"count": bikes.TotalCount}), bikes)
 }
 
 // CreateBike godoc
 // @Summary      Crear bicicleta
-// @Description  Registrar una nueva bicicleta en el sistema (admin)
+// @Description  Registrar una nueva bicicleta en el sistema. Sin cost_per_minute_override cobra la tarifa de su tipo de vehículo (admin)
 // @Tags         admin
 // @Accept       json
 // @Produce      json
@@ -64,58 +87,181 @@ func GetAllBikes(w http.ResponseWriter, r *http.Request) {
 // @Failure      500   {object}  map[string]interface{}
 // @Router       /admin/bikes [post]
 func CreateBike(w http.ResponseWriter, r *http.Request) {
-	var bikeForm *forms.BikeForm
-	if err := json.NewDecoder(r.Body).Decode(&bikeForm); err != nil {
-		utils.JsonResponse(w, http.StatusBadRequest, "Error al decodificar el cuerpo de la solicitud: "+err.Error(), nil)
+	var bikeForm forms.BikeForm
+	if err := decodeBody(w, r, &bikeForm, false); err != nil {
+		utils.ErrorResponse(w, r, "bike.create_error", err)
+		return
+	}
+
+	bike, err := services.CreateBike(r.Context(), &bikeForm)
+	if err != nil {
+		utils.ErrorResponse(w, r, "bike.create_error", err)
+		return
+	}
+
+	utils.JsonResponse(w, http.StatusCreated, i18n.T(r, "bike.created"), bike)
+}
+
+// GetBikeById godoc
+// @Summary      Obtener bicicleta por ID
+// @Description  Obtener información de una bicicleta específica, con su versión en el header ETag (admin)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BasicAuth
+// @Param        id   path      int  true  "ID de la bicicleta"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /admin/bikes/{id} [get]
+func GetBikeById(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIdParam(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "bike.get_error", err)
 		return
 	}
 
-	bike, err := services.CreateBike(bikeForm.ToBike())
+	bike, err := services.GetBikeById(r.Context(), id)
 	if err != nil {
-		utils.JsonResponse(w, http.StatusInternalServerError, "Error al crear la bicicleta: "+err.Error(), nil)
+		utils.ErrorResponse(w, r, "bike.get_error", err)
 		return
 	}
 
-	utils.JsonResponse(w, http.StatusCreated, "Bicicleta creada correctamente", bike)
+	setETag(w, bike.Version)
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "bike.get_ok"), bike)
 }
 
 // UpdateBike godoc
 // @Summary      Actualizar bicicleta
-// @Description  Modificar los datos de una bicicleta existente (admin)
+// @Description  Modificar los datos de una bicicleta existente con JSON Merge Patch (admin)
 // @Tags         admin
 // @Accept       json
 // @Produce      json
 // @Security     BasicAuth
-// @Param        id    path      int             true  "ID de la bicicleta"
-// @Param        bike  body      forms.BikeForm  true  "Datos actualizados de la bicicleta"
+// @Param        id        path      int             true   "ID de la bicicleta"
+// @Param        If-Match  header    string          true  "Versión esperada (ETag) de la bicicleta, * actualiza sin verificarla"
+// @Param        bike      body      forms.BikeForm  true   "Campos a modificar de la bicicleta"
 // @Success      200   {object}  map[string]interface{}
 // @Failure      400   {object}  map[string]interface{}
+// @Failure      412   {object}  map[string]interface{}
+// @Failure      428   {object}  map[string]interface{}
 // @Failure      500   {object}  map[string]interface{}
 // @Router       /admin/bikes/{id} [patch]
 func UpdateBike(w http.ResponseWriter, r *http.Request) {
-	idStr := chi.URLParam(r, "id")
-	if idStr == "" {
-		utils.JsonResponse(w, http.StatusBadRequest, "Parametro id no encontrado", nil)
+	id, err := parseIdParam(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "bike.update_error", err)
+		return
+	}
+
+	ifMatch, err := requireIfMatch(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "bike.update_error", err)
+		return
+	}
+
+	var bikeForm forms.BikeForm
+	patch, err := decodePatch(w, r, &bikeForm)
+	if err != nil {
+		utils.ErrorResponse(w, r, "bike.update_error", err)
+		return
+	}
+
+	bike, err := services.UpdateBike(r.Context(), id, patch, ifMatch)
+	if err != nil {
+		utils.ErrorResponse(w, r, "bike.update_error", err)
+		return
+	}
+
+	setETag(w, bike.Version)
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "bike.updated"), bike)
+}
+
+// ChangeBikeStatus godoc
+// @Summary      Cambiar estado de la bicicleta
+// @Description  Cambia el estado de la bicicleta (available, reserved, maintenance, missing, retired) y lo registra en su historial con el motivo.
+// @Description  Una bicicleta retirada no vuelve a circular y una alquilada solo puede marcarse como perdida (admin)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BasicAuth
+// @Param        id        path      int                   true   "ID de la bicicleta"
+// @Param        If-Match  header    string                false  "Versión esperada (ETag) de la bicicleta"
+// @Param        status    body      forms.BikeStatusForm  true   "Estado nuevo y motivo"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]interface{}
+// @Failure      412  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /admin/bikes/{id}/status [post]
+func ChangeBikeStatus(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIdParam(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "bike.status_error", err)
+		return
+	}
+
+	ifMatch, err := parseIfMatch(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "bike.status_error", err)
+		return
+	}
+
+	var statusForm forms.BikeStatusForm
+	if err := decodeBody(w, r, &statusForm, false); err != nil {
+		utils.ErrorResponse(w, r, "bike.status_error", err)
+		return
+	}
+
+	bike, err := services.ChangeBikeStatus(r.Context(), id, &statusForm, ifMatch)
+	if err != nil {
+		utils.ErrorResponse(w, r, "bike.status_error", err)
 		return
 	}
 
-	var bikeForm *forms.BikeForm
-	if err := json.NewDecoder(r.Body).Decode(&bikeForm); err != nil {
-		utils.JsonResponse(w, http.StatusBadRequest, "Error al decodificar el cuerpo de la solicitud: "+err.Error(), nil)
+	setETag(w, bike.Version)
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "bike.status_changed"), bike)
+}
+
+// GetBikeStatusHistory godoc
+// @Summary      Historial de estados
+// @Description  Cambios de estado de la bicicleta con su motivo y quién los hizo (admin)
+// @Tags         admin
+// @Produce      json
+// @Security     BasicAuth
+// @Param        id         path      int     true   "ID de la bicicleta"
+// @Param        limit      query     int     false  "Cantidad de resultados por página"
+// @Param        cursor     query     string  false  "Cursor de la página siguiente"
+// @Param        sort       query     string  false  "Campo de ordenamiento, con - para descendente (ej: -created_at)"
+// @Param        to_status  query     string  false  "Filtrar por el estado al que pasó"
+// @Param        actor      query     string  false  "Filtrar por quién hizo el cambio (user:<id>, admin, system)"
+// @Param        from       query     string  false  "Fecha desde (RFC3339 o 2006-01-02)"
+// @Param        to         query     string  false  "Fecha hasta (RFC3339 o 2006-01-02)"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /admin/bikes/{id}/status-history [get]
+func GetBikeStatusHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIdParam(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "bike.status_history_error", err)
 		return
 	}
 
-	id, err := strconv.Atoi(idStr)
+	spec, err := utils.ParseQuerySpec(r.URL.Query(), forms.BikeStatusHistoryQuery)
 	if err != nil {
-		utils.JsonResponse(w, http.StatusInternalServerError, "Error al convertir el parametro id: "+err.Error(), nil)
+		utils.ErrorResponse(w, r, "request.query_error", err)
 		return
 	}
 
-	bike, err := services.UpdateBike(int64(id), bikeForm)
+	changes, err := services.GetBikeStatusHistory(r.Context(), id, spec)
 	if err != nil {
-		utils.JsonResponse(w, http.StatusInternalServerError, "Error al actualizar la bicicleta: "+err.Error(), nil)
+		utils.ErrorResponse(w, r, "bike.status_history_error", err)
 		return
 	}
 
-	utils.JsonResponse(w, http.StatusOK, "Bicicleta actualizda correctamente", bike)
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "bike.status_history_ok", i18n.Params{"count": changes.TotalCount}), changes)
 }
@@ -0,0 +1,119 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/mbarolo/test_back/apperror"
+	"github.com/mbarolo/test_back/forms"
+	"github.com/mbarolo/test_back/i18n"
+)
+
+// Tamaño máximo del cuerpo de las solicitudes JSON
+const MaxBodyBytes = 1 << 20
+
+// decodeBody: Decodifica el cuerpo JSON en el form rechazando campos desconocidos y cuerpos demasiado grandes,
+// y luego lo valida. Con partial la regla required no se aplica (PATCH)
+func decodeBody(w http.ResponseWriter, r *http.Request, form interface{}, partial bool) error {
+	body, err := readBody(w, r)
+	if err != nil {
+		return err
+	}
+	return decodeForm(body, form, partial)
+}
+
+// decodePatch: Valida un JSON Merge Patch contra el form del endpoint y retorna el patch para aplicarlo en el servicio
+func decodePatch(w http.ResponseWriter, r *http.Request, form interface{}) ([]byte, error) {
+	body, err := readBody(w, r)
+	if err != nil {
+		return nil, err
+	}
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] != '{' {
+		return nil, apperror.BadRequest("request.patch_not_object")
+	}
+	if err := decodeForm(body, form, true); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+func readBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, MaxBodyBytes)
+	defer r.Body.Close()
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, decodeError(err)
+	}
+	return body, nil
+}
+
+func decodeForm(body []byte, form interface{}, partial bool) error {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(form); err != nil {
+		return decodeError(err)
+	}
+	// el cuerpo debe contener un único objeto JSON
+	if err := decoder.Decode(&struct{}{}); err != io.EOF {
+		return apperror.BadRequest("request.trailing_data")
+	}
+
+	if partial {
+		return forms.ValidatePartial(form)
+	}
+	return forms.Validate(form)
+}
+
+func decodeError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return apperror.New(apperror.TOO_LARGE, "request.too_large").WithParams(i18n.Params{"limit": maxBytesErr.Limit})
+	case errors.Is(err, io.EOF):
+		return apperror.BadRequest("request.empty_body")
+	case errors.As(err, &typeErr):
+		return &apperror.Error{
+			Code:    apperror.BAD_REQUEST,
+			Message: "validation.failed",
+			Fields:  []apperror.FieldError{{Field: typeErr.Field, Message: "validation.type", Params: i18n.Params{"type": jsonTypeName(typeErr.Type)}}},
+		}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return &apperror.Error{
+			Code:    apperror.BAD_REQUEST,
+			Message: "request.unknown_field",
+			Params:  i18n.Params{"field": field},
+			Fields:  []apperror.FieldError{{Field: field, Message: "validation.unknown_field"}},
+		}
+	}
+
+	return apperror.BadRequest("request.invalid_body").WithParams(i18n.Params{"error": err.Error()})
+}
+
+// jsonTypeName: Nombre del tipo JSON esperado para un tipo de Go
+func jsonTypeName(t reflect.Type) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	}
+	return "object"
+}
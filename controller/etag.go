@@ -0,0 +1,44 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/mbarolo/test_back/apperror"
+)
+
+// setETag: Agrega el header ETag con la versión del recurso
+func setETag(w http.ResponseWriter, version int64) {
+	w.Header().Set("ETag", `"`+strconv.FormatInt(version, 10)+`"`)
+	w.Header().Set("Accept-Patch", "application/merge-patch+json")
+}
+
+// requireIfMatch: Versión esperada del header If-Match de los PATCH. Es obligatorio (428) para que un cliente que
+// no lo envía no sobrescriba los cambios de otro; con * se actualiza sin verificar la versión
+func requireIfMatch(r *http.Request) (*int64, error) {
+	if strings.TrimSpace(r.Header.Get("If-Match")) == "" {
+		return nil, apperror.New(apperror.PRECONDITION_REQUIRED, "request.missing_if_match")
+	}
+	return parseIfMatch(r)
+}
+
+// parseIfMatch: Obtiene la versión esperada del header If-Match, opcional en las acciones (POST).
+// Retorna nil si el header no fue enviado o es *, en cuyo caso no se verifica la versión
+func parseIfMatch(r *http.Request) (*int64, error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return nil, nil
+	}
+
+	// If-Match usa comparación fuerte, los ETag débiles (W/) no se aceptan
+	if !strings.HasPrefix(header, `"`) || !strings.HasSuffix(header, `"`) || len(header) < 2 {
+		return nil, apperror.BadRequest("request.invalid_if_match")
+	}
+	version, err := strconv.ParseInt(strings.Trim(header, `"`), 10, 64)
+	if err != nil {
+		return nil, apperror.BadRequest("request.invalid_if_match")
+	}
+
+	return &version, nil
+}
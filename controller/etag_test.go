@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mbarolo/test_back/apperror"
+)
+
+func TestIfMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		required bool
+		version  int64 // 0 si no se verifica la versión
+		status   int   // 0 si no hay error
+	}{
+		{"version", `"3"`, true, 3, 0},
+		{"any version", "*", true, 0, 0},
+		{"missing on patch", "", true, 0, http.StatusPreconditionRequired},
+		{"blank on patch", "  ", true, 0, http.StatusPreconditionRequired},
+		{"missing on action", "", false, 0, 0},
+		{"weak etag", `W/"3"`, true, 0, http.StatusBadRequest},
+		{"not a version", `"abc"`, false, 0, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPatch, "/", nil)
+			if tt.header != "" {
+				r.Header.Set("If-Match", tt.header)
+			}
+
+			var version *int64
+			var err error
+			if tt.required {
+				version, err = requireIfMatch(r)
+			} else {
+				version, err = parseIfMatch(r)
+			}
+
+			var appErr *apperror.Error
+			switch {
+			case tt.status == 0 && err != nil:
+				t.Fatalf("error inesperado: %v", err)
+			case tt.status != 0 && (!errors.As(err, &appErr) || appErr.Code.Status() != tt.status):
+				t.Fatalf("error = %v, se esperaba %d", err, tt.status)
+			}
+			var got int64
+			if version != nil {
+				got = *version
+			}
+			if got != tt.version {
+				t.Errorf("versión = %d, se esperaba %d", got, tt.version)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/mbarolo/test_back/i18n"
+	"github.com/mbarolo/test_back/services"
+	"github.com/mbarolo/test_back/utils"
+)
+
+// Healthz godoc
+// @Summary      Liveness
+// @Description  Indica que el proceso está vivo, no verifica dependencias
+// @Tags         health
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}
+// @Router       /healthz [get]
+func Healthz(w http.ResponseWriter, r *http.Request) {
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "health.alive"), nil)
+}
+
+// Readyz godoc
+// @Summary      Readiness
+// @Description  Indica si el servicio puede recibir tráfico: base de datos accesible, migraciones aplicadas y sin apagado en curso
+// @Tags         health
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}
+// @Failure      503  {object}  map[string]interface{}
+// @Router       /readyz [get]
+func Readyz(w http.ResponseWriter, r *http.Request) {
+	if err := services.CheckReadiness(r.Context()); err != nil {
+		utils.ErrorResponse(w, r, "health.not_ready", err)
+		return
+	}
+
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "health.ready"), nil)
+}
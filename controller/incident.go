@@ -0,0 +1,166 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/mbarolo/test_back/forms"
+	"github.com/mbarolo/test_back/i18n"
+	"github.com/mbarolo/test_back/services"
+	"github.com/mbarolo/test_back/utils"
+)
+
+// GetIncidents godoc
+// @Summary      Listar incidentes
+// @Description  Incidentes abiertos por las reglas de detección: movimiento sin alquiler, sin señal, fuera del área de servicio y alquiler demasiado largo (admin)
+// @Tags         admin
+// @Produce      json
+// @Security     BasicAuth
+// @Param        limit     query     int     false  "Cantidad de resultados por página"
+// @Param        cursor    query     string  false  "Cursor de la página siguiente"
+// @Param        sort      query     string  false  "Campo de ordenamiento, con - para descendente (ej: -last_seen_at)"
+// @Param        status    query     string  false  "Filtrar por estado (open, acknowledged, resolved)"
+// @Param        rule      query     string  false  "Filtrar por regla (movement_without_rental, no_signal, outside_service_area, rental_overdue, lock_failed)"
+// @Param        severity  query     string  false  "Filtrar por severidad (low, medium, high, critical)"
+// @Param        bike_id   query     int     false  "Filtrar por bicicleta"
+// @Param        from      query     string  false  "Fecha de detección desde (RFC3339 o 2006-01-02)"
+// @Param        to        query     string  false  "Fecha de detección hasta (RFC3339 o 2006-01-02)"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /admin/incidents [get]
+func GetIncidents(w http.ResponseWriter, r *http.Request) {
+	spec, err := utils.ParseQuerySpec(r.URL.Query(), forms.IncidentQuery)
+	if err != nil {
+		utils.ErrorResponse(w, r, "request.query_error", err)
+		return
+	}
+
+	incidents, err := services.GetIncidents(r.Context(), spec)
+	if err != nil {
+		utils.ErrorResponse(w, r, "incident.list_error", err)
+		return
+	}
+
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "incident.list_ok", i18n.Params{"count": incidents.TotalCount}), incidents)
+}
+
+// GetIncident godoc
+// @Summary      Obtener incidente
+// @Description  Incidente con su versión en el header ETag (admin)
+// @Tags         admin
+// @Produce      json
+// @Security     BasicAuth
+// @Param        id   path      int  true  "ID del incidente"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /admin/incidents/{id} [get]
+func GetIncident(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIdParam(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "incident.get_error", err)
+		return
+	}
+
+	incident, err := services.GetIncident(r.Context(), id)
+	if err != nil {
+		utils.ErrorResponse(w, r, "incident.get_error", err)
+		return
+	}
+
+	setETag(w, incident.Version)
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "incident.get_ok"), incident)
+}
+
+// AcknowledgeIncident godoc
+// @Summary      Reconocer incidente
+// @Description  Indica quién se ocupa del incidente; las nuevas detecciones se siguen sumando pero no se vuelve a avisar salvo que aumente la severidad (admin)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BasicAuth
+// @Param        id        path      int                    true   "ID del incidente"
+// @Param        If-Match  header    string                 false  "Versión esperada (ETag) del incidente"
+// @Param        ack       body      forms.IncidentAckForm  true   "Quién se ocupa"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]interface{}
+// @Failure      412  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /admin/incidents/{id}/acknowledge [post]
+func AcknowledgeIncident(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIdParam(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "incident.acknowledge_error", err)
+		return
+	}
+
+	ifMatch, err := parseIfMatch(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "incident.acknowledge_error", err)
+		return
+	}
+
+	var ackForm forms.IncidentAckForm
+	if err := decodeBody(w, r, &ackForm, false); err != nil {
+		utils.ErrorResponse(w, r, "incident.acknowledge_error", err)
+		return
+	}
+
+	incident, err := services.AcknowledgeIncident(r.Context(), id, &ackForm, ifMatch)
+	if err != nil {
+		utils.ErrorResponse(w, r, "incident.acknowledge_error", err)
+		return
+	}
+
+	setETag(w, incident.Version)
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "incident.acknowledged"), incident)
+}
+
+// ResolveIncident godoc
+// @Summary      Resolver incidente
+// @Description  Cierra el incidente con su resolución; si la regla vuelve a cumplirse se abre uno nuevo (admin)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BasicAuth
+// @Param        id        path      int                        true   "ID del incidente"
+// @Param        If-Match  header    string                     false  "Versión esperada (ETag) del incidente"
+// @Param        resolve   body      forms.IncidentResolveForm  true   "Quién lo resuelve y cómo"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]interface{}
+// @Failure      412  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /admin/incidents/{id}/resolve [post]
+func ResolveIncident(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIdParam(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "incident.resolve_error", err)
+		return
+	}
+
+	ifMatch, err := parseIfMatch(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "incident.resolve_error", err)
+		return
+	}
+
+	var resolveForm forms.IncidentResolveForm
+	if err := decodeBody(w, r, &resolveForm, false); err != nil {
+		utils.ErrorResponse(w, r, "incident.resolve_error", err)
+		return
+	}
+
+	incident, err := services.ResolveIncident(r.Context(), id, &resolveForm, ifMatch)
+	if err != nil {
+		utils.ErrorResponse(w, r, "incident.resolve_error", err)
+		return
+	}
+
+	setETag(w, incident.Version)
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "incident.resolved"), incident)
+}
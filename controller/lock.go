@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/mbarolo/test_back/forms"
+	"github.com/mbarolo/test_back/i18n"
+	"github.com/mbarolo/test_back/services"
+	"github.com/mbarolo/test_back/utils"
+)
+
+// GetLockSimulatorDevice godoc
+// @Summary      Obtener candado simulado
+// @Description  Estado del candado simulado de la bicicleta. Solo disponible con el driver simulator o el broker MQTT embebido (admin)
+// @Tags         admin
+// @Produce      json
+// @Security     BasicAuth
+// @Param        id   path      int  true  "ID de la bicicleta"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /admin/bikes/{id}/lock-simulator [get]
+func GetLockSimulatorDevice(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIdParam(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "lock.simulator_error", err)
+		return
+	}
+
+	device, err := services.GetLockSimulatorDevice(r.Context(), id)
+	if err != nil {
+		utils.ErrorResponse(w, r, "lock.simulator_error", err)
+		return
+	}
+
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "lock.simulator_ok"), device)
+}
+
+// UpdateLockSimulatorDevice godoc
+// @Summary      Modificar candado simulado
+// @Description  Cambiar el estado del candado simulado para probar los casos de error: desconectado (offline), sin confirmación (silent) o trabado abierto (jammed) (admin)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BasicAuth
+// @Param        id      path      int                   true  "ID de la bicicleta"
+// @Param        device  body      forms.LockDeviceForm  true  "Estado del candado"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /admin/bikes/{id}/lock-simulator [put]
+func UpdateLockSimulatorDevice(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIdParam(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "lock.simulator_error", err)
+		return
+	}
+
+	var deviceForm forms.LockDeviceForm
+	if err := decodeBody(w, r, &deviceForm, false); err != nil {
+		utils.ErrorResponse(w, r, "lock.simulator_error", err)
+		return
+	}
+
+	device, err := services.SetLockSimulatorDevice(r.Context(), id, deviceForm.ToDevice())
+	if err != nil {
+		utils.ErrorResponse(w, r, "lock.simulator_error", err)
+		return
+	}
+
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "lock.simulator_updated"), device)
+}
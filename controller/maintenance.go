@@ -0,0 +1,345 @@
+package controller
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/mbarolo/test_back/apperror"
+	"github.com/mbarolo/test_back/forms"
+	"github.com/mbarolo/test_back/i18n"
+	"github.com/mbarolo/test_back/services"
+	"github.com/mbarolo/test_back/utils"
+)
+
+// Tamaño máximo de la foto de un reporte de daño
+const MaxPhotoBytes = 5 << 20
+
+// tipos de foto aceptados, se detectan por el contenido y no por el nombre del archivo
+var photoTypes = []string{"image/jpeg", "image/png", "image/webp"}
+
+// ReportDamage godoc
+// @Summary      Reportar daño
+// @Description  Reporta un daño de la bicicleta, como JSON o como multipart/form-data con una foto (jpeg, png o webp, hasta 5 MB).
+// @Description  Se suma a la orden de trabajo sin resolver de la misma categoría o abre una nueva; los frenos, el cuadro y la batería dejan la bicicleta fuera de servicio
+// @Tags         bikes
+// @Accept       json
+// @Accept       mpfd
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id           path      int                     true   "ID de la bicicleta"
+// @Param        report       body      forms.DamageReportForm  false  "Datos del reporte (JSON)"
+// @Param        category     formData  string                  false  "Categoría del daño (multipart)"
+// @Param        description  formData  string                  false  "Descripción del daño (multipart)"
+// @Param        photo        formData  file                    false  "Foto del daño (multipart)"
+// @Success      201  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      413  {object}  map[string]interface{}
+// @Failure      422  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /bikes/{id}/reports [post]
+func ReportDamage(w http.ResponseWriter, r *http.Request) {
+	user, err := services.GetCurrentUser(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "auth.current_user_error", err)
+		return
+	}
+
+	id, err := parseIdParam(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "damage_report.create_error", err)
+		return
+	}
+
+	var reportForm forms.DamageReportForm
+	var photo *services.Photo
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		photo, err = decodeDamageMultipart(w, r, &reportForm)
+	} else {
+		err = decodeBody(w, r, &reportForm, false)
+	}
+	if err != nil {
+		utils.ErrorResponse(w, r, "damage_report.create_error", err)
+		return
+	}
+
+	report, err := services.ReportDamage(r.Context(), user, id, &reportForm, photo)
+	if err != nil {
+		utils.ErrorResponse(w, r, "damage_report.create_error", err)
+		return
+	}
+
+	utils.JsonResponse(w, http.StatusCreated, i18n.T(r, "damage_report.created"), report)
+}
+
+// decodeDamageMultipart: Lee los campos del formulario multipart y la foto, si viene
+func decodeDamageMultipart(w http.ResponseWriter, r *http.Request, form *forms.DamageReportForm) (*services.Photo, error) {
+	// margen para los campos de texto y los encabezados de las partes
+	r.Body = http.MaxBytesReader(w, r.Body, MaxPhotoBytes+MaxBodyBytes)
+	if err := r.ParseMultipartForm(MaxBodyBytes); err != nil {
+		return nil, decodeError(err)
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	form.Category = r.FormValue("category")
+	form.Description = r.FormValue("description")
+	if err := forms.Validate(form); err != nil {
+		return nil, err
+	}
+
+	file, header, err := r.FormFile("photo")
+	if errors.Is(err, http.ErrMissingFile) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, decodeError(err)
+	}
+	defer file.Close()
+
+	if header.Size > MaxPhotoBytes {
+		return nil, apperror.New(apperror.TOO_LARGE, "request.too_large").WithParams(i18n.Params{"limit": MaxPhotoBytes})
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, decodeError(err)
+	}
+
+	contentType := http.DetectContentType(data)
+	if !slices.Contains(photoTypes, contentType) {
+		return nil, apperror.Validation("validation.failed", apperror.FieldError{Field: "photo", Message: "damage_report.invalid_photo"})
+	}
+	return &services.Photo{Data: data, ContentType: contentType}, nil
+}
+
+// GetDamagePhoto godoc
+// @Summary      Foto de un reporte de daño
+// @Description  Retorna la foto adjunta al reporte de daño (admin)
+// @Tags         admin
+// @Produce      image/jpeg
+// @Produce      image/png
+// @Produce      image/webp
+// @Security     BasicAuth
+// @Param        id   path      int  true  "ID del reporte de daño"
+// @Success      200  {file}    file
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /admin/damage-reports/{id}/photo [get]
+func GetDamagePhoto(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIdParam(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "damage_report.photo_error", err)
+		return
+	}
+
+	photo, err := services.GetDamagePhoto(r.Context(), id)
+	if err != nil {
+		utils.ErrorResponse(w, r, "damage_report.photo_error", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", photo.ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(photo.Data)))
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(photo.Data); err != nil {
+		slog.WarnContext(r.Context(), "write damage photo failed", "error", err)
+	}
+}
+
+// GetWorkOrders godoc
+// @Summary      Listar órdenes de trabajo
+// @Description  Órdenes de trabajo de mantenimiento de todas las bicicletas (admin)
+// @Tags         admin
+// @Produce      json
+// @Security     BasicAuth
+// @Param        limit     query     int     false  "Cantidad de resultados por página"
+// @Param        cursor    query     string  false  "Cursor de la página siguiente"
+// @Param        sort      query     string  false  "Campo de ordenamiento, con - para descendente (ej: -created_at)"
+// @Param        status    query     string  false  "Filtrar por estado (open, assigned, in_progress, resolved)"
+// @Param        priority  query     string  false  "Filtrar por prioridad (normal, critical)"
+// @Param        category  query     string  false  "Filtrar por categoría del daño"
+// @Param        mechanic  query     string  false  "Filtrar por mecánico asignado"
+// @Param        bike_id   query     int     false  "Filtrar por bicicleta"
+// @Param        from      query     string  false  "Fecha de creación desde (RFC3339 o 2006-01-02)"
+// @Param        to        query     string  false  "Fecha de creación hasta (RFC3339 o 2006-01-02)"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /admin/work-orders [get]
+func GetWorkOrders(w http.ResponseWriter, r *http.Request) {
+	spec, err := utils.ParseQuerySpec(r.URL.Query(), forms.WorkOrderQuery)
+	if err != nil {
+		utils.ErrorResponse(w, r, "request.query_error", err)
+		return
+	}
+
+	orders, err := services.GetWorkOrders(r.Context(), spec)
+	if err != nil {
+		utils.ErrorResponse(w, r, "work_order.list_error", err)
+		return
+	}
+
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "work_order.list_ok", i18n.Params{"count": orders.TotalCount}), orders)
+}
+
+// GetWorkOrder godoc
+// @Summary      Obtener orden de trabajo
+// @Description  Orden de trabajo con los reportes de daño que la originaron, con su versión en el header ETag (admin)
+// @Tags         admin
+// @Produce      json
+// @Security     BasicAuth
+// @Param        id   path      int  true  "ID de la orden de trabajo"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /admin/work-orders/{id} [get]
+func GetWorkOrder(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIdParam(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "work_order.get_error", err)
+		return
+	}
+
+	order, err := services.GetWorkOrder(r.Context(), id)
+	if err != nil {
+		utils.ErrorResponse(w, r, "work_order.get_error", err)
+		return
+	}
+
+	setETag(w, order.Version)
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "work_order.get_ok"), order)
+}
+
+// UpdateWorkOrder godoc
+// @Summary      Actualizar orden de trabajo
+// @Description  Asigna el mecánico, cambia el estado o la prioridad de la orden con JSON Merge Patch. Los estados son open, assigned, in_progress y resolved; una orden resuelta no se reabre (admin)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BasicAuth
+// @Param        id          path      int                       true   "ID de la orden de trabajo"
+// @Param        If-Match    header    string                    true  "Versión esperada (ETag) de la orden, * actualiza sin verificarla"
+// @Param        work_order  body      forms.WorkOrderPatchForm  true   "Campos a modificar"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]interface{}
+// @Failure      412  {object}  map[string]interface{}
+// @Failure      428  {object}  map[string]interface{}
+// @Failure      422  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /admin/work-orders/{id} [patch]
+func UpdateWorkOrder(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIdParam(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "work_order.update_error", err)
+		return
+	}
+
+	ifMatch, err := requireIfMatch(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "work_order.update_error", err)
+		return
+	}
+
+	var orderForm forms.WorkOrderPatchForm
+	patch, err := decodePatch(w, r, &orderForm)
+	if err != nil {
+		utils.ErrorResponse(w, r, "work_order.update_error", err)
+		return
+	}
+
+	order, err := services.UpdateWorkOrder(r.Context(), id, patch, ifMatch)
+	if err != nil {
+		utils.ErrorResponse(w, r, "work_order.update_error", err)
+		return
+	}
+
+	setETag(w, order.Version)
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "work_order.updated"), order)
+}
+
+// GetServiceHistory godoc
+// @Summary      Historial de mantenimiento
+// @Description  Órdenes de trabajo de una bicicleta (admin)
+// @Tags         admin
+// @Produce      json
+// @Security     BasicAuth
+// @Param        id        path      int     true   "ID de la bicicleta"
+// @Param        limit     query     int     false  "Cantidad de resultados por página"
+// @Param        cursor    query     string  false  "Cursor de la página siguiente"
+// @Param        sort      query     string  false  "Campo de ordenamiento, con - para descendente (ej: -created_at)"
+// @Param        status    query     string  false  "Filtrar por estado"
+// @Param        category  query     string  false  "Filtrar por categoría del daño"
+// @Param        from      query     string  false  "Fecha de creación desde (RFC3339 o 2006-01-02)"
+// @Param        to        query     string  false  "Fecha de creación hasta (RFC3339 o 2006-01-02)"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /admin/bikes/{id}/work-orders [get]
+func GetServiceHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIdParam(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "work_order.list_error", err)
+		return
+	}
+
+	spec, err := utils.ParseQuerySpec(r.URL.Query(), forms.ServiceHistoryQuery)
+	if err != nil {
+		utils.ErrorResponse(w, r, "request.query_error", err)
+		return
+	}
+
+	orders, err := services.GetServiceHistory(r.Context(), id, spec)
+	if err != nil {
+		utils.ErrorResponse(w, r, "work_order.list_error", err)
+		return
+	}
+
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "work_order.list_ok", i18n.Params{"count": orders.TotalCount}), orders)
+}
+
+// CreateWorkOrder godoc
+// @Summary      Abrir orden de trabajo
+// @Description  Abre una orden de trabajo para la bicicleta. Sin priority toma la de la categoría; con mechanic queda asignada (admin)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BasicAuth
+// @Param        id          path      int                  true  "ID de la bicicleta"
+// @Param        work_order  body      forms.WorkOrderForm  true  "Datos de la orden"
+// @Success      201  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /admin/bikes/{id}/work-orders [post]
+func CreateWorkOrder(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIdParam(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "work_order.create_error", err)
+		return
+	}
+
+	var orderForm forms.WorkOrderForm
+	if err := decodeBody(w, r, &orderForm, false); err != nil {
+		utils.ErrorResponse(w, r, "work_order.create_error", err)
+		return
+	}
+
+	order, err := services.CreateWorkOrder(r.Context(), id, &orderForm)
+	if err != nil {
+		utils.ErrorResponse(w, r, "work_order.create_error", err)
+		return
+	}
+
+	setETag(w, order.Version)
+	utils.JsonResponse(w, http.StatusCreated, i18n.T(r, "work_order.created"), order)
+}
@@ -0,0 +1,29 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mbarolo/test_back/apperror"
+)
+
+// parseIdParam: Obtiene el parametro id de la ruta y valida que sea un entero positivo
+func parseIdParam(r *http.Request) (int64, error) {
+	return parseIdParamNamed(r, "id")
+}
+
+// parseIdParamNamed: Igual que parseIdParam para las rutas con más de un id (ej: /webhooks/{id}/deliveries/{deliveryId})
+func parseIdParamNamed(r *http.Request, name string) (int64, error) {
+	idStr := chi.URLParam(r, name)
+	if idStr == "" {
+		return 0, apperror.Validation("param.id_missing", apperror.FieldError{Field: name, Message: "validation.required"})
+	}
+
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, apperror.Validation("param.id_invalid", apperror.FieldError{Field: name, Message: "field.positive_int"})
+	}
+
+	return id, nil
+}
@@ -0,0 +1,220 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/mbarolo/test_back/forms"
+	"github.com/mbarolo/test_back/i18n"
+	"github.com/mbarolo/test_back/services"
+	"github.com/mbarolo/test_back/utils"
+)
+
+// GetRebalancingForecast godoc
+// @Summary      Pronóstico de oferta y demanda
+// @Description  Bicicletas disponibles, alquileres y devoluciones esperados y bicicletas que sobran o faltan en cada celda de la grilla para la hora (admin)
+// @Tags         admin
+// @Produce      json
+// @Security     BasicAuth
+// @Param        hour  query     string  false  "Hora del pronóstico en RFC3339, por defecto la hora siguiente"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /admin/rebalancing/forecast [get]
+func GetRebalancingForecast(w http.ResponseWriter, r *http.Request) {
+	query, err := forms.ParseForecastQuery(r.URL.Query())
+	if err != nil {
+		utils.ErrorResponse(w, r, "request.query_error", err)
+		return
+	}
+
+	forecast, err := services.GetForecast(r.Context(), query.Hour)
+	if err != nil {
+		utils.ErrorResponse(w, r, "rebalancing.forecast_error", err)
+		return
+	}
+
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "rebalancing.forecast_ok", i18n.Params{"count": len(forecast.Zones)}), forecast)
+}
+
+// PlanRebalancing godoc
+// @Summary      Planificar traslados
+// @Description  Cancela las tareas abiertas y genera las de la hora según el pronóstico; las tareas tomadas se mantienen (admin)
+// @Tags         admin
+// @Produce      json
+// @Security     BasicAuth
+// @Param        hour  query     string  false  "Hora del pronóstico en RFC3339, por defecto la hora siguiente"
+// @Success      201  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /admin/rebalancing/plan [post]
+func PlanRebalancing(w http.ResponseWriter, r *http.Request) {
+	query, err := forms.ParseForecastQuery(r.URL.Query())
+	if err != nil {
+		utils.ErrorResponse(w, r, "request.query_error", err)
+		return
+	}
+
+	tasks, err := services.PlanRebalancing(r.Context(), query.Hour)
+	if err != nil {
+		utils.ErrorResponse(w, r, "rebalancing.plan_error", err)
+		return
+	}
+
+	utils.JsonResponse(w, http.StatusCreated, i18n.T(r, "rebalancing.planned", i18n.Params{"count": len(tasks)}), tasks)
+}
+
+// GetRebalancingTasks godoc
+// @Summary      Listar tareas de traslado
+// @Description  Tareas de traslado de bicicletas entre celdas (admin)
+// @Tags         admin
+// @Produce      json
+// @Security     BasicAuth
+// @Param        limit       query     int     false  "Cantidad de resultados por página"
+// @Param        cursor      query     string  false  "Cursor de la página siguiente"
+// @Param        sort        query     string  false  "Campo de ordenamiento, con - para descendente (ej: -bikes)"
+// @Param        status      query     string  false  "Filtrar por estado (open, claimed, completed, cancelled)"
+// @Param        claimed_by  query     string  false  "Filtrar por quién tomó la tarea"
+// @Param        from_cell   query     string  false  "Filtrar por celda de origen"
+// @Param        to_cell     query     string  false  "Filtrar por celda de destino"
+// @Param        from        query     string  false  "Fecha de creación desde (RFC3339 o 2006-01-02)"
+// @Param        to          query     string  false  "Fecha de creación hasta (RFC3339 o 2006-01-02)"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /admin/rebalancing/tasks [get]
+func GetRebalancingTasks(w http.ResponseWriter, r *http.Request) {
+	spec, err := utils.ParseQuerySpec(r.URL.Query(), forms.RebalancingTaskQuery)
+	if err != nil {
+		utils.ErrorResponse(w, r, "request.query_error", err)
+		return
+	}
+
+	tasks, err := services.GetRebalancingTasks(r.Context(), spec)
+	if err != nil {
+		utils.ErrorResponse(w, r, "rebalancing.list_error", err)
+		return
+	}
+
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "rebalancing.list_ok", i18n.Params{"count": tasks.TotalCount}), tasks)
+}
+
+// GetRebalancingTask godoc
+// @Summary      Obtener tarea de traslado
+// @Description  Tarea de traslado con su versión en el header ETag (admin)
+// @Tags         admin
+// @Produce      json
+// @Security     BasicAuth
+// @Param        id   path      int  true  "ID de la tarea"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /admin/rebalancing/tasks/{id} [get]
+func GetRebalancingTask(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIdParam(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "rebalancing.get_error", err)
+		return
+	}
+
+	task, err := services.GetRebalancingTask(r.Context(), id)
+	if err != nil {
+		utils.ErrorResponse(w, r, "rebalancing.get_error", err)
+		return
+	}
+
+	setETag(w, task.Version)
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "rebalancing.get_ok"), task)
+}
+
+// ClaimRebalancingTask godoc
+// @Summary      Tomar tarea de traslado
+// @Description  Asigna la tarea abierta a quien la va a hacer; una tarea tomada no se cancela al planificar de nuevo (admin)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BasicAuth
+// @Param        id        path      int                         true   "ID de la tarea"
+// @Param        If-Match  header    string                      false  "Versión esperada (ETag) de la tarea"
+// @Param        claim     body      forms.RebalancingClaimForm  true   "Quién toma la tarea"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]interface{}
+// @Failure      412  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /admin/rebalancing/tasks/{id}/claim [post]
+func ClaimRebalancingTask(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIdParam(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "rebalancing.claim_error", err)
+		return
+	}
+
+	ifMatch, err := parseIfMatch(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "rebalancing.claim_error", err)
+		return
+	}
+
+	var claimForm forms.RebalancingClaimForm
+	if err := decodeBody(w, r, &claimForm, false); err != nil {
+		utils.ErrorResponse(w, r, "rebalancing.claim_error", err)
+		return
+	}
+
+	task, err := services.ClaimRebalancingTask(r.Context(), id, &claimForm, ifMatch)
+	if err != nil {
+		utils.ErrorResponse(w, r, "rebalancing.claim_error", err)
+		return
+	}
+
+	setETag(w, task.Version)
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "rebalancing.claimed"), task)
+}
+
+// CompleteRebalancingTask godoc
+// @Summary      Completar tarea de traslado
+// @Description  Cierra la tarea tomada con las bicicletas que se movieron; con {} se toman como movidas todas (admin)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BasicAuth
+// @Param        id        path      int                            true   "ID de la tarea"
+// @Param        If-Match  header    string                         false  "Versión esperada (ETag) de la tarea"
+// @Param        complete  body      forms.RebalancingCompleteForm  true   "Bicicletas movidas"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]interface{}
+// @Failure      412  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /admin/rebalancing/tasks/{id}/complete [post]
+func CompleteRebalancingTask(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIdParam(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "rebalancing.complete_error", err)
+		return
+	}
+
+	ifMatch, err := parseIfMatch(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "rebalancing.complete_error", err)
+		return
+	}
+
+	var completeForm forms.RebalancingCompleteForm
+	if err := decodeBody(w, r, &completeForm, false); err != nil {
+		utils.ErrorResponse(w, r, "rebalancing.complete_error", err)
+		return
+	}
+
+	task, err := services.CompleteRebalancingTask(r.Context(), id, &completeForm, ifMatch)
+	if err != nil {
+		utils.ErrorResponse(w, r, "rebalancing.complete_error", err)
+		return
+	}
+
+	setETag(w, task.Version)
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "rebalancing.completed", i18n.Params{"count": *task.Moved}), task)
+}
@@ -1,12 +1,10 @@
 package controller
 
 import (
-	"encoding/json"
 	"net/http"
-	"strconv"
 
-	"github.com/go-chi/chi/v5"
 	"github.com/mbarolo/test_back/forms"
+	"github.com/mbarolo/test_back/i18n"
 	"github.com/mbarolo/test_back/services"
 	"github.com/mbarolo/test_back/utils"
 )
@@ -18,32 +16,34 @@ import (
 // @Accept       json
 // @Produce      json
 // @Security     BearerAuth
-// @Param        rental  body      forms.StartEndRentalForm  true  "ID de la bicicleta a alquilar"
+// @Param        Idempotency-Key  header    string                    false  "Clave para reintentar la solicitud sin duplicar el alquiler"
+// @Param        rental           body      forms.StartEndRentalForm  true   "ID de la bicicleta a alquilar"
 // @Success      201     {object}  map[string]interface{}
 // @Failure      400     {object}  map[string]interface{}
 // @Failure      401     {object}  map[string]interface{}
+// @Failure      409     {object}  map[string]interface{}
 // @Failure      500     {object}  map[string]interface{}
 // @Router       /rentals/start [post]
 func StartRental(w http.ResponseWriter, r *http.Request) {
 	user, err := services.GetCurrentUser(r)
 	if err != nil {
-		utils.JsonResponse(w, http.StatusUnauthorized, "Error al obtener el usuario autenticado: "+err.Error(), nil)
+		utils.ErrorResponse(w, r, "auth.current_user_error", err)
 		return
 	}
 
-	var rentalForm *forms.StartEndRentalForm
-	if err := json.NewDecoder(r.Body).Decode(&rentalForm); err != nil {
-		utils.JsonResponse(w, http.StatusBadRequest, "Error al decodificar el cuerpo de la solicitud: "+err.Error(), nil)
+	var rentalForm forms.StartEndRentalForm
+	if err := decodeBody(w, r, &rentalForm, false); err != nil {
+		utils.ErrorResponse(w, r, "rental.start_error", err)
 		return
 	}
 
-	rental, err := services.StartRental(user, rentalForm)
+	rental, err := services.StartRental(r.Context(), user, &rentalForm)
 	if err != nil {
-		utils.JsonResponse(w, http.StatusInternalServerError, "Error al alquilar la bicicleta: "+err.Error(), nil)
+		utils.ErrorResponse(w, r, "rental.start_error", err)
 		return
 	}
 
-	utils.JsonResponse(w, http.StatusCreated, "Bicicleta alquilada correctamente", rental)
+	utils.JsonResponse(w, http.StatusCreated, i18n.T(r, "rental.started"), rental)
 }
 
 // EndRental godoc
@@ -53,32 +53,34 @@ func StartRental(w http.ResponseWriter, r *http.Request) {
 // @Accept       json
 // @Produce      json
 // @Security     BearerAuth
-// @Param        rental  body      forms.StartEndRentalForm  true  "ID de la bicicleta a devolver"
+// @Param        Idempotency-Key  header    string                    false  "Clave para reintentar la solicitud sin duplicar el cobro"
+// @Param        rental           body      forms.StartEndRentalForm  true   "ID de la bicicleta a devolver"
 // @Success      200     {object}  map[string]interface{}
 // @Failure      400     {object}  map[string]interface{}
 // @Failure      401     {object}  map[string]interface{}
+// @Failure      409     {object}  map[string]interface{}
 // @Failure      500     {object}  map[string]interface{}
 // @Router       /rentals/end [post]
 func EndRental(w http.ResponseWriter, r *http.Request) {
 	user, err := services.GetCurrentUser(r)
 	if err != nil {
-		utils.JsonResponse(w, http.StatusUnauthorized, "Error al obtener el usuario autenticado: "+err.Error(), nil)
+		utils.ErrorResponse(w, r, "auth.current_user_error", err)
 		return
 	}
 
-	var rentalForm *forms.StartEndRentalForm
-	if err := json.NewDecoder(r.Body).Decode(&rentalForm); err != nil {
-		utils.JsonResponse(w, http.StatusBadRequest, "Error al decodificar el cuerpo de la solicitud: "+err.Error(), nil)
+	var rentalForm forms.StartEndRentalForm
+	if err := decodeBody(w, r, &rentalForm, false); err != nil {
+		utils.ErrorResponse(w, r, "rental.end_error", err)
 		return
 	}
 
-	rental, err := services.EndRental(user, rentalForm)
+	rental, err := services.EndRental(r.Context(), user, &rentalForm)
 	if err != nil {
-		utils.JsonResponse(w, http.StatusInternalServerError, "Error al finalizar el alquiler: "+err.Error(), nil)
+		utils.ErrorResponse(w, r, "rental.end_error", err)
 		return
 	}
 
-	utils.JsonResponse(w, http.StatusOK, "Alquiler de bicicleta finalizado correctamente", rental)
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "rental.ended"), rental)
 }
 
 // GetUserRentalHistory godoc
@@ -88,23 +90,37 @@ func EndRental(w http.ResponseWriter, r *http.Request) {
 // @Accept       json
 // @Produce      json
 // @Security     BearerAuth
+// @Param        limit    query     int     false  "Cantidad de resultados por página"
+// @Param        cursor   query     string  false  "Cursor de la página siguiente"
+// @Param        sort     query     string  false  "Campo de ordenamiento, con - para descendente (default: -start_time)"
+// @Param        status   query     string  false  "Filtrar por estado del alquiler (running, ended)"
+// @Param        bike_id  query     int     false  "Filtrar por bicicleta"
+// @Param        from     query     string  false  "Fecha de inicio desde (RFC3339 o 2006-01-02)"
+// @Param        to       query     string  false  "Fecha de inicio hasta (RFC3339 o 2006-01-02)"
 // @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
 // @Failure      401  {object}  map[string]interface{}
 // @Failure      500  {object}  map[string]interface{}
 // @Router       /rentals/history [get]
 func GetUserRentalHistory(w http.ResponseWriter, r *http.Request) {
 	user, err := services.GetCurrentUser(r)
 	if err != nil {
-		utils.JsonResponse(w, http.StatusUnauthorized, "Error al obtener el usuario autenticado: "+err.Error(), nil)
+		utils.ErrorResponse(w, r, "auth.current_user_error", err)
+		return
+	}
+
+	spec, err := utils.ParseQuerySpec(r.URL.Query(), forms.RentalHistoryQuery)
+	if err != nil {
+		utils.ErrorResponse(w, r, "request.query_error", err)
 		return
 	}
 
-	rentals, err := services.GetRentalHistory(user.Id)
+	rentals, err := services.GetRentalHistory(r.Context(), user.Id, spec)
 	if err != nil {
-		utils.JsonResponse(w, http.StatusInternalServerError, "Error al obtener el historial de alquileres: "+err.Error(), nil)
+		utils.ErrorResponse(w, r, "rental.history_error", err)
 		return
 	}
-	utils.JsonResponse(w, http.StatusOK, "Historial de alquileres obtenido correctamente", rentals)
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "rental.history_ok", i18n.Params{"count": rentals.TotalCount}), rentals)
 }
 
 // GetAllRentals godoc
@@ -114,17 +130,33 @@ func GetUserRentalHistory(w http.ResponseWriter, r *http.Request) {
 // @Accept       json
 // @Produce      json
 // @Security     BasicAuth
+// @Param        limit       query     int     false  "Cantidad de resultados por página"
+// @Param        cursor      query     string  false  "Cursor de la página siguiente"
+// @Param        sort        query     string  false  "Campo de ordenamiento, con - para descendente (default: -start_time)"
+// @Param        status      query     string  false  "Filtrar por estado del alquiler (running, ended)"
+// @Param        bike_id     query     int     false  "Filtrar por bicicleta"
+// @Param        user_id     query     int     false  "Filtrar por usuario"
+// @Param        end_reason  query     string  false  "Filtrar por motivo de finalización (rider, max_duration, idle)"
+// @Param        from        query     string  false  "Fecha de inicio desde (RFC3339 o 2006-01-02)"
+// @Param        to          query     string  false  "Fecha de inicio hasta (RFC3339 o 2006-01-02)"
 // @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
 // @Failure      500  {object}  map[string]interface{}
 // @Router       /admin/rentals [get]
 func GetAllRentals(w http.ResponseWriter, r *http.Request) {
-	rentals, err := services.GetAllRentals()
+	spec, err := utils.ParseQuerySpec(r.URL.Query(), forms.RentalQuery)
 	if err != nil {
-		utils.JsonResponse(w, http.StatusInternalServerError, "Error al obtener los alquileres: "+err.Error(), nil)
+		utils.ErrorResponse(w, r, "request.query_error", err)
 		return
 	}
 
-	utils.JsonResponse(w, http.StatusOK, "Alquileres obtenidos", rentals)
+	rentals, err := services.GetAllRentals(r.Context(), spec)
+	if err != nil {
+		utils.ErrorResponse(w, r, "rental.list_error", err)
+		return
+	}
+
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "rental.list_ok", i18n.Params{"count": rentals.TotalCount}), rentals)
 }
 
 // GetRentalById godoc
@@ -140,64 +172,65 @@ func GetAllRentals(w http.ResponseWriter, r *http.Request) {
 // @Failure      500  {object}  map[string]interface{}
 // @Router       /admin/rentals/{id} [get]
 func GetRentalById(w http.ResponseWriter, r *http.Request) {
-	idStr := chi.URLParam(r, "id")
-	if idStr == "" {
-		utils.JsonResponse(w, http.StatusBadRequest, "Parametro id no encontrado", nil)
-		return
-	}
-
-	id, err := strconv.Atoi(idStr)
+	id, err := parseIdParam(r)
 	if err != nil {
-		utils.JsonResponse(w, http.StatusInternalServerError, "Error al convertir el parametro id: "+err.Error(), nil)
+		utils.ErrorResponse(w, r, "rental.get_error", err)
 		return
 	}
 
-	rental, err := services.GetRentalById(int64(id))
+	rental, err := services.GetRentalById(r.Context(), id)
 	if err != nil {
-		utils.JsonResponse(w, http.StatusInternalServerError, "Error al obtener el alquiler: "+err.Error(), nil)
+		utils.ErrorResponse(w, r, "rental.get_error", err)
 		return
 	}
 
-	utils.JsonResponse(w, http.StatusOK, "Alquiler obtenido", rental)
+	setETag(w, rental.Version)
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "rental.get_ok"), rental)
 }
 
 // UpdateRental godoc
 // @Summary      Actualizar alquiler
-// @Description  Modificar información de un alquiler específico (admin)
+// @Description  Corregir las coordenadas de inicio y fin de un alquiler con JSON Merge Patch (admin). El usuario, la bicicleta, el estado y los tiempos no se pueden modificar (422), cambian al iniciar y finalizar el alquiler
 // @Tags         admin
 // @Accept       json
 // @Produce      json
 // @Security     BasicAuth
-// @Param        id      path      int              true  "ID del alquiler"
-// @Param        rental  body      forms.RentalForm true  "Datos actualizados del alquiler"
+// @Param        id        path      int               true   "ID del alquiler"
+// @Param        If-Match  header    string            true  "Versión esperada (ETag) del alquiler, * actualiza sin verificarla"
+// @Param        rental    body      forms.RentalForm  true   "Campos a modificar del alquiler"
 // @Success      200     {object}  map[string]interface{}
 // @Failure      400     {object}  map[string]interface{}
+// @Failure      412     {object}  map[string]interface{}
+// @Failure      428     {object}  map[string]interface{}
+// @Failure      422     {object}  map[string]interface{}
 // @Failure      500     {object}  map[string]interface{}
 // @Router       /admin/rentals/{id} [patch]
 func UpdateRental(w http.ResponseWriter, r *http.Request) {
-	idStr := chi.URLParam(r, "id")
-	if idStr == "" {
-		utils.JsonResponse(w, http.StatusBadRequest, "Parametro id no encontrado", nil)
+	id, err := parseIdParam(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "rental.update_error", err)
 		return
 	}
 
-	id, err := strconv.Atoi(idStr)
+	ifMatch, err := requireIfMatch(r)
 	if err != nil {
-		utils.JsonResponse(w, http.StatusInternalServerError, "Error al convertir el parametro id: "+err.Error(), nil)
+		utils.ErrorResponse(w, r, "rental.update_error", err)
 		return
 	}
 
-	var rentalForm *forms.RentalForm
-	if err := json.NewDecoder(r.Body).Decode(&rentalForm); err != nil {
-		utils.JsonResponse(w, http.StatusBadRequest, "Error al decodificar el cuerpo de la solicitud: "+err.Error(), nil)
+	var rentalForm forms.RentalForm
+	patch, err := decodePatch(w, r, &rentalForm)
+	if err != nil {
+		utils.ErrorResponse(w, r, "rental.update_error", err)
 		return
 	}
 
-	rental, err := services.UpdateRental(int64(id), rentalForm)
+	rental, err := services.UpdateRental(r.Context(), id, patch, ifMatch)
 	if err != nil {
-		utils.JsonResponse(w, http.StatusInternalServerError, "Error al actualizar el alquiler: "+err.Error(), nil)
+		utils.ErrorResponse(w, r, "rental.update_error", err)
 		return
 	}
 
-	utils.JsonResponse(w, http.StatusOK, "Alquiler actualizado", rental)
+	setETag(w, rental.Version)
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "rental.updated"), rental)
 }
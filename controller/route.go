@@ -0,0 +1,224 @@
+package controller
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mbarolo/test_back/apperror"
+	"github.com/mbarolo/test_back/forms"
+	"github.com/mbarolo/test_back/i18n"
+	"github.com/mbarolo/test_back/models"
+	"github.com/mbarolo/test_back/services"
+	"github.com/mbarolo/test_back/utils"
+)
+
+// formatos del recorrido de un alquiler
+var routeExporters = map[string]struct {
+	contentType string
+	encode      func(w io.Writer, route *models.Route) error
+}{
+	"geojson": {"application/geo+json", encodeRouteGeoJSON},
+	"gpx":     {"application/gpx+xml", encodeRouteGPX},
+}
+
+// RecordRoute godoc
+// @Summary      Registrar recorrido
+// @Description  Agrega puntos GPS de la app al recorrido del alquiler en curso del usuario autenticado
+// @Tags         rentals
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id     path      int              true  "ID del alquiler"
+// @Param        route  body      forms.RouteForm  true  "Puntos del recorrido"
+// @Success      200    {object}  map[string]interface{}
+// @Failure      400    {object}  map[string]interface{}
+// @Failure      401    {object}  map[string]interface{}
+// @Failure      404    {object}  map[string]interface{}
+// @Failure      409    {object}  map[string]interface{}
+// @Failure      422    {object}  map[string]interface{}
+// @Failure      500    {object}  map[string]interface{}
+// @Router       /rentals/{id}/route [post]
+func RecordRoute(w http.ResponseWriter, r *http.Request) {
+	user, err := services.GetCurrentUser(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "auth.current_user_error", err)
+		return
+	}
+
+	id, err := parseIdParam(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "route.record_error", err)
+		return
+	}
+
+	var routeForm forms.RouteForm
+	if err := decodeBody(w, r, &routeForm, false); err != nil {
+		utils.ErrorResponse(w, r, "route.record_error", err)
+		return
+	}
+
+	recorded, err := services.RecordRoute(r.Context(), user, id, routeForm.ToPoints())
+	if err != nil {
+		utils.ErrorResponse(w, r, "route.record_error", err)
+		return
+	}
+
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "route.recorded", i18n.Params{"count": recorded}), map[string]int{"recorded": recorded})
+}
+
+// GetRentalRoute godoc
+// @Summary      Recorrido del alquiler
+// @Description  Recorrido de un alquiler del usuario autenticado como GeoJSON (Feature con un LineString) o GPX
+// @Tags         rentals
+// @Produce      application/geo+json
+// @Produce      application/gpx+xml
+// @Security     BearerAuth
+// @Param        id      path      int     true   "ID del alquiler"
+// @Param        format  query     string  false  "Formato: geojson (default) o gpx"
+// @Success      200     {string}  string
+// @Failure      400     {object}  map[string]interface{}
+// @Failure      401     {object}  map[string]interface{}
+// @Failure      404     {object}  map[string]interface{}
+// @Failure      500     {object}  map[string]interface{}
+// @Router       /rentals/{id}/route [get]
+func GetRentalRoute(w http.ResponseWriter, r *http.Request) {
+	user, err := services.GetCurrentUser(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "auth.current_user_error", err)
+		return
+	}
+	writeRentalRoute(w, r, user)
+}
+
+// GetAdminRentalRoute godoc
+// @Summary      Recorrido de un alquiler
+// @Description  Recorrido de cualquier alquiler como GeoJSON (Feature con un LineString) o GPX (admin)
+// @Tags         admin
+// @Produce      application/geo+json
+// @Produce      application/gpx+xml
+// @Security     BasicAuth
+// @Param        id      path      int     true   "ID del alquiler"
+// @Param        format  query     string  false  "Formato: geojson (default) o gpx"
+// @Success      200     {string}  string
+// @Failure      400     {object}  map[string]interface{}
+// @Failure      404     {object}  map[string]interface{}
+// @Failure      500     {object}  map[string]interface{}
+// @Router       /admin/rentals/{id}/route [get]
+func GetAdminRentalRoute(w http.ResponseWriter, r *http.Request) {
+	writeRentalRoute(w, r, nil)
+}
+
+// writeRentalRoute: Responde el recorrido en el formato pedido, currentUser nil para el admin
+func writeRentalRoute(w http.ResponseWriter, r *http.Request, currentUser *models.User) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "geojson"
+	}
+	exporter, ok := routeExporters[format]
+	if !ok {
+		utils.ErrorResponse(w, r, "request.query_error", apperror.BadRequest("query.invalid_param").WithParams(i18n.Params{"param": "format"}))
+		return
+	}
+
+	id, err := parseIdParam(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "route.get_error", err)
+		return
+	}
+
+	route, err := services.GetRentalRoute(r.Context(), currentUser, id)
+	if err != nil {
+		utils.ErrorResponse(w, r, "route.get_error", err)
+		return
+	}
+
+	filename := "rental-" + strconv.FormatInt(id, 10) + "." + format
+	w.Header().Set("Content-Type", exporter.contentType)
+	w.Header().Set("Content-Disposition", `inline; filename="`+filename+`"`)
+	w.WriteHeader(http.StatusOK)
+	if err := exporter.encode(w, route); err != nil {
+		// la respuesta ya comenzó, solo se registra en el log
+		slog.WarnContext(r.Context(), "write route failed", "format", format, "error", err)
+	}
+}
+
+// geoJSONFeature: Feature de GeoJSON (RFC 7946). Con menos de dos puntos no hay LineString y la geometría es null
+type geoJSONFeature struct {
+	Type       string                 `json:"type"`
+	Geometry   *geoJSONLineString     `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type geoJSONLineString struct {
+	Type        string       `json:"type"`
+	Coordinates [][2]float64 `json:"coordinates"`
+}
+
+func encodeRouteGeoJSON(w io.Writer, route *models.Route) error {
+	times := make([]time.Time, len(route.Points))
+	for i, point := range route.Points {
+		times[i] = point.RecordedAt
+	}
+
+	feature := geoJSONFeature{
+		Type: "Feature",
+		Properties: map[string]interface{}{
+			"rental_id":     route.Rental.Id,
+			"bike_id":       route.Rental.BikeId,
+			"rental_status": route.Rental.RentalStatus,
+			"start_time":    route.Rental.StartTime,
+			"end_time":      route.Rental.EndTime,
+			"distance_m":    route.Rental.DistanceM,
+			"times":         times,
+		},
+	}
+	if len(route.Points) >= 2 {
+		// GeoJSON usa el orden longitud, latitud
+		coordinates := make([][2]float64, len(route.Points))
+		for i, point := range route.Points {
+			coordinates[i] = [2]float64{point.Longitude, point.Latitude}
+		}
+		feature.Geometry = &geoJSONLineString{Type: "LineString", Coordinates: coordinates}
+	}
+	return json.NewEncoder(w).Encode(feature)
+}
+
+// gpxDocument: Documento GPX 1.1 con un track de un solo segmento
+type gpxDocument struct {
+	XMLName xml.Name `xml:"gpx"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	Version string   `xml:"version,attr"`
+	Creator string   `xml:"creator,attr"`
+	Track   struct {
+		Name    string `xml:"name"`
+		Segment struct {
+			Points []gpxPoint `xml:"trkpt"`
+		} `xml:"trkseg"`
+	} `xml:"trk"`
+}
+
+type gpxPoint struct {
+	Latitude  float64   `xml:"lat,attr"`
+	Longitude float64   `xml:"lon,attr"`
+	Time      time.Time `xml:"time"`
+}
+
+func encodeRouteGPX(w io.Writer, route *models.Route) error {
+	doc := gpxDocument{Xmlns: "http://www.topografix.com/GPX/1/1", Version: "1.1", Creator: "test_back"}
+	doc.Track.Name = "rental " + strconv.FormatInt(route.Rental.Id, 10)
+	for _, point := range route.Points {
+		doc.Track.Segment.Points = append(doc.Track.Segment.Points, gpxPoint{Latitude: point.Latitude, Longitude: point.Longitude, Time: point.RecordedAt.UTC()})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	return encoder.Encode(doc)
+}
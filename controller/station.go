@@ -0,0 +1,240 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/mbarolo/test_back/forms"
+	"github.com/mbarolo/test_back/i18n"
+	"github.com/mbarolo/test_back/services"
+	"github.com/mbarolo/test_back/utils"
+)
+
+// GetStations godoc
+// @Summary      Estaciones
+// @Description  Estaciones activas con sus anclajes libres y las bicicletas que se pueden alquilar en cada una
+// @Tags         stations
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /stations [get]
+func GetStations(w http.ResponseWriter, r *http.Request) {
+	stations, err := services.GetStations(r.Context())
+	if err != nil {
+		utils.ErrorResponse(w, r, "station.list_error", err)
+		return
+	}
+
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "station.list_ok", i18n.Params{"count": len(stations)}), stations)
+}
+
+// GetAllStations godoc
+// @Summary      Listar estaciones
+// @Description  Todas las estaciones, activas o no, con sus anclajes ocupados y libres (admin)
+// @Tags         admin
+// @Produce      json
+// @Security     BasicAuth
+// @Param        limit   query     int     false  "Cantidad de resultados por página"
+// @Param        cursor  query     string  false  "Cursor de la página siguiente"
+// @Param        sort    query     string  false  "Campo de ordenamiento, con - para descendente (ej: -free_docks)"
+// @Param        active  query     bool    false  "Filtrar por estaciones activas"
+// @Param        name    query     string  false  "Filtrar por nombre"
+// @Param        from    query     string  false  "Fecha de creación desde (RFC3339 o 2006-01-02)"
+// @Param        to      query     string  false  "Fecha de creación hasta (RFC3339 o 2006-01-02)"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /admin/stations [get]
+func GetAllStations(w http.ResponseWriter, r *http.Request) {
+	spec, err := utils.ParseQuerySpec(r.URL.Query(), forms.StationQuery)
+	if err != nil {
+		utils.ErrorResponse(w, r, "request.query_error", err)
+		return
+	}
+
+	stations, err := services.GetAllStations(r.Context(), spec)
+	if err != nil {
+		utils.ErrorResponse(w, r, "station.list_error", err)
+		return
+	}
+
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "station.list_ok", i18n.Params{"count": stations.TotalCount}), stations)
+}
+
+// CreateStation godoc
+// @Summary      Crear estación
+// @Description  Registra una estación de anclaje con su ubicación y cantidad de anclajes (admin)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BasicAuth
+// @Param        station  body      forms.StationForm  true  "Datos de la estación"
+// @Success      201  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /admin/stations [post]
+func CreateStation(w http.ResponseWriter, r *http.Request) {
+	var stationForm forms.StationForm
+	if err := decodeBody(w, r, &stationForm, false); err != nil {
+		utils.ErrorResponse(w, r, "station.create_error", err)
+		return
+	}
+
+	station, err := services.CreateStation(r.Context(), &stationForm)
+	if err != nil {
+		utils.ErrorResponse(w, r, "station.create_error", err)
+		return
+	}
+
+	setETag(w, station.Version)
+	utils.JsonResponse(w, http.StatusCreated, i18n.T(r, "station.created"), station)
+}
+
+// GetStationById godoc
+// @Summary      Obtener estación
+// @Description  Estación con todas sus bicicletas ancladas, con su versión en el header ETag (admin)
+// @Tags         admin
+// @Produce      json
+// @Security     BasicAuth
+// @Param        id   path      int  true  "ID de la estación"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /admin/stations/{id} [get]
+func GetStationById(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIdParam(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "station.get_error", err)
+		return
+	}
+
+	station, err := services.GetStationById(r.Context(), id)
+	if err != nil {
+		utils.ErrorResponse(w, r, "station.get_error", err)
+		return
+	}
+
+	setETag(w, station.Version)
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "station.get_ok"), station)
+}
+
+// UpdateStation godoc
+// @Summary      Actualizar estación
+// @Description  Modifica la estación con JSON Merge Patch; con active false deja de aceptar devoluciones. La capacidad no puede quedar por debajo de un anclaje ocupado (admin)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BasicAuth
+// @Param        id        path      int                true   "ID de la estación"
+// @Param        If-Match  header    string             true  "Versión esperada (ETag) de la estación, * actualiza sin verificarla"
+// @Param        station   body      forms.StationForm  true   "Campos a modificar"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]interface{}
+// @Failure      412  {object}  map[string]interface{}
+// @Failure      428  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /admin/stations/{id} [patch]
+func UpdateStation(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIdParam(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "station.update_error", err)
+		return
+	}
+
+	ifMatch, err := requireIfMatch(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "station.update_error", err)
+		return
+	}
+
+	var stationForm forms.StationForm
+	patch, err := decodePatch(w, r, &stationForm)
+	if err != nil {
+		utils.ErrorResponse(w, r, "station.update_error", err)
+		return
+	}
+
+	station, err := services.UpdateStation(r.Context(), id, patch, ifMatch)
+	if err != nil {
+		utils.ErrorResponse(w, r, "station.update_error", err)
+		return
+	}
+
+	setETag(w, station.Version)
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "station.updated"), station)
+}
+
+// DockBike godoc
+// @Summary      Anclar bicicleta
+// @Description  Ancla la bicicleta en el primer anclaje libre de la estación; si estaba en otra estación la mueve (admin)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BasicAuth
+// @Param        id    path      int             true  "ID de la estación"
+// @Param        dock  body      forms.DockForm  true  "Bicicleta a anclar"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /admin/stations/{id}/bikes [post]
+func DockBike(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIdParam(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "station.dock_error", err)
+		return
+	}
+
+	var dockForm forms.DockForm
+	if err := decodeBody(w, r, &dockForm, false); err != nil {
+		utils.ErrorResponse(w, r, "station.dock_error", err)
+		return
+	}
+
+	bike, err := services.DockBike(r.Context(), id, dockForm.BikeID)
+	if err != nil {
+		utils.ErrorResponse(w, r, "station.dock_error", err)
+		return
+	}
+
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "station.docked", i18n.Params{"dock": *bike.Dock}), bike)
+}
+
+// UndockBike godoc
+// @Summary      Desanclar bicicleta
+// @Description  Libera el anclaje de la bicicleta, que queda suelta en la ubicación de la estación (admin)
+// @Tags         admin
+// @Produce      json
+// @Security     BasicAuth
+// @Param        id      path      int  true  "ID de la estación"
+// @Param        bikeId  path      int  true  "ID de la bicicleta"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /admin/stations/{id}/bikes/{bikeId} [delete]
+func UndockBike(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIdParam(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "station.undock_error", err)
+		return
+	}
+
+	bikeId, err := parseIdParamNamed(r, "bikeId")
+	if err != nil {
+		utils.ErrorResponse(w, r, "station.undock_error", err)
+		return
+	}
+
+	bike, err := services.UndockBike(r.Context(), id, bikeId)
+	if err != nil {
+		utils.ErrorResponse(w, r, "station.undock_error", err)
+		return
+	}
+
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "station.undocked"), bike)
+}
@@ -0,0 +1,195 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+
+	"github.com/mbarolo/test_back/apperror"
+	"github.com/mbarolo/test_back/i18n"
+	"github.com/mbarolo/test_back/services"
+	"github.com/mbarolo/test_back/stream"
+	"github.com/mbarolo/test_back/utils"
+)
+
+const (
+	// intervalo de los mensajes de keep-alive, menor al timeout habitual de los proxies
+	streamHeartbeat = 15 * time.Second
+	// tiempo máximo para escribir un evento, si el cliente no lo recibe se lo desconecta
+	streamWriteTimeout = 10 * time.Second
+)
+
+// streamMessage: Mensaje enviado por WebSocket. Por SSE el tipo va en el campo event
+type streamMessage struct {
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+// StreamBikes godoc
+// @Summary      Stream de bicicletas
+// @Description  Envía en tiempo real los cambios de disponibilidad y ubicación de las bicicletas, y los cambios de estado de los alquileres del usuario.
+// @Description  Con Accept: text/event-stream responde Server-Sent Events, con Upgrade: websocket abre un WebSocket. El primer evento (snapshot) tiene las bicicletas disponibles.
+// @Description  Eventos: snapshot, bike, rental y close (el servidor cerró el stream, reconectar)
+// @Tags         bikes
+// @Produce      text/event-stream
+// @Security     BearerAuth
+// @Param        bbox  query     string  false  "Zona a observar: min_lat,min_lng,max_lat,max_lng"
+// @Success      200   {string}  string
+// @Failure      400   {object}  map[string]interface{}
+// @Failure      401   {object}  map[string]interface{}
+// @Failure      503   {object}  map[string]interface{}
+// @Router       /bikes/stream [get]
+func StreamBikes(w http.ResponseWriter, r *http.Request) {
+	user, err := services.GetCurrentUser(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "auth.current_user_error", err)
+		return
+	}
+
+	bbox, err := stream.ParseBBox(r.URL.Query().Get("bbox"))
+	if err != nil {
+		utils.ErrorResponse(w, r, "request.query_error", apperror.BadRequest("query.invalid_param").WithParams(i18n.Params{"param": "bbox"}))
+		return
+	}
+
+	subscription, snapshot, err := services.SubscribeBikeStream(r.Context(), user, bbox)
+	if err != nil {
+		utils.ErrorResponse(w, r, "stream.error", err)
+		return
+	}
+	defer subscription.Close()
+
+	// la conexión dura más que el WriteTimeout del servidor, cada escritura tiene su propio límite
+	rc := http.NewResponseController(w)
+	rc.SetReadDeadline(time.Time{})
+	rc.SetWriteDeadline(time.Time{})
+
+	if isWebSocketUpgrade(r) {
+		err = streamWebSocket(w, r, subscription, snapshot)
+	} else {
+		err = streamSSE(w, r, rc, subscription, snapshot)
+	}
+	if err != nil && r.Context().Err() == nil {
+		slog.DebugContext(r.Context(), "bike stream write failed", "error", err)
+	}
+	slog.InfoContext(r.Context(), "bike stream closed", "reason", closeReason(subscription))
+}
+
+// streamSSE: Escribe los eventos con el formato de Server-Sent Events hasta que el cliente se desconecte
+// o el hub cierre la suscripción
+func streamSSE(w http.ResponseWriter, r *http.Request, rc *http.ResponseController, subscription *stream.Subscription, snapshot interface{}) error {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	write := func(event string, data interface{}) error {
+		rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+		if event == "" {
+			// comentario de keep-alive, los clientes lo ignoran
+			fmt.Fprint(w, ": ping\n\n")
+		} else {
+			payload, err := json.Marshal(data)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+		}
+		return rc.Flush()
+	}
+
+	if err := write(stream.EventSnapshot, snapshot); err != nil {
+		return err
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return nil
+		case <-subscription.Done():
+			return write("close", map[string]string{"reason": closeReason(subscription)})
+		case event := <-subscription.Events():
+			if err := write(event.Type, event.Data); err != nil {
+				return err
+			}
+		case <-heartbeat.C:
+			if err := write("", nil); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// streamWebSocket: Envía los eventos como mensajes JSON {type, data}. Los mensajes del cliente se ignoran
+func streamWebSocket(w http.ResponseWriter, r *http.Request, subscription *stream.Subscription, snapshot interface{}) error {
+	conn, err := websocket.Accept(w, r, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.CloseNow()
+
+	// CloseRead responde a los pings y cancela ctx cuando el cliente cierra la conexión
+	ctx := conn.CloseRead(r.Context())
+	write := func(msg streamMessage) error {
+		ctx, cancel := context.WithTimeout(ctx, streamWriteTimeout)
+		defer cancel()
+		return wsjson.Write(ctx, conn, msg)
+	}
+
+	if err := write(streamMessage{Type: stream.EventSnapshot, Data: snapshot}); err != nil {
+		return err
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-subscription.Done():
+			// 1013 (try again later): el cliente debe reconectarse
+			return conn.Close(websocket.StatusTryAgainLater, closeReason(subscription))
+		case event := <-subscription.Events():
+			if err := write(streamMessage{Type: event.Type, Data: event.Data}); err != nil {
+				return err
+			}
+		case <-heartbeat.C:
+			pingCtx, cancel := context.WithTimeout(ctx, streamWriteTimeout)
+			err := conn.Ping(pingCtx)
+			cancel()
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func isWebSocketUpgrade(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
+
+// closeReason: Motivo del cierre para el cliente y los logs
+func closeReason(subscription *stream.Subscription) string {
+	select {
+	case <-subscription.Done():
+	default:
+		return "client_disconnected"
+	}
+	switch err := subscription.Err(); {
+	case errors.Is(err, stream.ErrSlowConsumer):
+		return "slow_consumer"
+	case errors.Is(err, stream.ErrClosed):
+		return "shutting_down"
+	}
+	return "client_disconnected"
+}
@@ -0,0 +1,145 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/mbarolo/test_back/apperror"
+	"github.com/mbarolo/test_back/forms"
+	"github.com/mbarolo/test_back/i18n"
+	"github.com/mbarolo/test_back/middleware"
+	"github.com/mbarolo/test_back/services"
+	"github.com/mbarolo/test_back/utils"
+)
+
+// IngestTelemetry godoc
+// @Summary      Enviar telemetría
+// @Description  Recibe un lote de lecturas del dispositivo de la bicicleta: posiciones GPS, batería, estado del candado y códigos de error.
+// @Description  La bicicleta queda con el dato más reciente de cada tipo. Se autentica con el token del dispositivo
+// @Tags         devices
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        telemetry  body      forms.TelemetryForm  true  "Lote de lecturas"
+// @Success      200        {object}  map[string]interface{}
+// @Failure      400        {object}  map[string]interface{}
+// @Failure      401        {object}  map[string]interface{}
+// @Failure      422        {object}  map[string]interface{}
+// @Failure      429        {object}  map[string]interface{}
+// @Failure      500        {object}  map[string]interface{}
+// @Router       /devices/telemetry [post]
+func IngestTelemetry(w http.ResponseWriter, r *http.Request) {
+	bikeId, ok := middleware.DeviceBikeId(r.Context())
+	if !ok {
+		utils.ErrorResponse(w, r, "telemetry.ingest_error", apperror.Unauthorized("device.invalid_token"))
+		return
+	}
+
+	var telemetryForm forms.TelemetryForm
+	if err := decodeBody(w, r, &telemetryForm, false); err != nil {
+		utils.ErrorResponse(w, r, "telemetry.ingest_error", err)
+		return
+	}
+
+	accepted, err := services.IngestTelemetry(r.Context(), bikeId, telemetryForm.ToReadings())
+	if err != nil {
+		utils.ErrorResponse(w, r, "telemetry.ingest_error", err)
+		return
+	}
+
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "telemetry.accepted", i18n.Params{"count": accepted}), map[string]int{"accepted": accepted})
+}
+
+// GetBikeTelemetry godoc
+// @Summary      Telemetría de una bicicleta
+// @Description  Historial de lecturas enviadas por el dispositivo de la bicicleta (admin)
+// @Tags         admin
+// @Produce      json
+// @Security     BasicAuth
+// @Param        id          path      int     true   "ID de la bicicleta"
+// @Param        limit       query     int     false  "Cantidad de resultados por página"
+// @Param        cursor      query     string  false  "Cursor de la página siguiente"
+// @Param        sort        query     string  false  "Campo de ordenamiento, con - para descendente (default: -recorded_at)"
+// @Param        from        query     string  false  "Lecturas tomadas desde esta fecha (RFC 3339 o YYYY-MM-DD)"
+// @Param        to          query     string  false  "Lecturas tomadas antes de esta fecha (RFC 3339 o YYYY-MM-DD)"
+// @Param        lock_state  query     string  false  "Filtrar por estado del candado (locked, unlocked)"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /admin/bikes/{id}/telemetry [get]
+func GetBikeTelemetry(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIdParam(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "telemetry.list_error", err)
+		return
+	}
+
+	spec, err := utils.ParseQuerySpec(r.URL.Query(), forms.TelemetryQuery)
+	if err != nil {
+		utils.ErrorResponse(w, r, "request.query_error", err)
+		return
+	}
+
+	readings, err := services.GetBikeTelemetry(r.Context(), id, spec)
+	if err != nil {
+		utils.ErrorResponse(w, r, "telemetry.list_error", err)
+		return
+	}
+
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "telemetry.list_ok", i18n.Params{"count": readings.TotalCount}), readings)
+}
+
+// IssueDeviceToken godoc
+// @Summary      Emitir token de dispositivo
+// @Description  Genera el token con el que el dispositivo de la bicicleta envía telemetría. Si ya tenía uno lo reemplaza; el token solo se muestra en esta respuesta (admin)
+// @Tags         admin
+// @Produce      json
+// @Security     BasicAuth
+// @Param        id   path      int  true  "ID de la bicicleta"
+// @Success      201  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /admin/bikes/{id}/device [post]
+func IssueDeviceToken(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIdParam(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "device.issue_error", err)
+		return
+	}
+
+	device, err := services.IssueDeviceToken(r.Context(), id)
+	if err != nil {
+		utils.ErrorResponse(w, r, "device.issue_error", err)
+		return
+	}
+
+	utils.JsonResponse(w, http.StatusCreated, i18n.T(r, "device.issued"), device)
+}
+
+// RevokeDevice godoc
+// @Summary      Revocar dispositivo
+// @Description  Elimina el dispositivo de la bicicleta, su token deja de ser válido (admin)
+// @Tags         admin
+// @Produce      json
+// @Security     BasicAuth
+// @Param        id   path      int  true  "ID de la bicicleta"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /admin/bikes/{id}/device [delete]
+func RevokeDevice(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIdParam(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "device.revoke_error", err)
+		return
+	}
+
+	if err := services.RevokeDevice(r.Context(), id); err != nil {
+		utils.ErrorResponse(w, r, "device.revoke_error", err)
+		return
+	}
+
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "device.revoked"), nil)
+}
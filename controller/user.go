@@ -1,12 +1,10 @@
 package controller
 
 import (
-	"encoding/json"
 	"net/http"
-	"strconv"
 
-	"github.com/go-chi/chi/v5"
 	"github.com/mbarolo/test_back/forms"
+	"github.com/mbarolo/test_back/i18n"
 	"github.com/mbarolo/test_back/services"
 	"github.com/mbarolo/test_back/utils"
 )
@@ -24,11 +22,12 @@ import (
 func GetProfile(w http.ResponseWriter, r *http.Request) {
 	user, err := services.GetCurrentUser(r)
 	if err != nil {
-		utils.JsonResponse(w, http.StatusUnauthorized, "Error al obtener el usuario autenticado: "+err.Error(), nil)
+		utils.ErrorResponse(w, r, "auth.current_user_error", err)
 		return
 	}
 
-	utils.JsonResponse(w, http.StatusOK, "Perfil obtenido", user)
+	setETag(w, user.Version)
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "user.profile_ok"), user)
 }
 
 // GetAllUsers godoc
@@ -38,17 +37,31 @@ func GetProfile(w http.ResponseWriter, r *http.Request) {
 // @Accept       json
 // @Produce      json
 // @Security     BasicAuth
+// @Param        limit    query     int     false  "Cantidad de resultados por página"
+// @Param        cursor   query     string  false  "Cursor de la página siguiente"
+// @Param        sort     query     string  false  "Campo de ordenamiento, con - para descendente (ej: -created_at)"
+// @Param        email    query     string  false  "Filtrar por email"
+// @Param        deleted  query     bool    false  "Filtrar por usuarios eliminados"
+// @Param        from     query     string  false  "Fecha de creación desde (RFC3339 o 2006-01-02)"
+// @Param        to       query     string  false  "Fecha de creación hasta (RFC3339 o 2006-01-02)"
 // @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
 // @Failure      500  {object}  map[string]interface{}
 // @Router       /admin/users [get]
 func GetAllUsers(w http.ResponseWriter, r *http.Request) {
-	users, err := services.GetAllUsers()
+	spec, err := utils.ParseQuerySpec(r.URL.Query(), forms.UserQuery)
+	if err != nil {
+		utils.ErrorResponse(w, r, "request.query_error", err)
+		return
+	}
+
+	users, err := services.GetAllUsers(r.Context(), spec)
 	if err != nil {
-		utils.JsonResponse(w, http.StatusInternalServerError, "Error al obtener los usuarios: "+err.Error(), nil)
+		utils.ErrorResponse(w, r, "user.list_error", err)
 		return
 	}
 
-	utils.JsonResponse(w, http.StatusOK, "Usuarios obtenidos", users)
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "user.list_ok", i18n.Params{"count": users.TotalCount}), users)
 }
 
 // GetUserById godoc
@@ -64,25 +77,20 @@ func GetAllUsers(w http.ResponseWriter, r *http.Request) {
 // @Failure      500  {object}  map[string]interface{}
 // @Router       /admin/users/{id} [get]
 func GetUserById(w http.ResponseWriter, r *http.Request) {
-	idStr := chi.URLParam(r, "id")
-	if idStr == "" {
-		utils.JsonResponse(w, http.StatusBadRequest, "Parametro id no encontrado", nil)
-		return
-	}
-
-	id, err := strconv.Atoi(idStr)
+	id, err := parseIdParam(r)
 	if err != nil {
-		utils.JsonResponse(w, http.StatusInternalServerError, "Error al convertir el parametro id: "+err.Error(), nil)
+		utils.ErrorResponse(w, r, "user.get_error", err)
 		return
 	}
 
-	user, err := services.GetUserById(int64(id))
+	user, err := services.GetUserById(r.Context(), id)
 	if err != nil {
-		utils.JsonResponse(w, http.StatusInternalServerError, "Error al obtener el usuario: "+err.Error(), nil)
+		utils.ErrorResponse(w, r, "user.get_error", err)
 		return
 	}
 
-	utils.JsonResponse(w, http.StatusOK, "Usuario obtenido", user)
+	setETag(w, user.Version)
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "user.get_ok"), user)
 }
 
 // UpdateProfile godoc
@@ -92,32 +100,43 @@ func GetUserById(w http.ResponseWriter, r *http.Request) {
 // @Accept       json
 // @Produce      json
 // @Security     BearerAuth
-// @Param        user  body      forms.UserForm  true  "Datos actualizados del usuario"
+// @Param        If-Match  header    string          true  "Versión esperada (ETag) del perfil, * actualiza sin verificarla"
+// @Param        user      body      forms.UserForm  true   "Campos a modificar del usuario"
 // @Success      200   {object}  map[string]interface{}
 // @Failure      400   {object}  map[string]interface{}
 // @Failure      401   {object}  map[string]interface{}
+// @Failure      412   {object}  map[string]interface{}
+// @Failure      428   {object}  map[string]interface{}
 // @Failure      500   {object}  map[string]interface{}
 // @Router       /users/profile [patch]
 func UpdateProfile(w http.ResponseWriter, r *http.Request) {
 	user, err := services.GetCurrentUser(r)
 	if err != nil {
-		utils.JsonResponse(w, http.StatusUnauthorized, "Error al obtener el usuario autenticado: "+err.Error(), nil)
+		utils.ErrorResponse(w, r, "auth.current_user_error", err)
 		return
 	}
 
-	var userForm *forms.UserForm
-	if err := json.NewDecoder(r.Body).Decode(&userForm); err != nil {
-		utils.JsonResponse(w, http.StatusBadRequest, "Error al decodificar el cuerpo de la solicitud: "+err.Error(), nil)
+	ifMatch, err := requireIfMatch(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "user.update_error", err)
 		return
 	}
 
-	updatedUser, err := services.UpdateUser(user.Id, userForm)
+	var userForm forms.UserForm
+	patch, err := decodePatch(w, r, &userForm)
 	if err != nil {
-		utils.JsonResponse(w, http.StatusInternalServerError, "Error al actualizar el usuario: "+err.Error(), nil)
+		utils.ErrorResponse(w, r, "user.update_error", err)
 		return
 	}
 
-	utils.JsonResponse(w, http.StatusOK, "Usuario actualizado correctamente", updatedUser)
+	updatedUser, err := services.UpdateUser(r.Context(), user.Id, &userForm, patch, ifMatch)
+	if err != nil {
+		utils.ErrorResponse(w, r, "user.update_error", err)
+		return
+	}
+
+	setETag(w, updatedUser.Version)
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "user.updated"), updatedUser)
 }
 
 // UpdateUser godoc
@@ -127,36 +146,41 @@ func UpdateProfile(w http.ResponseWriter, r *http.Request) {
 // @Accept       json
 // @Produce      json
 // @Security     BasicAuth
-// @Param        id    path      int             true  "ID del usuario"
-// @Param        user  body      forms.UserForm  true  "Datos actualizados del usuario"
+// @Param        id        path      int             true   "ID del usuario"
+// @Param        If-Match  header    string          true  "Versión esperada (ETag) del usuario, * actualiza sin verificarla"
+// @Param        user      body      forms.UserForm  true   "Campos a modificar del usuario"
 // @Success      200   {object}  map[string]interface{}
 // @Failure      400   {object}  map[string]interface{}
+// @Failure      412   {object}  map[string]interface{}
+// @Failure      428   {object}  map[string]interface{}
 // @Failure      500   {object}  map[string]interface{}
 // @Router       /admin/users/{id} [patch]
 func UpdateUser(w http.ResponseWriter, r *http.Request) {
-	idStr := chi.URLParam(r, "id")
-	if idStr == "" {
-		utils.JsonResponse(w, http.StatusBadRequest, "Parametro id no encontrado", nil)
+	id, err := parseIdParam(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "user.update_error", err)
 		return
 	}
 
-	id, err := strconv.Atoi(idStr)
+	ifMatch, err := requireIfMatch(r)
 	if err != nil {
-		utils.JsonResponse(w, http.StatusInternalServerError, "Error al convertir el parametro id: "+err.Error(), nil)
+		utils.ErrorResponse(w, r, "user.update_error", err)
 		return
 	}
 
-	var userForm *forms.UserForm
-	if err := json.NewDecoder(r.Body).Decode(&userForm); err != nil {
-		utils.JsonResponse(w, http.StatusBadRequest, "Error al decodificar el cuerpo de la solicitud: "+err.Error(), nil)
+	var userForm forms.UserForm
+	patch, err := decodePatch(w, r, &userForm)
+	if err != nil {
+		utils.ErrorResponse(w, r, "user.update_error", err)
 		return
 	}
 
-	updatedUser, err := services.UpdateUser(int64(id), userForm)
+	updatedUser, err := services.UpdateUser(r.Context(), id, &userForm, patch, ifMatch)
 	if err != nil {
-		utils.JsonResponse(w, http.StatusInternalServerError, "Error al actualizar el usuario: "+err.Error(), nil)
+		utils.ErrorResponse(w, r, "user.update_error", err)
 		return
 	}
 
-	utils.JsonResponse(w, http.StatusOK, "Usuario actualizado correctamente", updatedUser)
+	setETag(w, updatedUser.Version)
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "user.updated"), updatedUser)
 }
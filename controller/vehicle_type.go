@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/mbarolo/test_back/forms"
+	"github.com/mbarolo/test_back/i18n"
+	"github.com/mbarolo/test_back/services"
+	"github.com/mbarolo/test_back/utils"
+)
+
+// GetVehicleTypes godoc
+// @Summary      Tipos de vehículo
+// @Description  Catálogo de tipos de vehículo con su tarifa, cargo de desbloqueo y atributos
+// @Tags         bikes
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /vehicle-types [get]
+func GetVehicleTypes(w http.ResponseWriter, r *http.Request) {
+	vehicleTypes, err := services.GetVehicleTypes(r.Context())
+	if err != nil {
+		utils.ErrorResponse(w, r, "vehicle_type.list_error", err)
+		return
+	}
+
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "vehicle_type.list_ok", i18n.Params{"count": len(vehicleTypes)}), vehicleTypes)
+}
+
+// UpdateVehicleType godoc
+// @Summary      Actualizar tipo de vehículo
+// @Description  Modificar la tarifa y los atributos de un tipo de vehículo con JSON Merge Patch. La tarifa nueva aplica a las bicicletas del tipo sin tarifa propia (admin)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BasicAuth
+// @Param        id            path      int                    true   "ID del tipo de vehículo"
+// @Param        If-Match      header    string                 true  "Versión esperada (ETag) del tipo de vehículo, * actualiza sin verificarla"
+// @Param        vehicle_type  body      forms.VehicleTypeForm  true   "Campos a modificar"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      412  {object}  map[string]interface{}
+// @Failure      428  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /admin/vehicle-types/{id} [patch]
+func UpdateVehicleType(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIdParam(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "vehicle_type.update_error", err)
+		return
+	}
+
+	ifMatch, err := requireIfMatch(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "vehicle_type.update_error", err)
+		return
+	}
+
+	var vehicleTypeForm forms.VehicleTypeForm
+	patch, err := decodePatch(w, r, &vehicleTypeForm)
+	if err != nil {
+		utils.ErrorResponse(w, r, "vehicle_type.update_error", err)
+		return
+	}
+
+	vehicleType, err := services.UpdateVehicleType(r.Context(), id, patch, ifMatch)
+	if err != nil {
+		utils.ErrorResponse(w, r, "vehicle_type.update_error", err)
+		return
+	}
+
+	setETag(w, vehicleType.Version)
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "vehicle_type.updated"), vehicleType)
+}
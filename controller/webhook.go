@@ -0,0 +1,249 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/mbarolo/test_back/forms"
+	"github.com/mbarolo/test_back/i18n"
+	"github.com/mbarolo/test_back/services"
+	"github.com/mbarolo/test_back/utils"
+)
+
+// GetAllWebhooks godoc
+// @Summary      Obtener webhooks
+// @Description  Listar las suscripciones de webhooks, sin sus secretos (admin)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BasicAuth
+// @Param        limit      query     int     false  "Cantidad de resultados por página"
+// @Param        cursor     query     string  false  "Cursor de la página siguiente"
+// @Param        sort       query     string  false  "Campo de ordenamiento, con - para descendente (ej: -created_at)"
+// @Param        is_active  query     bool    false  "Filtrar por suscripciones activas"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /admin/webhooks [get]
+func GetAllWebhooks(w http.ResponseWriter, r *http.Request) {
+	spec, err := utils.ParseQuerySpec(r.URL.Query(), forms.WebhookQuery)
+	if err != nil {
+		utils.ErrorResponse(w, r, "request.query_error", err)
+		return
+	}
+
+	webhooks, err := services.GetAllWebhooks(r.Context(), spec)
+	if err != nil {
+		utils.ErrorResponse(w, r, "webhook.list_error", err)
+		return
+	}
+
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "webhook.list_ok", i18n.Params{"count": webhooks.TotalCount}), webhooks)
+}
+
+// CreateWebhook godoc
+// @Summary      Crear webhook
+// @Description  Registrar un endpoint que recibe los eventos indicados (o * para todos) firmados con HMAC-SHA256. Si no se envía un secreto se genera uno, que solo se muestra en esta respuesta (admin)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BasicAuth
+// @Param        webhook  body      forms.WebhookForm  true  "Datos de la suscripción"
+// @Success      201      {object}  map[string]interface{}
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      500      {object}  map[string]interface{}
+// @Router       /admin/webhooks [post]
+func CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var webhookForm forms.WebhookForm
+	if err := decodeBody(w, r, &webhookForm, false); err != nil {
+		utils.ErrorResponse(w, r, "webhook.create_error", err)
+		return
+	}
+
+	webhook, err := services.CreateWebhook(r.Context(), webhookForm.ToWebhook())
+	if err != nil {
+		utils.ErrorResponse(w, r, "webhook.create_error", err)
+		return
+	}
+
+	setETag(w, webhook.Version)
+	utils.JsonResponse(w, http.StatusCreated, i18n.T(r, "webhook.created"), webhook)
+}
+
+// GetWebhookById godoc
+// @Summary      Obtener webhook por ID
+// @Description  Obtener una suscripción de webhook, con su versión en el header ETag (admin)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BasicAuth
+// @Param        id   path      int  true  "ID del webhook"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /admin/webhooks/{id} [get]
+func GetWebhookById(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIdParam(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "webhook.get_error", err)
+		return
+	}
+
+	webhook, err := services.GetWebhookById(r.Context(), id)
+	if err != nil {
+		utils.ErrorResponse(w, r, "webhook.get_error", err)
+		return
+	}
+
+	setETag(w, webhook.Version)
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "webhook.get_ok"), webhook)
+}
+
+// UpdateWebhook godoc
+// @Summary      Actualizar webhook
+// @Description  Modificar una suscripción con JSON Merge Patch, ej: desactivarla o rotar su secreto (admin)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Security     BasicAuth
+// @Param        id        path      int                true   "ID del webhook"
+// @Param        If-Match  header    string             true  "Versión esperada (ETag) del webhook, * actualiza sin verificarla"
+// @Param        webhook   body      forms.WebhookForm  true   "Campos a modificar de la suscripción"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      412  {object}  map[string]interface{}
+// @Failure      428  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /admin/webhooks/{id} [patch]
+func UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIdParam(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "webhook.update_error", err)
+		return
+	}
+
+	ifMatch, err := requireIfMatch(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "webhook.update_error", err)
+		return
+	}
+
+	var webhookForm forms.WebhookForm
+	patch, err := decodePatch(w, r, &webhookForm)
+	if err != nil {
+		utils.ErrorResponse(w, r, "webhook.update_error", err)
+		return
+	}
+
+	webhook, err := services.UpdateWebhook(r.Context(), id, patch, ifMatch)
+	if err != nil {
+		utils.ErrorResponse(w, r, "webhook.update_error", err)
+		return
+	}
+
+	setETag(w, webhook.Version)
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "webhook.updated"), webhook)
+}
+
+// DeleteWebhook godoc
+// @Summary      Eliminar webhook
+// @Description  Eliminar una suscripción junto con su historial de entregas (admin)
+// @Tags         admin
+// @Produce      json
+// @Security     BasicAuth
+// @Param        id   path      int  true  "ID del webhook"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /admin/webhooks/{id} [delete]
+func DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIdParam(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "webhook.delete_error", err)
+		return
+	}
+
+	if err := services.DeleteWebhook(r.Context(), id); err != nil {
+		utils.ErrorResponse(w, r, "webhook.delete_error", err)
+		return
+	}
+
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "webhook.deleted"), nil)
+}
+
+// GetWebhookDeliveries godoc
+// @Summary      Entregas de un webhook
+// @Description  Historial de entregas de la suscripción, con el estado (pending, succeeded, dead), los intentos y el resultado del último intento (admin)
+// @Tags         admin
+// @Produce      json
+// @Security     BasicAuth
+// @Param        id          path      int     true   "ID del webhook"
+// @Param        limit       query     int     false  "Cantidad de resultados por página"
+// @Param        cursor      query     string  false  "Cursor de la página siguiente"
+// @Param        sort        query     string  false  "Campo de ordenamiento, con - para descendente (default: -id)"
+// @Param        status      query     string  false  "Filtrar por estado (pending, succeeded, dead)"
+// @Param        event_type  query     string  false  "Filtrar por tipo de evento"
+// @Param        event_id    query     int     false  "Filtrar por id del evento"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /admin/webhooks/{id}/deliveries [get]
+func GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIdParam(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "webhook.deliveries_error", err)
+		return
+	}
+
+	spec, err := utils.ParseQuerySpec(r.URL.Query(), forms.WebhookDeliveryQuery)
+	if err != nil {
+		utils.ErrorResponse(w, r, "request.query_error", err)
+		return
+	}
+
+	deliveries, err := services.GetWebhookDeliveries(r.Context(), id, spec)
+	if err != nil {
+		utils.ErrorResponse(w, r, "webhook.deliveries_error", err)
+		return
+	}
+
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "webhook.deliveries_ok", i18n.Params{"count": deliveries.TotalCount}), deliveries)
+}
+
+// RetryWebhookDelivery godoc
+// @Summary      Reintentar entrega
+// @Description  Volver a encolar una entrega que agotó sus reintentos (estado dead) (admin)
+// @Tags         admin
+// @Produce      json
+// @Security     BasicAuth
+// @Param        id          path      int  true  "ID del webhook"
+// @Param        deliveryId  path      int  true  "ID de la entrega"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]interface{}
+// @Failure      500  {object}  map[string]interface{}
+// @Router       /admin/webhooks/{id}/deliveries/{deliveryId}/retry [post]
+func RetryWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	id, err := parseIdParam(r)
+	if err != nil {
+		utils.ErrorResponse(w, r, "webhook.retry_error", err)
+		return
+	}
+	deliveryId, err := parseIdParamNamed(r, "deliveryId")
+	if err != nil {
+		utils.ErrorResponse(w, r, "webhook.retry_error", err)
+		return
+	}
+
+	delivery, err := services.RetryWebhookDelivery(r.Context(), id, deliveryId)
+	if err != nil {
+		utils.ErrorResponse(w, r, "webhook.retry_error", err)
+		return
+	}
+
+	utils.JsonResponse(w, http.StatusOK, i18n.T(r, "webhook.retry_ok"), delivery)
+}
@@ -3,17 +3,19 @@ package forms
 import "github.com/mbarolo/test_back/models"
 
 type UserForm struct {
-	Email          string `json:"email,omitempty"`
-	HashedPassword string `json:"hashed_password,omitempty"`
-	FirstName      string `json:"first_name,omitempty"`
-	LastName       string `json:"last_name,omitempty"`
+	Email     string `json:"email,omitempty" validate:"required,email,max=254"`
+	Password  string `json:"password,omitempty" validate:"required,password,max_bytes=72"`
+	FirstName string `json:"first_name,omitempty" validate:"required,max=100"`
+	LastName  string `json:"last_name,omitempty" validate:"required,max=100"`
+	Language  string `json:"language,omitempty" validate:"language"`
 }
 
+// ToUser: Convierte el form en usuario, la contraseña se hashea por separado
 func (uf *UserForm) ToUser() *models.User {
 	return &models.User{
-		Email:          uf.Email,
-		HashedPassword: uf.HashedPassword,
-		FirstName:      uf.FirstName,
-		LastName:       uf.LastName,
+		Email:     uf.Email,
+		FirstName: uf.FirstName,
+		LastName:  uf.LastName,
+		Language:  uf.Language,
 	}
 }
@@ -1,19 +1,97 @@
 package forms
 
-import "github.com/mbarolo/test_back/models"
+import (
+	"net/url"
+	"strconv"
+	"strings"
 
+	"github.com/mbarolo/test_back/apperror"
+	"github.com/mbarolo/test_back/i18n"
+	"github.com/mbarolo/test_back/models"
+)
+
+// Radio de búsqueda de bicicletas cercanas, en metros
+const (
+	DefaultNearbyRadius = 1000
+	MaxNearbyRadius     = 20000
+)
+
+// BikeForm: Sin cost_per_minute_override la bicicleta cobra la tarifa de su tipo (classic por defecto) y sigue
+// sus cambios; en un patch null la vuelve a la tarifa del tipo. El estado se cambia aparte con BikeStatusForm
 type BikeForm struct {
-	IsAvailable   *bool    `json:"is_available"`
-	Latitude      *float64 `json:"latitude"`
-	Longitude     *float64 `json:"longitude"`
-	CostPerMinute *int     `json:"cost_per_minute"`
+	Latitude              *float64 `json:"latitude" validate:"required,min=-90,max=90"`
+	Longitude             *float64 `json:"longitude" validate:"required,min=-180,max=180"`
+	CostPerMinuteOverride *int     `json:"cost_per_minute_override" validate:"min=0"`
+	VehicleType           *string  `json:"vehicle_type"`
+	BatteryLevel          *int     `json:"battery_level" validate:"min=0,max=100"`
 }
 
+// ToBike: Convierte el form en bicicleta, los campos omitidos quedan con su valor cero
 func (bf *BikeForm) ToBike() *models.Bike {
-	return &models.Bike{
-		IsAvailable:   *bf.IsAvailable,
-		Latitude:      *bf.Latitude,
-		Longitude:     *bf.Longitude,
-		CostPerMinute: *bf.CostPerMinute,
+	bike := &models.Bike{VehicleType: models.VehicleClassic, CostPerMinuteOverride: bf.CostPerMinuteOverride, BatteryLevel: bf.BatteryLevel}
+	if bf.Latitude != nil {
+		bike.Latitude = *bf.Latitude
+	}
+	if bf.Longitude != nil {
+		bike.Longitude = *bf.Longitude
 	}
+	if bf.VehicleType != nil {
+		bike.VehicleType = *bf.VehicleType
+	}
+	return bike
+}
+
+// BikeStatusForm: Estado nuevo de la bicicleta y el motivo del cambio. El estado rented solo lo asignan los alquileres
+type BikeStatusForm struct {
+	Status string `json:"status" validate:"required,oneof=available reserved maintenance missing retired"`
+	Reason string `json:"reason" validate:"required,min=1,max=500"`
+}
+
+// AvailableBikesQuery: Filtros de las bicicletas disponibles. Con Near se buscan las que están a menos de RadiusM
+// metros, ordenadas por distancia
+type AvailableBikesQuery struct {
+	VehicleType string
+	MinBattery  *int
+	Near        *[2]float64 // latitud, longitud
+	RadiusM     float64
+}
+
+// ParseAvailableBikesQuery: Interpreta los parámetros type, min_battery, near (lat,lng) y radius (metros)
+func ParseAvailableBikesQuery(values url.Values) (*AvailableBikesQuery, error) {
+	query := &AvailableBikesQuery{VehicleType: values.Get("type"), RadiusM: DefaultNearbyRadius}
+
+	if minBattery := values.Get("min_battery"); minBattery != "" {
+		n, err := strconv.Atoi(minBattery)
+		if err != nil || n < 0 || n > 100 {
+			return nil, invalidParam("min_battery")
+		}
+		query.MinBattery = &n
+	}
+
+	if near := values.Get("near"); near != "" {
+		parts := strings.Split(near, ",")
+		if len(parts) != 2 {
+			return nil, invalidParam("near")
+		}
+		lat, errLat := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+		lng, errLng := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if errLat != nil || errLng != nil || lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+			return nil, invalidParam("near")
+		}
+		query.Near = &[2]float64{lat, lng}
+	}
+
+	if radius := values.Get("radius"); radius != "" {
+		n, err := strconv.ParseFloat(radius, 64)
+		if err != nil || n <= 0 || n > MaxNearbyRadius || query.Near == nil {
+			return nil, invalidParam("radius")
+		}
+		query.RadiusM = n
+	}
+
+	return query, nil
+}
+
+func invalidParam(name string) error {
+	return apperror.BadRequest("query.invalid_param").WithParams(i18n.Params{"param": name})
 }
@@ -0,0 +1,12 @@
+package forms
+
+// IncidentAckForm: Quién se ocupa del incidente
+type IncidentAckForm struct {
+	AcknowledgedBy string `json:"acknowledged_by" validate:"required,min=1,max=100"`
+}
+
+// IncidentResolveForm: Cierre del incidente, ej: "bicicleta recuperada" o "falsa alarma"
+type IncidentResolveForm struct {
+	ResolvedBy string `json:"resolved_by" validate:"required,min=1,max=100"`
+	Resolution string `json:"resolution" validate:"required,min=1,max=2000"`
+}
@@ -0,0 +1,30 @@
+package forms
+
+import (
+	"github.com/mbarolo/test_back/lock"
+)
+
+// LockDeviceForm: Estado del candado simulado, los campos que no se envían quedan en false y el candado cerrado
+type LockDeviceForm struct {
+	State   *string `json:"state" validate:"oneof=locked unlocked"`
+	Offline *bool   `json:"offline"`
+	Silent  *bool   `json:"silent"`
+	Jammed  *bool   `json:"jammed"`
+}
+
+func (lf *LockDeviceForm) ToDevice() lock.Device {
+	device := lock.Device{State: lock.StateLocked}
+	if lf.State != nil {
+		device.State = *lf.State
+	}
+	if lf.Offline != nil {
+		device.Offline = *lf.Offline
+	}
+	if lf.Silent != nil {
+		device.Silent = *lf.Silent
+	}
+	if lf.Jammed != nil {
+		device.Jammed = *lf.Jammed
+	}
+	return device
+}
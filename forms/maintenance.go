@@ -0,0 +1,24 @@
+package forms
+
+// DamageReportForm: Daño reportado por el ciclista, la foto llega aparte en el formulario multipart
+type DamageReportForm struct {
+	Category    string `json:"category" validate:"required,oneof=brakes tires chain lights battery lock frame other"`
+	Description string `json:"description" validate:"required,min=1,max=2000"`
+}
+
+// WorkOrderForm: Orden de trabajo abierta por el admin. Sin priority toma la de la categoría
+type WorkOrderForm struct {
+	Category    string  `json:"category" validate:"required,oneof=brakes tires chain lights battery lock frame other"`
+	Description string  `json:"description" validate:"required,min=1,max=2000"`
+	Priority    *string `json:"priority" validate:"oneof=normal critical"`
+	Mechanic    *string `json:"mechanic" validate:"min=1,max=100"`
+}
+
+// WorkOrderPatchForm: Campos modificables de una orden de trabajo, la bicicleta y la categoría son fijas
+type WorkOrderPatchForm struct {
+	Status      *string `json:"status" validate:"oneof=open assigned in_progress resolved"`
+	Priority    *string `json:"priority" validate:"oneof=normal critical"`
+	Description *string `json:"description" validate:"min=1,max=2000"`
+	Mechanic    *string `json:"mechanic" validate:"min=1,max=100"`
+	Resolution  *string `json:"resolution" validate:"max=2000"`
+}
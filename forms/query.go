@@ -0,0 +1,199 @@
+package forms
+
+import "github.com/mbarolo/test_back/utils"
+
+// Parámetros de consulta aceptados por los listados paginados
+
+var UserQuery = utils.QueryConfig{
+	Filters: map[string]utils.QueryField{
+		"email":   {Column: "email", Kind: utils.KindString},
+		"deleted": {Column: "deleted", Kind: utils.KindBool},
+	},
+	Sorts: map[string]utils.QueryField{
+		"created_at": {Column: "created_at", Kind: utils.KindTime},
+		"email":      {Column: "email", Kind: utils.KindString},
+	},
+	RangeField: &utils.QueryField{Column: "created_at", Kind: utils.KindTime},
+}
+
+var BikeQuery = utils.QueryConfig{
+	Filters: map[string]utils.QueryField{
+		"status":       {Column: "status", Kind: utils.KindString},
+		"vehicle_type": {Column: "vehicle_type", Kind: utils.KindString},
+	},
+	Sorts: map[string]utils.QueryField{
+		"created_at":      {Column: "created_at", Kind: utils.KindTime},
+		"cost_per_minute": {Column: "cost_per_minute", Kind: utils.KindInt},
+	},
+	RangeField: &utils.QueryField{Column: "created_at", Kind: utils.KindTime},
+}
+
+var RentalQuery = utils.QueryConfig{
+	Filters: map[string]utils.QueryField{
+		"status":     {Column: "rental_status", Kind: utils.KindString},
+		"bike_id":    {Column: "bike_id", Kind: utils.KindInt},
+		"user_id":    {Column: "user_id", Kind: utils.KindInt},
+		"end_reason": {Column: "end_reason", Kind: utils.KindString},
+	},
+	Sorts: map[string]utils.QueryField{
+		"start_time": {Column: "start_time", Kind: utils.KindTime},
+	},
+	RangeField:  &utils.QueryField{Column: "start_time", Kind: utils.KindTime},
+	DefaultSort: "-start_time",
+}
+
+// RentalHistoryQuery: El historial ya está filtrado por el usuario autenticado
+var RentalHistoryQuery = utils.QueryConfig{
+	Filters: map[string]utils.QueryField{
+		"status":  RentalQuery.Filters["status"],
+		"bike_id": RentalQuery.Filters["bike_id"],
+	},
+	Sorts:       RentalQuery.Sorts,
+	RangeField:  RentalQuery.RangeField,
+	DefaultSort: RentalQuery.DefaultSort,
+}
+
+var AuditQuery = utils.QueryConfig{
+	Filters: map[string]utils.QueryField{
+		"actor":       {Column: "actor", Kind: utils.KindString},
+		"action":      {Column: "action", Kind: utils.KindString},
+		"entity_type": {Column: "entity_type", Kind: utils.KindString},
+		"entity_id":   {Column: "entity_id", Kind: utils.KindInt},
+		"request_id":  {Column: "request_id", Kind: utils.KindString},
+	},
+	Sorts: map[string]utils.QueryField{
+		"created_at": {Column: "created_at", Kind: utils.KindTime},
+	},
+	RangeField:  &utils.QueryField{Column: "created_at", Kind: utils.KindTime},
+	DefaultSort: "-id",
+}
+
+// AuditExportQuery: Filtros del listado de auditoría más el formato de la exportación
+var AuditExportQuery = utils.QueryConfig{
+	Filters:     AuditQuery.Filters,
+	Sorts:       AuditQuery.Sorts,
+	RangeField:  AuditQuery.RangeField,
+	DefaultSort: AuditQuery.DefaultSort,
+	Params:      []string{"format"},
+}
+
+var WebhookQuery = utils.QueryConfig{
+	Filters: map[string]utils.QueryField{
+		"is_active": {Column: "is_active", Kind: utils.KindBool},
+	},
+	Sorts: map[string]utils.QueryField{
+		"created_at": {Column: "created_at", Kind: utils.KindTime},
+	},
+	RangeField: &utils.QueryField{Column: "created_at", Kind: utils.KindTime},
+}
+
+var WebhookDeliveryQuery = utils.QueryConfig{
+	Filters: map[string]utils.QueryField{
+		"status":     {Column: "status", Kind: utils.KindString},
+		"event_type": {Column: "event_type", Kind: utils.KindString},
+		"event_id":   {Column: "event_id", Kind: utils.KindInt},
+	},
+	Sorts: map[string]utils.QueryField{
+		"created_at":      {Column: "created_at", Kind: utils.KindTime},
+		"next_attempt_at": {Column: "next_attempt_at", Kind: utils.KindTime},
+	},
+	RangeField:  &utils.QueryField{Column: "created_at", Kind: utils.KindTime},
+	DefaultSort: "-id",
+}
+
+// BikeStatusHistoryQuery: El historial ya está filtrado por la bicicleta
+var BikeStatusHistoryQuery = utils.QueryConfig{
+	Filters: map[string]utils.QueryField{
+		"to_status": {Column: "to_status", Kind: utils.KindString},
+		"actor":     {Column: "actor", Kind: utils.KindString},
+	},
+	Sorts: map[string]utils.QueryField{
+		"created_at": {Column: "created_at", Kind: utils.KindTime},
+	},
+	RangeField:  &utils.QueryField{Column: "created_at", Kind: utils.KindTime},
+	DefaultSort: "-id",
+}
+
+// TelemetryQuery: El historial ya está filtrado por la bicicleta
+var TelemetryQuery = utils.QueryConfig{
+	Filters: map[string]utils.QueryField{
+		"lock_state": {Column: "lock_state", Kind: utils.KindString},
+	},
+	Sorts: map[string]utils.QueryField{
+		"recorded_at": {Column: "recorded_at", Kind: utils.KindTime},
+	},
+	RangeField:  &utils.QueryField{Column: "recorded_at", Kind: utils.KindTime},
+	DefaultSort: "-recorded_at",
+}
+
+var WorkOrderQuery = utils.QueryConfig{
+	Filters: map[string]utils.QueryField{
+		"status":   {Column: "status", Kind: utils.KindString},
+		"priority": {Column: "priority", Kind: utils.KindString},
+		"category": {Column: "category", Kind: utils.KindString},
+		"mechanic": {Column: "mechanic", Kind: utils.KindString},
+		"bike_id":  {Column: "bike_id", Kind: utils.KindInt},
+	},
+	Sorts: map[string]utils.QueryField{
+		"created_at": {Column: "created_at", Kind: utils.KindTime},
+		"updated_at": {Column: "updated_at", Kind: utils.KindTime},
+	},
+	RangeField:  &utils.QueryField{Column: "created_at", Kind: utils.KindTime},
+	DefaultSort: "-id",
+}
+
+// ServiceHistoryQuery: El historial ya está filtrado por la bicicleta
+var ServiceHistoryQuery = utils.QueryConfig{
+	Filters: map[string]utils.QueryField{
+		"status":   WorkOrderQuery.Filters["status"],
+		"category": WorkOrderQuery.Filters["category"],
+	},
+	Sorts:       WorkOrderQuery.Sorts,
+	RangeField:  WorkOrderQuery.RangeField,
+	DefaultSort: WorkOrderQuery.DefaultSort,
+}
+
+var StationQuery = utils.QueryConfig{
+	Filters: map[string]utils.QueryField{
+		"active": {Column: "active", Kind: utils.KindBool},
+		"name":   {Column: "name", Kind: utils.KindString},
+	},
+	Sorts: map[string]utils.QueryField{
+		"created_at": {Column: "created_at", Kind: utils.KindTime},
+		"name":       {Column: "name", Kind: utils.KindString},
+		"free_docks": {Column: "free_docks", Kind: utils.KindInt},
+	},
+	RangeField: &utils.QueryField{Column: "created_at", Kind: utils.KindTime},
+}
+
+var RebalancingTaskQuery = utils.QueryConfig{
+	Filters: map[string]utils.QueryField{
+		"status":     {Column: "status", Kind: utils.KindString},
+		"claimed_by": {Column: "claimed_by", Kind: utils.KindString},
+		"from_cell":  {Column: "from_cell", Kind: utils.KindString},
+		"to_cell":    {Column: "to_cell", Kind: utils.KindString},
+	},
+	Sorts: map[string]utils.QueryField{
+		"created_at": {Column: "created_at", Kind: utils.KindTime},
+		"bikes":      {Column: "bikes", Kind: utils.KindInt},
+		"distance_m": {Column: "distance_m", Kind: utils.KindInt},
+	},
+	RangeField:  &utils.QueryField{Column: "created_at", Kind: utils.KindTime},
+	DefaultSort: "-id",
+}
+
+var IncidentQuery = utils.QueryConfig{
+	Filters: map[string]utils.QueryField{
+		"status":   {Column: "status", Kind: utils.KindString},
+		"rule":     {Column: "rule", Kind: utils.KindString},
+		"severity": {Column: "severity", Kind: utils.KindString},
+		"bike_id":  {Column: "bike_id", Kind: utils.KindInt},
+	},
+	Sorts: map[string]utils.QueryField{
+		"first_seen_at": {Column: "first_seen_at", Kind: utils.KindTime},
+		"last_seen_at":  {Column: "last_seen_at", Kind: utils.KindTime},
+		"occurrences":   {Column: "occurrences", Kind: utils.KindInt},
+	},
+	RangeField:  &utils.QueryField{Column: "first_seen_at", Kind: utils.KindTime},
+	DefaultSort: "-id",
+}
@@ -0,0 +1,36 @@
+package forms
+
+import (
+	"net/url"
+	"time"
+)
+
+// RebalancingClaimForm: Quién del equipo de operaciones toma la tarea
+type RebalancingClaimForm struct {
+	ClaimedBy string `json:"claimed_by" validate:"required,min=1,max=100"`
+}
+
+// RebalancingCompleteForm: Cierre de la tarea. Sin moved se toman como movidas todas las bicicletas de la tarea
+type RebalancingCompleteForm struct {
+	Moved *int `json:"moved" validate:"min=0,max=1000"`
+}
+
+// ForecastQuery: Hora del pronóstico, por defecto la hora siguiente
+type ForecastQuery struct {
+	Hour time.Time
+}
+
+// ParseForecastQuery: Lee hour (RFC3339) y la redondea al comienzo de la hora
+func ParseForecastQuery(values url.Values) (*ForecastQuery, error) {
+	query := &ForecastQuery{Hour: time.Now().Truncate(time.Hour).Add(time.Hour)}
+
+	if hour := values.Get("hour"); hour != "" {
+		t, err := time.Parse(time.RFC3339, hour)
+		if err != nil {
+			return nil, invalidParam("hour")
+		}
+		query.Hour = t.Truncate(time.Hour)
+	}
+
+	return query, nil
+}
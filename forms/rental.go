@@ -7,20 +7,22 @@ import (
 )
 
 type StartEndRentalForm struct {
-	BikeID int64 `json:"bike_id"`
+	BikeID int64 `json:"bike_id" validate:"required,min=1"`
 }
 
+// RentalForm: Corrección de un alquiler desde el admin. Solo se pueden modificar las coordenadas; los demás campos
+// se aceptan en el form para que el servicio responda 422 (validation.readonly) en lugar de campo desconocido
 type RentalForm struct {
-	UserID         *int64               `json:"user_id"`
-	BikeID         *int64               `json:"bike_id"`
-	Status         *models.RentalStatus `json:"rental_status"`
+	UserID         *int64               `json:"user_id" validate:"min=1"`
+	BikeID         *int64               `json:"bike_id" validate:"min=1"`
+	Status         *models.RentalStatus `json:"rental_status" validate:"oneof=running ended"`
 	StartTime      *time.Time           `json:"start_time"`
 	EndTime        *time.Time           `json:"end_time"`
-	StartLatitude  *float64             `json:"start_latitude"`
-	StartLongitude *float64             `json:"start_longitude"`
-	EndLatitude    *float64             `json:"end_latitude"`
-	EndLongitude   *float64             `json:"end_longitude"`
-	Duration       *int                 `json:"duration"` // minutes
+	StartLatitude  *float64             `json:"start_latitude" validate:"min=-90,max=90"`
+	StartLongitude *float64             `json:"start_longitude" validate:"min=-180,max=180"`
+	EndLatitude    *float64             `json:"end_latitude" validate:"min=-90,max=90"`
+	EndLongitude   *float64             `json:"end_longitude" validate:"min=-180,max=180"`
+	Duration       *int                 `json:"duration" validate:"min=0"` // minutes
 }
 
 /*
@@ -0,0 +1,32 @@
+package forms
+
+import (
+	"time"
+
+	"github.com/mbarolo/test_back/models"
+)
+
+type RoutePointForm struct {
+	RecordedAt *time.Time `json:"recorded_at" validate:"required"`
+	Latitude   *float64   `json:"latitude" validate:"required,min=-90,max=90"`
+	Longitude  *float64   `json:"longitude" validate:"required,min=-180,max=180"`
+	Accuracy   *float64   `json:"accuracy" validate:"min=0"`
+}
+
+// RouteForm: Lote de hasta 500 puntos del recorrido enviado por la app del ciclista
+type RouteForm struct {
+	Points []RoutePointForm `json:"points" validate:"required,min=1,max=500,dive"`
+}
+
+func (rf *RouteForm) ToPoints() []*models.RoutePoint {
+	points := make([]*models.RoutePoint, len(rf.Points))
+	for i, pf := range rf.Points {
+		points[i] = &models.RoutePoint{
+			RecordedAt: *pf.RecordedAt,
+			Latitude:   *pf.Latitude,
+			Longitude:  *pf.Longitude,
+			Accuracy:   pf.Accuracy,
+		}
+	}
+	return points
+}
@@ -0,0 +1,26 @@
+package forms
+
+import "github.com/mbarolo/test_back/models"
+
+// StationForm: Datos de una estación de anclaje, en el PATCH todos son opcionales
+type StationForm struct {
+	Name      *string  `json:"name" validate:"required,min=1,max=100"`
+	Latitude  *float64 `json:"latitude" validate:"required,min=-90,max=90"`
+	Longitude *float64 `json:"longitude" validate:"required,min=-180,max=180"`
+	Capacity  *int     `json:"capacity" validate:"required,min=1,max=200"`
+	Active    *bool    `json:"active"`
+}
+
+// ToStation: Convierte el form validado en estación, activa salvo que se indique lo contrario
+func (sf *StationForm) ToStation() *models.Station {
+	station := &models.Station{Name: *sf.Name, Latitude: *sf.Latitude, Longitude: *sf.Longitude, Capacity: *sf.Capacity, Active: true}
+	if sf.Active != nil {
+		station.Active = *sf.Active
+	}
+	return station
+}
+
+// DockForm: Bicicleta a anclar en la estación
+type DockForm struct {
+	BikeID int64 `json:"bike_id" validate:"required,min=1"`
+}
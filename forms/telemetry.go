@@ -0,0 +1,44 @@
+package forms
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/mbarolo/test_back/models"
+)
+
+type TelemetryReadingForm struct {
+	RecordedAt   *time.Time `json:"recorded_at" validate:"required"`
+	Latitude     *float64   `json:"latitude" validate:"min=-90,max=90"`
+	Longitude    *float64   `json:"longitude" validate:"min=-180,max=180"`
+	Accuracy     *float64   `json:"accuracy" validate:"min=0"`
+	BatteryLevel *int       `json:"battery_level" validate:"min=0,max=100"`
+	LockState    *string    `json:"lock_state" validate:"oneof=locked unlocked"`
+	ErrorCodes   []string   `json:"error_codes" validate:"max=20"`
+}
+
+// TelemetryForm: Lote de hasta 500 lecturas enviado por el dispositivo
+type TelemetryForm struct {
+	Readings []TelemetryReadingForm `json:"readings" validate:"required,min=1,max=500,dive"`
+}
+
+// ToReadings: Convierte el lote en lecturas. Una posición incompleta (solo latitud o longitud) se descarta
+func (tf *TelemetryForm) ToReadings() []*models.TelemetryReading {
+	readings := make([]*models.TelemetryReading, len(tf.Readings))
+	for i, rf := range tf.Readings {
+		reading := &models.TelemetryReading{
+			RecordedAt:   *rf.RecordedAt,
+			Accuracy:     rf.Accuracy,
+			BatteryLevel: rf.BatteryLevel,
+			LockState:    rf.LockState,
+		}
+		if rf.Latitude != nil && rf.Longitude != nil {
+			reading.Latitude, reading.Longitude = rf.Latitude, rf.Longitude
+		}
+		if len(rf.ErrorCodes) > 0 {
+			reading.ErrorCodes, _ = json.Marshal(rf.ErrorCodes)
+		}
+		readings[i] = reading
+	}
+	return readings
+}
@@ -0,0 +1,188 @@
+package forms
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/mbarolo/test_back/apperror"
+	"github.com/mbarolo/test_back/i18n"
+)
+
+// Reglas soportadas en el tag `validate` de los forms:
+//
+//	required      el campo debe venir en el cuerpo (puntero no nulo o valor distinto de cero)
+//	min=N, max=N  rango para números, largo para strings, cantidad de elementos para slices
+//	max_bytes=N   largo máximo de un string en bytes, ej: las contraseñas (bcrypt usa solo los primeros 72)
+//	email         dirección de correo válida
+//	password      al menos 8 caracteres, con letras y números
+//	oneof=a b c   el valor debe ser uno de los listados
+//	language      idioma soportado por el catálogo de mensajes
+//	dive          valida cada elemento de un slice de structs con sus propias reglas
+const PasswordMinLength = 8
+
+// Validate: Valida todas las reglas del form y retorna todos los errores juntos
+func Validate(form interface{}) error {
+	return validate(form, false)
+}
+
+// ValidatePartial: Igual que Validate pero ignora la regla required, para los PATCH
+func ValidatePartial(form interface{}) error {
+	return validate(form, true)
+}
+
+func validate(form interface{}, partial bool) error {
+	value := reflect.ValueOf(form)
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return apperror.Validation("validation.failed", apperror.FieldError{Field: "body", Message: "validation.required"})
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+
+	fields := []apperror.FieldError{}
+	for i := 0; i < value.NumField(); i++ {
+		structField := value.Type().Field(i)
+		tag := structField.Tag.Get("validate")
+		if tag == "" {
+			continue
+		}
+		name := strings.Split(structField.Tag.Get("json"), ",")[0]
+		if name == "" {
+			name = structField.Name
+		}
+
+		if fieldErr := validateField(name, value.Field(i), strings.Split(tag, ","), partial); fieldErr != nil {
+			fields = append(fields, *fieldErr)
+		}
+	}
+
+	if len(fields) > 0 {
+		return apperror.Validation("validation.failed", fields...)
+	}
+	return nil
+}
+
+// validateField: Aplica las reglas en orden y retorna el primer error del campo
+func validateField(name string, field reflect.Value, rules []string, partial bool) *apperror.FieldError {
+	fieldErr := func(message string, params i18n.Params) *apperror.FieldError {
+		return &apperror.FieldError{Field: name, Message: message, Params: params}
+	}
+
+	present := !field.IsZero()
+	if field.Kind() == reflect.Ptr {
+		present = !field.IsNil()
+		if present {
+			field = field.Elem()
+		}
+	}
+
+	for _, rule := range rules {
+		rule, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		if rule == "required" {
+			if !present && !partial {
+				return fieldErr("validation.required", nil)
+			}
+			continue
+		}
+		// las demás reglas solo aplican si el campo vino en el cuerpo
+		if !present {
+			return nil
+		}
+
+		switch rule {
+		case "min", "max":
+			limit, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				panic(fmt.Sprintf("regla %s inválida en el campo %s", rule, name))
+			}
+			n, isLength := numericValue(field)
+			if (rule == "min" && n < limit) || (rule == "max" && n > limit) {
+				message := "validation." + rule
+				if field.Kind() == reflect.Slice {
+					message += "_items"
+				} else if isLength {
+					message += "_length"
+				}
+				return fieldErr(message, i18n.Params{rule: arg})
+			}
+		case "max_bytes":
+			limit, err := strconv.Atoi(arg)
+			if err != nil {
+				panic(fmt.Sprintf("regla %s inválida en el campo %s", rule, name))
+			}
+			if len(field.String()) > limit {
+				return fieldErr("validation.max_bytes", i18n.Params{"max": arg})
+			}
+		case "email":
+			if _, err := mail.ParseAddress(field.String()); err != nil {
+				return fieldErr("validation.email", nil)
+			}
+		case "password":
+			if !isStrongPassword(field.String()) {
+				return fieldErr("validation.password", i18n.Params{"min": PasswordMinLength})
+			}
+		case "oneof":
+			options := strings.Fields(arg)
+			if !slices.Contains(options, field.String()) {
+				return fieldErr("validation.oneof", i18n.Params{"values": strings.Join(options, ", ")})
+			}
+		case "language":
+			if !i18n.IsSupported(i18n.Lang(field.String())) {
+				return fieldErr("user.invalid_language", nil)
+			}
+		case "dive":
+			// se informa el primer error, con el índice del elemento (ej: readings[2].latitude)
+			for j := 0; j < field.Len(); j++ {
+				var appErr *apperror.Error
+				if err := validate(field.Index(j).Interface(), partial); errors.As(err, &appErr) && len(appErr.Fields) > 0 {
+					elemErr := appErr.Fields[0]
+					elemErr.Field = fmt.Sprintf("%s[%d].%s", name, j, elemErr.Field)
+					return &elemErr
+				}
+			}
+		default:
+			panic(fmt.Sprintf("regla de validación desconocida %s en el campo %s", rule, name))
+		}
+	}
+
+	return nil
+}
+
+// numericValue: Valor con el que se comparan min y max, para strings se usa el largo
+func numericValue(field reflect.Value) (float64, bool) {
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(field.Int()), false
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(field.Uint()), false
+	case reflect.Float32, reflect.Float64:
+		return field.Float(), false
+	case reflect.String:
+		return float64(len([]rune(field.String()))), true
+	case reflect.Slice:
+		return float64(field.Len()), true
+	}
+	return 0, false
+}
+
+func isStrongPassword(password string) bool {
+	var letter, digit bool
+	for _, c := range password {
+		switch {
+		case unicode.IsLetter(c):
+			letter = true
+		case unicode.IsDigit(c):
+			digit = true
+		}
+	}
+	return len([]rune(password)) >= PasswordMinLength && letter && digit
+}
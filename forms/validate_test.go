@@ -0,0 +1,44 @@
+package forms
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mbarolo/test_back/apperror"
+)
+
+func TestValidatePasswordBytes(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		message  string
+	}{
+		{"valid", "secreto123", ""},
+		{"72 ascii bytes", strings.Repeat("a", 71) + "1", ""},
+		{"73 ascii bytes", strings.Repeat("a", 72) + "1", "validation.max_bytes"},
+		// 40 caracteres pero 79 bytes: max=72 por caracteres lo aceptaba y bcrypt lo truncaba
+		{"multi-byte over 72 bytes", strings.Repeat("ñ", 39) + "1", "validation.max_bytes"},
+		{"multi-byte within 72 bytes", strings.Repeat("ñ", 35) + "1", ""},
+		{"weak", "abcdefgh", "validation.password"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := &UserForm{FirstName: "Ana", LastName: "Díaz", Email: "ana@example.com", Password: tt.password}
+			err := Validate(form)
+			if tt.message == "" {
+				if err != nil {
+					t.Fatalf("error inesperado: %v", err)
+				}
+				return
+			}
+			var appErr *apperror.Error
+			if !errors.As(err, &appErr) || len(appErr.Fields) != 1 {
+				t.Fatalf("error = %v, se esperaba un error de validación del campo password", err)
+			}
+			if field := appErr.Fields[0]; field.Field != "password" || field.Message != tt.message {
+				t.Errorf("error = %s %s, se esperaba password %s", field.Field, field.Message, tt.message)
+			}
+		})
+	}
+}
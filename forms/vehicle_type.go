@@ -0,0 +1,11 @@
+package forms
+
+// VehicleTypeForm: Campos modificables de un tipo de vehículo, el código y si es eléctrico son fijos
+type VehicleTypeForm struct {
+	Name          *string `json:"name" validate:"min=1,max=100"`
+	CostPerMinute *int    `json:"cost_per_minute" validate:"min=0"`
+	UnlockFee     *int    `json:"unlock_fee" validate:"min=0"`
+	RangeKm       *int    `json:"range_km" validate:"min=1"`
+	MaxSpeedKmh   *int    `json:"max_speed_kmh" validate:"min=1"`
+	MaxLoadKg     *int    `json:"max_load_kg" validate:"min=1"`
+}
@@ -0,0 +1,34 @@
+package forms
+
+import (
+	"encoding/json"
+
+	"github.com/mbarolo/test_back/models"
+)
+
+type WebhookForm struct {
+	Url         *string  `json:"url" validate:"required,max=2048"`
+	Description *string  `json:"description" validate:"max=255"`
+	EventTypes  []string `json:"event_types" validate:"required"`
+	Secret      *string  `json:"secret" validate:"max=255"`
+	IsActive    *bool    `json:"is_active"`
+}
+
+// ToWebhook: Convierte el form en suscripción, activa si no se indica lo contrario
+func (wf *WebhookForm) ToWebhook() *models.WebhookSubscription {
+	subscription := &models.WebhookSubscription{IsActive: true}
+	if wf.Url != nil {
+		subscription.Url = *wf.Url
+	}
+	if wf.Description != nil {
+		subscription.Description = *wf.Description
+	}
+	subscription.EventTypes, _ = json.Marshal(wf.EventTypes)
+	if wf.Secret != nil {
+		subscription.Secret = *wf.Secret
+	}
+	if wf.IsActive != nil {
+		subscription.IsActive = *wf.IsActive
+	}
+	return subscription
+}
@@ -0,0 +1,290 @@
+package i18n
+
+var catalogEN = map[string]Message{
+	// general
+	"status.ok":               {Other: "ok"},
+	"request.not_found":       {Other: "Service not found."},
+	"request.query_error":     {Other: "Invalid query parameters"},
+	"error.internal":          {Other: "internal error"},
+	"error.not_found":         {Other: "resource not found"},
+	"error.version_mismatch":  {Other: "the resource was modified by another request, fetch it again and retry"},
+	"error.concurrent_update": {Other: "the resource was modified by another request, please retry"},
+	"param.id_missing":        {Other: "id parameter not found"},
+	"param.id_invalid":        {Other: "invalid id parameter"},
+	"field.positive_int":      {Other: "must be a positive integer"},
+	"query.invalid_param":     {Other: "invalid {param} parameter"},
+	"query.invalid_sort":      {Other: "cannot sort by {field}"},
+	"query.unknown_param":     {Other: "unknown {param} parameter"},
+
+	// validation
+	"request.invalid_body":     {Other: "invalid request body: {error}"},
+	"request.trailing_data":    {Other: "the request body contains data after the JSON object"},
+	"request.empty_body":       {Other: "the request body is empty"},
+	"request.too_large":        {Other: "the request body exceeds the maximum of {limit} bytes"},
+	"request.unknown_field":    {Other: "unknown field {field}"},
+	"request.invalid_if_match": {Other: "invalid If-Match header, it must be an ETag like \"3\""},
+	"request.missing_if_match": {Other: "the If-Match header with the ETag of the resource is required, fetch it and retry"},
+	"request.patch_not_object": {Other: "a merge patch body must be a JSON object"},
+	"validation.failed":        {Other: "invalid fields"},
+	"validation.required":      {Other: "required"},
+	"validation.min":           {Other: "must be greater than or equal to {min}"},
+	"validation.max":           {Other: "must be less than or equal to {max}"},
+	"validation.min_length":    {Other: "must have at least {min} characters"},
+	"validation.max_length":    {Other: "must have at most {max} characters"},
+	"validation.max_bytes":     {Other: "must be at most {max} bytes long"},
+	"validation.min_items":     {Other: "must have at least {min} items"},
+	"validation.max_items":     {Other: "must have at most {max} items"},
+	"validation.email":         {Other: "invalid email"},
+	"validation.password":      {Other: "must have at least {min} characters, with letters and numbers"},
+	"validation.oneof":         {Other: "must be one of: {values}"},
+	"validation.type":          {Other: "must be of type {type}"},
+	"validation.unknown_field": {Other: "field not allowed"},
+	"validation.not_nullable":  {Other: "cannot be null"},
+	"validation.invalid_value": {Other: "invalid value"},
+	"validation.readonly":      {Other: "cannot be modified"},
+
+	// authentication
+	"auth.invalid_header":       {Other: "Unexpected authorization header format"},
+	"auth.token_missing":        {Other: "Token not provided"},
+	"auth.token_invalid":        {Other: "Invalid or expired token"},
+	"auth.credentials_error":    {Other: "Error validating credentials"},
+	"auth.credentials_invalid":  {Other: "Invalid credentials"},
+	"auth.login_invalid":        {Other: "The login information is incorrect"},
+	"auth.login_error":          {Other: "Error logging in"},
+	"auth.login_ok":             {Other: "Logged in successfully"},
+	"auth.current_user_error":   {Other: "Error getting the authenticated user"},
+	"auth.claims_invalid":       {Other: "invalid token claims"},
+	"auth.token_user_not_found": {Other: "the token's user does not exist"},
+	"auth.hash_error":           {Other: "Error hashing the password"},
+
+	// users
+	"user.list_ok":          {One: "{count} user retrieved", Other: "{count} users retrieved"},
+	"user.list_error":       {Other: "Error getting the users"},
+	"user.get_ok":           {Other: "User retrieved"},
+	"user.get_error":        {Other: "Error getting the user"},
+	"user.profile_ok":       {Other: "Profile retrieved"},
+	"user.created":          {Other: "User created successfully"},
+	"user.create_error":     {Other: "Error creating the user"},
+	"user.updated":          {Other: "User updated successfully"},
+	"user.update_error":     {Other: "Error updating the user"},
+	"user.not_found":        {Other: "user not found"},
+	"user.email_taken":      {Other: "email already registered"},
+	"user.invalid_fields":   {Other: "invalid user fields"},
+	"user.invalid_email":    {Other: "invalid email"},
+	"user.invalid_first":    {Other: "invalid first name"},
+	"user.invalid_last":     {Other: "invalid last name"},
+	"user.invalid_password": {Other: "invalid password"},
+	"user.invalid_language": {Other: "unsupported language"},
+
+	// bikes
+	"bike.list_ok":        {One: "{count} bike retrieved", Other: "{count} bikes retrieved"},
+	"bike.list_error":     {Other: "Error getting the bikes"},
+	"bike.get_ok":         {Other: "Bike retrieved"},
+	"bike.get_error":      {Other: "Error retrieving the bike"},
+	"bike.created":        {Other: "Bike created successfully"},
+	"bike.create_error":   {Other: "Error creating the bike"},
+	"bike.updated":        {Other: "Bike updated successfully"},
+	"bike.update_error":   {Other: "Error updating the bike"},
+	"bike.not_found":      {Other: "bike not found"},
+	"bike.not_available":  {Other: "bike not available"},
+	"bike.invalid_fields": {Other: "invalid bike fields"},
+	"bike.invalid_cost":   {Other: "invalid cost"},
+
+	// rentals
+	"rental.started":         {Other: "Bike rented successfully"},
+	"rental.start_error":     {Other: "Error renting the bike"},
+	"rental.ended":           {Other: "Bike rental ended successfully"},
+	"rental.end_error":       {Other: "Error ending the rental"},
+	"rental.history_ok":      {One: "{count} rental in history", Other: "{count} rentals in history"},
+	"rental.history_error":   {Other: "Error getting the rental history"},
+	"rental.list_ok":         {One: "{count} rental retrieved", Other: "{count} rentals retrieved"},
+	"rental.list_error":      {Other: "Error getting the rentals"},
+	"rental.get_ok":          {Other: "Rental retrieved"},
+	"rental.get_error":       {Other: "Error getting the rental"},
+	"rental.updated":         {Other: "Rental updated"},
+	"rental.update_error":    {Other: "Error updating the rental"},
+	"rental.not_found":       {Other: "rental not found"},
+	"rental.already_running": {Other: "user already has a rental in progress"},
+	"rental.none_running":    {Other: "user has no active rental"},
+	"rental.wrong_bike":      {Other: "user is not renting this bike"},
+
+	// idempotency
+	"idempotency.error":        {Other: "Error processing the idempotency key"},
+	"idempotency.invalid_key":  {Other: "the Idempotency-Key must be at most {max} characters long"},
+	"idempotency.key_mismatch": {Other: "the Idempotency-Key was already used with a different request"},
+	"idempotency.in_progress":  {Other: "a request with the same Idempotency-Key is being processed, please retry"},
+
+	// health
+	"health.alive":              {Other: "service alive"},
+	"health.ready":              {Other: "service ready to receive requests"},
+	"health.not_ready":          {Other: "service unavailable"},
+	"health.shutting_down":      {Other: "the service is shutting down"},
+	"health.db_unavailable":     {Other: "the database is not responding"},
+	"health.migrations_pending": {Other: "pending migrations: {columns}"},
+
+	// rate limiting
+	"ratelimit.exceeded":    {Other: "Too many requests"},
+	"ratelimit.retry_after": {One: "retry in {count} second", Other: "retry in {count} seconds"},
+
+	// audit
+	"audit.list_ok":      {One: "{count} audit entry retrieved", Other: "{count} audit entries retrieved"},
+	"audit.list_error":   {Other: "Error retrieving the audit log"},
+	"audit.verify_error": {Other: "Error verifying the audit log"},
+	"audit.chain_valid":  {One: "{count} entry verified, the chain is intact", Other: "{count} entries verified, the chain is intact"},
+	"audit.chain_broken": {Other: "The audit log chain has been tampered with"},
+
+	// webhooks
+	"webhook.list_ok":             {One: "{count} webhook retrieved", Other: "{count} webhooks retrieved"},
+	"webhook.list_error":          {Other: "Error retrieving webhooks"},
+	"webhook.created":             {Other: "Webhook created, store the secret as it will not be shown again"},
+	"webhook.create_error":        {Other: "Error creating webhook"},
+	"webhook.get_ok":              {Other: "Webhook retrieved"},
+	"webhook.get_error":           {Other: "Error retrieving webhook"},
+	"webhook.updated":             {Other: "Webhook updated"},
+	"webhook.update_error":        {Other: "Error updating webhook"},
+	"webhook.deleted":             {Other: "Webhook deleted"},
+	"webhook.delete_error":        {Other: "Error deleting webhook"},
+	"webhook.deliveries_ok":       {One: "{count} delivery retrieved", Other: "{count} deliveries retrieved"},
+	"webhook.deliveries_error":    {Other: "Error retrieving webhook deliveries"},
+	"webhook.retry_ok":            {Other: "Delivery queued again"},
+	"webhook.retry_error":         {Other: "Error retrying delivery"},
+	"webhook.not_found":           {Other: "webhook not found"},
+	"webhook.delivery_not_found":  {Other: "delivery not found"},
+	"webhook.delivery_not_dead":   {Other: "only deliveries that exhausted their attempts can be retried"},
+	"webhook.invalid_fields":      {Other: "invalid webhook data"},
+	"webhook.invalid_url":         {Other: "must be an http or https URL"},
+	"webhook.invalid_event_types": {Other: "at least one event type is required"},
+	"webhook.unknown_event":       {Other: "unknown event type: {event}"},
+
+	// stream
+	"stream.error": {Other: "Error opening the bike stream"},
+
+	// locks
+	"lock.offline":            {Other: "the bike lock is offline, please try again"},
+	"lock.no_ack":             {Other: "the bike lock did not respond, please try again"},
+	"lock.open":               {Other: "the lock is still open, close it and try again"},
+	"lock.still_locked":       {Other: "the lock could not be opened"},
+	"lock.simulator_ok":       {Other: "Simulated lock retrieved"},
+	"lock.simulator_updated":  {Other: "Simulated lock updated"},
+	"lock.simulator_error":    {Other: "Simulated lock error"},
+	"lock.simulator_disabled": {Other: "locks are not simulated"},
+
+	// devices and telemetry
+	"device.invalid_token":         {Other: "Invalid device token"},
+	"device.auth_error":            {Other: "Error authenticating the device"},
+	"device.issued":                {Other: "Device token issued, store it as it will not be shown again"},
+	"device.issue_error":           {Other: "Error issuing the device token"},
+	"device.revoked":               {Other: "Device revoked"},
+	"device.revoke_error":          {Other: "Error revoking the device"},
+	"device.not_found":             {Other: "the bike has no registered device"},
+	"telemetry.accepted":           {One: "{count} reading received", Other: "{count} readings received"},
+	"telemetry.ingest_error":       {Other: "Error receiving telemetry"},
+	"telemetry.list_ok":            {One: "{count} reading retrieved", Other: "{count} readings retrieved"},
+	"telemetry.list_error":         {Other: "Error retrieving telemetry"},
+	"telemetry.recorded_in_future": {Other: "the reading date is in the future"},
+
+	// rental routes
+	"route.recorded":             {One: "{count} point recorded", Other: "{count} points recorded"},
+	"route.record_error":         {Other: "Error recording the route"},
+	"route.get_error":            {Other: "Error retrieving the route"},
+	"route.rental_not_running":   {Other: "the rental has already ended"},
+	"route.point_outside_rental": {Other: "the point date is outside the rental"},
+
+	// vehicle types
+	"vehicle_type.list_ok":        {One: "{count} vehicle type retrieved", Other: "{count} vehicle types retrieved"},
+	"vehicle_type.list_error":     {Other: "Error retrieving vehicle types"},
+	"vehicle_type.updated":        {Other: "Vehicle type updated"},
+	"vehicle_type.update_error":   {Other: "Error updating the vehicle type"},
+	"vehicle_type.not_found":      {Other: "vehicle type not found"},
+	"vehicle_type.range_required": {Other: "required for electric vehicles"},
+	"bike.invalid_vehicle_type":   {Other: "unknown vehicle type"},
+	"bike.battery_low":            {Other: "the vehicle battery is below {min}%"},
+
+	// maintenance
+	"damage_report.created":         {Other: "Damage reported, thank you"},
+	"damage_report.create_error":    {Other: "Error reporting the damage"},
+	"damage_report.not_found":       {Other: "damage report not found"},
+	"damage_report.photo_not_found": {Other: "the report has no photo"},
+	"damage_report.photo_error":     {Other: "Error getting the report photo"},
+	"damage_report.invalid_photo":   {Other: "the photo must be jpeg, png or webp"},
+	"work_order.list_ok":            {One: "{count} work order retrieved", Other: "{count} work orders retrieved"},
+	"work_order.list_error":         {Other: "Error getting the work orders"},
+	"work_order.get_ok":             {Other: "Work order retrieved"},
+	"work_order.get_error":          {Other: "Error getting the work order"},
+	"work_order.created":            {Other: "Work order opened"},
+	"work_order.create_error":       {Other: "Error opening the work order"},
+	"work_order.updated":            {Other: "Work order updated"},
+	"work_order.update_error":       {Other: "Error updating the work order"},
+	"work_order.not_found":          {Other: "work order not found"},
+	"work_order.invalid_transition": {Other: "the work order cannot move from {from} to {to}"},
+	"work_order.mechanic_required":  {Other: "required to assign the work order"},
+	"bike.out_of_service":           {Other: "the bike is out of service for maintenance"},
+
+	// bike statuses
+	"bike.status_changed":            {Other: "Bike status updated"},
+	"bike.status_error":              {Other: "Error changing the bike status"},
+	"bike.status_history_ok":         {One: "{count} status change retrieved", Other: "{count} status changes retrieved"},
+	"bike.status_history_error":      {Other: "Error getting the status history"},
+	"bike.invalid_status_transition": {Other: "the bike cannot move from {from} to {to}"},
+	"bike.in_rental":                 {Other: "the bike is rented, it can only be marked as missing"},
+
+	// stations
+	"station.list_ok":               {One: "{count} station retrieved", Other: "{count} stations retrieved"},
+	"station.list_error":            {Other: "Error getting the stations"},
+	"station.get_ok":                {Other: "Station retrieved"},
+	"station.get_error":             {Other: "Error getting the station"},
+	"station.created":               {Other: "Station created"},
+	"station.create_error":          {Other: "Error creating the station"},
+	"station.updated":               {Other: "Station updated"},
+	"station.update_error":          {Other: "Error updating the station"},
+	"station.docked":                {Other: "Bike docked at dock {dock}"},
+	"station.dock_error":            {Other: "Error docking the bike"},
+	"station.undocked":              {Other: "Bike undocked"},
+	"station.undock_error":          {Other: "Error undocking the bike"},
+	"station.not_found":             {Other: "station not found"},
+	"station.inactive":              {Other: "the station is not active"},
+	"station.full":                  {Other: "the station has no free docks"},
+	"station.return_required":       {Other: "the bike must be returned within {meters} meters of a station"},
+	"station.capacity_below_docked": {Other: "dock {dock} is in use, the capacity cannot be lower"},
+	"station.bike_not_dockable":     {Other: "a bike in status {status} cannot be docked"},
+	"station.bike_not_docked":       {Other: "the bike is not docked at this station"},
+
+	// rebalancing
+	"rebalancing.forecast_ok":        {One: "Forecast for {count} cell", Other: "Forecast for {count} cells"},
+	"rebalancing.forecast_error":     {Other: "Error computing the forecast"},
+	"rebalancing.planned":            {One: "{count} rebalancing task generated", Other: "{count} rebalancing tasks generated"},
+	"rebalancing.plan_error":         {Other: "Error planning the rebalancing"},
+	"rebalancing.list_ok":            {One: "{count} rebalancing task retrieved", Other: "{count} rebalancing tasks retrieved"},
+	"rebalancing.list_error":         {Other: "Error retrieving the rebalancing tasks"},
+	"rebalancing.get_ok":             {Other: "Rebalancing task retrieved"},
+	"rebalancing.get_error":          {Other: "Error retrieving the rebalancing task"},
+	"rebalancing.claimed":            {Other: "Rebalancing task claimed"},
+	"rebalancing.claim_error":        {Other: "Error claiming the rebalancing task"},
+	"rebalancing.completed":          {One: "Rebalancing task completed, {count} bike moved", Other: "Rebalancing task completed, {count} bikes moved"},
+	"rebalancing.complete_error":     {Other: "Error completing the rebalancing task"},
+	"rebalancing.not_found":          {Other: "rebalancing task not found"},
+	"rebalancing.already_claimed":    {Other: "the task was already claimed by {claimed_by}"},
+	"rebalancing.invalid_transition": {Other: "the task cannot go from {from} to {to}"},
+
+	// incidents
+	"incident.list_ok":                      {One: "{count} incident retrieved", Other: "{count} incidents retrieved"},
+	"incident.list_error":                   {Other: "Error retrieving the incidents"},
+	"incident.get_ok":                       {Other: "Incident retrieved"},
+	"incident.get_error":                    {Other: "Error retrieving the incident"},
+	"incident.acknowledged":                 {Other: "Incident acknowledged"},
+	"incident.acknowledge_error":            {Other: "Error acknowledging the incident"},
+	"incident.resolved":                     {Other: "Incident resolved"},
+	"incident.resolve_error":                {Other: "Error resolving the incident"},
+	"incident.not_found":                    {Other: "incident not found"},
+	"incident.invalid_transition":           {Other: "the incident cannot go from {from} to {to}"},
+	"incident.auto_resolved":                {Other: "the rule no longer matches"},
+	"incident.rule.movement_without_rental": {Other: "bike {bike_id} moved without a running rental"},
+	"incident.rule.no_signal":               {Other: "bike {bike_id} stopped sending telemetry"},
+	"incident.rule.outside_service_area":    {Other: "bike {bike_id} is outside the service area"},
+	"incident.rule.rental_overdue":          {Other: "the rental of bike {bike_id} exceeds the maximum duration"},
+	"incident.rule.lock_failed":             {Other: "the lock of bike {bike_id} could not be confirmed closed"},
+
+	// automatic rental closing
+	"rental.end_warning.max_duration": {One: "Your rental exceeded the maximum duration and will be ended in {count} minute", Other: "Your rental exceeded the maximum duration and will be ended in {count} minutes"},
+	"rental.end_warning.idle":         {One: "Your rental shows no activity and will be ended in {count} minute unless you keep using it", Other: "Your rental shows no activity and will be ended in {count} minutes unless you keep using it"},
+}
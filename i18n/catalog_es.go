@@ -0,0 +1,290 @@
+package i18n
+
+var catalogES = map[string]Message{
+	// generales
+	"status.ok":               {Other: "ok"},
+	"request.not_found":       {Other: "Servicio no encontrado."},
+	"request.query_error":     {Other: "Error en los parametros de consulta"},
+	"error.internal":          {Other: "error interno"},
+	"error.not_found":         {Other: "recurso no encontrado"},
+	"error.version_mismatch":  {Other: "el recurso fue modificado por otra solicitud, vuelva a obtenerlo e intente nuevamente"},
+	"error.concurrent_update": {Other: "el recurso fue modificado por otra solicitud, intente nuevamente"},
+	"param.id_missing":        {Other: "parametro id no encontrado"},
+	"param.id_invalid":        {Other: "parametro id inválido"},
+	"field.positive_int":      {Other: "debe ser un entero positivo"},
+	"query.invalid_param":     {Other: "parametro {param} inválido"},
+	"query.invalid_sort":      {Other: "no se puede ordenar por {field}"},
+	"query.unknown_param":     {Other: "parametro {param} desconocido"},
+
+	// validación
+	"request.invalid_body":     {Other: "cuerpo de la solicitud inválido: {error}"},
+	"request.trailing_data":    {Other: "el cuerpo de la solicitud contiene datos luego del objeto JSON"},
+	"request.empty_body":       {Other: "el cuerpo de la solicitud está vacío"},
+	"request.too_large":        {Other: "el cuerpo de la solicitud supera el máximo de {limit} bytes"},
+	"request.unknown_field":    {Other: "campo desconocido {field}"},
+	"request.invalid_if_match": {Other: "header If-Match inválido, debe ser un ETag como \"3\""},
+	"request.missing_if_match": {Other: "el header If-Match con el ETag del recurso es obligatorio, obténgalo e intente nuevamente"},
+	"request.patch_not_object": {Other: "el cuerpo de un merge patch debe ser un objeto JSON"},
+	"validation.failed":        {Other: "campos inválidos"},
+	"validation.required":      {Other: "requerido"},
+	"validation.min":           {Other: "debe ser mayor o igual a {min}"},
+	"validation.max":           {Other: "debe ser menor o igual a {max}"},
+	"validation.min_length":    {Other: "debe tener al menos {min} caracteres"},
+	"validation.max_length":    {Other: "debe tener como máximo {max} caracteres"},
+	"validation.max_bytes":     {Other: "debe ocupar como máximo {max} bytes"},
+	"validation.min_items":     {Other: "debe tener al menos {min} elementos"},
+	"validation.max_items":     {Other: "debe tener como máximo {max} elementos"},
+	"validation.email":         {Other: "email inválido"},
+	"validation.password":      {Other: "debe tener al menos {min} caracteres, con letras y números"},
+	"validation.oneof":         {Other: "debe ser uno de: {values}"},
+	"validation.type":          {Other: "debe ser de tipo {type}"},
+	"validation.unknown_field": {Other: "campo no permitido"},
+	"validation.not_nullable":  {Other: "no admite null"},
+	"validation.invalid_value": {Other: "valor inválido"},
+	"validation.readonly":      {Other: "no se puede modificar"},
+
+	// autenticación
+	"auth.invalid_header":       {Other: "Estructura de header de autorización inesperada"},
+	"auth.token_missing":        {Other: "Token no proporcionado"},
+	"auth.token_invalid":        {Other: "Token inválido o expirado"},
+	"auth.credentials_error":    {Other: "Error al validar las credenciales"},
+	"auth.credentials_invalid":  {Other: "Credenciales inválidas"},
+	"auth.login_invalid":        {Other: "La información de inicio de sesión es incorrecta"},
+	"auth.login_error":          {Other: "Error al iniciar sesión"},
+	"auth.login_ok":             {Other: "Sesión iniciada correctamente"},
+	"auth.current_user_error":   {Other: "Error al obtener el usuario autenticado"},
+	"auth.claims_invalid":       {Other: "claims del token inválidos"},
+	"auth.token_user_not_found": {Other: "el usuario del token no existe"},
+	"auth.hash_error":           {Other: "Error al hashear la contraseña"},
+
+	// usuarios
+	"user.list_ok":          {One: "{count} usuario obtenido", Other: "{count} usuarios obtenidos"},
+	"user.list_error":       {Other: "Error al obtener los usuarios"},
+	"user.get_ok":           {Other: "Usuario obtenido"},
+	"user.get_error":        {Other: "Error al obtener el usuario"},
+	"user.profile_ok":       {Other: "Perfil obtenido"},
+	"user.created":          {Other: "Usuario creado correctamente"},
+	"user.create_error":     {Other: "Error al crear el usuario"},
+	"user.updated":          {Other: "Usuario actualizado correctamente"},
+	"user.update_error":     {Other: "Error al actualizar el usuario"},
+	"user.not_found":        {Other: "usuario no encontrado"},
+	"user.email_taken":      {Other: "correo electrónico ya registrado en la base de datos"},
+	"user.invalid_fields":   {Other: "campos del usuario inválidos"},
+	"user.invalid_email":    {Other: "email inválido"},
+	"user.invalid_first":    {Other: "nombre inválido"},
+	"user.invalid_last":     {Other: "apellido inválido"},
+	"user.invalid_password": {Other: "contraseña inválida"},
+	"user.invalid_language": {Other: "idioma no soportado"},
+
+	// bicicletas
+	"bike.list_ok":        {One: "{count} bicicleta obtenida", Other: "{count} bicicletas obtenidas"},
+	"bike.list_error":     {Other: "Error al obtener las bicicletas"},
+	"bike.get_ok":         {Other: "Bicicleta obtenida"},
+	"bike.get_error":      {Other: "Error al obtener la bicicleta"},
+	"bike.created":        {Other: "Bicicleta creada correctamente"},
+	"bike.create_error":   {Other: "Error al crear la bicicleta"},
+	"bike.updated":        {Other: "Bicicleta actualizada correctamente"},
+	"bike.update_error":   {Other: "Error al actualizar la bicicleta"},
+	"bike.not_found":      {Other: "bicicleta no encontrada"},
+	"bike.not_available":  {Other: "bicicleta no disponible"},
+	"bike.invalid_fields": {Other: "campos de la bicicleta inválidos"},
+	"bike.invalid_cost":   {Other: "costo inválido"},
+
+	// alquileres
+	"rental.started":         {Other: "Bicicleta alquilada correctamente"},
+	"rental.start_error":     {Other: "Error al alquilar la bicicleta"},
+	"rental.ended":           {Other: "Alquiler de bicicleta finalizado correctamente"},
+	"rental.end_error":       {Other: "Error al finalizar el alquiler"},
+	"rental.history_ok":      {One: "{count} alquiler en el historial", Other: "{count} alquileres en el historial"},
+	"rental.history_error":   {Other: "Error al obtener el historial de alquileres"},
+	"rental.list_ok":         {One: "{count} alquiler obtenido", Other: "{count} alquileres obtenidos"},
+	"rental.list_error":      {Other: "Error al obtener los alquileres"},
+	"rental.get_ok":          {Other: "Alquiler obtenido"},
+	"rental.get_error":       {Other: "Error al obtener el alquiler"},
+	"rental.updated":         {Other: "Alquiler actualizado"},
+	"rental.update_error":    {Other: "Error al actualizar el alquiler"},
+	"rental.not_found":       {Other: "alquiler no encontrado"},
+	"rental.already_running": {Other: "usuario ya tiene un alquiler en curso"},
+	"rental.none_running":    {Other: "el usuario no tiene un alquiler activo"},
+	"rental.wrong_bike":      {Other: "el usuario no está alquilando esta bicicleta"},
+
+	// idempotencia
+	"idempotency.error":        {Other: "Error al procesar la clave de idempotencia"},
+	"idempotency.invalid_key":  {Other: "la clave Idempotency-Key debe tener como máximo {max} caracteres"},
+	"idempotency.key_mismatch": {Other: "la clave Idempotency-Key ya fue usada con una solicitud distinta"},
+	"idempotency.in_progress":  {Other: "una solicitud con la misma Idempotency-Key se está procesando, intente nuevamente"},
+
+	// salud
+	"health.alive":              {Other: "servicio activo"},
+	"health.ready":              {Other: "servicio listo para recibir solicitudes"},
+	"health.not_ready":          {Other: "servicio no disponible"},
+	"health.shutting_down":      {Other: "el servicio se está apagando"},
+	"health.db_unavailable":     {Other: "la base de datos no responde"},
+	"health.migrations_pending": {Other: "migraciones pendientes: {columns}"},
+
+	// rate limiting
+	"ratelimit.exceeded":    {Other: "Demasiadas solicitudes"},
+	"ratelimit.retry_after": {One: "intente nuevamente en {count} segundo", Other: "intente nuevamente en {count} segundos"},
+
+	// auditoría
+	"audit.list_ok":      {One: "{count} registro de auditoría obtenido", Other: "{count} registros de auditoría obtenidos"},
+	"audit.list_error":   {Other: "Error al obtener el log de auditoría"},
+	"audit.verify_error": {Other: "Error al verificar el log de auditoría"},
+	"audit.chain_valid":  {One: "{count} registro verificado, la cadena está íntegra", Other: "{count} registros verificados, la cadena está íntegra"},
+	"audit.chain_broken": {Other: "La cadena del log de auditoría fue alterada"},
+
+	// webhooks
+	"webhook.list_ok":             {One: "{count} webhook obtenido", Other: "{count} webhooks obtenidos"},
+	"webhook.list_error":          {Other: "Error al obtener los webhooks"},
+	"webhook.created":             {Other: "Webhook creado, guarde el secreto ya que no se vuelve a mostrar"},
+	"webhook.create_error":        {Other: "Error al crear el webhook"},
+	"webhook.get_ok":              {Other: "Webhook obtenido"},
+	"webhook.get_error":           {Other: "Error al obtener el webhook"},
+	"webhook.updated":             {Other: "Webhook actualizado"},
+	"webhook.update_error":        {Other: "Error al actualizar el webhook"},
+	"webhook.deleted":             {Other: "Webhook eliminado"},
+	"webhook.delete_error":        {Other: "Error al eliminar el webhook"},
+	"webhook.deliveries_ok":       {One: "{count} entrega obtenida", Other: "{count} entregas obtenidas"},
+	"webhook.deliveries_error":    {Other: "Error al obtener las entregas del webhook"},
+	"webhook.retry_ok":            {Other: "Entrega encolada nuevamente"},
+	"webhook.retry_error":         {Other: "Error al reintentar la entrega"},
+	"webhook.not_found":           {Other: "webhook no encontrado"},
+	"webhook.delivery_not_found":  {Other: "entrega no encontrada"},
+	"webhook.delivery_not_dead":   {Other: "solo se pueden reintentar las entregas que agotaron sus intentos"},
+	"webhook.invalid_fields":      {Other: "datos del webhook inválidos"},
+	"webhook.invalid_url":         {Other: "debe ser una URL http o https"},
+	"webhook.invalid_event_types": {Other: "debe indicar al menos un tipo de evento"},
+	"webhook.unknown_event":       {Other: "tipo de evento desconocido: {event}"},
+
+	// stream
+	"stream.error": {Other: "Error al abrir el stream de bicicletas"},
+
+	// candados
+	"lock.offline":            {Other: "el candado de la bicicleta no está conectado, intente nuevamente"},
+	"lock.no_ack":             {Other: "el candado de la bicicleta no respondió, intente nuevamente"},
+	"lock.open":               {Other: "el candado sigue abierto, ciérrelo y vuelva a intentar"},
+	"lock.still_locked":       {Other: "el candado no se pudo abrir"},
+	"lock.simulator_ok":       {Other: "Candado simulado obtenido"},
+	"lock.simulator_updated":  {Other: "Candado simulado actualizado"},
+	"lock.simulator_error":    {Other: "Error en el candado simulado"},
+	"lock.simulator_disabled": {Other: "los candados no son simulados"},
+
+	// dispositivos y telemetría
+	"device.invalid_token":         {Other: "Token de dispositivo inválido"},
+	"device.auth_error":            {Other: "Error al autenticar el dispositivo"},
+	"device.issued":                {Other: "Token de dispositivo emitido, guárdelo ya que no se vuelve a mostrar"},
+	"device.issue_error":           {Other: "Error al emitir el token del dispositivo"},
+	"device.revoked":               {Other: "Dispositivo revocado"},
+	"device.revoke_error":          {Other: "Error al revocar el dispositivo"},
+	"device.not_found":             {Other: "la bicicleta no tiene un dispositivo registrado"},
+	"telemetry.accepted":           {One: "{count} lectura recibida", Other: "{count} lecturas recibidas"},
+	"telemetry.ingest_error":       {Other: "Error al recibir la telemetría"},
+	"telemetry.list_ok":            {One: "{count} lectura obtenida", Other: "{count} lecturas obtenidas"},
+	"telemetry.list_error":         {Other: "Error al obtener la telemetría"},
+	"telemetry.recorded_in_future": {Other: "la fecha de la lectura está en el futuro"},
+
+	// recorrido de los alquileres
+	"route.recorded":             {One: "{count} punto registrado", Other: "{count} puntos registrados"},
+	"route.record_error":         {Other: "Error al registrar el recorrido"},
+	"route.get_error":            {Other: "Error al obtener el recorrido"},
+	"route.rental_not_running":   {Other: "el alquiler ya terminó"},
+	"route.point_outside_rental": {Other: "la fecha del punto está fuera del alquiler"},
+
+	// tipos de vehículo
+	"vehicle_type.list_ok":        {One: "{count} tipo de vehículo obtenido", Other: "{count} tipos de vehículo obtenidos"},
+	"vehicle_type.list_error":     {Other: "Error al obtener los tipos de vehículo"},
+	"vehicle_type.updated":        {Other: "Tipo de vehículo actualizado"},
+	"vehicle_type.update_error":   {Other: "Error al actualizar el tipo de vehículo"},
+	"vehicle_type.not_found":      {Other: "tipo de vehículo no encontrado"},
+	"vehicle_type.range_required": {Other: "requerido para los vehículos eléctricos"},
+	"bike.invalid_vehicle_type":   {Other: "tipo de vehículo inexistente"},
+	"bike.battery_low":            {Other: "la batería del vehículo está por debajo del {min}%"},
+
+	// mantenimiento
+	"damage_report.created":         {Other: "Daño reportado, gracias"},
+	"damage_report.create_error":    {Other: "Error al reportar el daño"},
+	"damage_report.not_found":       {Other: "reporte de daño no encontrado"},
+	"damage_report.photo_not_found": {Other: "el reporte no tiene foto"},
+	"damage_report.photo_error":     {Other: "Error al obtener la foto del reporte"},
+	"damage_report.invalid_photo":   {Other: "la foto debe ser jpeg, png o webp"},
+	"work_order.list_ok":            {One: "{count} orden de trabajo obtenida", Other: "{count} órdenes de trabajo obtenidas"},
+	"work_order.list_error":         {Other: "Error al obtener las órdenes de trabajo"},
+	"work_order.get_ok":             {Other: "Orden de trabajo obtenida"},
+	"work_order.get_error":          {Other: "Error al obtener la orden de trabajo"},
+	"work_order.created":            {Other: "Orden de trabajo abierta"},
+	"work_order.create_error":       {Other: "Error al abrir la orden de trabajo"},
+	"work_order.updated":            {Other: "Orden de trabajo actualizada"},
+	"work_order.update_error":       {Other: "Error al actualizar la orden de trabajo"},
+	"work_order.not_found":          {Other: "orden de trabajo no encontrada"},
+	"work_order.invalid_transition": {Other: "la orden no puede pasar de {from} a {to}"},
+	"work_order.mechanic_required":  {Other: "requerido para asignar la orden"},
+	"bike.out_of_service":           {Other: "la bicicleta está fuera de servicio por mantenimiento"},
+
+	// estados de las bicicletas
+	"bike.status_changed":            {Other: "Estado de la bicicleta actualizado"},
+	"bike.status_error":              {Other: "Error al cambiar el estado de la bicicleta"},
+	"bike.status_history_ok":         {One: "{count} cambio de estado obtenido", Other: "{count} cambios de estado obtenidos"},
+	"bike.status_history_error":      {Other: "Error al obtener el historial de estados"},
+	"bike.invalid_status_transition": {Other: "la bicicleta no puede pasar de {from} a {to}"},
+	"bike.in_rental":                 {Other: "la bicicleta está alquilada, solo puede marcarse como perdida"},
+
+	// estaciones
+	"station.list_ok":               {One: "{count} estación obtenida", Other: "{count} estaciones obtenidas"},
+	"station.list_error":            {Other: "Error al obtener las estaciones"},
+	"station.get_ok":                {Other: "Estación obtenida"},
+	"station.get_error":             {Other: "Error al obtener la estación"},
+	"station.created":               {Other: "Estación creada"},
+	"station.create_error":          {Other: "Error al crear la estación"},
+	"station.updated":               {Other: "Estación actualizada"},
+	"station.update_error":          {Other: "Error al actualizar la estación"},
+	"station.docked":                {Other: "Bicicleta anclada en el anclaje {dock}"},
+	"station.dock_error":            {Other: "Error al anclar la bicicleta"},
+	"station.undocked":              {Other: "Bicicleta desanclada"},
+	"station.undock_error":          {Other: "Error al desanclar la bicicleta"},
+	"station.not_found":             {Other: "estación no encontrada"},
+	"station.inactive":              {Other: "la estación no está activa"},
+	"station.full":                  {Other: "la estación no tiene anclajes libres"},
+	"station.return_required":       {Other: "la bicicleta debe devolverse a menos de {meters} metros de una estación"},
+	"station.capacity_below_docked": {Other: "el anclaje {dock} está ocupado, la capacidad no puede ser menor"},
+	"station.bike_not_dockable":     {Other: "una bicicleta en estado {status} no se puede anclar"},
+	"station.bike_not_docked":       {Other: "la bicicleta no está anclada en esta estación"},
+
+	// rebalanceo
+	"rebalancing.forecast_ok":        {One: "Pronóstico de {count} celda", Other: "Pronóstico de {count} celdas"},
+	"rebalancing.forecast_error":     {Other: "Error al calcular el pronóstico"},
+	"rebalancing.planned":            {One: "{count} tarea de traslado generada", Other: "{count} tareas de traslado generadas"},
+	"rebalancing.plan_error":         {Other: "Error al planificar los traslados"},
+	"rebalancing.list_ok":            {One: "{count} tarea de traslado obtenida", Other: "{count} tareas de traslado obtenidas"},
+	"rebalancing.list_error":         {Other: "Error al obtener las tareas de traslado"},
+	"rebalancing.get_ok":             {Other: "Tarea de traslado obtenida"},
+	"rebalancing.get_error":          {Other: "Error al obtener la tarea de traslado"},
+	"rebalancing.claimed":            {Other: "Tarea de traslado tomada"},
+	"rebalancing.claim_error":        {Other: "Error al tomar la tarea de traslado"},
+	"rebalancing.completed":          {One: "Tarea de traslado completada, {count} bicicleta movida", Other: "Tarea de traslado completada, {count} bicicletas movidas"},
+	"rebalancing.complete_error":     {Other: "Error al completar la tarea de traslado"},
+	"rebalancing.not_found":          {Other: "tarea de traslado no encontrada"},
+	"rebalancing.already_claimed":    {Other: "la tarea ya la tomó {claimed_by}"},
+	"rebalancing.invalid_transition": {Other: "la tarea no puede pasar de {from} a {to}"},
+
+	// incidentes
+	"incident.list_ok":                      {One: "{count} incidente obtenido", Other: "{count} incidentes obtenidos"},
+	"incident.list_error":                   {Other: "Error al obtener los incidentes"},
+	"incident.get_ok":                       {Other: "Incidente obtenido"},
+	"incident.get_error":                    {Other: "Error al obtener el incidente"},
+	"incident.acknowledged":                 {Other: "Incidente reconocido"},
+	"incident.acknowledge_error":            {Other: "Error al reconocer el incidente"},
+	"incident.resolved":                     {Other: "Incidente resuelto"},
+	"incident.resolve_error":                {Other: "Error al resolver el incidente"},
+	"incident.not_found":                    {Other: "incidente no encontrado"},
+	"incident.invalid_transition":           {Other: "el incidente no puede pasar de {from} a {to}"},
+	"incident.auto_resolved":                {Other: "la regla dejó de cumplirse"},
+	"incident.rule.movement_without_rental": {Other: "la bicicleta {bike_id} se movió sin un alquiler en curso"},
+	"incident.rule.no_signal":               {Other: "la bicicleta {bike_id} dejó de enviar telemetría"},
+	"incident.rule.outside_service_area":    {Other: "la bicicleta {bike_id} está fuera del área de servicio"},
+	"incident.rule.rental_overdue":          {Other: "el alquiler de la bicicleta {bike_id} supera la duración máxima"},
+	"incident.rule.lock_failed":             {Other: "no se pudo confirmar que el candado de la bicicleta {bike_id} quedó cerrado"},
+
+	// cierre automático de alquileres
+	"rental.end_warning.max_duration": {One: "Tu alquiler superó la duración máxima y se va a finalizar en {count} minuto", Other: "Tu alquiler superó la duración máxima y se va a finalizar en {count} minutos"},
+	"rental.end_warning.idle":         {One: "Tu alquiler no registra actividad y se va a finalizar en {count} minuto si no lo seguís usando", Other: "Tu alquiler no registra actividad y se va a finalizar en {count} minutos si no lo seguís usando"},
+}
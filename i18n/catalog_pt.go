@@ -0,0 +1,290 @@
+package i18n
+
+var catalogPT = map[string]Message{
+	// gerais
+	"status.ok":               {Other: "ok"},
+	"request.not_found":       {Other: "Serviço não encontrado."},
+	"request.query_error":     {Other: "Parâmetros de consulta inválidos"},
+	"error.internal":          {Other: "erro interno"},
+	"error.not_found":         {Other: "recurso não encontrado"},
+	"error.version_mismatch":  {Other: "o recurso foi modificado por outra requisição, obtenha-o novamente e tente outra vez"},
+	"error.concurrent_update": {Other: "o recurso foi modificado por outra requisição, tente novamente"},
+	"param.id_missing":        {Other: "parâmetro id não encontrado"},
+	"param.id_invalid":        {Other: "parâmetro id inválido"},
+	"field.positive_int":      {Other: "deve ser um inteiro positivo"},
+	"query.invalid_param":     {Other: "parâmetro {param} inválido"},
+	"query.invalid_sort":      {Other: "não é possível ordenar por {field}"},
+	"query.unknown_param":     {Other: "parâmetro {param} desconhecido"},
+
+	// validação
+	"request.invalid_body":     {Other: "corpo da requisição inválido: {error}"},
+	"request.trailing_data":    {Other: "o corpo da requisição contém dados após o objeto JSON"},
+	"request.empty_body":       {Other: "o corpo da requisição está vazio"},
+	"request.too_large":        {Other: "o corpo da requisição excede o máximo de {limit} bytes"},
+	"request.unknown_field":    {Other: "campo desconhecido {field}"},
+	"request.invalid_if_match": {Other: "header If-Match inválido, deve ser um ETag como \"3\""},
+	"request.missing_if_match": {Other: "o header If-Match com o ETag do recurso é obrigatório, obtenha-o e tente outra vez"},
+	"request.patch_not_object": {Other: "o corpo de um merge patch deve ser um objeto JSON"},
+	"validation.failed":        {Other: "campos inválidos"},
+	"validation.required":      {Other: "obrigatório"},
+	"validation.min":           {Other: "deve ser maior ou igual a {min}"},
+	"validation.max":           {Other: "deve ser menor ou igual a {max}"},
+	"validation.min_length":    {Other: "deve ter pelo menos {min} caracteres"},
+	"validation.max_length":    {Other: "deve ter no máximo {max} caracteres"},
+	"validation.max_bytes":     {Other: "deve ocupar no máximo {max} bytes"},
+	"validation.min_items":     {Other: "deve ter pelo menos {min} itens"},
+	"validation.max_items":     {Other: "deve ter no máximo {max} itens"},
+	"validation.email":         {Other: "email inválido"},
+	"validation.password":      {Other: "deve ter pelo menos {min} caracteres, com letras e números"},
+	"validation.oneof":         {Other: "deve ser um de: {values}"},
+	"validation.type":          {Other: "deve ser do tipo {type}"},
+	"validation.unknown_field": {Other: "campo não permitido"},
+	"validation.not_nullable":  {Other: "não aceita null"},
+	"validation.invalid_value": {Other: "valor inválido"},
+	"validation.readonly":      {Other: "não pode ser modificado"},
+
+	// autenticação
+	"auth.invalid_header":       {Other: "Formato inesperado do header de autorização"},
+	"auth.token_missing":        {Other: "Token não fornecido"},
+	"auth.token_invalid":        {Other: "Token inválido ou expirado"},
+	"auth.credentials_error":    {Other: "Erro ao validar as credenciais"},
+	"auth.credentials_invalid":  {Other: "Credenciais inválidas"},
+	"auth.login_invalid":        {Other: "As informações de login estão incorretas"},
+	"auth.login_error":          {Other: "Erro ao iniciar sessão"},
+	"auth.login_ok":             {Other: "Sessão iniciada com sucesso"},
+	"auth.current_user_error":   {Other: "Erro ao obter o usuário autenticado"},
+	"auth.claims_invalid":       {Other: "claims do token inválidas"},
+	"auth.token_user_not_found": {Other: "o usuário do token não existe"},
+	"auth.hash_error":           {Other: "Erro ao gerar o hash da senha"},
+
+	// usuários
+	"user.list_ok":          {One: "{count} usuário obtido", Other: "{count} usuários obtidos"},
+	"user.list_error":       {Other: "Erro ao obter os usuários"},
+	"user.get_ok":           {Other: "Usuário obtido"},
+	"user.get_error":        {Other: "Erro ao obter o usuário"},
+	"user.profile_ok":       {Other: "Perfil obtido"},
+	"user.created":          {Other: "Usuário criado com sucesso"},
+	"user.create_error":     {Other: "Erro ao criar o usuário"},
+	"user.updated":          {Other: "Usuário atualizado com sucesso"},
+	"user.update_error":     {Other: "Erro ao atualizar o usuário"},
+	"user.not_found":        {Other: "usuário não encontrado"},
+	"user.email_taken":      {Other: "email já registrado"},
+	"user.invalid_fields":   {Other: "campos do usuário inválidos"},
+	"user.invalid_email":    {Other: "email inválido"},
+	"user.invalid_first":    {Other: "nome inválido"},
+	"user.invalid_last":     {Other: "sobrenome inválido"},
+	"user.invalid_password": {Other: "senha inválida"},
+	"user.invalid_language": {Other: "idioma não suportado"},
+
+	// bicicletas
+	"bike.list_ok":        {One: "{count} bicicleta obtida", Other: "{count} bicicletas obtidas"},
+	"bike.list_error":     {Other: "Erro ao obter as bicicletas"},
+	"bike.get_ok":         {Other: "Bicicleta obtida"},
+	"bike.get_error":      {Other: "Erro ao obter a bicicleta"},
+	"bike.created":        {Other: "Bicicleta criada com sucesso"},
+	"bike.create_error":   {Other: "Erro ao criar a bicicleta"},
+	"bike.updated":        {Other: "Bicicleta atualizada com sucesso"},
+	"bike.update_error":   {Other: "Erro ao atualizar a bicicleta"},
+	"bike.not_found":      {Other: "bicicleta não encontrada"},
+	"bike.not_available":  {Other: "bicicleta não disponível"},
+	"bike.invalid_fields": {Other: "campos da bicicleta inválidos"},
+	"bike.invalid_cost":   {Other: "custo inválido"},
+
+	// aluguéis
+	"rental.started":         {Other: "Bicicleta alugada com sucesso"},
+	"rental.start_error":     {Other: "Erro ao alugar a bicicleta"},
+	"rental.ended":           {Other: "Aluguel da bicicleta finalizado com sucesso"},
+	"rental.end_error":       {Other: "Erro ao finalizar o aluguel"},
+	"rental.history_ok":      {One: "{count} aluguel no histórico", Other: "{count} aluguéis no histórico"},
+	"rental.history_error":   {Other: "Erro ao obter o histórico de aluguéis"},
+	"rental.list_ok":         {One: "{count} aluguel obtido", Other: "{count} aluguéis obtidos"},
+	"rental.list_error":      {Other: "Erro ao obter os aluguéis"},
+	"rental.get_ok":          {Other: "Aluguel obtido"},
+	"rental.get_error":       {Other: "Erro ao obter o aluguel"},
+	"rental.updated":         {Other: "Aluguel atualizado"},
+	"rental.update_error":    {Other: "Erro ao atualizar o aluguel"},
+	"rental.not_found":       {Other: "aluguel não encontrado"},
+	"rental.already_running": {Other: "o usuário já tem um aluguel em andamento"},
+	"rental.none_running":    {Other: "o usuário não tem um aluguel ativo"},
+	"rental.wrong_bike":      {Other: "o usuário não está alugando esta bicicleta"},
+
+	// idempotência
+	"idempotency.error":        {Other: "Erro ao processar a chave de idempotência"},
+	"idempotency.invalid_key":  {Other: "a chave Idempotency-Key deve ter no máximo {max} caracteres"},
+	"idempotency.key_mismatch": {Other: "a chave Idempotency-Key já foi usada com uma requisição diferente"},
+	"idempotency.in_progress":  {Other: "uma requisição com a mesma Idempotency-Key está sendo processada, tente novamente"},
+
+	// saúde
+	"health.alive":              {Other: "serviço ativo"},
+	"health.ready":              {Other: "serviço pronto para receber requisições"},
+	"health.not_ready":          {Other: "serviço indisponível"},
+	"health.shutting_down":      {Other: "o serviço está sendo desligado"},
+	"health.db_unavailable":     {Other: "o banco de dados não está respondendo"},
+	"health.migrations_pending": {Other: "migrações pendentes: {columns}"},
+
+	// limite de requisições
+	"ratelimit.exceeded":    {Other: "Muitas requisições"},
+	"ratelimit.retry_after": {One: "tente novamente em {count} segundo", Other: "tente novamente em {count} segundos"},
+
+	// auditoria
+	"audit.list_ok":      {One: "{count} registro de auditoria obtido", Other: "{count} registros de auditoria obtidos"},
+	"audit.list_error":   {Other: "Erro ao obter o log de auditoria"},
+	"audit.verify_error": {Other: "Erro ao verificar o log de auditoria"},
+	"audit.chain_valid":  {One: "{count} registro verificado, a cadeia está íntegra", Other: "{count} registros verificados, a cadeia está íntegra"},
+	"audit.chain_broken": {Other: "A cadeia do log de auditoria foi alterada"},
+
+	// webhooks
+	"webhook.list_ok":             {One: "{count} webhook obtido", Other: "{count} webhooks obtidos"},
+	"webhook.list_error":          {Other: "Erro ao obter os webhooks"},
+	"webhook.created":             {Other: "Webhook criado, guarde o segredo pois ele não será exibido novamente"},
+	"webhook.create_error":        {Other: "Erro ao criar o webhook"},
+	"webhook.get_ok":              {Other: "Webhook obtido"},
+	"webhook.get_error":           {Other: "Erro ao obter o webhook"},
+	"webhook.updated":             {Other: "Webhook atualizado"},
+	"webhook.update_error":        {Other: "Erro ao atualizar o webhook"},
+	"webhook.deleted":             {Other: "Webhook excluído"},
+	"webhook.delete_error":        {Other: "Erro ao excluir o webhook"},
+	"webhook.deliveries_ok":       {One: "{count} entrega obtida", Other: "{count} entregas obtidas"},
+	"webhook.deliveries_error":    {Other: "Erro ao obter as entregas do webhook"},
+	"webhook.retry_ok":            {Other: "Entrega enfileirada novamente"},
+	"webhook.retry_error":         {Other: "Erro ao reenviar a entrega"},
+	"webhook.not_found":           {Other: "webhook não encontrado"},
+	"webhook.delivery_not_found":  {Other: "entrega não encontrada"},
+	"webhook.delivery_not_dead":   {Other: "só é possível reenviar as entregas que esgotaram suas tentativas"},
+	"webhook.invalid_fields":      {Other: "dados do webhook inválidos"},
+	"webhook.invalid_url":         {Other: "deve ser uma URL http ou https"},
+	"webhook.invalid_event_types": {Other: "informe pelo menos um tipo de evento"},
+	"webhook.unknown_event":       {Other: "tipo de evento desconhecido: {event}"},
+
+	// stream
+	"stream.error": {Other: "Erro ao abrir o stream de bicicletas"},
+
+	// cadeados
+	"lock.offline":            {Other: "o cadeado da bicicleta não está conectado, tente novamente"},
+	"lock.no_ack":             {Other: "o cadeado da bicicleta não respondeu, tente novamente"},
+	"lock.open":               {Other: "o cadeado continua aberto, feche-o e tente novamente"},
+	"lock.still_locked":       {Other: "não foi possível abrir o cadeado"},
+	"lock.simulator_ok":       {Other: "Cadeado simulado obtido"},
+	"lock.simulator_updated":  {Other: "Cadeado simulado atualizado"},
+	"lock.simulator_error":    {Other: "Erro no cadeado simulado"},
+	"lock.simulator_disabled": {Other: "os cadeados não são simulados"},
+
+	// dispositivos e telemetria
+	"device.invalid_token":         {Other: "Token de dispositivo inválido"},
+	"device.auth_error":            {Other: "Erro ao autenticar o dispositivo"},
+	"device.issued":                {Other: "Token de dispositivo emitido, guarde-o pois não será exibido novamente"},
+	"device.issue_error":           {Other: "Erro ao emitir o token do dispositivo"},
+	"device.revoked":               {Other: "Dispositivo revogado"},
+	"device.revoke_error":          {Other: "Erro ao revogar o dispositivo"},
+	"device.not_found":             {Other: "a bicicleta não tem um dispositivo registrado"},
+	"telemetry.accepted":           {One: "{count} leitura recebida", Other: "{count} leituras recebidas"},
+	"telemetry.ingest_error":       {Other: "Erro ao receber a telemetria"},
+	"telemetry.list_ok":            {One: "{count} leitura obtida", Other: "{count} leituras obtidas"},
+	"telemetry.list_error":         {Other: "Erro ao obter a telemetria"},
+	"telemetry.recorded_in_future": {Other: "a data da leitura está no futuro"},
+
+	// percurso dos aluguéis
+	"route.recorded":             {One: "{count} ponto registrado", Other: "{count} pontos registrados"},
+	"route.record_error":         {Other: "Erro ao registrar o percurso"},
+	"route.get_error":            {Other: "Erro ao obter o percurso"},
+	"route.rental_not_running":   {Other: "o aluguel já terminou"},
+	"route.point_outside_rental": {Other: "a data do ponto está fora do aluguel"},
+
+	// tipos de veículo
+	"vehicle_type.list_ok":        {One: "{count} tipo de veículo obtido", Other: "{count} tipos de veículo obtidos"},
+	"vehicle_type.list_error":     {Other: "Erro ao obter os tipos de veículo"},
+	"vehicle_type.updated":        {Other: "Tipo de veículo atualizado"},
+	"vehicle_type.update_error":   {Other: "Erro ao atualizar o tipo de veículo"},
+	"vehicle_type.not_found":      {Other: "tipo de veículo não encontrado"},
+	"vehicle_type.range_required": {Other: "obrigatório para veículos elétricos"},
+	"bike.invalid_vehicle_type":   {Other: "tipo de veículo inexistente"},
+	"bike.battery_low":            {Other: "a bateria do veículo está abaixo de {min}%"},
+
+	// manutenção
+	"damage_report.created":         {Other: "Dano reportado, obrigado"},
+	"damage_report.create_error":    {Other: "Erro ao reportar o dano"},
+	"damage_report.not_found":       {Other: "relatório de dano não encontrado"},
+	"damage_report.photo_not_found": {Other: "o relatório não tem foto"},
+	"damage_report.photo_error":     {Other: "Erro ao obter a foto do relatório"},
+	"damage_report.invalid_photo":   {Other: "a foto deve ser jpeg, png ou webp"},
+	"work_order.list_ok":            {One: "{count} ordem de serviço obtida", Other: "{count} ordens de serviço obtidas"},
+	"work_order.list_error":         {Other: "Erro ao obter as ordens de serviço"},
+	"work_order.get_ok":             {Other: "Ordem de serviço obtida"},
+	"work_order.get_error":          {Other: "Erro ao obter a ordem de serviço"},
+	"work_order.created":            {Other: "Ordem de serviço aberta"},
+	"work_order.create_error":       {Other: "Erro ao abrir a ordem de serviço"},
+	"work_order.updated":            {Other: "Ordem de serviço atualizada"},
+	"work_order.update_error":       {Other: "Erro ao atualizar a ordem de serviço"},
+	"work_order.not_found":          {Other: "ordem de serviço não encontrada"},
+	"work_order.invalid_transition": {Other: "a ordem não pode passar de {from} para {to}"},
+	"work_order.mechanic_required":  {Other: "obrigatório para atribuir a ordem"},
+	"bike.out_of_service":           {Other: "a bicicleta está fora de serviço para manutenção"},
+
+	// estados das bicicletas
+	"bike.status_changed":            {Other: "Estado da bicicleta atualizado"},
+	"bike.status_error":              {Other: "Erro ao alterar o estado da bicicleta"},
+	"bike.status_history_ok":         {One: "{count} mudança de estado obtida", Other: "{count} mudanças de estado obtidas"},
+	"bike.status_history_error":      {Other: "Erro ao obter o histórico de estados"},
+	"bike.invalid_status_transition": {Other: "a bicicleta não pode passar de {from} para {to}"},
+	"bike.in_rental":                 {Other: "a bicicleta está alugada, só pode ser marcada como perdida"},
+
+	// estações
+	"station.list_ok":               {One: "{count} estação obtida", Other: "{count} estações obtidas"},
+	"station.list_error":            {Other: "Erro ao obter as estações"},
+	"station.get_ok":                {Other: "Estação obtida"},
+	"station.get_error":             {Other: "Erro ao obter a estação"},
+	"station.created":               {Other: "Estação criada"},
+	"station.create_error":          {Other: "Erro ao criar a estação"},
+	"station.updated":               {Other: "Estação atualizada"},
+	"station.update_error":          {Other: "Erro ao atualizar a estação"},
+	"station.docked":                {Other: "Bicicleta ancorada na doca {dock}"},
+	"station.dock_error":            {Other: "Erro ao ancorar a bicicleta"},
+	"station.undocked":              {Other: "Bicicleta desancorada"},
+	"station.undock_error":          {Other: "Erro ao desancorar a bicicleta"},
+	"station.not_found":             {Other: "estação não encontrada"},
+	"station.inactive":              {Other: "a estação não está ativa"},
+	"station.full":                  {Other: "a estação não tem docas livres"},
+	"station.return_required":       {Other: "a bicicleta deve ser devolvida a menos de {meters} metros de uma estação"},
+	"station.capacity_below_docked": {Other: "a doca {dock} está ocupada, a capacidade não pode ser menor"},
+	"station.bike_not_dockable":     {Other: "uma bicicleta no estado {status} não pode ser ancorada"},
+	"station.bike_not_docked":       {Other: "a bicicleta não está ancorada nesta estação"},
+
+	// rebalanceamento
+	"rebalancing.forecast_ok":        {One: "Previsão de {count} célula", Other: "Previsão de {count} células"},
+	"rebalancing.forecast_error":     {Other: "Erro ao calcular a previsão"},
+	"rebalancing.planned":            {One: "{count} tarefa de remanejamento gerada", Other: "{count} tarefas de remanejamento geradas"},
+	"rebalancing.plan_error":         {Other: "Erro ao planejar os remanejamentos"},
+	"rebalancing.list_ok":            {One: "{count} tarefa de remanejamento obtida", Other: "{count} tarefas de remanejamento obtidas"},
+	"rebalancing.list_error":         {Other: "Erro ao obter as tarefas de remanejamento"},
+	"rebalancing.get_ok":             {Other: "Tarefa de remanejamento obtida"},
+	"rebalancing.get_error":          {Other: "Erro ao obter a tarefa de remanejamento"},
+	"rebalancing.claimed":            {Other: "Tarefa de remanejamento assumida"},
+	"rebalancing.claim_error":        {Other: "Erro ao assumir a tarefa de remanejamento"},
+	"rebalancing.completed":          {One: "Tarefa de remanejamento concluída, {count} bicicleta movida", Other: "Tarefa de remanejamento concluída, {count} bicicletas movidas"},
+	"rebalancing.complete_error":     {Other: "Erro ao concluir a tarefa de remanejamento"},
+	"rebalancing.not_found":          {Other: "tarefa de remanejamento não encontrada"},
+	"rebalancing.already_claimed":    {Other: "a tarefa já foi assumida por {claimed_by}"},
+	"rebalancing.invalid_transition": {Other: "a tarefa não pode passar de {from} para {to}"},
+
+	// incidentes
+	"incident.list_ok":                      {One: "{count} incidente obtido", Other: "{count} incidentes obtidos"},
+	"incident.list_error":                   {Other: "Erro ao obter os incidentes"},
+	"incident.get_ok":                       {Other: "Incidente obtido"},
+	"incident.get_error":                    {Other: "Erro ao obter o incidente"},
+	"incident.acknowledged":                 {Other: "Incidente reconhecido"},
+	"incident.acknowledge_error":            {Other: "Erro ao reconhecer o incidente"},
+	"incident.resolved":                     {Other: "Incidente resolvido"},
+	"incident.resolve_error":                {Other: "Erro ao resolver o incidente"},
+	"incident.not_found":                    {Other: "incidente não encontrado"},
+	"incident.invalid_transition":           {Other: "o incidente não pode passar de {from} para {to}"},
+	"incident.auto_resolved":                {Other: "a regra deixou de ser cumprida"},
+	"incident.rule.movement_without_rental": {Other: "a bicicleta {bike_id} se moveu sem um aluguel em andamento"},
+	"incident.rule.no_signal":               {Other: "a bicicleta {bike_id} parou de enviar telemetria"},
+	"incident.rule.outside_service_area":    {Other: "a bicicleta {bike_id} está fora da área de serviço"},
+	"incident.rule.rental_overdue":          {Other: "o aluguel da bicicleta {bike_id} excede a duração máxima"},
+	"incident.rule.lock_failed":             {Other: "não foi possível confirmar que o cadeado da bicicleta {bike_id} ficou fechado"},
+
+	// encerramento automático de aluguéis
+	"rental.end_warning.max_duration": {One: "Seu aluguel excedeu a duração máxima e será finalizado em {count} minuto", Other: "Seu aluguel excedeu a duração máxima e será finalizado em {count} minutos"},
+	"rental.end_warning.idle":         {One: "Seu aluguel não registra atividade e será finalizado em {count} minuto se você não continuar usando", Other: "Seu aluguel não registra atividade e será finalizado em {count} minutos se você não continuar usando"},
+}
@@ -0,0 +1,189 @@
+package i18n
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Lang string
+
+const (
+	ES Lang = "es"
+	EN Lang = "en"
+	PT Lang = "pt"
+)
+
+const DefaultLang = ES
+
+// Params: Valores que reemplazan los {placeholders} de un mensaje.
+// El parámetro "count" además selecciona la forma singular o plural
+type Params = map[string]interface{}
+
+// Message: Texto de un mensaje, One solo se usa en mensajes con plural
+type Message struct {
+	One   string
+	Other string
+}
+
+var catalogs = map[Lang]map[string]Message{
+	ES: catalogES,
+	EN: catalogEN,
+	PT: catalogPT,
+}
+
+func init() {
+	// un catálogo incompleto es un error de programación, se detecta al iniciar
+	if err := Validate(); err != nil {
+		panic(err)
+	}
+}
+
+// IsSupported: Indica si existe un catálogo para el idioma
+func IsSupported(lang Lang) bool {
+	_, ok := catalogs[lang]
+	return ok
+}
+
+// Translate: Obtiene el mensaje en el idioma pedido y reemplaza sus parámetros.
+// Si el idioma no existe se usa el idioma por defecto y si el mensaje no existe se retorna su id
+func Translate(lang Lang, id string, params ...Params) string {
+	catalog, ok := catalogs[lang]
+	if !ok {
+		lang, catalog = DefaultLang, catalogs[DefaultLang]
+	}
+	msg, ok := catalog[id]
+	if !ok {
+		return id
+	}
+
+	var p Params
+	if len(params) > 0 {
+		p = params[0]
+	}
+
+	text := msg.Other
+	if count, ok := p["count"]; ok && msg.One != "" && isOne(lang, count) {
+		text = msg.One
+	}
+
+	for key, value := range p {
+		text = strings.ReplaceAll(text, "{"+key+"}", fmt.Sprint(value))
+	}
+	return text
+}
+
+// T: Traduce un mensaje al idioma negociado para la request
+func T(r *http.Request, id string, params ...Params) string {
+	return Translate(FromContext(r.Context()), id, params...)
+}
+
+// isOne: Regla de plural de cada idioma
+func isOne(lang Lang, count interface{}) bool {
+	n, err := strconv.ParseFloat(fmt.Sprint(count), 64)
+	if err != nil {
+		return false
+	}
+	if lang == PT {
+		// en portugués el cero también usa la forma singular
+		return n == 0 || n == 1
+	}
+	return n == 1
+}
+
+// Negotiate: Elige el idioma soportado con mayor prioridad según el header Accept-Language
+func Negotiate(acceptLanguage string) Lang {
+	type candidate struct {
+		lang Lang
+		q    float64
+	}
+	candidates := []candidate{}
+
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, q := strings.TrimSpace(part), 1.0
+		if i := strings.Index(tag, ";"); i >= 0 {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(tag[i+1:]), "q="); ok {
+				if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+					q = parsed
+				}
+			}
+			tag = tag[:i]
+		}
+		// se compara solo el idioma principal, ej: en-US -> en
+		primary, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		if lang := Lang(primary); IsSupported(lang) && q > 0 {
+			candidates = append(candidates, candidate{lang, q})
+		}
+	}
+
+	if len(candidates) == 0 {
+		return DefaultLang
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	return candidates[0].lang
+}
+
+type langKey struct{}
+
+// el idioma se guarda como puntero para que la preferencia del usuario, que se conoce
+// recién al cargarlo en el servicio, pueda reemplazar al negociado por el middleware
+type langHolder struct {
+	lang Lang
+}
+
+// WithLang: Retorna un contexto con el idioma de la request
+func WithLang(ctx context.Context, lang Lang) context.Context {
+	return context.WithValue(ctx, langKey{}, &langHolder{lang})
+}
+
+// SetLang: Reemplaza el idioma de la request, ej: por la preferencia del usuario
+func SetLang(ctx context.Context, lang Lang) {
+	if holder, ok := ctx.Value(langKey{}).(*langHolder); ok && IsSupported(lang) {
+		holder.lang = lang
+	}
+}
+
+// FromContext: Idioma de la request, o el idioma por defecto si no fue negociado
+func FromContext(ctx context.Context) Lang {
+	if holder, ok := ctx.Value(langKey{}).(*langHolder); ok {
+		return holder.lang
+	}
+	return DefaultLang
+}
+
+// Validate: Verifica que todos los catálogos tengan las mismas claves que el idioma por defecto
+// y que los mensajes con plural tengan ambas formas
+func Validate() error {
+	return validateCatalogs(catalogs)
+}
+
+func validateCatalogs(catalogs map[Lang]map[string]Message) error {
+	problems := []string{}
+	base := catalogs[DefaultLang]
+	for lang, catalog := range catalogs {
+		for id, msg := range base {
+			translated, ok := catalog[id]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s: falta el mensaje %s", lang, id))
+				continue
+			}
+			if (msg.One == "") != (translated.One == "") {
+				problems = append(problems, fmt.Sprintf("%s: el mensaje %s no coincide en su forma plural", lang, id))
+			}
+		}
+		for id := range catalog {
+			if _, ok := base[id]; !ok {
+				problems = append(problems, fmt.Sprintf("%s: el mensaje %s no existe en %s", lang, id, DefaultLang))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("catálogos de mensajes incompletos:\n%s", strings.Join(problems, "\n"))
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package i18n
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCatalogsComplete(t *testing.T) {
+	if err := Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidateCatalogs(t *testing.T) {
+	base := map[string]Message{
+		"bike.get_ok":  {Other: "Bicicleta obtenida"},
+		"bike.list_ok": {One: "{count} bicicleta", Other: "{count} bicicletas"},
+	}
+
+	tests := []struct {
+		name    string
+		other   map[string]Message
+		problem string
+	}{
+		{"complete", map[string]Message{
+			"bike.get_ok":  {Other: "Bike retrieved"},
+			"bike.list_ok": {One: "{count} bike", Other: "{count} bikes"},
+		}, ""},
+		{"missing key", map[string]Message{
+			"bike.list_ok": {One: "{count} bike", Other: "{count} bikes"},
+		}, "en: falta el mensaje bike.get_ok"},
+		{"extra key", map[string]Message{
+			"bike.get_ok":  {Other: "Bike retrieved"},
+			"bike.list_ok": {One: "{count} bike", Other: "{count} bikes"},
+			"bike.deleted": {Other: "Bike deleted"},
+		}, "en: el mensaje bike.deleted no existe en es"},
+		{"missing plural form", map[string]Message{
+			"bike.get_ok":  {Other: "Bike retrieved"},
+			"bike.list_ok": {Other: "{count} bikes"},
+		}, "en: el mensaje bike.list_ok no coincide en su forma plural"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateCatalogs(map[Lang]map[string]Message{ES: base, EN: tt.other})
+			if tt.problem == "" {
+				if err != nil {
+					t.Fatalf("error inesperado: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("se esperaba un error")
+			}
+			if !strings.Contains(err.Error(), tt.problem) {
+				t.Errorf("error = %q, se esperaba que incluya %q", err, tt.problem)
+			}
+		})
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header string
+		want   Lang
+	}{
+		{"", DefaultLang},
+		{"en", EN},
+		{"en-US,en;q=0.9", EN},
+		{"PT-BR", PT},
+		{"fr-FR,fr;q=0.9", DefaultLang},
+		{"fr, pt;q=0.5, en;q=0.8", EN},
+		{"en;q=0, pt", PT},
+		{"de;q=1, es;q=0.1", ES},
+		{"en;q=abc", EN},
+		{"pt;q=0.5, en;q=0.5", PT},
+	}
+	for _, tt := range tests {
+		if got := Negotiate(tt.header); got != tt.want {
+			t.Errorf("Negotiate(%q) = %s, se esperaba %s", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestTranslatePlural(t *testing.T) {
+	saved := catalogs
+	t.Cleanup(func() { catalogs = saved })
+	catalogs = map[Lang]map[string]Message{
+		ES: {"bike.list_ok": {One: "{count} bicicleta obtenida", Other: "{count} bicicletas obtenidas"}},
+		EN: {"bike.list_ok": {One: "{count} bike retrieved", Other: "{count} bikes retrieved"}},
+		PT: {"bike.list_ok": {One: "{count} bicicleta obtida", Other: "{count} bicicletas obtidas"}},
+	}
+
+	tests := []struct {
+		lang  Lang
+		count interface{}
+		want  string
+	}{
+		{ES, 1, "1 bicicleta obtenida"},
+		{ES, 0, "0 bicicletas obtenidas"},
+		{ES, 2, "2 bicicletas obtenidas"},
+		{EN, int64(1), "1 bike retrieved"},
+		{EN, 0, "0 bikes retrieved"},
+		{EN, 1.5, "1.5 bikes retrieved"},
+		// en portugués el cero usa la forma singular
+		{PT, 0, "0 bicicleta obtida"},
+		{PT, 1, "1 bicicleta obtida"},
+		{PT, 3, "3 bicicletas obtidas"},
+		// un idioma sin catálogo usa el idioma por defecto
+		{Lang("fr"), 1, "1 bicicleta obtenida"},
+	}
+	for _, tt := range tests {
+		got := Translate(tt.lang, "bike.list_ok", Params{"count": tt.count})
+		if got != tt.want {
+			t.Errorf("Translate(%s, count=%v) = %q, se esperaba %q", tt.lang, tt.count, got, tt.want)
+		}
+	}
+
+	if got := Translate(EN, "bike.list_ok"); got != "{count} bikes retrieved" {
+		t.Errorf("sin count = %q, se esperaba la forma plural", got)
+	}
+	if got := Translate(EN, "missing.key"); got != "missing.key" {
+		t.Errorf("mensaje inexistente = %q, se esperaba su id", got)
+	}
+}
@@ -0,0 +1,35 @@
+package lock
+
+import (
+	"log/slog"
+
+	mqttserver "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+)
+
+// Broker: Broker MQTT embebido, para desarrollo y tests sin un broker externo
+type Broker struct {
+	server *mqttserver.Server
+}
+
+// StartBroker: Inicia el broker escuchando en addr (host:puerto). Acepta cualquier cliente, no se debe exponer
+func StartBroker(addr string) (*Broker, error) {
+	server := mqttserver.New(&mqttserver.Options{
+		Logger: slog.Default().With("component", "mqtt_broker"),
+	})
+	if err := server.AddHook(new(auth.AllowHook), nil); err != nil {
+		return nil, err
+	}
+	if err := server.AddListener(listeners.NewTCP(listeners.Config{ID: "tcp", Address: addr})); err != nil {
+		return nil, err
+	}
+	if err := server.Serve(); err != nil {
+		return nil, err
+	}
+	return &Broker{server: server}, nil
+}
+
+func (b *Broker) Close() error {
+	return b.server.Close()
+}
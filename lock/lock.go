@@ -0,0 +1,108 @@
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Drivers soportados por la configuración
+const (
+	DriverSimulator = "simulator"
+	DriverMQTT      = "mqtt"
+)
+
+// Comandos que se envían al candado
+const (
+	ActionUnlock = "unlock"
+	ActionLock   = "lock"
+)
+
+// Estados que reporta el candado
+const (
+	StateLocked   = "locked"
+	StateUnlocked = "unlocked"
+)
+
+var (
+	// ErrOffline: El candado (o el broker) no está conectado, el comando no se envió
+	ErrOffline = errors.New("candado desconectado")
+	// ErrNoAck: El candado no confirmó el comando dentro del tiempo límite
+	ErrNoAck = errors.New("el candado no confirmó el comando")
+	// ErrLockOpen: Se pidió cerrar pero el candado reporta que sigue abierto (ej: el usuario no lo trabó)
+	ErrLockOpen = errors.New("el candado reporta que está abierto")
+	// ErrStillLocked: Se pidió abrir pero el candado reporta que sigue cerrado (ej: está trabado)
+	ErrStillLocked = errors.New("el candado reporta que sigue cerrado")
+)
+
+// Controller: Envía comandos a los candados de las bicicletas y espera su confirmación.
+// Los métodos retornan cuando el candado confirma el estado pedido, o con uno de los errores del paquete.
+// El tiempo límite lo define el ctx, al vencer se retorna ErrNoAck
+type Controller interface {
+	Unlock(ctx context.Context, bikeId int64) error
+	Lock(ctx context.Context, bikeId int64) error
+	Close() error
+}
+
+// Command: Comando enviado al candado
+type Command struct {
+	Id     string    `json:"id"`
+	Action string    `json:"action"`
+	SentAt time.Time `json:"sent_at"`
+}
+
+// Ack: Confirmación de un comando con el estado del candado después de ejecutarlo.
+// Error lo completa el candado si no pudo ejecutar el comando
+type Ack struct {
+	CommandId string `json:"command_id"`
+	State     string `json:"state"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Status: Estado publicado por el candado al conectarse y cada vez que cambia
+type Status struct {
+	Online bool   `json:"online"`
+	State  string `json:"state,omitempty"`
+}
+
+func newCommand(action string) Command {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return Command{Id: hex.EncodeToString(b), Action: action, SentAt: time.Now().UTC()}
+}
+
+// checkAck: Verifica que el estado confirmado sea el que pidió el comando
+func checkAck(action string, ack Ack) error {
+	expected := StateLocked
+	if action == ActionUnlock {
+		expected = StateUnlocked
+	}
+	if ack.State == expected {
+		return nil
+	}
+
+	var err error
+	switch ack.State {
+	case StateUnlocked:
+		err = ErrLockOpen
+	case StateLocked:
+		err = ErrStillLocked
+	default:
+		return fmt.Errorf("estado de candado desconocido %q", ack.State)
+	}
+	if ack.Error != "" {
+		return fmt.Errorf("%w: %s", err, ack.Error)
+	}
+	return err
+}
+
+// waitErr: Error al cancelarse ctx esperando la confirmación, el vencimiento del plazo es ErrNoAck
+func waitErr(ctx context.Context) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return ErrNoAck
+	}
+	return ctx.Err()
+}
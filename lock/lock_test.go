@@ -0,0 +1,152 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestSimulator(t *testing.T) {
+	tests := []struct {
+		name   string
+		device Device
+		action string
+		want   error
+		state  string
+	}{
+		{"unlock", Device{}, ActionUnlock, nil, StateUnlocked},
+		{"lock", Device{State: StateUnlocked}, ActionLock, nil, StateLocked},
+		{"lock already locked", Device{}, ActionLock, nil, StateLocked},
+		{"offline", Device{Offline: true}, ActionUnlock, ErrOffline, StateLocked},
+		{"silent executes without ack", Device{Silent: true}, ActionUnlock, ErrNoAck, StateUnlocked},
+		{"jammed stays open", Device{State: StateUnlocked, Jammed: true}, ActionLock, ErrLockOpen, StateUnlocked},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim := NewSimulator(time.Millisecond)
+			sim.SetDevice(1, tt.device)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			var err error
+			if tt.action == ActionUnlock {
+				err = sim.Unlock(ctx, 1)
+			} else {
+				err = sim.Lock(ctx, 1)
+			}
+
+			if !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, se esperaba %v", err, tt.want)
+			}
+			if state := sim.Device(1).State; state != tt.state {
+				t.Errorf("estado = %s, se esperaba %s", state, tt.state)
+			}
+		})
+	}
+}
+
+func TestSimulatorDefaultDevice(t *testing.T) {
+	sim := NewSimulator(0)
+	if device := sim.Device(7); device != (Device{State: StateLocked}) {
+		t.Errorf("candado = %+v, se esperaba conectado y cerrado", device)
+	}
+}
+
+// freeAddr: Dirección local con un puerto libre para el broker
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	return addr
+}
+
+// eventually: Reintenta fn hasta que retorne nil o venza el plazo, los mensajes MQTT llegan en forma asíncrona
+func eventually(t *testing.T, fn func() error) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		err := fn()
+		if err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal(err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestMQTTEmbeddedBroker(t *testing.T) {
+	addr := freeAddr(t)
+	broker, err := StartBroker(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { broker.Close() })
+
+	opts := MQTTOptions{Broker: "tcp://" + addr, ClientId: "test", TopicPrefix: "bikes", ConnectTimeout: 5 * time.Second}
+	sim := NewSimulator(time.Millisecond)
+	ctx, stop := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- ServeDevices(ctx, opts, sim) }()
+	t.Cleanup(func() {
+		stop()
+		if err := <-served; err != nil {
+			t.Error(err)
+		}
+	})
+
+	controller, err := NewMQTTController(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { controller.Close() })
+
+	command := func(action string, bikeId int64) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		if action == ActionUnlock {
+			return controller.Unlock(ctx, bikeId)
+		}
+		return controller.Lock(ctx, bikeId)
+	}
+
+	// hasta que la flota simulada se suscribe los comandos no se confirman
+	eventually(t, func() error { return command(ActionUnlock, 1) })
+	if state := sim.Device(1).State; state != StateUnlocked {
+		t.Errorf("estado después de unlock = %s", state)
+	}
+	if err := command(ActionLock, 1); err != nil {
+		t.Errorf("lock: %v", err)
+	}
+	if state := sim.Device(1).State; state != StateLocked {
+		t.Errorf("estado después de lock = %s", state)
+	}
+
+	sim.SetDevice(2, Device{State: StateUnlocked, Jammed: true})
+	if err := command(ActionLock, 2); !errors.Is(err, ErrLockOpen) {
+		t.Errorf("lock trabado: error = %v, se esperaba %v", err, ErrLockOpen)
+	}
+
+	sim.SetDevice(3, Device{Silent: true})
+	if err := command(ActionUnlock, 3); !errors.Is(err, ErrNoAck) {
+		t.Errorf("candado silencioso: error = %v, se esperaba %v", err, ErrNoAck)
+	}
+
+	// el estado offline se publica retenido, el controlador rechaza el comando sin enviarlo. Se vuelve a publicar
+	// por si la flota todavía no registró el aviso de cambios de estado
+	eventually(t, func() error {
+		sim.SetDevice(4, Device{Offline: true})
+		if err := command(ActionUnlock, 4); !errors.Is(err, ErrOffline) {
+			return fmt.Errorf("el controlador no recibió el estado offline: %v", err)
+		}
+		return nil
+	})
+}
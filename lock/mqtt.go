@@ -0,0 +1,253 @@
+package lock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// Topics por bicicleta, debajo del prefijo: {prefix}/{bike_id}/command recibe los comandos (QoS 1),
+// {prefix}/{bike_id}/ack las confirmaciones y {prefix}/{bike_id}/status el estado del candado (retenido,
+// el candado lo publica como last will con online=false para que se sepa cuando se desconecta)
+const (
+	topicCommand = "command"
+	topicAck     = "ack"
+	topicStatus  = "status"
+)
+
+// MQTTOptions: Conexión al broker. Broker es la URL (ej: tcp://localhost:1883)
+type MQTTOptions struct {
+	Broker         string
+	ClientId       string
+	Username       string
+	Password       string
+	TopicPrefix    string
+	ConnectTimeout time.Duration
+}
+
+// MQTTController: Controller que envía los comandos por MQTT y espera la confirmación en el topic ack.
+// Guarda el último estado publicado por cada candado, los candados que reportaron online=false se rechazan
+// sin enviar el comando
+type MQTTController struct {
+	client  mqtt.Client
+	prefix  string
+	mu      sync.Mutex
+	pending map[string]chan Ack
+	status  map[int64]Status
+}
+
+// NewMQTTController: Se conecta al broker. Si no responde dentro de ConnectTimeout sigue reintentando
+// en segundo plano y los comandos fallan con ErrOffline hasta que se conecte
+func NewMQTTController(opts MQTTOptions) (*MQTTController, error) {
+	c := &MQTTController{
+		prefix:  opts.TopicPrefix,
+		pending: map[string]chan Ack{},
+		status:  map[int64]Status{},
+	}
+
+	clientOpts := newClientOptions(opts).
+		SetOnConnectHandler(func(client mqtt.Client) {
+			// la sesión es limpia, las suscripciones se renuevan en cada reconexión
+			filters := map[string]byte{topic(c.prefix, "+", topicAck): 1, topic(c.prefix, "+", topicStatus): 1}
+			if token := client.SubscribeMultiple(filters, c.handle); token.Wait() && token.Error() != nil {
+				slog.Error("mqtt subscribe failed", "error", token.Error())
+				return
+			}
+			slog.Info("mqtt connected", "broker", opts.Broker)
+		}).
+		SetConnectionLostHandler(func(client mqtt.Client, err error) {
+			slog.Warn("mqtt connection lost", "broker", opts.Broker, "error", err)
+		})
+	c.client = mqtt.NewClient(clientOpts)
+
+	token := c.client.Connect()
+	if !token.WaitTimeout(opts.ConnectTimeout) {
+		slog.Warn("mqtt broker unreachable, retrying in background", "broker", opts.Broker)
+		return c, nil
+	}
+	if err := token.Error(); err != nil {
+		return nil, fmt.Errorf("error al conectar con el broker MQTT %s: %w", opts.Broker, err)
+	}
+	return c, nil
+}
+
+func (c *MQTTController) Unlock(ctx context.Context, bikeId int64) error {
+	return c.send(ctx, bikeId, ActionUnlock)
+}
+
+func (c *MQTTController) Lock(ctx context.Context, bikeId int64) error {
+	return c.send(ctx, bikeId, ActionLock)
+}
+
+func (c *MQTTController) Close() error {
+	c.client.Disconnect(250)
+	return nil
+}
+
+func (c *MQTTController) send(ctx context.Context, bikeId int64, action string) error {
+	if !c.client.IsConnectionOpen() {
+		return fmt.Errorf("broker MQTT desconectado: %w", ErrOffline)
+	}
+
+	// un candado sin estado conocido puede estar conectado, se le envía el comando igual
+	c.mu.Lock()
+	status, known := c.status[bikeId]
+	c.mu.Unlock()
+	if known && !status.Online {
+		return ErrOffline
+	}
+
+	cmd := newCommand(action)
+	acks := make(chan Ack, 1)
+	c.mu.Lock()
+	c.pending[cmd.Id] = acks
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, cmd.Id)
+		c.mu.Unlock()
+	}()
+
+	payload, err := json.Marshal(cmd)
+	if err != nil {
+		return err
+	}
+	token := c.client.Publish(topic(c.prefix, strconv.FormatInt(bikeId, 10), topicCommand), 1, false, payload)
+	select {
+	case <-token.Done():
+		if err := token.Error(); err != nil {
+			return fmt.Errorf("error al publicar el comando: %w", err)
+		}
+	case <-ctx.Done():
+		return waitErr(ctx)
+	}
+
+	select {
+	case ack := <-acks:
+		return checkAck(action, ack)
+	case <-ctx.Done():
+		return waitErr(ctx)
+	}
+}
+
+// handle: Procesa las confirmaciones y los estados publicados por los candados
+func (c *MQTTController) handle(_ mqtt.Client, msg mqtt.Message) {
+	bikeId, kind, ok := parseTopic(c.prefix, msg.Topic())
+	if !ok {
+		return
+	}
+
+	switch kind {
+	case topicAck:
+		var ack Ack
+		if err := json.Unmarshal(msg.Payload(), &ack); err != nil {
+			slog.Warn("invalid lock ack", "topic", msg.Topic(), "error", err)
+			return
+		}
+		c.mu.Lock()
+		acks, ok := c.pending[ack.CommandId]
+		c.mu.Unlock()
+		// las confirmaciones de comandos que ya vencieron se descartan
+		if ok {
+			select {
+			case acks <- ack:
+			default:
+			}
+		}
+	case topicStatus:
+		var status Status
+		if err := json.Unmarshal(msg.Payload(), &status); err != nil {
+			slog.Warn("invalid lock status", "topic", msg.Topic(), "error", err)
+			return
+		}
+		c.mu.Lock()
+		c.status[bikeId] = status
+		c.mu.Unlock()
+	}
+}
+
+func topic(prefix string, bikeId string, kind string) string {
+	return prefix + "/" + bikeId + "/" + kind
+}
+
+// parseTopic: Extrae el id de la bicicleta y el tipo de mensaje de {prefix}/{bike_id}/{kind}
+func parseTopic(prefix string, name string) (int64, string, bool) {
+	parts := strings.Split(strings.TrimPrefix(name, prefix+"/"), "/")
+	if len(parts) != 2 {
+		return 0, "", false
+	}
+	bikeId, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, "", false
+	}
+	return bikeId, parts[1], true
+}
+
+func newClientOptions(opts MQTTOptions) *mqtt.ClientOptions {
+	return mqtt.NewClientOptions().
+		AddBroker(opts.Broker).
+		SetClientID(opts.ClientId).
+		SetUsername(opts.Username).
+		SetPassword(opts.Password).
+		SetConnectTimeout(opts.ConnectTimeout).
+		SetAutoReconnect(true).
+		SetConnectRetry(true)
+}
+
+// ServeDevices: Conecta el simulador al broker como si fuera la flota de candados: ejecuta los comandos
+// recibidos y publica las confirmaciones y los cambios de estado. Bloquea hasta que se cancele ctx
+func ServeDevices(ctx context.Context, opts MQTTOptions, sim *Simulator) error {
+	opts.ClientId += "-devices"
+	client := mqtt.NewClient(newClientOptions(opts).
+		SetOnConnectHandler(func(client mqtt.Client) {
+			token := client.Subscribe(topic(opts.TopicPrefix, "+", topicCommand), 1, func(client mqtt.Client, msg mqtt.Message) {
+				bikeId, _, ok := parseTopic(opts.TopicPrefix, msg.Topic())
+				if !ok {
+					return
+				}
+				var cmd Command
+				if err := json.Unmarshal(msg.Payload(), &cmd); err != nil {
+					slog.Warn("invalid lock command", "topic", msg.Topic(), "error", err)
+					return
+				}
+				// se responde en otra goroutine para no bloquear la recepción durante la latencia
+				go func() {
+					ack, ok := sim.Execute(ctx, bikeId, cmd)
+					if !ok {
+						return
+					}
+					payload, _ := json.Marshal(ack)
+					client.Publish(topic(opts.TopicPrefix, strconv.FormatInt(bikeId, 10), topicAck), 1, false, payload)
+				}()
+			})
+			if token.Wait() && token.Error() != nil {
+				slog.Error("mqtt subscribe failed", "error", token.Error())
+			}
+		}))
+
+	token := client.Connect()
+	if !token.WaitTimeout(opts.ConnectTimeout) {
+		return fmt.Errorf("el broker MQTT %s no respondió", opts.Broker)
+	}
+	if err := token.Error(); err != nil {
+		return fmt.Errorf("error al conectar con el broker MQTT %s: %w", opts.Broker, err)
+	}
+
+	sim.mu.Lock()
+	sim.onStatus = func(bikeId int64, status Status) {
+		payload, _ := json.Marshal(status)
+		client.Publish(topic(opts.TopicPrefix, strconv.FormatInt(bikeId, 10), topicStatus), 1, true, payload)
+	}
+	sim.mu.Unlock()
+
+	<-ctx.Done()
+	client.Disconnect(250)
+	return nil
+}
@@ -0,0 +1,119 @@
+package lock
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Device: Estado simulado de un candado. Offline no recibe comandos, Silent los ejecuta pero no confirma
+// y Jammed no traba al recibir lock (reporta que sigue abierto)
+type Device struct {
+	State   string `json:"state"`
+	Offline bool   `json:"offline"`
+	Silent  bool   `json:"silent"`
+	Jammed  bool   `json:"jammed"`
+}
+
+// Simulator: Flota de candados en memoria. Los candados que no se configuraron están conectados y cerrados.
+// Implementa Controller para desarrollo, y con ServeDevices responde los comandos que llegan por MQTT
+type Simulator struct {
+	mu       sync.Mutex
+	devices  map[int64]*Device
+	latency  time.Duration
+	onStatus func(bikeId int64, status Status)
+}
+
+// NewSimulator: Crea el simulador, latency es el tiempo que tarda cada candado en ejecutar un comando
+func NewSimulator(latency time.Duration) *Simulator {
+	return &Simulator{devices: map[int64]*Device{}, latency: latency}
+}
+
+// Device: Estado actual del candado de la bicicleta
+func (s *Simulator) Device(bikeId int64) Device {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.device(bikeId)
+}
+
+// SetDevice: Reemplaza el estado del candado de la bicicleta, ej: para simular que se desconecta
+func (s *Simulator) SetDevice(bikeId int64, device Device) {
+	if device.State == "" {
+		device.State = StateLocked
+	}
+	s.mu.Lock()
+	s.devices[bikeId] = &device
+	onStatus := s.onStatus
+	s.mu.Unlock()
+
+	if onStatus != nil {
+		onStatus(bikeId, Status{Online: !device.Offline, State: device.State})
+	}
+}
+
+func (s *Simulator) Unlock(ctx context.Context, bikeId int64) error {
+	return s.send(ctx, bikeId, ActionUnlock)
+}
+
+func (s *Simulator) Lock(ctx context.Context, bikeId int64) error {
+	return s.send(ctx, bikeId, ActionLock)
+}
+
+func (s *Simulator) Close() error {
+	return nil
+}
+
+func (s *Simulator) send(ctx context.Context, bikeId int64, action string) error {
+	if s.Device(bikeId).Offline {
+		return ErrOffline
+	}
+
+	ack, ok := s.Execute(ctx, bikeId, newCommand(action))
+	if !ok {
+		// el candado no confirma, se espera hasta que venza el plazo
+		<-ctx.Done()
+		return waitErr(ctx)
+	}
+	return checkAck(action, ack)
+}
+
+// Execute: Ejecuta el comando en el candado simulado. Retorna false si el candado no responde
+// (desconectado o silencioso) o si se canceló ctx durante la latencia
+func (s *Simulator) Execute(ctx context.Context, bikeId int64, cmd Command) (Ack, bool) {
+	select {
+	case <-time.After(s.latency):
+	case <-ctx.Done():
+		return Ack{}, false
+	}
+
+	s.mu.Lock()
+	device := s.device(bikeId)
+	if device.Offline {
+		s.mu.Unlock()
+		return Ack{}, false
+	}
+	switch {
+	case cmd.Action == ActionUnlock:
+		device.State = StateUnlocked
+	case cmd.Action == ActionLock && !device.Jammed:
+		device.State = StateLocked
+	}
+	ack := Ack{CommandId: cmd.Id, State: device.State}
+	if cmd.Action == ActionLock && device.Jammed {
+		ack.Error = "el pestillo no cerró"
+	}
+	silent := device.Silent
+	s.mu.Unlock()
+
+	return ack, !silent
+}
+
+// device: Candado de la bicicleta, se crea conectado y cerrado si no existe. Se llama con mu tomado
+func (s *Simulator) device(bikeId int64) *Device {
+	device, ok := s.devices[bikeId]
+	if !ok {
+		device = &Device{State: StateLocked}
+		s.devices[bikeId] = device
+	}
+	return device
+}
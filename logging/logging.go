@@ -0,0 +1,154 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Valor que reemplaza a los datos sensibles en los logs
+const Redacted = "[REDACTED]"
+
+// claves de atributos (y headers) cuyo valor nunca se escribe en los logs
+var sensitiveKeys = map[string]bool{
+	"authorization":       true,
+	"proxy-authorization": true,
+	"cookie":              true,
+	"set-cookie":          true,
+	"password":            true,
+	"hashed_password":     true,
+	"token":               true,
+	"secret":              true,
+	"jwt_secret":          true,
+	"admin_credentials":   true,
+}
+
+var level = new(slog.LevelVar)
+
+// Init: Configura slog como logger por defecto, con salida JSON y el nivel indicado (debug, info, warn o error)
+func Init(lvl string) error {
+	if err := SetLevel(lvl); err != nil {
+		return err
+	}
+	slog.SetDefault(New(os.Stdout, level))
+	return nil
+}
+
+// New: Crea un logger JSON que redacta los datos sensibles y agrega los atributos de la request del contexto
+func New(w io.Writer, lvl slog.Leveler) *slog.Logger {
+	handler := slog.NewJSONHandler(w, &slog.HandlerOptions{Level: lvl, ReplaceAttr: redact})
+	return slog.New(&contextHandler{handler})
+}
+
+// SetLevel: Cambia el nivel del logger por defecto. Un nivel vacío equivale a info
+func SetLevel(lvl string) error {
+	if lvl == "" {
+		level.Set(slog.LevelInfo)
+		return nil
+	}
+
+	var parsed slog.Level
+	if err := parsed.UnmarshalText([]byte(lvl)); err != nil {
+		return err
+	}
+	level.Set(parsed)
+	return nil
+}
+
+func redact(groups []string, a slog.Attr) slog.Attr {
+	if sensitiveKeys[strings.ToLower(a.Key)] {
+		return slog.String(a.Key, Redacted)
+	}
+	return a
+}
+
+type requestKey struct{}
+
+// requestFields: Atributos de la request que se agregan a cada línea de log.
+// Es mutable para que los middlewares y servicios internos (ej: AuthMiddleware con user_id)
+// agreguen atributos visibles también para el log de acceso
+type requestFields struct {
+	mu    sync.Mutex
+	id    string
+	attrs []slog.Attr
+}
+
+// WithRequestID: Retorna un contexto con el id de la request
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestKey{}, &requestFields{id: id})
+}
+
+// RequestID: Obtiene el id de la request del contexto, vacío si no existe
+func RequestID(ctx context.Context) string {
+	if fields, ok := ctx.Value(requestKey{}).(*requestFields); ok {
+		return fields.id
+	}
+	return ""
+}
+
+// AddAttrs: Agrega atributos (ej: user_id, rental_id, bike_id) a todos los logs siguientes de la request.
+// Un atributo con la misma clave reemplaza al anterior
+func AddAttrs(ctx context.Context, attrs ...slog.Attr) {
+	fields, ok := ctx.Value(requestKey{}).(*requestFields)
+	if !ok {
+		return
+	}
+
+	fields.mu.Lock()
+	defer fields.mu.Unlock()
+	for _, attr := range attrs {
+		replaced := false
+		for i := range fields.attrs {
+			if fields.attrs[i].Key == attr.Key {
+				fields.attrs[i] = attr
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			fields.attrs = append(fields.attrs, attr)
+		}
+	}
+}
+
+// contextHandler: Handler que agrega a cada registro el request_id y los atributos guardados en el contexto,
+// salvo los que el registro ya incluye
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if fields, ok := ctx.Value(requestKey{}).(*requestFields); ok {
+		present := map[string]bool{}
+		record.Attrs(func(a slog.Attr) bool {
+			present[a.Key] = true
+			return true
+		})
+
+		fields.mu.Lock()
+		record.AddAttrs(slog.String("request_id", fields.id))
+		for _, attr := range fields.attrs {
+			if !present[attr.Key] {
+				record.AddAttrs(attr)
+			}
+		}
+		fields.mu.Unlock()
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		record.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{h.Handler.WithGroup(name)}
+}
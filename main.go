@@ -1,10 +1,17 @@
 package main
 
 import (
-	"encoding/json"
-	"log"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	chimiddleware "github.com/go-chi/chi/v5/middleware"
@@ -12,46 +19,265 @@ import (
 
 	"github.com/mbarolo/test_back/config"
 	_ "github.com/mbarolo/test_back/docs"
+	"github.com/mbarolo/test_back/i18n"
+	"github.com/mbarolo/test_back/lock"
+	"github.com/mbarolo/test_back/logging"
+	"github.com/mbarolo/test_back/metrics"
+	"github.com/mbarolo/test_back/middleware"
 	"github.com/mbarolo/test_back/routes"
+	"github.com/mbarolo/test_back/services"
+	"github.com/mbarolo/test_back/tracing"
 	"github.com/mbarolo/test_back/utils"
 )
 
 func main() {
+	args := os.Args[1:]
 
-	// se cargan las variables de entorno
-	utils.LoadEnv()
+	// test_back config print [flags]: muestra la configuración efectiva sin iniciar el servidor
+	if len(args) > 0 && args[0] == "config" {
+		os.Exit(configCommand(args[1:]))
+	}
 
-	// logging
-	log.SetFlags(log.LstdFlags | log.Lmicroseconds)
-	log.Println("Start test_back")
-	log.Printf("serverUp, %s", os.Getenv("ADDR"))
+	// se carga la configuración: valores por defecto, archivo YAML, variables de entorno y flags
+	cfg, err := config.Load(args)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		slog.Error("invalid configuration", "error", err)
+		os.Exit(2)
+	}
+	config.Set(cfg)
 
-	defer config.CloseDB()
+	// logging JSON con el nivel de log.level (debug, info, warn, error)
+	if err := logging.Init(cfg.Log.Level); err != nil {
+		slog.Error("invalid log level", "error", err)
+		os.Exit(1)
+	}
+	slog.Info("starting test_back", "addr", cfg.Server.Addr)
+
+	// trazas OpenTelemetry, con el exportador de tracing.exporter (none, otlp, stdout)
+	exporter, err := tracing.NewExporter(context.Background(), cfg.Tracing.Exporter)
+	if err != nil {
+		slog.Error("invalid traces exporter", "error", err)
+		os.Exit(1)
+	}
+	shutdownTracing := tracing.Init(cfg.Tracing.ServiceName, exporter)
+
+	services.Init(cfg)
+
+	// candados de las bicicletas, con el driver de locks.driver (simulator, mqtt)
+	if err := services.InitLocks(cfg.Locks); err != nil {
+		slog.Error("locks init failed", "error", err)
+		os.Exit(1)
+	}
+
+	// avisos de incidentes, con el driver de incidents.notifier (log, http)
+	if err := services.InitIncidents(cfg.Incidents); err != nil {
+		slog.Error("incidents init failed", "error", err)
+		os.Exit(1)
+	}
+
+	// SIGINT/SIGTERM cancelan el contexto y disparan el apagado ordenado
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// tareas en segundo plano, terminan al cancelar jobsCtx
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	var jobs sync.WaitGroup
+	jobs.Add(1)
+	go func() {
+		defer jobs.Done()
+		services.CleanExpiredIdempotencyKeys(jobsCtx, time.Hour)
+	}()
+	jobs.Add(1)
+	go func() {
+		defer jobs.Done()
+		services.DropExpiredTelemetry(jobsCtx, time.Hour)
+	}()
+	jobs.Add(1)
+	go func() {
+		defer jobs.Done()
+		reloadOnSIGHUP(jobsCtx, args)
+	}()
+	if cfg.Webhooks.Enabled {
+		jobs.Add(1)
+		go func() {
+			defer jobs.Done()
+			services.DispatchWebhooks(jobsCtx, cfg.Webhooks.PollInterval)
+		}()
+	}
+	if cfg.Incidents.Enabled {
+		jobs.Add(1)
+		go func() {
+			defer jobs.Done()
+			services.WatchIncidents(jobsCtx, cfg.Incidents.Interval)
+		}()
+	}
+	if cfg.Rentals.AutoEnd.Enabled {
+		jobs.Add(1)
+		go func() {
+			defer jobs.Done()
+			services.CloseAbandonedRentals(jobsCtx, cfg.Rentals.AutoEnd.Interval)
+		}()
+	}
+	if cfg.Rebalancing.Enabled {
+		jobs.Add(1)
+		go func() {
+			defer jobs.Done()
+			services.ScheduleRebalancing(jobsCtx, cfg.Rebalancing.Interval)
+		}()
+	}
+	if cfg.Locks.Driver == lock.DriverMQTT && cfg.Locks.MQTT.EmbeddedBroker != "" {
+		jobs.Add(1)
+		go func() {
+			defer jobs.Done()
+			services.ServeSimulatedLocks(jobsCtx, cfg.Locks)
+		}()
+	}
 
 	// se configura go-chi
 	app := chi.NewRouter()
-	app.Use(chimiddleware.Logger)
+	app.Use(middleware.RequestID)
+	if cfg.Server.TrustProxy {
+		app.Use(chimiddleware.RealIP)
+	}
+	app.Use(middleware.ClientIP)
+	app.Use(middleware.Tracing)
+	app.Use(middleware.RequestLogger)
+	app.Use(middleware.Metrics)
 	app.Use(chimiddleware.Recoverer)
+	app.Use(middleware.LanguageMiddleware)
 
 	// Swagger para documentacion de api
 	app.Get("/swagger/*", httpSwagger.Handler(
-		httpSwagger.URL("http://localhost:8080/swagger/doc.json"),
+		httpSwagger.URL(cfg.Swagger.URL),
 	))
 
+	// métricas en formato Prometheus, protegidas con las credenciales de administrador
+	app.With(middleware.AdminMiddleware).Get("/metrics", metrics.Handler().ServeHTTP)
+
 	// se define la response default para 404
 	app.NotFound(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-type", "application/json")
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(map[string]string{"message": "Servicio no encontrado."})
+		utils.JsonResponse(w, http.StatusNotFound, i18n.T(r, "request.not_found"), nil)
 	})
 
 	// registramos las rutas en la aplicación
 	routes.InitRoutes(app)
 	chi.Walk(app, func(method string, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
-		log.Printf("[%s] %s", method, route)
+		slog.Debug("route registered", "method", method, "route", route)
 		return nil
 	})
 
-	log.Printf("Server starting on %s", os.Getenv("ADDR"))
-	http.ListenAndServe(os.Getenv("ADDR"), app)
+	server := &http.Server{
+		Addr:              cfg.Server.Addr,
+		Handler:           app,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		slog.Info("server starting", "addr", server.Addr)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	exitCode := 0
+	select {
+	case err := <-serverErr:
+		slog.Error("server failed", "error", err)
+		exitCode = 1
+	case <-ctx.Done():
+		shutdownTimeout := config.Current().Server.ShutdownTimeout
+		slog.Info("shutdown signal received", "timeout", shutdownTimeout.String())
+		services.StartDraining()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			slog.Error("server shutdown failed", "error", err)
+			exitCode = 1
+		}
+		if err := <-serverErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("server failed", "error", err)
+			exitCode = 1
+		}
+		if err := services.WaitForStreams(shutdownCtx); err != nil {
+			slog.Error("streams shutdown failed", "error", err)
+		}
+		if err := services.WaitForAlerts(shutdownCtx); err != nil {
+			slog.Error("incident alerts shutdown failed", "error", err)
+		}
+	}
+
+	stopJobs()
+	jobs.Wait()
+
+	if err := services.CloseLocks(); err != nil {
+		slog.Error("locks close failed", "error", err)
+	}
+
+	if err := shutdownTracing(context.Background()); err != nil {
+		slog.Error("tracing shutdown failed", "error", err)
+	}
+	if err := config.CloseDB(); err != nil {
+		slog.Error("database close failed", "error", err)
+		exitCode = 1
+	}
+	slog.Info("server stopped")
+	os.Exit(exitCode)
+}
+
+// configCommand: Subcomandos de configuración. print escribe la configuración efectiva con los secretos redactados
+// y los errores de validación, si existen
+func configCommand(args []string) int {
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Fprintln(os.Stderr, "uso: test_back config print [flags]")
+		return 2
+	}
+
+	cfg, err := config.Load(args[1:])
+	if cfg != nil {
+		if err := config.Print(os.Stdout, cfg); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// reloadOnSIGHUP: Vuelve a cargar la configuración al recibir SIGHUP y aplica los campos recargables
+// (nivel de log, vigencia de los tokens y timeout de apagado), hasta que se cancele el contexto
+func reloadOnSIGHUP(ctx context.Context, args []string) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+		}
+
+		cfg, applied, restart, err := config.Reload(args)
+		if err != nil {
+			slog.Error("config reload failed", "error", err)
+			continue
+		}
+		if err := logging.SetLevel(cfg.Log.Level); err != nil {
+			slog.Error("config reload failed", "error", err)
+			continue
+		}
+		if len(restart) > 0 {
+			slog.Warn("config changes require restart", "keys", restart)
+		}
+		slog.Info("config reloaded", "applied", applied)
+	}
 }
@@ -0,0 +1,177 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "test_back"
+
+// Registry: Registro propio de métricas, así solo se exponen las de la aplicación, Go y el proceso
+var Registry = prometheus.NewRegistry()
+
+// Métricas HTTP, etiquetadas con el patrón de la ruta de chi (ej: /api/v1/admin/bikes/{id})
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Cantidad de requests HTTP atendidas.",
+	}, []string{"method", "route", "status"})
+
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Duración de las requests HTTP.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	HTTPInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "http_requests_in_flight",
+		Help:      "Cantidad de requests HTTP en curso.",
+	})
+)
+
+// Métricas de negocio
+var (
+	RentalsStarted = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rentals_started_total",
+		Help:      "Cantidad de alquileres iniciados.",
+	})
+
+	RentalsEnded = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rentals_ended_total",
+		Help:      "Cantidad de alquileres finalizados.",
+	})
+
+	Revenue = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "revenue_minor_units_total",
+		Help:      "Monto cobrado por los alquileres finalizados, en unidades menores de la moneda.",
+	})
+
+	RentalsAutoEnded = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rentals_auto_ended_total",
+		Help:      "Cantidad de alquileres abandonados cerrados automáticamente, por motivo.",
+	}, []string{"reason"})
+
+	LoginFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "login_failures_total",
+		Help:      "Cantidad de inicios de sesión fallidos, por motivo.",
+	}, []string{"reason"})
+
+	RateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_total",
+		Help:      "Cantidad de requests rechazadas por superar el límite, por grupo de rutas.",
+	}, []string{"scope"})
+
+	WebhookDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_delivery_attempts_total",
+		Help:      "Cantidad de intentos de entrega a los webhooks, por resultado.",
+	}, []string{"result"})
+
+	StreamSlowClients = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "stream_slow_clients_total",
+		Help:      "Cantidad de clientes del stream de bicicletas desconectados por no consumir los eventos a tiempo.",
+	})
+
+	LockCommands = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "lock_commands_total",
+		Help:      "Cantidad de comandos enviados a los candados, por acción y resultado.",
+	}, []string{"action", "result"})
+
+	LockCommandDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "lock_command_duration_seconds",
+		Help:      "Tiempo hasta la confirmación (o el error) de los comandos a los candados.",
+		Buckets:   []float64{.05, .1, .25, .5, 1, 2, 3, 5},
+	}, []string{"action"})
+
+	TelemetryReadings = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "telemetry_readings_total",
+		Help:      "Cantidad de lecturas de telemetría recibidas de los dispositivos.",
+	})
+)
+
+// Resultados de un intento de entrega a un webhook
+const (
+	WebhookSucceeded = "succeeded"
+	WebhookFailed    = "failed"
+	WebhookDead      = "dead"
+)
+
+// Resultados de un comando a un candado
+const (
+	LockConfirmed   = "confirmed"
+	LockOffline     = "offline"
+	LockNoAck       = "no_ack"
+	LockOpen        = "open"
+	LockStillLocked = "still_locked"
+	LockFailed      = "failed"
+)
+
+// Motivos de login fallido
+const (
+	LoginUnknownUser   = "unknown_user"
+	LoginWrongPassword = "wrong_password"
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPDuration,
+		HTTPInFlight,
+		RentalsStarted,
+		RentalsEnded,
+		Revenue,
+		RentalsAutoEnded,
+		LoginFailures,
+		RateLimited,
+		WebhookDeliveries,
+		StreamSlowClients,
+		LockCommands,
+		LockCommandDuration,
+		TelemetryReadings,
+	)
+
+	// los motivos se inicializan en 0 para que la serie exista antes del primer fallo
+	LoginFailures.WithLabelValues(LoginUnknownUser)
+	LoginFailures.WithLabelValues(LoginWrongPassword)
+	WebhookDeliveries.WithLabelValues(WebhookSucceeded)
+	WebhookDeliveries.WithLabelValues(WebhookFailed)
+	WebhookDeliveries.WithLabelValues(WebhookDead)
+}
+
+// RegisterDB: Agrega las estadísticas del pool de conexiones (sql.DBStats) de la base de datos
+func RegisterDB(db *sql.DB, name string) {
+	Registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// RegisterGauge: Agrega un gauge cuyo valor se calcula en cada scrape
+func RegisterGauge(name string, help string, value func() float64) {
+	Registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      name,
+		Help:      help,
+	}, value))
+}
+
+// Handler: Endpoint con las métricas en formato de texto de Prometheus
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// scrape: Pide /metrics al Handler y retorna el valor de cada serie (nombre con sus etiquetas)
+func scrape(t *testing.T) map[string]float64 {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("content type = %q, se esperaba el formato de texto de Prometheus", ct)
+	}
+
+	series := map[string]float64{}
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.LastIndex(line, " ")
+		value, err := strconv.ParseFloat(line[i+1:], 64)
+		if err != nil {
+			t.Fatalf("línea inválida %q: %v", line, err)
+		}
+		series[line[:i]] = value
+	}
+	return series
+}
+
+func TestHandlerCounters(t *testing.T) {
+	before := scrape(t)
+
+	RentalsStarted.Inc()
+	RentalsStarted.Inc()
+	RentalsEnded.Inc()
+	Revenue.Add(350)
+	LoginFailures.WithLabelValues(LoginWrongPassword).Inc()
+	RentalsAutoEnded.WithLabelValues("idle").Inc()
+
+	after := scrape(t)
+	tests := []struct {
+		series string
+		delta  float64
+	}{
+		{"test_back_rentals_started_total", 2},
+		{"test_back_rentals_ended_total", 1},
+		{"test_back_revenue_minor_units_total", 350},
+		{`test_back_login_failures_total{reason="wrong_password"}`, 1},
+		{`test_back_login_failures_total{reason="unknown_user"}`, 0},
+		{`test_back_rentals_auto_ended_total{reason="idle"}`, 1},
+	}
+	for _, tt := range tests {
+		value, ok := after[tt.series]
+		if !ok {
+			t.Errorf("falta la serie %s", tt.series)
+			continue
+		}
+		if delta := value - before[tt.series]; delta != tt.delta {
+			t.Errorf("%s aumentó %v, se esperaba %v", tt.series, delta, tt.delta)
+		}
+	}
+
+	// los motivos inicializados existen antes del primer fallo
+	if _, ok := before[`test_back_webhook_delivery_attempts_total{result="dead"}`]; !ok {
+		t.Error("falta la serie inicializada de webhooks muertos")
+	}
+}
+
+func TestRegisterGauge(t *testing.T) {
+	available := 3.0
+	RegisterGauge("test_bikes_available", "Bicicletas disponibles (test).", func() float64 { return available })
+
+	if got := scrape(t)["test_back_test_bikes_available"]; got != 3 {
+		t.Errorf("gauge = %v, se esperaba 3", got)
+	}
+	// el valor se calcula en cada scrape
+	available = 5
+	if got := scrape(t)["test_back_test_bikes_available"]; got != 5 {
+		t.Errorf("gauge = %v, se esperaba 5", got)
+	}
+}
@@ -6,12 +6,16 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
-	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/mbarolo/test_back/config"
+	"github.com/mbarolo/test_back/i18n"
+	"github.com/mbarolo/test_back/logging"
 	"github.com/mbarolo/test_back/models"
 	"github.com/mbarolo/test_back/utils"
 )
@@ -32,23 +36,10 @@ func (c Claims) Valid() error {
 	return nil
 }
 
-const EXPIRATION_IN_DAYS = 30
-
-var jwtKey []byte
-
-func init() {
-	// Asignamos la key del .env, si no existe se asigna una default
-	key := os.Getenv("JWT_KEY")
-	if key == "" {
-		key = "default_key"
-	}
-
-	jwtKey = []byte(key)
-}
-
-// GenerateToken: Genera un token json segun las claims del struct
+// GenerateToken: Genera un token json segun las claims del struct, con la vigencia de auth.token_ttl
 func GenerateToken(user models.User) (string, time.Time, error) {
-	expirationTime := time.Now().Add(time.Hour * 24 * EXPIRATION_IN_DAYS)
+	authConfig := config.Current().Auth
+	expirationTime := time.Now().Add(authConfig.TokenTTL)
 	claims := &Claims{
 		Sub:       fmt.Sprintf("%d", user.Id),
 		Exp:       expirationTime.Unix(),
@@ -58,7 +49,7 @@ func GenerateToken(user models.User) (string, time.Time, error) {
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(jwtKey)
+	tokenString, err := token.SignedString([]byte(authConfig.JWTSecret))
 	if err != nil {
 		return "", time.Time{}, err
 	}
@@ -74,7 +65,7 @@ func validateToken(tokenString string) (*Claims, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("método de firma inválido")
 		}
-		return jwtKey, nil
+		return []byte(config.Current().Auth.JWTSecret), nil
 	})
 
 	if err != nil {
@@ -99,30 +90,35 @@ func AuthMiddleware(next http.Handler) http.Handler {
 			if len(parts) == 2 && parts[0] == "Bearer" {
 				token = parts[1]
 			} else {
-				utils.JsonResponse(w, http.StatusUnauthorized, "Estructura de header de autorización inesperada", nil)
+				utils.JsonResponse(w, http.StatusUnauthorized, i18n.T(r, "auth.invalid_header"), nil)
 				return
 			}
 		} else {
-			utils.JsonResponse(w, http.StatusUnauthorized, "Token no proporcionado", nil)
+			utils.JsonResponse(w, http.StatusUnauthorized, i18n.T(r, "auth.token_missing"), nil)
 			return
 		}
 
 		claims, err := validateToken(token)
 		if err != nil {
-			utils.JsonResponse(w, http.StatusUnauthorized, "Error al validar el token: "+err.Error(), nil)
+			slog.DebugContext(r.Context(), "token rejected", "error", err)
+			utils.JsonResponse(w, http.StatusUnauthorized, i18n.T(r, "auth.token_invalid"), nil)
 			return
 		}
 
+		if userId, err := strconv.ParseInt(claims.Sub, 10, 64); err == nil {
+			logging.AddAttrs(r.Context(), slog.Int64("user_id", userId))
+		}
+
 		ctx := context.WithValue(r.Context(), "claims", claims)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
-// validateAdminCredentials compara las credenciales del encabezado de autorización con las del .env
+// validateAdminCredentials compara las credenciales del encabezado de autorización con las de la configuración
 func validateAdminCredentials(creds string) (bool, error) {
-	adminCredentials, ok := os.LookupEnv("ADMIN_CREDENTIALS")
-	if !ok {
-		return false, errors.New("credenciales de administrador no presentes en el entorno")
+	adminCredentials := config.Current().Auth.AdminCredentials
+	if adminCredentials == "" {
+		return false, errors.New("credenciales de administrador no configuradas")
 	}
 
 	if _, err := base64.StdEncoding.DecodeString(creds); err != nil {
@@ -142,19 +138,21 @@ func AdminMiddleware(next http.Handler) http.Handler {
 			if len(parts) == 2 && parts[0] == "Basic" {
 				creds = parts[1]
 			} else {
-				utils.JsonResponse(w, http.StatusUnauthorized, "Estructura de header de autorización inesperada", nil)
+				utils.JsonResponse(w, http.StatusUnauthorized, i18n.T(r, "auth.invalid_header"), nil)
 				return
 			}
 		}
 
 		if valid, err := validateAdminCredentials(creds); err != nil {
-			utils.JsonResponse(w, http.StatusUnauthorized, "Error al validar las credenciales"+err.Error(), nil)
+			utils.JsonResponse(w, http.StatusUnauthorized, i18n.T(r, "auth.credentials_error"), nil)
 			return
 		} else if !valid {
-			utils.JsonResponse(w, http.StatusUnauthorized, "Credenciales inválidas", nil)
+			utils.JsonResponse(w, http.StatusUnauthorized, i18n.T(r, "auth.credentials_invalid"), nil)
 			return
 		}
 
+		logging.AddAttrs(r.Context(), slog.Bool("admin", true))
+
 		ctx := context.WithValue(r.Context(), "claims", "")
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+)
+
+type clientIPKey struct{}
+
+// ClientIP: Middleware que guarda en el contexto la IP del cliente, sin el puerto.
+// Detrás de un proxy debe usarse después de chimiddleware.RealIP
+func ClientIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), clientIPKey{}, remoteHost(r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// ClientIPFromContext: IP del cliente guardada por ClientIP, vacío fuera de una request
+func ClientIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
+
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/mbarolo/test_back/apperror"
+	"github.com/mbarolo/test_back/i18n"
+	"github.com/mbarolo/test_back/logging"
+	"github.com/mbarolo/test_back/utils"
+)
+
+type deviceKey struct{}
+
+// DeviceAuthenticator: Valida el token de un dispositivo y retorna el id de su bicicleta.
+// Un token desconocido debe retornar un error apperror UNAUTHORIZED
+type DeviceAuthenticator func(ctx context.Context, token string) (int64, error)
+
+// DeviceAuth: Middleware para las rutas de los dispositivos de las bicicletas, autenticados con
+// Authorization: Bearer <token>. Guarda en el contexto el id de la bicicleta del dispositivo
+func DeviceAuth(authenticate DeviceAuthenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := r.Header.Get("Authorization")
+			if header == "" {
+				utils.JsonResponse(w, http.StatusUnauthorized, i18n.T(r, "auth.token_missing"), nil)
+				return
+			}
+			token, ok := strings.CutPrefix(header, "Bearer ")
+			if !ok || token == "" {
+				utils.JsonResponse(w, http.StatusUnauthorized, i18n.T(r, "auth.invalid_header"), nil)
+				return
+			}
+
+			bikeId, err := authenticate(r.Context(), token)
+			var appErr *apperror.Error
+			if errors.As(err, &appErr) && appErr.Code == apperror.UNAUTHORIZED {
+				slog.DebugContext(r.Context(), "device token rejected")
+				utils.JsonResponse(w, http.StatusUnauthorized, i18n.T(r, appErr.Message), nil)
+				return
+			}
+			if err != nil {
+				utils.ErrorResponse(w, r, "device.auth_error", err)
+				return
+			}
+
+			logging.AddAttrs(r.Context(), slog.Int64("bike_id", bikeId))
+
+			ctx := context.WithValue(r.Context(), deviceKey{}, bikeId)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// DeviceBikeId: Id de la bicicleta del dispositivo autenticado por DeviceAuth
+func DeviceBikeId(ctx context.Context) (int64, bool) {
+	bikeId, ok := ctx.Value(deviceKey{}).(int64)
+	return bikeId, ok
+}
+
+// KeyByDevice: Agrupa las requests por dispositivo, debe usarse después de DeviceAuth
+func KeyByDevice(r *http.Request) string {
+	if bikeId, ok := DeviceBikeId(r.Context()); ok {
+		return "device:" + strconv.FormatInt(bikeId, 10)
+	}
+	return KeyByIP(r)
+}
@@ -0,0 +1,208 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mbarolo/test_back/apperror"
+	"github.com/mbarolo/test_back/i18n"
+	"github.com/mbarolo/test_back/models"
+	"github.com/mbarolo/test_back/utils"
+)
+
+const (
+	IdempotencyHeader = "Idempotency-Key"
+	// Tiempo durante el cual se repite la respuesta guardada para una clave
+	IdempotencyTTL = 24 * time.Hour
+
+	maxIdempotencyKeyLength = 255
+	maxIdempotentBodyBytes  = 1 << 20
+)
+
+// IdempotencyStore: Almacenamiento de las respuestas asociadas a cada Idempotency-Key
+type IdempotencyStore interface {
+	Reserve(ctx context.Context, record *models.IdempotencyRecord) (bool, error)
+	Get(ctx context.Context, userId int64, key string) (*models.IdempotencyRecord, error)
+	Complete(ctx context.Context, record *models.IdempotencyRecord) error
+	Release(ctx context.Context, id int64) error
+}
+
+// Idempotency: Middleware que hace idempotentes las solicitudes que envían el header Idempotency-Key.
+// La primera solicitud con una clave se ejecuta y su respuesta se guarda por usuario y clave; los reintentos
+// con el mismo cuerpo reciben la respuesta guardada y una clave reutilizada con otro cuerpo retorna 409.
+// Debe usarse después de AuthMiddleware
+func Idempotency(store IdempotencyStore, ttl time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyHeader)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				utils.ErrorResponse(w, r, "idempotency.error", apperror.BadRequest("idempotency.invalid_key").WithParams(i18n.Params{"max": maxIdempotencyKeyLength}))
+				return
+			}
+
+			userId, err := idempotencyScope(r)
+			if err != nil {
+				utils.ErrorResponse(w, r, "idempotency.error", err)
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBodyBytes))
+			if err != nil {
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					err = apperror.New(apperror.TOO_LARGE, "request.too_large").WithParams(i18n.Params{"limit": maxBytesErr.Limit})
+				} else {
+					err = apperror.BadRequest("request.invalid_body").WithParams(i18n.Params{"error": err.Error()})
+				}
+				utils.ErrorResponse(w, r, "idempotency.error", err)
+				return
+			}
+			// el handler vuelve a leer el cuerpo
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			now := time.Now()
+			record := &models.IdempotencyRecord{
+				UserId:         userId,
+				IdempotencyKey: key,
+				Fingerprint:    fingerprint(r, body),
+				CreatedAt:      now,
+				ExpiresAt:      now.Add(ttl),
+			}
+
+			reserved, err := store.Reserve(r.Context(), record)
+			if err != nil {
+				utils.ErrorResponse(w, r, "idempotency.error", err)
+				return
+			}
+			if !reserved {
+				replayResponse(w, r, store, record)
+				return
+			}
+
+			// si el handler falla o entra en pánico la clave se libera para permitir el reintento,
+			// aunque el cliente ya haya cerrado la conexión
+			ctx := context.WithoutCancel(r.Context())
+			completed := false
+			defer func() {
+				if !completed {
+					if err := store.Release(ctx, record.Id); err != nil {
+						slog.ErrorContext(ctx, "release idempotency key failed", "idempotency_key", key, "error", err)
+					}
+				}
+			}()
+
+			recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(recorder, r)
+
+			// los errores del servidor no se guardan, el cliente puede reintentar con la misma clave
+			if recorder.status >= http.StatusInternalServerError {
+				return
+			}
+
+			record.StatusCode = &recorder.status
+			record.ContentType = recorder.Header().Get("Content-Type")
+			record.ResponseBody = recorder.body.String()
+			if err := store.Complete(ctx, record); err != nil {
+				slog.ErrorContext(ctx, "store idempotent response failed", "idempotency_key", key, "error", err)
+				return
+			}
+			completed = true
+		})
+	}
+}
+
+// replayResponse: Responde a un reintento con la respuesta guardada para la clave
+func replayResponse(w http.ResponseWriter, r *http.Request, store IdempotencyStore, record *models.IdempotencyRecord) {
+	stored, err := store.Get(r.Context(), record.UserId, record.IdempotencyKey)
+	if errors.Is(err, sql.ErrNoRows) {
+		// la solicitud original falló y liberó la clave mientras tanto
+		utils.ErrorResponse(w, r, "idempotency.error", apperror.Conflict("idempotency.in_progress"))
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(w, r, "idempotency.error", err)
+		return
+	}
+
+	if stored.Fingerprint != record.Fingerprint {
+		utils.ErrorResponse(w, r, "idempotency.error", apperror.Conflict("idempotency.key_mismatch"))
+		return
+	}
+	if stored.StatusCode == nil {
+		utils.ErrorResponse(w, r, "idempotency.error", apperror.Conflict("idempotency.in_progress"))
+		return
+	}
+
+	if stored.ContentType != "" {
+		w.Header().Set("Content-Type", stored.ContentType)
+	}
+	slog.InfoContext(r.Context(), "idempotent response replayed", "idempotency_key", record.IdempotencyKey)
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(*stored.StatusCode)
+	w.Write([]byte(stored.ResponseBody))
+}
+
+// idempotencyScope: Id del usuario autenticado dueño de la clave. Las claves del administrador usan el id 0
+func idempotencyScope(r *http.Request) (int64, error) {
+	switch claims := r.Context().Value("claims").(type) {
+	case *Claims:
+		id, err := strconv.ParseInt(claims.Sub, 10, 64)
+		if err != nil {
+			return 0, apperror.Unauthorized("auth.claims_invalid")
+		}
+		return id, nil
+	case string:
+		return 0, nil
+	}
+	return 0, apperror.Unauthorized("auth.claims_invalid")
+}
+
+// fingerprint: Hash del método, la ruta y el cuerpo de la solicitud.
+// Los cuerpos JSON se compactan para que los espacios no cambien el resultado
+func fingerprint(r *http.Request, body []byte) string {
+	compacted := &bytes.Buffer{}
+	if err := json.Compact(compacted, body); err == nil {
+		body = compacted.Bytes()
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// responseRecorder: ResponseWriter que además de responder guarda el código y el cuerpo de la respuesta
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (rr *responseRecorder) WriteHeader(status int) {
+	if !rr.wroteHeader {
+		rr.status = status
+		rr.wroteHeader = true
+	}
+	rr.ResponseWriter.WriteHeader(status)
+}
+
+func (rr *responseRecorder) Write(data []byte) (int, error) {
+	rr.wroteHeader = true
+	rr.body.Write(data)
+	return rr.ResponseWriter.Write(data)
+}
@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mbarolo/test_back/config"
+	"github.com/mbarolo/test_back/repository"
+)
+
+// idempotentHandler: Handler detrás del middleware, con la base de datos en un directorio temporal.
+// calls cuenta las ejecuciones del handler
+func idempotentHandler(t *testing.T, handler http.HandlerFunc) (http.Handler, *atomic.Int32) {
+	t.Helper()
+	if err := config.InitDB(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { config.CloseDB() })
+
+	calls := &atomic.Int32{}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		handler(w, r)
+	})
+	return Idempotency(repository.NewIdempotencyRepository(config.DB), IdempotencyTTL)(next), calls
+}
+
+// idempotentRequest: POST del administrador con la clave y el cuerpo
+func idempotentRequest(h http.Handler, key, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/rentals/start", strings.NewReader(body))
+	r.Header.Set(IdempotencyHeader, key)
+	r = r.WithContext(context.WithValue(r.Context(), "claims", "admin"))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	return rec
+}
+
+// errorCode: Código de la respuesta de error
+func errorCode(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body struct {
+		Code string `json:"code"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("respuesta %q: %v", rec.Body.String(), err)
+	}
+	return body.Code
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	h, calls := idempotentHandler(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":1}`))
+	})
+
+	first := idempotentRequest(h, "key", `{"bike_id": 1}`)
+	// los espacios del JSON no cambian la solicitud
+	retry := idempotentRequest(h, "key", `{"bike_id":1}`)
+
+	if calls.Load() != 1 {
+		t.Errorf("ejecuciones = %d, se esperaba 1", calls.Load())
+	}
+	if retry.Code != first.Code || retry.Body.String() != first.Body.String() || retry.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("reintento = %d %s, se esperaba %d %s", retry.Code, retry.Body, first.Code, first.Body)
+	}
+	if retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("falta el header Idempotent-Replayed")
+	}
+}
+
+func TestIdempotencyKeyMismatch(t *testing.T) {
+	h, calls := idempotentHandler(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	idempotentRequest(h, "key", `{"bike_id": 1}`)
+	rec := idempotentRequest(h, "key", `{"bike_id": 2}`)
+	if rec.Code != http.StatusConflict || errorCode(t, rec) != "CONFLICT" {
+		t.Errorf("respuesta = %d %s, se esperaba 409", rec.Code, rec.Body)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("ejecuciones = %d, se esperaba 1", calls.Load())
+	}
+}
+
+func TestIdempotencyInProgress(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	h, calls := idempotentHandler(t, func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- idempotentRequest(h, "key", `{"bike_id": 1}`) }()
+	<-started
+
+	rec := idempotentRequest(h, "key", `{"bike_id": 1}`)
+	if rec.Code != http.StatusConflict {
+		t.Errorf("respuesta = %d %s, se esperaba 409 mientras se procesa", rec.Code, rec.Body)
+	}
+
+	close(release)
+	if first := <-done; first.Code != http.StatusCreated {
+		t.Errorf("solicitud original = %d, se esperaba 201", first.Code)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("ejecuciones = %d, se esperaba 1", calls.Load())
+	}
+}
+
+func TestIdempotencyServerErrorReleasesKey(t *testing.T) {
+	var failed atomic.Bool
+	h, calls := idempotentHandler(t, func(w http.ResponseWriter, r *http.Request) {
+		if !failed.Swap(true) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	if rec := idempotentRequest(h, "key", `{"bike_id": 1}`); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("respuesta = %d, se esperaba 503", rec.Code)
+	}
+	// el 5xx no se guarda, el reintento vuelve a ejecutar la solicitud
+	if rec := idempotentRequest(h, "key", `{"bike_id": 1}`); rec.Code != http.StatusCreated || rec.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("reintento = %d, se esperaba 201 sin repetir la respuesta", rec.Code)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("ejecuciones = %d, se esperaba 2", calls.Load())
+	}
+}
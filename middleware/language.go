@@ -0,0 +1,17 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/mbarolo/test_back/i18n"
+)
+
+// Middleware que negocia el idioma de la respuesta según el header Accept-Language
+func LanguageMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lang := i18n.Negotiate(r.Header.Get("Accept-Language"))
+		w.Header().Add("Vary", "Accept-Language")
+		ctx := i18n.WithLang(r.Context(), lang)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"time"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/mbarolo/test_back/logging"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// ids recibidos del cliente o de un proxy, se aceptan solo si son seguros para escribir en los logs
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// Middleware que asigna un id a cada request, reutilizando el header X-Request-ID si fue enviado
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = newRequestID()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+		ctx := logging.WithRequestID(r.Context(), id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Middleware que registra cada request al finalizar, con su estado y duración.
+// En nivel debug se incluyen los headers, con los valores sensibles redactados
+func RequestLogger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		} else if status >= http.StatusBadRequest {
+			level = slog.LevelWarn
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", status),
+			slog.Int("bytes", ww.BytesWritten()),
+			slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("remote_addr", r.RemoteAddr),
+			slog.String("user_agent", r.UserAgent()),
+		}
+		ctx := r.Context()
+		if slog.Default().Enabled(ctx, slog.LevelDebug) {
+			attrs = append(attrs, headerAttrs(r.Header))
+		}
+
+		slog.LogAttrs(ctx, level, "request completed", attrs...)
+	})
+}
+
+// headerAttrs: Agrupa los headers de la request, la redacción la aplica el handler de logging
+func headerAttrs(header http.Header) slog.Attr {
+	values := make([]any, 0, len(header))
+	for name := range header {
+		values = append(values, slog.String(name, header.Get(name)))
+	}
+	return slog.Group("headers", values...)
+}
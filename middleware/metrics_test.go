@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mbarolo/test_back/metrics"
+)
+
+// scrapeSeries: Valor de cada serie del scrape de /metrics
+func scrapeSeries(t *testing.T, r http.Handler) map[string]float64 {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	series := map[string]float64{}
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.LastIndex(line, " ")
+		value, err := strconv.ParseFloat(line[i+1:], 64)
+		if err != nil {
+			t.Fatalf("línea inválida %q: %v", line, err)
+		}
+		series[line[:i]] = value
+	}
+	return series
+}
+
+func TestMetricsRoutePattern(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(Metrics)
+	r.Get("/bikes/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if chi.URLParam(r, "id") == "0" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+	r.Get("/metrics", metrics.Handler().ServeHTTP)
+
+	before := scrapeSeries(t, r)
+	for _, path := range []string{"/bikes/1", "/bikes/2", "/bikes/0"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	after := scrapeSeries(t, r)
+
+	// las requests se agrupan por el patrón de la ruta, no por la url
+	tests := []struct {
+		series string
+		delta  float64
+	}{
+		{`test_back_http_requests_total{method="GET",route="/bikes/{id}",status="200"}`, 2},
+		{`test_back_http_requests_total{method="GET",route="/bikes/{id}",status="404"}`, 1},
+		{`test_back_http_request_duration_seconds_count{method="GET",route="/bikes/{id}",status="200"}`, 2},
+		{`test_back_http_requests_total{method="GET",route="/metrics",status="200"}`, 1},
+	}
+	for _, tt := range tests {
+		if delta := after[tt.series] - before[tt.series]; delta != tt.delta {
+			t.Errorf("%s aumentó %v, se esperaba %v", tt.series, delta, tt.delta)
+		}
+	}
+	// el scrape se cuenta a sí mismo como request en curso
+	if got := after["test_back_http_requests_in_flight"]; got != 1 {
+		t.Errorf("requests en curso = %v, se esperaba 1", got)
+	}
+	for series := range after {
+		if strings.Contains(series, `route="/bikes/1"`) {
+			t.Errorf("se creó una serie por url: %s", series)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mbarolo/test_back/apperror"
+	"github.com/mbarolo/test_back/i18n"
+	"github.com/mbarolo/test_back/metrics"
+	"github.com/mbarolo/test_back/ratelimit"
+	"github.com/mbarolo/test_back/utils"
+)
+
+// RateLimitStore: Almacenamiento de los buckets de tokens, uno por clave
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error)
+}
+
+// RateLimitKey: Obtiene la clave con la que se agrupan las requests de un mismo cliente
+type RateLimitKey func(r *http.Request) string
+
+// KeyByIP: Agrupa las requests por IP del cliente
+func KeyByIP(r *http.Request) string {
+	return "ip:" + remoteHost(r)
+}
+
+// KeyByUser: Agrupa las requests por usuario autenticado y las del administrador por sus credenciales.
+// Debe usarse después de AuthMiddleware o AdminMiddleware, sin autenticación agrupa por IP
+func KeyByUser(r *http.Request) string {
+	switch claims := r.Context().Value("claims").(type) {
+	case *Claims:
+		return "user:" + claims.Sub
+	case string:
+		return "admin"
+	}
+	return KeyByIP(r)
+}
+
+// RateLimit: Middleware que limita la cantidad de requests por cliente con un token bucket por scope y clave.
+// El límite se obtiene en cada request para que se pueda recargar la configuración. Responde con los headers
+// RateLimit-* y, al superar el límite, con 429 y Retry-After. Si el almacenamiento falla la request se admite
+func RateLimit(scope string, store RateLimitStore, limit func() ratelimit.Limit, key RateLimitKey) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			l := limit()
+			if l.Requests == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			result, err := store.Take(r.Context(), scope+":"+key(r), l)
+			if err != nil {
+				slog.ErrorContext(r.Context(), "rate limit store failed", "scope", scope, "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(l.Requests))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			w.Header().Set("RateLimit-Reset", ceilSeconds(result.Reset))
+			w.Header().Set("RateLimit-Policy", l.Policy())
+
+			if !result.Allowed {
+				retryAfter := ceilSeconds(result.RetryAfter)
+				w.Header().Set("Retry-After", retryAfter)
+				metrics.RateLimited.WithLabelValues(scope).Inc()
+				utils.ErrorResponse(w, r, "ratelimit.exceeded", apperror.New(apperror.RATE_LIMITED, "ratelimit.retry_after").WithParams(i18n.Params{"count": retryAfter}))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ceilSeconds: Segundos enteros, redondeados hacia arriba, para los headers
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
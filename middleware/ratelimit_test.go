@@ -0,0 +1,159 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mbarolo/test_back/ratelimit"
+)
+
+// fakeRateLimitStore: Retorna el resultado indicado y registra la clave consumida
+type fakeRateLimitStore struct {
+	result ratelimit.Result
+	err    error
+	key    string
+}
+
+func (s *fakeRateLimitStore) Take(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	s.key = key
+	return s.result, s.err
+}
+
+func TestRateLimit(t *testing.T) {
+	limit := ratelimit.Limit{Requests: 10, Window: time.Minute}
+	tests := []struct {
+		name    string
+		limit   ratelimit.Limit
+		result  ratelimit.Result
+		err     error
+		status  int
+		headers map[string]string // "" si el header no debe estar
+	}{
+		{
+			name:   "allowed",
+			limit:  limit,
+			result: ratelimit.Result{Allowed: true, Remaining: 7, Reset: 18 * time.Second},
+			status: http.StatusOK,
+			headers: map[string]string{
+				"RateLimit-Limit": "10", "RateLimit-Remaining": "7", "RateLimit-Reset": "18", "RateLimit-Policy": "10;w=60", "Retry-After": "",
+			},
+		},
+		{
+			// los segundos se redondean hacia arriba para no invitar a reintentar antes de tiempo
+			name:   "exceeded",
+			limit:  limit,
+			result: ratelimit.Result{Remaining: 0, Reset: 59500 * time.Millisecond, RetryAfter: 5100 * time.Millisecond},
+			status: http.StatusTooManyRequests,
+			headers: map[string]string{
+				"RateLimit-Limit": "10", "RateLimit-Remaining": "0", "RateLimit-Reset": "60", "Retry-After": "6",
+			},
+		},
+		{
+			name:    "disabled",
+			limit:   ratelimit.Limit{},
+			status:  http.StatusOK,
+			headers: map[string]string{"RateLimit-Limit": "", "Retry-After": ""},
+		},
+		{
+			// sin almacenamiento se admite la request en lugar de cortar el servicio
+			name:    "store failed",
+			limit:   limit,
+			err:     errors.New("sin conexión"),
+			status:  http.StatusOK,
+			headers: map[string]string{"RateLimit-Limit": "", "Retry-After": ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeRateLimitStore{result: tt.result, err: tt.err}
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+			h := RateLimit("api", store, func() ratelimit.Limit { return tt.limit }, KeyByIP)(next)
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/bikes", nil))
+
+			if rec.Code != tt.status {
+				t.Fatalf("respuesta = %d %s, se esperaba %d", rec.Code, rec.Body, tt.status)
+			}
+			// el bucket es del scope y el cliente
+			if tt.limit.Requests > 0 && store.key != "api:ip:192.0.2.1" {
+				t.Errorf("clave = %q, se esperaba api:ip:192.0.2.1", store.key)
+			}
+			if tt.status == http.StatusTooManyRequests && errorCode(t, rec) != "RATE_LIMITED" {
+				t.Errorf("código = %s, se esperaba RATE_LIMITED", rec.Body)
+			}
+			for header, want := range tt.headers {
+				if got := rec.Header().Get(header); got != want {
+					t.Errorf("%s = %q, se esperaba %q", header, got, want)
+				}
+			}
+		})
+	}
+}
+
+// TestRateLimitMemoryStore: Con el almacenamiento real, la request que supera el límite espera la reposición
+// de un token
+func TestRateLimitMemoryStore(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	limit := ratelimit.Limit{Requests: 2, Window: time.Minute}
+	h := RateLimit("api", ratelimit.NewMemoryStore(), func() ratelimit.Limit { return limit }, KeyByIP)(next)
+
+	want := []struct {
+		status    int
+		remaining string
+	}{
+		{http.StatusOK, "1"},
+		{http.StatusOK, "0"},
+		{http.StatusTooManyRequests, "0"},
+	}
+	for i, w := range want {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/bikes", nil))
+		if rec.Code != w.status || rec.Header().Get("RateLimit-Remaining") != w.remaining {
+			t.Errorf("request %d = %d con %s restantes, se esperaba %d con %s", i+1, rec.Code, rec.Header().Get("RateLimit-Remaining"), w.status, w.remaining)
+		}
+		if w.status == http.StatusTooManyRequests && rec.Header().Get("Retry-After") != "30" {
+			t.Errorf("Retry-After = %q, se esperaba 30", rec.Header().Get("Retry-After"))
+		}
+	}
+
+	// otra IP tiene su propio límite
+	r := httptest.NewRequest(http.MethodGet, "/bikes", nil)
+	r.RemoteAddr = "10.0.0.2:1234"
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	if rec.Code != http.StatusOK {
+		t.Errorf("respuesta = %d, se esperaba 200 para otra IP", rec.Code)
+	}
+}
+
+func TestRateLimitKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims interface{}
+		key    RateLimitKey
+		want   string
+	}{
+		{"ip", nil, KeyByIP, "ip:10.0.0.1"},
+		{"user", &Claims{Sub: "7"}, KeyByUser, "user:7"},
+		{"admin", "admin", KeyByUser, "admin"},
+		{"anonymous user", nil, KeyByUser, "ip:10.0.0.1"},
+		{"anonymous device", nil, KeyByDevice, "ip:10.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = "10.0.0.1:5678"
+			if tt.claims != nil {
+				r = r.WithContext(context.WithValue(r.Context(), "claims", tt.claims))
+			}
+			if got := tt.key(r); got != tt.want {
+				t.Errorf("clave = %q, se esperaba %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// Actores que no corresponden a un usuario autenticado
+const (
+	ActorAdmin     = "admin"
+	ActorAnonymous = "anonymous"
+	ActorSystem    = "system"
+)
+
+// AuditEntry: Registro inmutable de una acción que modificó el estado del sistema.
+// Changes tiene, por cada campo modificado, el valor anterior y el nuevo. Cada registro guarda el hash
+// del anterior (PrevHash), así modificar o eliminar un registro rompe la cadena
+type AuditEntry struct {
+	Id         int64           `json:"id"`
+	Actor      string          `json:"actor"`
+	Action     string          `json:"action"`
+	EntityType string          `json:"entity_type"`
+	EntityId   int64           `json:"entity_id"`
+	Changes    json.RawMessage `json:"changes"`
+	RequestId  string          `json:"request_id"`
+	Ip         string          `json:"ip"`
+	CreatedAt  time.Time       `json:"created_at"`
+	PrevHash   string          `json:"prev_hash"`
+	Hash       string          `json:"hash"`
+}
+
+// ComputeHash: Hash SHA-256 del registro encadenado con PrevHash. La fecha se normaliza a UTC
+// para que el resultado no dependa de la zona horaria con la que se lee de la base de datos
+func (e *AuditEntry) ComputeHash() string {
+	data, _ := json.Marshal(struct {
+		PrevHash   string          `json:"prev_hash"`
+		Actor      string          `json:"actor"`
+		Action     string          `json:"action"`
+		EntityType string          `json:"entity_type"`
+		EntityId   int64           `json:"entity_id"`
+		Changes    json.RawMessage `json:"changes"`
+		RequestId  string          `json:"request_id"`
+		Ip         string          `json:"ip"`
+		CreatedAt  string          `json:"created_at"`
+	}{e.PrevHash, e.Actor, e.Action, e.EntityType, e.EntityId, e.Changes, e.RequestId, e.Ip, e.CreatedAt.UTC().Format(time.RFC3339Nano)})
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
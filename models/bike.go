@@ -1,23 +1,39 @@
 package models
 
 import (
-	"errors"
 	"time"
+
+	"github.com/mbarolo/test_back/apperror"
 )
 
 type Bike struct {
-	Id            int64     `json:"id"`
-	IsAvailable   bool      `json:"is_available"`
-	Latitude      float64   `json:"latitude"`
-	Longitude     float64   `json:"longitude"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
-	CostPerMinute int       `json:"cost_per_minute"`
+	Id            int64      `json:"id"`
+	Status        BikeStatus `json:"status"`
+	Latitude      float64    `json:"latitude"`
+	Longitude     float64    `json:"longitude"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	CostPerMinute int        `json:"cost_per_minute"` // tarifa vigente: la propia si tiene, si no la de su tipo de vehículo
+	Version       int64      `json:"version"`
+	VehicleType   string     `json:"vehicle_type"`
+	// tarifa propia de la bicicleta, nil cobra la de su tipo y sigue sus cambios
+	CostPerMinuteOverride *int `json:"cost_per_minute_override"`
+	// autonomía estimada según la batería y el tipo de vehículo, nil si no es eléctrico o no se conoce la batería
+	EstimatedRangeKm *float64 `json:"estimated_range_km"`
+	// estación y número de anclaje donde está la bicicleta, nil si está suelta
+	StationId *int64 `json:"station_id"`
+	Dock      *int   `json:"dock"`
+	// tiene una orden de trabajo crítica sin resolver, no se puede alquilar aunque esté disponible
+	OutOfService bool `json:"out_of_service"`
+	// último estado reportado por el dispositivo de la bicicleta, nil si nunca envió telemetría
+	BatteryLevel *int       `json:"battery_level"`
+	LockState    *string    `json:"lock_state"`
+	LastSeenAt   *time.Time `json:"last_seen_at"`
 }
 
 func (b *Bike) ValidateFields() error {
-	if b.CostPerMinute < 0 {
-		return errors.New("costo inválido")
+	if b.CostPerMinuteOverride != nil && *b.CostPerMinuteOverride < 0 {
+		return apperror.Validation("bike.invalid_fields", apperror.FieldError{Field: "cost_per_minute_override", Message: "bike.invalid_cost"})
 	}
 
 	return nil
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+type BikeStatus string
+
+const (
+	BikeAvailable   BikeStatus = "available"
+	BikeReserved    BikeStatus = "reserved"
+	BikeRented      BikeStatus = "rented"
+	BikeMaintenance BikeStatus = "maintenance"
+	BikeMissing     BikeStatus = "missing"
+	BikeRetired     BikeStatus = "retired"
+)
+
+// bikeTransitions: Estados a los que puede pasar cada estado. Solo una bicicleta disponible se alquila
+// (queda reservada mientras se abre el candado) y una retirada no vuelve a circular
+var bikeTransitions = map[BikeStatus][]BikeStatus{
+	BikeAvailable:   {BikeReserved, BikeRented, BikeMaintenance, BikeMissing, BikeRetired},
+	BikeReserved:    {BikeAvailable, BikeRented, BikeMaintenance, BikeMissing, BikeRetired},
+	BikeRented:      {BikeAvailable, BikeMaintenance, BikeMissing},
+	BikeMaintenance: {BikeAvailable, BikeMissing, BikeRetired},
+	BikeMissing:     {BikeAvailable, BikeMaintenance, BikeRetired},
+}
+
+// CanTransition: Indica si una bicicleta en este estado puede pasar al estado to
+func (s BikeStatus) CanTransition(to BikeStatus) bool {
+	return slices.Contains(bikeTransitions[s], to)
+}
+
+// Motivos de los cambios de estado automáticos, los del admin son texto libre
+const (
+	StatusReasonCreated     = "bike.create"
+	StatusReasonRentalStart = "rental.start"
+	StatusReasonRentalEnd   = "rental.end"
+	// no se pudo abrir el candado, la bicicleta reservada para el alquiler vuelve a estar disponible
+	StatusReasonUnlockFailed = "rental.unlock_failed"
+	// no se pudo confirmar que el candado quedó cerrado, la bicicleta pasa a mantenimiento
+	StatusReasonLockFailed = "rental.lock_failed"
+)
+
+// BikeStatusChange: Cambio de estado de una bicicleta. FromStatus es nil en el alta.
+// Actor sigue el formato del log de auditoría (user:<id>, admin, system)
+type BikeStatusChange struct {
+	Id         int64       `json:"id"`
+	BikeId     int64       `json:"bike_id"`
+	FromStatus *BikeStatus `json:"from_status"`
+	ToStatus   BikeStatus  `json:"to_status"`
+	Reason     string      `json:"reason"`
+	Actor      string      `json:"actor"`
+	CreatedAt  time.Time   `json:"created_at"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// IdempotencyRecord: Respuesta guardada para una clave Idempotency-Key de un usuario.
+// StatusCode es nil mientras la solicitud original se está procesando
+type IdempotencyRecord struct {
+	Id             int64     `json:"id"`
+	UserId         int64     `json:"user_id"`
+	IdempotencyKey string    `json:"idempotency_key"`
+	Fingerprint    string    `json:"fingerprint"`
+	StatusCode     *int      `json:"status_code"`
+	ContentType    string    `json:"content_type"`
+	ResponseBody   string    `json:"response_body"`
+	CreatedAt      time.Time `json:"created_at"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
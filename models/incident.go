@@ -0,0 +1,65 @@
+package models
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+)
+
+type IncidentStatus string
+
+const (
+	IncidentOpen         IncidentStatus = "open"
+	IncidentAcknowledged IncidentStatus = "acknowledged"
+	IncidentResolved     IncidentStatus = "resolved"
+)
+
+// Reglas de detección de incidentes
+const (
+	RuleMovementWithoutRental = "movement_without_rental"
+	RuleNoSignal              = "no_signal"
+	RuleOutsideServiceArea    = "outside_service_area"
+	RuleRentalOverdue         = "rental_overdue"
+	// no la evalúa el motor de reglas, se abre cuando no se puede confirmar que el candado quedó cerrado (al
+	// finalizar automáticamente un alquiler o cuando no respondió al abrirlo)
+	RuleLockFailed = "lock_failed"
+)
+
+// Severidades de los incidentes, de menor a mayor
+const (
+	SeverityLow      = "low"
+	SeverityMedium   = "medium"
+	SeverityHigh     = "high"
+	SeverityCritical = "critical"
+)
+
+var severities = []string{SeverityLow, SeverityMedium, SeverityHigh, SeverityCritical}
+
+// SeverityAbove: Indica si la severidad a es mayor que b
+func SeverityAbove(a, b string) bool {
+	return slices.Index(severities, a) > slices.Index(severities, b)
+}
+
+// Incident: Incidente abierto por una regla sobre una bicicleta. Mientras no se resuelva, las nuevas coincidencias
+// de la misma regla y bicicleta se suman a Occurrences en lugar de abrir otro. Details depende de la regla
+type Incident struct {
+	Id             int64           `json:"id"`
+	BikeId         int64           `json:"bike_id"`
+	RentalId       *int64          `json:"rental_id"`
+	Rule           string          `json:"rule"`
+	Severity       string          `json:"severity"`
+	Status         IncidentStatus  `json:"status"`
+	Details        json.RawMessage `json:"details"`
+	Latitude       float64         `json:"latitude"`
+	Longitude      float64         `json:"longitude"`
+	Occurrences    int             `json:"occurrences"`
+	FirstSeenAt    time.Time       `json:"first_seen_at"`
+	LastSeenAt     time.Time       `json:"last_seen_at"`
+	AcknowledgedBy *string         `json:"acknowledged_by"`
+	AcknowledgedAt *time.Time      `json:"acknowledged_at"`
+	ResolvedBy     *string         `json:"resolved_by"`
+	ResolvedAt     *time.Time      `json:"resolved_at"`
+	Resolution     *string         `json:"resolution"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+	Version        int64           `json:"version"`
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+type WorkOrderStatus string
+
+const (
+	WorkOrderOpen       WorkOrderStatus = "open"
+	WorkOrderAssigned   WorkOrderStatus = "assigned"
+	WorkOrderInProgress WorkOrderStatus = "in_progress"
+	WorkOrderResolved   WorkOrderStatus = "resolved"
+)
+
+// workOrderTransitions: Estados a los que puede pasar cada estado, una orden resuelta no se reabre
+var workOrderTransitions = map[WorkOrderStatus][]WorkOrderStatus{
+	WorkOrderOpen:       {WorkOrderAssigned, WorkOrderResolved},
+	WorkOrderAssigned:   {WorkOrderOpen, WorkOrderInProgress, WorkOrderResolved},
+	WorkOrderInProgress: {WorkOrderAssigned, WorkOrderResolved},
+}
+
+// Prioridades de las órdenes de trabajo. Una orden crítica sin resolver deja la bicicleta fuera de servicio
+const (
+	PriorityNormal   = "normal"
+	PriorityCritical = "critical"
+)
+
+// Categorías de los daños reportados
+const (
+	DamageBrakes  = "brakes"
+	DamageTires   = "tires"
+	DamageChain   = "chain"
+	DamageLights  = "lights"
+	DamageBattery = "battery"
+	DamageLock    = "lock"
+	DamageFrame   = "frame"
+	DamageOther   = "other"
+)
+
+// criticalDamage: Categorías que impiden usar la bicicleta de forma segura
+var criticalDamage = []string{DamageBrakes, DamageFrame, DamageBattery}
+
+// DamagePriority: Prioridad de la orden de trabajo que se abre por un daño de la categoría
+func DamagePriority(category string) string {
+	if slices.Contains(criticalDamage, category) {
+		return PriorityCritical
+	}
+	return PriorityNormal
+}
+
+// WorkOrder: Orden de trabajo de mantenimiento de una bicicleta, abierta por el admin o por los reportes de daño.
+// Reports solo se completa al consultar una orden
+type WorkOrder struct {
+	Id          int64           `json:"id"`
+	BikeId      int64           `json:"bike_id"`
+	Category    string          `json:"category"`
+	Priority    string          `json:"priority"`
+	Status      WorkOrderStatus `json:"status"`
+	Description string          `json:"description"`
+	Mechanic    *string         `json:"mechanic"`
+	Resolution  *string         `json:"resolution"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	AssignedAt  *time.Time      `json:"assigned_at"`
+	ResolvedAt  *time.Time      `json:"resolved_at"`
+	Version     int64           `json:"version"`
+	Reports     []*DamageReport `json:"reports,omitempty"`
+}
+
+// CanTransition: Indica si una orden en este estado puede pasar al estado to, quedarse en el mismo siempre es válido
+func (s WorkOrderStatus) CanTransition(to WorkOrderStatus) bool {
+	return s == to || slices.Contains(workOrderTransitions[s], to)
+}
+
+// DamageReport: Daño reportado por un ciclista. Photo es el nombre del archivo guardado en maintenance.photo_dir
+type DamageReport struct {
+	Id          int64     `json:"id"`
+	BikeId      int64     `json:"bike_id"`
+	UserId      int64     `json:"user_id"`
+	WorkOrderId int64     `json:"work_order_id"`
+	Category    string    `json:"category"`
+	Description string    `json:"description"`
+	Photo       *string   `json:"photo"`
+	PhotoType   *string   `json:"photo_type"`
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+type RebalancingTaskStatus string
+
+const (
+	RebalancingOpen      RebalancingTaskStatus = "open"
+	RebalancingClaimed   RebalancingTaskStatus = "claimed"
+	RebalancingCompleted RebalancingTaskStatus = "completed"
+	RebalancingCancelled RebalancingTaskStatus = "cancelled"
+)
+
+// ZoneForecast: Oferta y demanda pronosticadas de una celda de la grilla para una hora. Pickups y Dropoffs son el
+// promedio de alquileres que empezaron y terminaron en la celda a esa hora del día en los días de historial
+type ZoneForecast struct {
+	Cell      string  `json:"cell"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Supply    int     `json:"supply"` // bicicletas disponibles, contando las de las tareas tomadas como ya movidas
+	Pickups   float64 `json:"pickups"`
+	Dropoffs  float64 `json:"dropoffs"`
+	Expected  float64 `json:"expected"` // bicicletas al terminar la hora, negativo si la demanda no se cubre
+	Target    int     `json:"target"`   // bicicletas necesarias al empezar la hora para cubrir los alquileres
+	Balance   int     `json:"balance"`  // supply - target, positivo sobran y negativo faltan
+}
+
+// Forecast: Pronóstico de todas las celdas con bicicletas o alquileres para la hora
+type Forecast struct {
+	Hour     time.Time       `json:"hour"`
+	CellSize int             `json:"cell_size"` // metros
+	Zones    []*ZoneForecast `json:"zones"`
+}
+
+// RebalancingTask: Traslado de bicicletas entre dos celdas de la grilla. Al planificar de nuevo las tareas abiertas
+// se cancelan, las tomadas siguen vigentes hasta completarse
+type RebalancingTask struct {
+	Id            int64                 `json:"id"`
+	FromCell      string                `json:"from_cell"`
+	FromLatitude  float64               `json:"from_latitude"`
+	FromLongitude float64               `json:"from_longitude"`
+	ToCell        string                `json:"to_cell"`
+	ToLatitude    float64               `json:"to_latitude"`
+	ToLongitude   float64               `json:"to_longitude"`
+	Bikes         int                   `json:"bikes"`
+	DistanceM     int                   `json:"distance_m"`
+	Status        RebalancingTaskStatus `json:"status"`
+	ForecastHour  time.Time             `json:"forecast_hour"`
+	ClaimedBy     *string               `json:"claimed_by"`
+	ClaimedAt     *time.Time            `json:"claimed_at"`
+	CompletedAt   *time.Time            `json:"completed_at"`
+	Moved         *int                  `json:"moved"` // bicicletas que se movieron realmente
+	CreatedAt     time.Time             `json:"created_at"`
+	UpdatedAt     time.Time             `json:"updated_at"`
+	Version       int64                 `json:"version"`
+}
@@ -9,6 +9,15 @@ const (
 	ENDED   RentalStatus = "ended"
 )
 
+// RentalEndReason: Motivo por el que finalizó el alquiler
+type RentalEndReason string
+
+const (
+	EndReasonRider       RentalEndReason = "rider"        // lo finalizó el usuario
+	EndReasonMaxDuration RentalEndReason = "max_duration" // cerrado por superar rentals.auto_end.max_duration
+	EndReasonIdle        RentalEndReason = "idle"         // cerrado por no registrar recorrido en rentals.auto_end.idle_after
+)
+
 type Rental struct {
 	Id             int64        `json:"id"`
 	UserId         int64        `json:"user_id"`
@@ -22,4 +31,14 @@ type Rental struct {
 	EndLongitude   *float64     `json:"end_longitude"`
 	Duration       *int         `json:"duration"` // minutes
 	Cost           *int         `json:"cost"`
+	DistanceM      *int         `json:"distance_m"` // metros recorridos, se calcula al finalizar; nil si no se registró recorrido
+	StartStationId *int64       `json:"start_station_id"`
+	EndStationId   *int64       `json:"end_station_id"` // estación donde se devolvió, nil si quedó suelta
+	Version        int64        `json:"version"`
+	// motivo de la finalización y multa del cierre automático, incluida en cost
+	EndReason  *RentalEndReason `json:"end_reason"`
+	PenaltyFee *int             `json:"penalty_fee"`
+	// aviso de cierre automático: cuándo se avisó al usuario y cuándo se cierra si sigue abandonado
+	WarnedAt  *time.Time `json:"warned_at"`
+	AutoEndAt *time.Time `json:"auto_end_at"`
 }
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Origen de los puntos del recorrido
+const (
+	RouteSourceDevice = "device"
+	RouteSourceApp    = "app"
+)
+
+// RoutePoint: Punto del recorrido de un alquiler, enviado por el dispositivo de la bicicleta o por la app del ciclista
+type RoutePoint struct {
+	Id         int64     `json:"id"`
+	RentalId   int64     `json:"rental_id"`
+	RecordedAt time.Time `json:"recorded_at"`
+	Latitude   float64   `json:"latitude"`
+	Longitude  float64   `json:"longitude"`
+	Accuracy   *float64  `json:"accuracy"`
+	Source     string    `json:"source"`
+}
+
+// Position: Latitud y longitud del punto, para los cálculos de utils.PathDistance y utils.SimplifyPath
+func (p *RoutePoint) Position() (float64, float64) {
+	return p.Latitude, p.Longitude
+}
+
+// Route: Recorrido completo del alquiler, desde la posición de inicio hasta la de fin (si terminó)
+type Route struct {
+	Rental *Rental       `json:"rental"`
+	Points []*RoutePoint `json:"points"`
+}
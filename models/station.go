@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Station: Estación de anclaje. Docked, FreeDocks y AvailableBikes se calculan al consultarla;
+// Bikes solo se completa al consultar las estaciones con sus bicicletas
+type Station struct {
+	Id             int64     `json:"id"`
+	Name           string    `json:"name"`
+	Latitude       float64   `json:"latitude"`
+	Longitude      float64   `json:"longitude"`
+	Capacity       int       `json:"capacity"`
+	Active         bool      `json:"active"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	Version        int64     `json:"version"`
+	Docked         int       `json:"docked"`
+	FreeDocks      int       `json:"free_docks"`
+	AvailableBikes int       `json:"available_bikes"`
+	Bikes          []*Bike   `json:"bikes,omitempty"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Device: Dispositivo IoT de una bicicleta. Se autentica con un token del que solo se guarda el hash,
+// Token solo se completa al emitir las credenciales
+type Device struct {
+	Id         int64      `json:"id"`
+	BikeId     int64      `json:"bike_id"`
+	TokenHash  string     `json:"-"`
+	Token      string     `json:"token,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt *time.Time `json:"last_seen_at"`
+}
+
+// TelemetryReading: Lectura enviada por el dispositivo. Todos los datos son opcionales salvo la fecha en que
+// se tomó, una lectura puede tener solo la posición GPS, solo la batería, etc. ErrorCodes es un array JSON
+type TelemetryReading struct {
+	Id           int64           `json:"id"`
+	BikeId       int64           `json:"bike_id"`
+	RecordedAt   time.Time       `json:"recorded_at"`
+	ReceivedAt   time.Time       `json:"received_at"`
+	Latitude     *float64        `json:"latitude"`
+	Longitude    *float64        `json:"longitude"`
+	Accuracy     *float64        `json:"accuracy"`
+	BatteryLevel *int            `json:"battery_level"`
+	LockState    *string         `json:"lock_state"`
+	ErrorCodes   json.RawMessage `json:"error_codes"`
+}
+
+// HasPosition: Indica si la lectura tiene una posición GPS
+func (t *TelemetryReading) HasPosition() bool {
+	return t.Latitude != nil && t.Longitude != nil
+}
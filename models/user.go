@@ -1,9 +1,11 @@
 package models
 
 import (
-	"errors"
 	"net/mail"
 	"time"
+
+	"github.com/mbarolo/test_back/apperror"
+	"github.com/mbarolo/test_back/i18n"
 )
 
 type User struct {
@@ -12,24 +14,33 @@ type User struct {
 	HashedPassword string    `json:"hashed_password"`
 	FirstName      string    `json:"first_name"`
 	LastName       string    `json:"last_name"`
+	Language       string    `json:"language"`
 	Deleted        bool      `json:"deleted"`
+	Version        int64     `json:"version"`
 	CreatedAt      time.Time `json:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at"`
 }
 
 func (u *User) ValidateFields() error {
+	fields := []apperror.FieldError{}
 	if _, err := mail.ParseAddress(u.Email); err != nil {
-		return errors.New("email inválido")
+		fields = append(fields, apperror.FieldError{Field: "email", Message: "user.invalid_email"})
 	}
 	if u.FirstName == "" {
-		return errors.New("nombre inválido")
+		fields = append(fields, apperror.FieldError{Field: "first_name", Message: "user.invalid_first"})
 	}
 	if u.LastName == "" {
-		return errors.New("apellido inválido")
+		fields = append(fields, apperror.FieldError{Field: "last_name", Message: "user.invalid_last"})
 	}
 	if u.HashedPassword == "" {
-		return errors.New("contraseña inválida")
+		fields = append(fields, apperror.FieldError{Field: "password", Message: "user.invalid_password"})
+	}
+	if u.Language != "" && !i18n.IsSupported(i18n.Lang(u.Language)) {
+		fields = append(fields, apperror.FieldError{Field: "language", Message: "user.invalid_language"})
 	}
 
+	if len(fields) > 0 {
+		return apperror.Validation("user.invalid_fields", fields...)
+	}
 	return nil
 }
@@ -0,0 +1,24 @@
+package models
+
+// Códigos de los tipos de vehículo del catálogo inicial
+const (
+	VehicleClassic = "classic"
+	VehicleEBike   = "e-bike"
+	VehicleCargo   = "cargo"
+	VehicleScooter = "scooter"
+)
+
+// VehicleType: Tipo de vehículo con su tarifa y atributos. CostPerMinute es la tarifa de los vehículos del tipo que no
+// tienen una propia, UnlockFee se cobra en cada alquiler. RangeKm es la autonomía con la batería completa (solo eléctricos)
+type VehicleType struct {
+	Id            int64  `json:"id"`
+	Code          string `json:"code"`
+	Name          string `json:"name"`
+	Electric      bool   `json:"electric"`
+	CostPerMinute int    `json:"cost_per_minute"`
+	UnlockFee     int    `json:"unlock_fee"`
+	RangeKm       *int   `json:"range_km"`
+	MaxSpeedKmh   *int   `json:"max_speed_kmh"`
+	MaxLoadKg     *int   `json:"max_load_kg"`
+	Version       int64  `json:"version"`
+}
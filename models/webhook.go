@@ -0,0 +1,122 @@
+package models
+
+import (
+	"encoding/json"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/mbarolo/test_back/apperror"
+	"github.com/mbarolo/test_back/i18n"
+)
+
+// Tipos de eventos de dominio que se publican a los webhooks
+const (
+	EventUserRegistered    = "user.registered"
+	EventBikeCreated       = "bike.created"
+	EventBikeUpdated       = "bike.updated"
+	EventRentalStarted     = "rental.started"
+	EventRentalEnded       = "rental.ended"
+	EventRentalEndWarning  = "rental.end_warning"
+	EventBikeStatusChanged = "bike.status_changed"
+	EventWorkOrderOpened   = "work_order.opened"
+	EventWorkOrderUpdated  = "work_order.updated"
+)
+
+// EventTypes: Eventos a los que se puede suscribir un webhook
+var EventTypes = []string{EventUserRegistered, EventBikeCreated, EventBikeUpdated, EventBikeStatusChanged, EventRentalStarted, EventRentalEnded, EventRentalEndWarning, EventWorkOrderOpened, EventWorkOrderUpdated}
+
+// AllEvents: Suscripción a todos los eventos, incluidos los que se agreguen en el futuro
+const AllEvents = "*"
+
+// OutboxEvent: Evento de dominio guardado en la misma transacción que el cambio que lo originó.
+// DispatchedAt es nil hasta que el dispatcher crea las entregas para los webhooks suscritos
+type OutboxEvent struct {
+	Id           int64           `json:"id"`
+	EventType    string          `json:"type"`
+	EntityType   string          `json:"entity_type"`
+	EntityId     int64           `json:"entity_id"`
+	Payload      json.RawMessage `json:"data"`
+	RequestId    string          `json:"request_id"`
+	CreatedAt    time.Time       `json:"created_at"`
+	DispatchedAt *time.Time      `json:"dispatched_at"`
+}
+
+// WebhookSubscription: Endpoint que recibe los eventos de EventTypes (array JSON), firmados con Secret.
+// El secreto solo se muestra al crear la suscripción
+type WebhookSubscription struct {
+	Id          int64           `json:"id"`
+	Url         string          `json:"url"`
+	Description string          `json:"description"`
+	EventTypes  json.RawMessage `json:"event_types"`
+	Secret      string          `json:"secret,omitempty"`
+	IsActive    bool            `json:"is_active"`
+	Version     int64           `json:"version"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// Largo mínimo del secreto con el que se firman las entregas
+const WebhookSecretMinLength = 16
+
+func (s *WebhookSubscription) ValidateFields() error {
+	fields := []apperror.FieldError{}
+	if u, err := url.Parse(s.Url); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		fields = append(fields, apperror.FieldError{Field: "url", Message: "webhook.invalid_url"})
+	}
+
+	var eventTypes []string
+	if err := json.Unmarshal(s.EventTypes, &eventTypes); err != nil || len(eventTypes) == 0 {
+		fields = append(fields, apperror.FieldError{Field: "event_types", Message: "webhook.invalid_event_types"})
+	} else {
+		for _, eventType := range eventTypes {
+			if eventType != AllEvents && !slices.Contains(EventTypes, eventType) {
+				fields = append(fields, apperror.FieldError{Field: "event_types", Message: "webhook.unknown_event", Params: i18n.Params{"event": eventType}})
+				break
+			}
+		}
+	}
+
+	if len(s.Secret) < WebhookSecretMinLength {
+		fields = append(fields, apperror.FieldError{Field: "secret", Message: "validation.min_length", Params: i18n.Params{"min": WebhookSecretMinLength}})
+	}
+
+	if len(fields) > 0 {
+		return apperror.Validation("webhook.invalid_fields", fields...)
+	}
+	return nil
+}
+
+// Subscribed: Indica si la suscripción recibe el tipo de evento
+func (s *WebhookSubscription) Subscribed(eventType string) bool {
+	var eventTypes []string
+	if err := json.Unmarshal(s.EventTypes, &eventTypes); err != nil {
+		return false
+	}
+	return slices.Contains(eventTypes, AllEvents) || slices.Contains(eventTypes, eventType)
+}
+
+// Estados de una entrega
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryDead      = "dead"
+)
+
+// WebhookDelivery: Entrega de un evento a una suscripción. Las entregas fallidas se reintentan en NextAttemptAt
+// hasta agotar los intentos, después quedan en estado dead. LastStatusCode y LastError son del último intento
+type WebhookDelivery struct {
+	Id             int64      `json:"id"`
+	SubscriptionId int64      `json:"subscription_id"`
+	EventId        int64      `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	LastStatusCode *int       `json:"last_status_code"`
+	LastError      string     `json:"last_error"`
+	LastDurationMs *int64     `json:"last_duration_ms"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+}
@@ -0,0 +1,88 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Drivers soportados por la configuración
+const (
+	DriverLog  = "log"
+	DriverHTTP = "http"
+)
+
+// Alert: Aviso de un incidente abierto o cuya severidad aumentó
+type Alert struct {
+	IncidentId int64     `json:"incident_id"`
+	BikeId     int64     `json:"bike_id"`
+	Rule       string    `json:"rule"`
+	Severity   string    `json:"severity"`
+	Summary    string    `json:"summary"`
+	Latitude   float64   `json:"latitude"`
+	Longitude  float64   `json:"longitude"`
+	OpenedAt   time.Time `json:"opened_at"`
+}
+
+// Notifier: Envía los avisos de incidentes al equipo de operaciones. Un error no deshace el incidente,
+// solo se registra
+type Notifier interface {
+	Notify(ctx context.Context, alert Alert) error
+}
+
+// New: Notifier del driver, url solo se usa con el driver http
+func New(driver string, url string, timeout time.Duration) (Notifier, error) {
+	switch driver {
+	case DriverLog:
+		return LogNotifier{}, nil
+	case DriverHTTP:
+		return &HTTPNotifier{url: url, client: &http.Client{Timeout: timeout}}, nil
+	}
+	return nil, fmt.Errorf("driver de notificaciones inválido %q", driver)
+}
+
+// LogNotifier: Escribe los avisos en el log, para desarrollo o cuando el log ya se monitorea
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(ctx context.Context, alert Alert) error {
+	slog.WarnContext(ctx, "incident alert", "incident_id", alert.IncidentId, "bike_id", alert.BikeId, "rule", alert.Rule, "severity", alert.Severity, "summary", alert.Summary)
+	return nil
+}
+
+// HTTPNotifier: Envía el aviso en JSON por POST. Incluye text para que se vea en los webhooks entrantes de
+// Slack o Mattermost sin adaptar el formato
+type HTTPNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func (n *HTTPNotifier) Notify(ctx context.Context, alert Alert) error {
+	body, err := json.Marshal(struct {
+		Alert
+		Text string `json:"text"`
+	}{alert, fmt.Sprintf("[%s] bicicleta %d: %s", alert.Severity, alert.BikeId, alert.Summary)})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "test_back-incidents")
+
+	res, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("el notificador respondió %d", res.StatusCode)
+	}
+	return nil
+}
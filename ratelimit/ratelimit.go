@@ -0,0 +1,124 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limit: Cantidad de requests permitidas por ventana de tiempo. Se aplica como token bucket:
+// se admiten ráfagas de hasta Requests y los tokens se reponen de forma continua a lo largo de Window
+type Limit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// ParseLimit: Interpreta un límite con el formato requests/ventana (ej: 10/1m, 100/1h)
+func ParseLimit(s string) (Limit, error) {
+	requests, window, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("límite inválido %q, se espera requests/ventana (ej: 10/1m)", s)
+	}
+
+	n, err := strconv.Atoi(requests)
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("límite inválido %q, la cantidad de requests debe ser un entero positivo", s)
+	}
+	d, err := time.ParseDuration(window)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("límite inválido %q, la ventana debe ser una duración positiva", s)
+	}
+
+	return Limit{Requests: n, Window: d}, nil
+}
+
+// Policy: Descripción del límite para el header RateLimit-Policy (ej: 10;w=60)
+func (l Limit) Policy() string {
+	return fmt.Sprintf("%d;w=%d", l.Requests, int(math.Ceil(l.Window.Seconds())))
+}
+
+// Result: Resultado de consumir un token de un bucket
+type Result struct {
+	Allowed   bool
+	Remaining int
+	// tiempo hasta que el bucket vuelve a estar lleno
+	Reset time.Duration
+	// tiempo hasta que haya un token disponible, solo si no se admitió la request
+	RetryAfter time.Duration
+}
+
+// cada cuánto se eliminan los buckets que volvieron a llenarse
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	full    time.Time
+}
+
+// MemoryStore: Buckets en memoria del proceso. Con varias instancias cada una aplica su propio límite,
+// para compartirlos se debe implementar el mismo método sobre un almacenamiento común (ej: Redis)
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	// hora actual, se reemplaza en los tests para controlar la reposición de tokens
+	now func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]*bucket{}, now: time.Now}
+}
+
+// Take: Consume un token del bucket de la clave, si hay disponible
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		s.sweep(now)
+	}
+
+	capacity := float64(limit.Requests)
+	rate := capacity / limit.Window.Seconds()
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, updated: now}
+		s.buckets[key] = b
+	}
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.updated).Seconds()*rate)
+	b.updated = now
+
+	result := Result{}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = seconds((capacity - b.tokens) / rate)
+	b.full = now.Add(result.Reset)
+
+	return result, nil
+}
+
+// sweep: Elimina los buckets llenos, equivalen a no tener bucket
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
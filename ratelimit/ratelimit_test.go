@@ -0,0 +1,129 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// fakeClock: Hora que solo avanza cuando el test lo indica
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func newTestStore() (*MemoryStore, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	store := NewMemoryStore()
+	store.now = clock.Now
+	return store, clock
+}
+
+// approx: Compara duraciones calculadas con float64, con un margen de un milisegundo
+func approx(got, want time.Duration) bool {
+	diff := got - want
+	return diff > -time.Millisecond && diff < time.Millisecond
+}
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		spec  string
+		limit Limit
+		err   bool
+	}{
+		{"10/1m", Limit{Requests: 10, Window: time.Minute}, false},
+		{"100/1h", Limit{Requests: 100, Window: time.Hour}, false},
+		{"5/30s", Limit{Requests: 5, Window: 30 * time.Second}, false},
+		{"10", Limit{}, true},
+		{"0/1m", Limit{}, true},
+		{"-1/1m", Limit{}, true},
+		{"diez/1m", Limit{}, true},
+		{"10/0s", Limit{}, true},
+		{"10/minuto", Limit{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			limit, err := ParseLimit(tt.spec)
+			if (err != nil) != tt.err || limit != tt.limit {
+				t.Errorf("límite = %+v (%v), se esperaba %+v con error %v", limit, err, tt.limit, tt.err)
+			}
+		})
+	}
+}
+
+func TestLimitPolicy(t *testing.T) {
+	if policy := (Limit{Requests: 10, Window: 90 * time.Second}).Policy(); policy != "10;w=90" {
+		t.Errorf("policy = %s, se esperaba 10;w=90", policy)
+	}
+}
+
+// TestMemoryStoreRefill: 3 requests cada 30s, se repone un token cada 10s
+func TestMemoryStoreRefill(t *testing.T) {
+	store, clock := newTestStore()
+	limit := Limit{Requests: 3, Window: 30 * time.Second}
+
+	steps := []struct {
+		name    string
+		advance time.Duration
+		want    Result
+	}{
+		{"first", 0, Result{Allowed: true, Remaining: 2, Reset: 10 * time.Second}},
+		{"second", 0, Result{Allowed: true, Remaining: 1, Reset: 20 * time.Second}},
+		{"burst exhausted", 0, Result{Allowed: true, Remaining: 0, Reset: 30 * time.Second}},
+		{"denied", 0, Result{Remaining: 0, Reset: 30 * time.Second, RetryAfter: 10 * time.Second}},
+		{"half token", 5 * time.Second, Result{Remaining: 0, Reset: 25 * time.Second, RetryAfter: 5 * time.Second}},
+		{"one token", 5 * time.Second, Result{Allowed: true, Remaining: 0, Reset: 30 * time.Second}},
+		{"partial refill", 25 * time.Second, Result{Allowed: true, Remaining: 1, Reset: 15 * time.Second}},
+		// los tokens no superan la capacidad aunque pase más de una ventana
+		{"full", time.Hour, Result{Allowed: true, Remaining: 2, Reset: 10 * time.Second}},
+	}
+	for _, step := range steps {
+		clock.now = clock.now.Add(step.advance)
+		got, err := store.Take(context.Background(), "user:1", limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Allowed != step.want.Allowed || got.Remaining != step.want.Remaining || !approx(got.Reset, step.want.Reset) || !approx(got.RetryAfter, step.want.RetryAfter) {
+			t.Errorf("%s: resultado = %+v, se esperaba %+v", step.name, got, step.want)
+		}
+	}
+}
+
+func TestMemoryStoreKeys(t *testing.T) {
+	store, _ := newTestStore()
+	ctx := context.Background()
+	limit := Limit{Requests: 1, Window: time.Minute}
+
+	if result, _ := store.Take(ctx, "user:1", limit); !result.Allowed {
+		t.Fatal("se esperaba admitir la primera request")
+	}
+	if result, _ := store.Take(ctx, "user:1", limit); result.Allowed {
+		t.Error("se esperaba rechazar la segunda request de la misma clave")
+	}
+	// cada clave tiene su propio bucket
+	if result, _ := store.Take(ctx, "user:2", limit); !result.Allowed {
+		t.Error("se esperaba admitir la request de otra clave")
+	}
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	store, clock := newTestStore()
+	ctx := context.Background()
+	limit := Limit{Requests: 2, Window: 10 * time.Second}
+
+	store.Take(ctx, "user:1", limit)
+	store.Take(ctx, "user:2", Limit{Requests: 2, Window: time.Hour})
+
+	// user:1 ya se llenó, user:2 todavía no
+	clock.now = clock.now.Add(sweepInterval)
+	store.Take(ctx, "user:3", limit)
+	if _, ok := store.buckets["user:1"]; ok {
+		t.Error("se esperaba eliminar el bucket lleno")
+	}
+	if _, ok := store.buckets["user:2"]; !ok {
+		t.Error("se eliminó un bucket que no estaba lleno")
+	}
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/mbarolo/test_back/models"
+	"github.com/mbarolo/test_back/utils"
+)
+
+type AuditRepository struct {
+	db *sql.DB
+}
+
+func NewAuditRepository(db *sql.DB) *AuditRepository {
+	return &AuditRepository{db}
+}
+
+// Append: Agrega el registro al final de la cadena, calculando su hash a partir del último registro.
+// Debe llamarse dentro de la transacción del cambio auditado, que además serializa las escrituras de la cadena
+func (r *AuditRepository) Append(ctx context.Context, entry *models.AuditEntry) (int64, error) {
+	var prevHash string
+	err := conn(ctx, r.db).QueryRowContext(ctx, "SELECT hash FROM "+TableNameAudit+" ORDER BY id DESC LIMIT 1").Scan(&prevHash)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return -1, err
+	}
+	entry.PrevHash = prevHash
+	entry.Hash = entry.ComputeHash()
+
+	query := "INSERT INTO " + TableNameAudit + " (actor, action, entity_type, entity_id, changes, request_id, ip, created_at, prev_hash, hash) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, entry.Actor, entry.Action, entry.EntityType, entry.EntityId, string(entry.Changes), entry.RequestId, entry.Ip, entry.CreatedAt, entry.PrevHash, entry.Hash)
+	if err != nil {
+		return -1, err
+	}
+
+	return res.LastInsertId()
+}
+
+func (r *AuditRepository) GetAll(ctx context.Context, spec *utils.QuerySpec) (*utils.Page[models.AuditEntry], error) {
+	entries, err := utils.GenericScanPage[models.AuditEntry](ctx, conn(ctx, r.db), TableNameAudit, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// Each: Recorre en orden de id los registros que cumplen los filtros del QuerySpec
+func (r *AuditRepository) Each(ctx context.Context, spec *utils.QuerySpec, fn func(*models.AuditEntry) error) error {
+	return utils.GenericScanEach(ctx, conn(ctx, r.db), TableNameAudit, spec, 500, fn)
+}
@@ -1,13 +1,24 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"strings"
 	"time"
 
 	"github.com/mbarolo/test_back/models"
+	"github.com/mbarolo/test_back/stream"
 	"github.com/mbarolo/test_back/utils"
 )
 
+// bikeView: Bicicletas con su tarifa y su autonomía estimada, la batería es un porcentaje de la autonomía de su tipo.
+// La tarifa es la del tipo salvo que la bicicleta tenga una propia. Una bicicleta con una orden de trabajo crítica
+// sin resolver queda fuera de servicio
+var bikeView = "(SELECT b.*, COALESCE(b.cost_per_minute_override, t.cost_per_minute) AS cost_per_minute," +
+	" CASE WHEN t.electric = 1 AND b.battery_level IS NOT NULL THEN ROUND(b.battery_level * t.range_km / 100.0, 1) END AS estimated_range_km," +
+	" EXISTS (SELECT 1 FROM " + TableNameWorkOrder + " o WHERE o.bike_id = b.id AND o.priority = '" + models.PriorityCritical + "' AND o.status != '" + string(models.WorkOrderResolved) + "') AS out_of_service" +
+	" FROM " + TableNameBike + " b LEFT JOIN " + TableNameVehicleType + " t ON t.code = b.vehicle_type)"
+
 type BikeRepository struct {
 	db *sql.DB
 }
@@ -16,20 +27,32 @@ func NewBikeRepository(db *sql.DB) *BikeRepository {
 	return &BikeRepository{db}
 }
 
-func (r *BikeRepository) IsAvailable(id string) (bool, error) {
-	var available bool
-	query := "SELECT available FROM " + TableNameBike + " WHERE id = ?"
-	err := r.db.QueryRow(query, id).Scan(&available)
+func (r *BikeRepository) GetAll(ctx context.Context, spec *utils.QuerySpec) (*utils.Page[models.Bike], error) {
+	bikes, err := utils.GenericScanPage[models.Bike](ctx, conn(ctx, r.db), bikeView, spec)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
-	return available, nil
+	return bikes, nil
 }
 
-func (r *BikeRepository) GetAll() ([]*models.Bike, error) {
-	query := "SELECT * FROM " + TableNameBike
-	bikes, err := utils.GenericScanAll[models.Bike](r.db, query)
+// GetAllAvailable: Bicicletas disponibles y en servicio, opcionalmente de un tipo, con una batería mínima y dentro de bbox
+func (r *BikeRepository) GetAllAvailable(ctx context.Context, vehicleType string, minBattery *int, bbox *stream.BBox) ([]*models.Bike, error) {
+	query := "SELECT * FROM " + bikeView + " WHERE status = ? AND out_of_service = 0"
+	args := []interface{}{models.BikeAvailable}
+	if vehicleType != "" {
+		query += " AND vehicle_type = ?"
+		args = append(args, vehicleType)
+	}
+	if minBattery != nil {
+		query += " AND battery_level >= ?"
+		args = append(args, *minBattery)
+	}
+	if bbox != nil {
+		query += " AND latitude BETWEEN ? AND ? AND longitude BETWEEN ? AND ?"
+		args = append(args, bbox.MinLatitude, bbox.MaxLatitude, bbox.MinLongitude, bbox.MaxLongitude)
+	}
+	bikes, err := utils.GenericScanAll[models.Bike](ctx, conn(ctx, r.db), query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -37,19 +60,37 @@ func (r *BikeRepository) GetAll() ([]*models.Bike, error) {
 	return bikes, nil
 }
 
-func (r *BikeRepository) GetAllAvailable() ([]*models.Bike, error) {
-	query := "SELECT * FROM " + TableNameBike + " WHERE is_available = 1"
-	bikes, err := utils.GenericScanAll[models.Bike](r.db, query)
-	if err != nil {
-		return nil, err
+func (r *BikeRepository) CountAvailable(ctx context.Context) (int64, error) {
+	var count int64
+	query := "SELECT COUNT(*) FROM " + bikeView + " WHERE status = ? AND out_of_service = 0"
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, models.BikeAvailable).Scan(&count); err != nil {
+		return 0, err
 	}
+	return count, nil
+}
 
-	return bikes, nil
+// GetInCirculation: Bicicletas que no están retiradas
+func (r *BikeRepository) GetInCirculation(ctx context.Context) ([]*models.Bike, error) {
+	query := "SELECT * FROM " + bikeView + " WHERE status != ? ORDER BY id"
+	return utils.GenericScanAll[models.Bike](ctx, conn(ctx, r.db), query, models.BikeRetired)
+}
+
+// GetDocked: Bicicletas ancladas en las estaciones, ordenadas por estación y anclaje
+func (r *BikeRepository) GetDocked(ctx context.Context, stationIds []int64) ([]*models.Bike, error) {
+	if len(stationIds) == 0 {
+		return nil, nil
+	}
+	query := "SELECT * FROM " + bikeView + " WHERE station_id IN (?" + strings.Repeat(", ?", len(stationIds)-1) + ") ORDER BY station_id, dock"
+	args := make([]interface{}, len(stationIds))
+	for i, id := range stationIds {
+		args[i] = id
+	}
+	return utils.GenericScanAll[models.Bike](ctx, conn(ctx, r.db), query, args...)
 }
 
-func (r *BikeRepository) GetById(id int64) (*models.Bike, error) {
-	query := "SELECT * FROM " + TableNameBike + " WHERE id = ?"
-	bike, err := utils.GenericScanAll[models.Bike](r.db, query, id)
+func (r *BikeRepository) GetById(ctx context.Context, id int64) (*models.Bike, error) {
+	query := "SELECT * FROM " + bikeView + " WHERE id = ?"
+	bike, err := utils.GenericScanAll[models.Bike](ctx, conn(ctx, r.db), query, id)
 	if err != nil {
 		return nil, err
 	}
@@ -59,9 +100,9 @@ func (r *BikeRepository) GetById(id int64) (*models.Bike, error) {
 	return bike[0], nil
 }
 
-func (r *BikeRepository) CreateBike(bike *models.Bike) (int64, error) {
-	query := "INSERT INTO " + TableNameBike + " (is_available, latitude, longitude, cost_per_minute, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)"
-	res, err := r.db.Exec(query, bike.IsAvailable, bike.Latitude, bike.Longitude, bike.CostPerMinute, bike.CreatedAt, bike.UpdatedAt)
+func (r *BikeRepository) CreateBike(ctx context.Context, bike *models.Bike) (int64, error) {
+	query := "INSERT INTO " + TableNameBike + " (status, latitude, longitude, cost_per_minute_override, vehicle_type, battery_level, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, bike.Status, bike.Latitude, bike.Longitude, bike.CostPerMinuteOverride, bike.VehicleType, bike.BatteryLevel, bike.CreatedAt, bike.UpdatedAt)
 	if err != nil {
 		return -1, err
 	}
@@ -69,11 +110,21 @@ func (r *BikeRepository) CreateBike(bike *models.Bike) (int64, error) {
 	return res.LastInsertId()
 }
 
-func (r *BikeRepository) UpdateBike(bike *models.Bike) (int64, error) {
-	query := "UPDATE " + TableNameBike + " SET is_available = ?, latitude = ?, longitude = ?, updated_at = ?"
-	res, err := r.db.Exec(query, bike.IsAvailable, bike.Latitude, bike.Longitude, time.Now())
+// UpdateTelemetry: Guarda el último estado reportado por el dispositivo. No incrementa la versión: la telemetría
+// llega constantemente y no debe invalidar el ETag con el que se modifica la bicicleta
+func (r *BikeRepository) UpdateTelemetry(ctx context.Context, bike *models.Bike) error {
+	query := "UPDATE " + TableNameBike + " SET latitude = ?, longitude = ?, battery_level = ?, lock_state = ?, last_seen_at = ? WHERE id = ?"
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, bike.Latitude, bike.Longitude, bike.BatteryLevel, bike.LockState, bike.LastSeenAt, bike.Id)
+	return err
+}
+
+// UpdateBike: Actualiza la bicicleta solo si no cambió desde que se leyó (misma versión).
+// Retorna 0 filas afectadas si la versión no coincide
+func (r *BikeRepository) UpdateBike(ctx context.Context, bike *models.Bike) (int64, error) {
+	query := "UPDATE " + TableNameBike + " SET status = ?, latitude = ?, longitude = ?, cost_per_minute_override = ?, vehicle_type = ?, battery_level = ?, station_id = ?, dock = ?, updated_at = ?, version = version + 1 WHERE id = ? AND version = ?"
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, bike.Status, bike.Latitude, bike.Longitude, bike.CostPerMinuteOverride, bike.VehicleType, bike.BatteryLevel, bike.StationId, bike.Dock, time.Now(), bike.Id, bike.Version)
 	if err != nil {
-		return -1, nil
+		return -1, err
 	}
 	return res.RowsAffected()
 }
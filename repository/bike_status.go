@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/mbarolo/test_back/models"
+	"github.com/mbarolo/test_back/utils"
+)
+
+// BikeStatusRepository: Historial de estados de las bicicletas, solo se agregan registros
+type BikeStatusRepository struct {
+	db *sql.DB
+}
+
+func NewBikeStatusRepository(db *sql.DB) *BikeStatusRepository {
+	return &BikeStatusRepository{db}
+}
+
+func (r *BikeStatusRepository) GetPage(ctx context.Context, spec *utils.QuerySpec) (*utils.Page[models.BikeStatusChange], error) {
+	return utils.GenericScanPage[models.BikeStatusChange](ctx, conn(ctx, r.db), TableNameBikeStatus, spec)
+}
+
+func (r *BikeStatusRepository) Create(ctx context.Context, change *models.BikeStatusChange) (int64, error) {
+	query := "INSERT INTO " + TableNameBikeStatus + " (bike_id, from_status, to_status, reason, actor, created_at) VALUES (?, ?, ?, ?, ?, ?)"
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, change.BikeId, change.FromStatus, change.ToStatus, change.Reason, change.Actor, change.CreatedAt)
+	if err != nil {
+		return -1, err
+	}
+	return res.LastInsertId()
+}
@@ -1,7 +1,23 @@
 package repository
 
 const (
-	TableNameUser   = "users"
-	TableNameBike   = "bikes"
-	TableNameRental = "rentals"
+	TableNameUser        = "users"
+	TableNameBike        = "bikes"
+	TableNameVehicleType = "vehicle_types"
+	TableNameRental      = "rentals"
+	TableNameIdempotency = "idempotency_keys"
+	TableNameAudit       = "audit_log"
+	TableNameOutbox      = "outbox_events"
+	TableNameWebhook     = "webhook_subscriptions"
+	TableNameDelivery    = "webhook_deliveries"
+	TableNameDevice      = "devices"
+	TableNameRoutePoint  = "rental_route_points"
+	TableNameWorkOrder   = "work_orders"
+	TableNameDamage      = "damage_reports"
+	TableNameBikeStatus  = "bike_status_history"
+	TableNameStation     = "stations"
+	TableNameRebalancing = "rebalancing_tasks"
+	TableNameIncident    = "incidents"
+	// TableNameTelemetry: Prefijo de las tablas de telemetría, una por mes (ej: bike_telemetry_202610)
+	TableNameTelemetry = "bike_telemetry"
 )
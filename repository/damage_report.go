@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/mbarolo/test_back/models"
+	"github.com/mbarolo/test_back/utils"
+)
+
+type DamageReportRepository struct {
+	db *sql.DB
+}
+
+func NewDamageReportRepository(db *sql.DB) *DamageReportRepository {
+	return &DamageReportRepository{db}
+}
+
+func (r *DamageReportRepository) GetById(ctx context.Context, id int64) (*models.DamageReport, error) {
+	query := "SELECT * FROM " + TableNameDamage + " WHERE id = ?"
+	reports, err := utils.GenericScanAll[models.DamageReport](ctx, conn(ctx, r.db), query, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(reports) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return reports[0], nil
+}
+
+func (r *DamageReportRepository) GetByWorkOrder(ctx context.Context, workOrderId int64) ([]*models.DamageReport, error) {
+	query := "SELECT * FROM " + TableNameDamage + " WHERE work_order_id = ? ORDER BY id"
+	return utils.GenericScanAll[models.DamageReport](ctx, conn(ctx, r.db), query, workOrderId)
+}
+
+func (r *DamageReportRepository) Create(ctx context.Context, report *models.DamageReport) (int64, error) {
+	query := "INSERT INTO " + TableNameDamage + " (bike_id, user_id, work_order_id, category, description, photo, photo_type, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, report.BikeId, report.UserId, report.WorkOrderId, report.Category, report.Description, report.Photo, report.PhotoType, report.CreatedAt)
+	if err != nil {
+		return -1, err
+	}
+	return res.LastInsertId()
+}
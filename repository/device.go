@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/mbarolo/test_back/models"
+	"github.com/mbarolo/test_back/utils"
+)
+
+type DeviceRepository struct {
+	db *sql.DB
+}
+
+func NewDeviceRepository(db *sql.DB) *DeviceRepository {
+	return &DeviceRepository{db}
+}
+
+func (r *DeviceRepository) GetByBikeId(ctx context.Context, bikeId int64) (*models.Device, error) {
+	query := "SELECT * FROM " + TableNameDevice + " WHERE bike_id = ?"
+	devices, err := utils.GenericScanAll[models.Device](ctx, conn(ctx, r.db), query, bikeId)
+	if err != nil {
+		return nil, err
+	}
+	if len(devices) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return devices[0], nil
+}
+
+func (r *DeviceRepository) GetByTokenHash(ctx context.Context, tokenHash string) (*models.Device, error) {
+	query := "SELECT * FROM " + TableNameDevice + " WHERE token_hash = ?"
+	devices, err := utils.GenericScanAll[models.Device](ctx, conn(ctx, r.db), query, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+	if len(devices) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return devices[0], nil
+}
+
+// Upsert: Registra el dispositivo de la bicicleta, si ya tenía uno reemplaza su token (el anterior deja de valer)
+func (r *DeviceRepository) Upsert(ctx context.Context, device *models.Device) (int64, error) {
+	query := "INSERT INTO " + TableNameDevice + " (bike_id, token_hash, created_at) VALUES (?, ?, ?)" +
+		" ON CONFLICT (bike_id) DO UPDATE SET token_hash = excluded.token_hash, created_at = excluded.created_at, last_seen_at = NULL" +
+		" RETURNING id"
+	var id int64
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, device.BikeId, device.TokenHash, device.CreatedAt).Scan(&id); err != nil {
+		return -1, err
+	}
+	return id, nil
+}
+
+func (r *DeviceRepository) DeleteByBikeId(ctx context.Context, bikeId int64) (int64, error) {
+	query := "DELETE FROM " + TableNameDevice + " WHERE bike_id = ?"
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, bikeId)
+	if err != nil {
+		return -1, err
+	}
+	return res.RowsAffected()
+}
+
+// Touch: Registra la última vez que el dispositivo envió datos
+func (r *DeviceRepository) Touch(ctx context.Context, bikeId int64, seenAt time.Time) error {
+	query := "UPDATE " + TableNameDevice + " SET last_seen_at = ? WHERE bike_id = ?"
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, seenAt, bikeId)
+	return err
+}
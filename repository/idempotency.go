@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/mbarolo/test_back/models"
+	"github.com/mbarolo/test_back/utils"
+)
+
+type IdempotencyRepository struct {
+	db *sql.DB
+}
+
+func NewIdempotencyRepository(db *sql.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{db}
+}
+
+// Reserve: Registra la clave como en proceso. Retorna false si ya existía una clave vigente para el usuario,
+// el índice único (user_id, idempotency_key) garantiza que entre solicitudes concurrentes solo una la obtenga
+func (r *IdempotencyRepository) Reserve(ctx context.Context, record *models.IdempotencyRecord) (bool, error) {
+	// las claves vencidas se liberan para que puedan volver a usarse
+	deleteQuery := "DELETE FROM " + TableNameIdempotency + " WHERE user_id = ? AND idempotency_key = ? AND substr(expires_at, 1, 19) <= ?"
+	if _, err := conn(ctx, r.db).ExecContext(ctx, deleteQuery, record.UserId, record.IdempotencyKey, time.Now().Format(time.DateTime)); err != nil {
+		return false, err
+	}
+
+	query := "INSERT INTO " + TableNameIdempotency + " (user_id, idempotency_key, fingerprint, created_at, expires_at) VALUES (?, ?, ?, ?, ?) ON CONFLICT (user_id, idempotency_key) DO NOTHING"
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, record.UserId, record.IdempotencyKey, record.Fingerprint, record.CreatedAt, record.ExpiresAt)
+	if err != nil {
+		return false, err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rows == 0 {
+		return false, nil
+	}
+
+	record.Id, err = res.LastInsertId()
+	return true, err
+}
+
+func (r *IdempotencyRepository) Get(ctx context.Context, userId int64, key string) (*models.IdempotencyRecord, error) {
+	query := "SELECT * FROM " + TableNameIdempotency + " WHERE user_id = ? AND idempotency_key = ?"
+	records, err := utils.GenericScanAll[models.IdempotencyRecord](ctx, conn(ctx, r.db), query, userId, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return records[0], nil
+}
+
+// Complete: Guarda la respuesta de la solicitud original para repetirla en los reintentos
+func (r *IdempotencyRepository) Complete(ctx context.Context, record *models.IdempotencyRecord) error {
+	query := "UPDATE " + TableNameIdempotency + " SET status_code = ?, content_type = ?, response_body = ? WHERE id = ?"
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, record.StatusCode, record.ContentType, record.ResponseBody, record.Id)
+	return err
+}
+
+// Release: Elimina la clave para que un reintento vuelva a ejecutar la solicitud
+func (r *IdempotencyRepository) Release(ctx context.Context, id int64) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM "+TableNameIdempotency+" WHERE id = ?", id)
+	return err
+}
+
+// DeleteExpired: Elimina las claves vencidas de todos los usuarios
+func (r *IdempotencyRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	res, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM "+TableNameIdempotency+" WHERE substr(expires_at, 1, 19) <= ?", time.Now().Format(time.DateTime))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
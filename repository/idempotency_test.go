@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mbarolo/test_back/config"
+	"github.com/mbarolo/test_back/models"
+)
+
+// newTestDB: Base de datos vacía en un directorio temporal, se cierra al terminar el test
+func newTestDB(t *testing.T) {
+	t.Helper()
+	if err := config.InitDB(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { config.CloseDB() })
+}
+
+func idempotencyRecord(key string, expiresAt time.Time) *models.IdempotencyRecord {
+	return &models.IdempotencyRecord{UserId: 1, IdempotencyKey: key, Fingerprint: "f", CreatedAt: time.Now(), ExpiresAt: expiresAt}
+}
+
+func TestIdempotencyReserveConcurrent(t *testing.T) {
+	newTestDB(t)
+	repo := NewIdempotencyRepository(config.DB)
+	ctx := context.Background()
+	expiresAt := time.Now().Add(time.Hour)
+
+	const requests = 20
+	var wg sync.WaitGroup
+	reserved := make(chan int64, requests)
+	errs := make(chan error, requests)
+	for range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			record := idempotencyRecord("key", expiresAt)
+			ok, err := repo.Reserve(ctx, record)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if ok {
+				reserved <- record.Id
+			}
+		}()
+	}
+	wg.Wait()
+	close(reserved)
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+	if n := len(reserved); n != 1 {
+		t.Fatalf("reservas = %d, se esperaba una sola", n)
+	}
+	if stored, err := repo.Get(ctx, 1, "key"); err != nil || stored.Id != <-reserved {
+		t.Errorf("clave guardada = %+v (%v), se esperaba la reservada", stored, err)
+	}
+}
+
+func TestIdempotencyReserveExpired(t *testing.T) {
+	newTestDB(t)
+	repo := NewIdempotencyRepository(config.DB)
+	ctx := context.Background()
+
+	if ok, err := repo.Reserve(ctx, idempotencyRecord("key", time.Now().Add(-time.Minute))); err != nil || !ok {
+		t.Fatalf("reserva = %v (%v), se esperaba reservada", ok, err)
+	}
+	// la clave vencida se vuelve a usar, la vigente no
+	if ok, err := repo.Reserve(ctx, idempotencyRecord("key", time.Now().Add(time.Hour))); err != nil || !ok {
+		t.Fatalf("reserva = %v (%v), se esperaba reservar la clave vencida", ok, err)
+	}
+	if ok, err := repo.Reserve(ctx, idempotencyRecord("key", time.Now().Add(time.Hour))); err != nil || ok {
+		t.Fatalf("reserva = %v (%v), se esperaba la clave vigente ocupada", ok, err)
+	}
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/mbarolo/test_back/models"
+	"github.com/mbarolo/test_back/utils"
+)
+
+type IncidentRepository struct {
+	db *sql.DB
+}
+
+func NewIncidentRepository(db *sql.DB) *IncidentRepository {
+	return &IncidentRepository{db}
+}
+
+func (r *IncidentRepository) GetPage(ctx context.Context, spec *utils.QuerySpec) (*utils.Page[models.Incident], error) {
+	return utils.GenericScanPage[models.Incident](ctx, conn(ctx, r.db), TableNameIncident, spec)
+}
+
+func (r *IncidentRepository) GetById(ctx context.Context, id int64) (*models.Incident, error) {
+	query := "SELECT * FROM " + TableNameIncident + " WHERE id = ?"
+	incidents, err := utils.GenericScanAll[models.Incident](ctx, conn(ctx, r.db), query, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(incidents) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return incidents[0], nil
+}
+
+// GetUnresolved: Incidentes sin resolver de la bicicleta, o de toda la flota si bikeId es 0
+func (r *IncidentRepository) GetUnresolved(ctx context.Context, bikeId int64) ([]*models.Incident, error) {
+	query := "SELECT * FROM " + TableNameIncident + " WHERE status != ?"
+	args := []interface{}{models.IncidentResolved}
+	if bikeId != 0 {
+		query += " AND bike_id = ?"
+		args = append(args, bikeId)
+	}
+	return utils.GenericScanAll[models.Incident](ctx, conn(ctx, r.db), query+" ORDER BY id", args...)
+}
+
+func (r *IncidentRepository) Create(ctx context.Context, incident *models.Incident) (int64, error) {
+	query := "INSERT INTO " + TableNameIncident + " (bike_id, rental_id, rule, severity, status, details, latitude, longitude, occurrences, first_seen_at, last_seen_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, incident.BikeId, incident.RentalId, incident.Rule, incident.Severity, incident.Status, string(incident.Details), incident.Latitude, incident.Longitude, incident.Occurrences, incident.FirstSeenAt, incident.LastSeenAt, incident.UpdatedAt)
+	if err != nil {
+		return -1, err
+	}
+	return res.LastInsertId()
+}
+
+// Update: Actualiza el incidente solo si no cambió desde que se leyó (misma versión)
+func (r *IncidentRepository) Update(ctx context.Context, incident *models.Incident) (int64, error) {
+	query := "UPDATE " + TableNameIncident + " SET rental_id = ?, severity = ?, status = ?, details = ?, latitude = ?, longitude = ?, occurrences = ?, last_seen_at = ?, acknowledged_by = ?, acknowledged_at = ?, resolved_by = ?, resolved_at = ?, resolution = ?, updated_at = ?, version = version + 1 WHERE id = ? AND version = ?"
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, incident.RentalId, incident.Severity, incident.Status, string(incident.Details), incident.Latitude, incident.Longitude, incident.Occurrences, incident.LastSeenAt, incident.AcknowledgedBy, incident.AcknowledgedAt, incident.ResolvedBy, incident.ResolvedAt, incident.Resolution, incident.UpdatedAt, incident.Id, incident.Version)
+	if err != nil {
+		return -1, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/mbarolo/test_back/models"
+	"github.com/mbarolo/test_back/utils"
+)
+
+type OutboxRepository struct {
+	db *sql.DB
+}
+
+func NewOutboxRepository(db *sql.DB) *OutboxRepository {
+	return &OutboxRepository{db}
+}
+
+// Append: Guarda el evento, debe llamarse dentro de la transacción del cambio que lo originó
+func (r *OutboxRepository) Append(ctx context.Context, event *models.OutboxEvent) (int64, error) {
+	query := "INSERT INTO " + TableNameOutbox + " (event_type, entity_type, entity_id, payload, request_id, created_at) VALUES (?, ?, ?, ?, ?, ?)"
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, event.EventType, event.EntityType, event.EntityId, string(event.Payload), event.RequestId, event.CreatedAt)
+	if err != nil {
+		return -1, err
+	}
+
+	return res.LastInsertId()
+}
+
+func (r *OutboxRepository) GetById(ctx context.Context, id int64) (*models.OutboxEvent, error) {
+	query := "SELECT * FROM " + TableNameOutbox + " WHERE id = ?"
+	events, err := utils.GenericScanAll[models.OutboxEvent](ctx, conn(ctx, r.db), query, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return events[0], nil
+}
+
+// GetUndispatched: Eventos que todavía no tienen sus entregas creadas, en orden de publicación
+func (r *OutboxRepository) GetUndispatched(ctx context.Context, limit int) ([]*models.OutboxEvent, error) {
+	query := "SELECT * FROM " + TableNameOutbox + " WHERE dispatched_at IS NULL ORDER BY id LIMIT ?"
+	return utils.GenericScanAll[models.OutboxEvent](ctx, conn(ctx, r.db), query, limit)
+}
+
+func (r *OutboxRepository) MarkDispatched(ctx context.Context, id int64) error {
+	query := "UPDATE " + TableNameOutbox + " SET dispatched_at = ? WHERE id = ?"
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, time.Now(), id)
+	return err
+}
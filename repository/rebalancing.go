@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/mbarolo/test_back/models"
+	"github.com/mbarolo/test_back/utils"
+)
+
+type RebalancingRepository struct {
+	db *sql.DB
+}
+
+func NewRebalancingRepository(db *sql.DB) *RebalancingRepository {
+	return &RebalancingRepository{db}
+}
+
+func (r *RebalancingRepository) GetPage(ctx context.Context, spec *utils.QuerySpec) (*utils.Page[models.RebalancingTask], error) {
+	return utils.GenericScanPage[models.RebalancingTask](ctx, conn(ctx, r.db), TableNameRebalancing, spec)
+}
+
+func (r *RebalancingRepository) GetById(ctx context.Context, id int64) (*models.RebalancingTask, error) {
+	query := "SELECT * FROM " + TableNameRebalancing + " WHERE id = ?"
+	tasks, err := utils.GenericScanAll[models.RebalancingTask](ctx, conn(ctx, r.db), query, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(tasks) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return tasks[0], nil
+}
+
+func (r *RebalancingRepository) GetByStatus(ctx context.Context, status models.RebalancingTaskStatus) ([]*models.RebalancingTask, error) {
+	query := "SELECT * FROM " + TableNameRebalancing + " WHERE status = ? ORDER BY id"
+	return utils.GenericScanAll[models.RebalancingTask](ctx, conn(ctx, r.db), query, status)
+}
+
+// CancelOpen: Cancela las tareas que nadie tomó, retorna cuántas
+func (r *RebalancingRepository) CancelOpen(ctx context.Context, now time.Time) (int64, error) {
+	query := "UPDATE " + TableNameRebalancing + " SET status = ?, updated_at = ?, version = version + 1 WHERE status = ?"
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, models.RebalancingCancelled, now, models.RebalancingOpen)
+	if err != nil {
+		return -1, err
+	}
+	return res.RowsAffected()
+}
+
+func (r *RebalancingRepository) Create(ctx context.Context, task *models.RebalancingTask) (int64, error) {
+	query := "INSERT INTO " + TableNameRebalancing + " (from_cell, from_latitude, from_longitude, to_cell, to_latitude, to_longitude, bikes, distance_m, status, forecast_hour, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, task.FromCell, task.FromLatitude, task.FromLongitude, task.ToCell, task.ToLatitude, task.ToLongitude, task.Bikes, task.DistanceM, task.Status, task.ForecastHour, task.CreatedAt, task.UpdatedAt)
+	if err != nil {
+		return -1, err
+	}
+	return res.LastInsertId()
+}
+
+// Update: Actualiza la tarea solo si no cambió desde que se leyó (misma versión)
+func (r *RebalancingRepository) Update(ctx context.Context, task *models.RebalancingTask) (int64, error) {
+	query := "UPDATE " + TableNameRebalancing + " SET status = ?, claimed_by = ?, claimed_at = ?, completed_at = ?, moved = ?, updated_at = ?, version = version + 1 WHERE id = ? AND version = ?"
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, task.Status, task.ClaimedBy, task.ClaimedAt, task.CompletedAt, task.Moved, task.UpdatedAt, task.Id, task.Version)
+	if err != nil {
+		return -1, err
+	}
+	return res.RowsAffected()
+}
@@ -1,7 +1,9 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"time"
 
 	"github.com/mbarolo/test_back/models"
 	"github.com/mbarolo/test_back/utils"
@@ -15,21 +17,17 @@ func NewRentalRepository(db *sql.DB) *RentalRepository {
 	return &RentalRepository{db}
 }
 
-func (r *RentalRepository) GetAll() ([]*models.Rental, error) {
-	query := "SELECT * FROM " + TableNameRental
-	rentals, err := utils.GenericScanAll[models.Rental](r.db, query)
+func (r *RentalRepository) GetAll(ctx context.Context, spec *utils.QuerySpec) (*utils.Page[models.Rental], error) {
+	rentals, err := utils.GenericScanPage[models.Rental](ctx, conn(ctx, r.db), TableNameRental, spec)
 	if err != nil {
 		return nil, err
 	}
-	if len(rentals) == 0 {
-		return []*models.Rental{}, nil
-	}
 	return rentals, nil
 }
 
-func (r *RentalRepository) GetUserHistory(userId int64) ([]*models.Rental, error) {
-	query := "SELECT * FROM " + TableNameRental + " WHERE user_id = ?"
-	rentals, err := utils.GenericScanAll[models.Rental](r.db, query, userId)
+func (r *RentalRepository) GetUserHistory(ctx context.Context, userId int64, spec *utils.QuerySpec) (*utils.Page[models.Rental], error) {
+	spec.AddFilter("user_id", "=", userId)
+	rentals, err := utils.GenericScanPage[models.Rental](ctx, conn(ctx, r.db), TableNameRental, spec)
 	if err != nil {
 		return nil, err
 	}
@@ -37,9 +35,9 @@ func (r *RentalRepository) GetUserHistory(userId int64) ([]*models.Rental, error
 	return rentals, nil
 }
 
-func (r *RentalRepository) GetById(id int64) (*models.Rental, error) {
+func (r *RentalRepository) GetById(ctx context.Context, id int64) (*models.Rental, error) {
 	query := "SELECT * FROM " + TableNameRental + " WHERE id = ?"
-	rental, err := utils.GenericScanAll[models.Rental](r.db, query, id)
+	rental, err := utils.GenericScanAll[models.Rental](ctx, conn(ctx, r.db), query, id)
 	if err != nil {
 		return nil, err
 	}
@@ -49,9 +47,9 @@ func (r *RentalRepository) GetById(id int64) (*models.Rental, error) {
 	return rental[0], nil
 }
 
-func (r *RentalRepository) GetRunningRental(userId int64) (*models.Rental, error) {
+func (r *RentalRepository) GetRunningRental(ctx context.Context, userId int64) (*models.Rental, error) {
 	query := "SELECT * FROM " + TableNameRental + " WHERE user_id = ? AND rental_status = ?"
-	rental, err := utils.GenericScanAll[models.Rental](r.db, query, userId, models.RUNNING)
+	rental, err := utils.GenericScanAll[models.Rental](ctx, conn(ctx, r.db), query, userId, models.RUNNING)
 	if err != nil {
 		return nil, err
 	}
@@ -61,9 +59,43 @@ func (r *RentalRepository) GetRunningRental(userId int64) (*models.Rental, error
 	return rental[0], nil
 }
 
-func (r *RentalRepository) Create(rental *models.Rental) (int64, error) {
-	query := "INSERT INTO " + TableNameRental + " (user_id, bike_id, rental_status, start_time, end_time, start_latitude, start_longitude, end_latitude, end_longitude) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)"
-	res, err := r.db.Exec(query, rental.UserId, rental.BikeId, rental.RentalStatus, rental.StartTime, rental.EndTime, rental.StartLatitude, rental.StartLongitude, rental.EndLatitude, rental.EndLongitude)
+// GetRunningByBike: Alquiler en curso de la bicicleta, nil si no tiene
+func (r *RentalRepository) GetRunningByBike(ctx context.Context, bikeId int64) (*models.Rental, error) {
+	query := "SELECT * FROM " + TableNameRental + " WHERE bike_id = ? AND rental_status = ?"
+	rental, err := utils.GenericScanAll[models.Rental](ctx, conn(ctx, r.db), query, bikeId, models.RUNNING)
+	if err != nil {
+		return nil, err
+	}
+	if len(rental) == 0 {
+		return nil, nil
+	}
+	return rental[0], nil
+}
+
+// GetAllRunning: Alquileres en curso de toda la flota
+func (r *RentalRepository) GetAllRunning(ctx context.Context) ([]*models.Rental, error) {
+	query := "SELECT * FROM " + TableNameRental + " WHERE rental_status = ?"
+	return utils.GenericScanAll[models.Rental](ctx, conn(ctx, r.db), query, models.RUNNING)
+}
+
+func (r *RentalRepository) CountRunning(ctx context.Context) (int64, error) {
+	var count int64
+	query := "SELECT COUNT(*) FROM " + TableNameRental + " WHERE rental_status = ?"
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, models.RUNNING).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// GetStartedSince: Posición y hora de inicio y fin de los alquileres que empezaron desde since
+func (r *RentalRepository) GetStartedSince(ctx context.Context, since time.Time) ([]*models.Rental, error) {
+	query := "SELECT start_time, start_latitude, start_longitude, end_time, end_latitude, end_longitude FROM " + TableNameRental + " WHERE substr(start_time, 1, 19) >= ?"
+	return utils.GenericScanAll[models.Rental](ctx, conn(ctx, r.db), query, since.Format(time.DateTime))
+}
+
+func (r *RentalRepository) Create(ctx context.Context, rental *models.Rental) (int64, error) {
+	query := "INSERT INTO " + TableNameRental + " (user_id, bike_id, rental_status, start_time, end_time, start_latitude, start_longitude, end_latitude, end_longitude, start_station_id) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, rental.UserId, rental.BikeId, rental.RentalStatus, rental.StartTime, rental.EndTime, rental.StartLatitude, rental.StartLongitude, rental.EndLatitude, rental.EndLongitude, rental.StartStationId)
 	if err != nil {
 		return -1, err
 	}
@@ -71,9 +103,10 @@ func (r *RentalRepository) Create(rental *models.Rental) (int64, error) {
 	return res.LastInsertId()
 }
 
-func (r *RentalRepository) Update(rental *models.Rental) (int64, error) {
-	query := "UPDATE " + TableNameRental + " SET user_id = ?, bike_id = ?, rental_status = ?, start_time = ?, end_time = ?, start_latitude = ?, start_longitude = ?, end_latitude = ?, end_longitude = ?, duration = ?, cost = ? WHERE id = ?"
-	res, err := r.db.Exec(query, rental.UserId, rental.BikeId, rental.RentalStatus, rental.StartTime, rental.EndTime, rental.StartLatitude, rental.StartLongitude, rental.EndLatitude, rental.EndLongitude, rental.Duration, rental.Cost, rental.Id)
+// Update: Actualiza el alquiler solo si no cambió desde que se leyó (misma versión)
+func (r *RentalRepository) Update(ctx context.Context, rental *models.Rental) (int64, error) {
+	query := "UPDATE " + TableNameRental + " SET user_id = ?, bike_id = ?, rental_status = ?, start_time = ?, end_time = ?, start_latitude = ?, start_longitude = ?, end_latitude = ?, end_longitude = ?, duration = ?, cost = ?, distance_m = ?, end_station_id = ?, end_reason = ?, penalty_fee = ?, warned_at = ?, auto_end_at = ?, version = version + 1 WHERE id = ? AND version = ?"
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, rental.UserId, rental.BikeId, rental.RentalStatus, rental.StartTime, rental.EndTime, rental.StartLatitude, rental.StartLongitude, rental.EndLatitude, rental.EndLongitude, rental.Duration, rental.Cost, rental.DistanceM, rental.EndStationId, rental.EndReason, rental.PenaltyFee, rental.WarnedAt, rental.AutoEndAt, rental.Id, rental.Version)
 	if err != nil {
 		return -1, err
 	}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/mbarolo/test_back/models"
+	"github.com/mbarolo/test_back/utils"
+)
+
+type RouteRepository struct {
+	db *sql.DB
+}
+
+func NewRouteRepository(db *sql.DB) *RouteRepository {
+	return &RouteRepository{db}
+}
+
+// GetByRental: Puntos del recorrido del alquiler en orden cronológico
+func (r *RouteRepository) GetByRental(ctx context.Context, rentalId int64) ([]*models.RoutePoint, error) {
+	query := "SELECT * FROM " + TableNameRoutePoint + " WHERE rental_id = ? ORDER BY recorded_at, id"
+	return utils.GenericScanAll[models.RoutePoint](ctx, conn(ctx, r.db), query, rentalId)
+}
+
+// GetLast: Último punto registrado del recorrido del alquiler, nil si no tiene puntos
+func (r *RouteRepository) GetLast(ctx context.Context, rentalId int64) (*models.RoutePoint, error) {
+	query := "SELECT * FROM " + TableNameRoutePoint + " WHERE rental_id = ? ORDER BY recorded_at DESC, id DESC LIMIT 1"
+	points, err := utils.GenericScanAll[models.RoutePoint](ctx, conn(ctx, r.db), query, rentalId)
+	if err != nil {
+		return nil, err
+	}
+	if len(points) == 0 {
+		return nil, nil
+	}
+	return points[0], nil
+}
+
+func (r *RouteRepository) InsertBatch(ctx context.Context, points []*models.RoutePoint) error {
+	query := "INSERT INTO " + TableNameRoutePoint + " (rental_id, recorded_at, latitude, longitude, accuracy, source) VALUES (?, ?, ?, ?, ?, ?)"
+	for _, point := range points {
+		res, err := conn(ctx, r.db).ExecContext(ctx, query, point.RentalId, point.RecordedAt, point.Latitude, point.Longitude, point.Accuracy, point.Source)
+		if err != nil {
+			return err
+		}
+		if point.Id, err = res.LastInsertId(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteExcept: Elimina los puntos del recorrido del alquiler que no están en keep
+func (r *RouteRepository) DeleteExcept(ctx context.Context, rentalId int64, keep []*models.RoutePoint) (int64, error) {
+	query := "DELETE FROM " + TableNameRoutePoint + " WHERE rental_id = ?"
+	args := []interface{}{rentalId}
+	if len(keep) > 0 {
+		query += " AND id NOT IN (" + strings.TrimSuffix(strings.Repeat("?, ", len(keep)), ", ") + ")"
+		for _, point := range keep {
+			args = append(args, point.Id)
+		}
+	}
+
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/mbarolo/test_back/models"
+	"github.com/mbarolo/test_back/utils"
+)
+
+// stationView: Estaciones con sus anclajes ocupados y libres, y las bicicletas que se pueden alquilar
+var stationView = "(SELECT s.*, (SELECT COUNT(*) FROM " + TableNameBike + " b WHERE b.station_id = s.id) AS docked," +
+	" s.capacity - (SELECT COUNT(*) FROM " + TableNameBike + " b WHERE b.station_id = s.id) AS free_docks," +
+	" (SELECT COUNT(*) FROM " + bikeView + " b WHERE b.station_id = s.id AND b.status = '" + string(models.BikeAvailable) + "' AND b.out_of_service = 0) AS available_bikes" +
+	" FROM " + TableNameStation + " s)"
+
+type StationRepository struct {
+	db *sql.DB
+}
+
+func NewStationRepository(db *sql.DB) *StationRepository {
+	return &StationRepository{db}
+}
+
+func (r *StationRepository) GetPage(ctx context.Context, spec *utils.QuerySpec) (*utils.Page[models.Station], error) {
+	return utils.GenericScanPage[models.Station](ctx, conn(ctx, r.db), stationView, spec)
+}
+
+// GetActive: Estaciones activas, por nombre
+func (r *StationRepository) GetActive(ctx context.Context) ([]*models.Station, error) {
+	query := "SELECT * FROM " + stationView + " WHERE active = 1 ORDER BY name, id"
+	return utils.GenericScanAll[models.Station](ctx, conn(ctx, r.db), query)
+}
+
+// GetActiveInBox: Estaciones activas dentro del rectángulo
+func (r *StationRepository) GetActiveInBox(ctx context.Context, minLat, minLon, maxLat, maxLon float64) ([]*models.Station, error) {
+	query := "SELECT * FROM " + stationView + " WHERE active = 1 AND latitude BETWEEN ? AND ? AND longitude BETWEEN ? AND ?"
+	return utils.GenericScanAll[models.Station](ctx, conn(ctx, r.db), query, minLat, maxLat, minLon, maxLon)
+}
+
+func (r *StationRepository) GetById(ctx context.Context, id int64) (*models.Station, error) {
+	query := "SELECT * FROM " + stationView + " WHERE id = ?"
+	stations, err := utils.GenericScanAll[models.Station](ctx, conn(ctx, r.db), query, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(stations) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return stations[0], nil
+}
+
+// GetUsedDocks: Números de anclaje ocupados de la estación, de menor a mayor
+func (r *StationRepository) GetUsedDocks(ctx context.Context, id int64) ([]int, error) {
+	query := "SELECT dock FROM " + TableNameBike + " WHERE station_id = ? ORDER BY dock"
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var docks []int
+	for rows.Next() {
+		var dock int
+		if err := rows.Scan(&dock); err != nil {
+			return nil, err
+		}
+		docks = append(docks, dock)
+	}
+	return docks, rows.Err()
+}
+
+func (r *StationRepository) Create(ctx context.Context, station *models.Station) (int64, error) {
+	query := "INSERT INTO " + TableNameStation + " (name, latitude, longitude, capacity, active, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)"
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, station.Name, station.Latitude, station.Longitude, station.Capacity, station.Active, station.CreatedAt, station.UpdatedAt)
+	if err != nil {
+		return -1, err
+	}
+	return res.LastInsertId()
+}
+
+// Update: Actualiza la estación solo si no cambió desde que se leyó (misma versión)
+func (r *StationRepository) Update(ctx context.Context, station *models.Station) (int64, error) {
+	query := "UPDATE " + TableNameStation + " SET name = ?, latitude = ?, longitude = ?, capacity = ?, active = ?, updated_at = ?, version = version + 1 WHERE id = ? AND version = ?"
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, station.Name, station.Latitude, station.Longitude, station.Capacity, station.Active, station.UpdatedAt, station.Id, station.Version)
+	if err != nil {
+		return -1, err
+	}
+	return res.RowsAffected()
+}
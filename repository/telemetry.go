@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/mbarolo/test_back/models"
+	"github.com/mbarolo/test_back/utils"
+)
+
+// formato del mes en el nombre de las particiones
+const telemetryPartitionLayout = "200601"
+
+// TelemetryRepository: Historial de telemetría particionado por mes de recepción. Cada partición es una tabla
+// (bike_telemetry_YYYYMM), así la retención elimina meses completos con DROP TABLE en lugar de borrar filas
+type TelemetryRepository struct {
+	db *sql.DB
+}
+
+func NewTelemetryRepository(db *sql.DB) *TelemetryRepository {
+	return &TelemetryRepository{db}
+}
+
+// TelemetryPartition: Nombre de la partición del mes de t
+func TelemetryPartition(t time.Time) string {
+	return TableNameTelemetry + "_" + t.UTC().Format(telemetryPartitionLayout)
+}
+
+// Partitions: Particiones existentes, de la más antigua a la más nueva
+func (r *TelemetryRepository) Partitions(ctx context.Context) ([]string, error) {
+	query := "SELECT name FROM sqlite_master WHERE type = 'table' AND name GLOB ? ORDER BY name"
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, TableNameTelemetry+"_[0-9][0-9][0-9][0-9][0-9][0-9]")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	partitions := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		partitions = append(partitions, name)
+	}
+	return partitions, rows.Err()
+}
+
+// ensurePartition: Crea la partición si no existe. El AUTOINCREMENT de la nueva tabla continúa desde el máximo
+// de las anteriores, así los ids son únicos entre particiones y sirven como cursor de paginación
+func (r *TelemetryRepository) ensurePartition(ctx context.Context, name string) error {
+	db := conn(ctx, r.db)
+	var exists int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name).Scan(&exists); err != nil {
+		return err
+	}
+	if exists > 0 {
+		return nil
+	}
+
+	schema := `
+    CREATE TABLE ` + name + ` (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        bike_id INTEGER NOT NULL,
+        recorded_at DATETIME NOT NULL,
+        received_at DATETIME NOT NULL,
+        latitude REAL,
+        longitude REAL,
+        accuracy REAL,
+        battery_level INTEGER,
+        lock_state TEXT,
+        error_codes TEXT
+    );
+    CREATE INDEX idx_` + name + `_bike ON ` + name + `(bike_id, recorded_at);`
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		return err
+	}
+
+	query := "INSERT INTO sqlite_sequence (name, seq) SELECT ?, COALESCE(MAX(seq), 0) FROM sqlite_sequence WHERE name GLOB ?"
+	_, err := db.ExecContext(ctx, query, name, TableNameTelemetry+"_*")
+	return err
+}
+
+// InsertBatch: Guarda las lecturas en la partición del mes en que se recibieron
+func (r *TelemetryRepository) InsertBatch(ctx context.Context, receivedAt time.Time, readings []*models.TelemetryReading) error {
+	partition := TelemetryPartition(receivedAt)
+	if err := r.ensurePartition(ctx, partition); err != nil {
+		return err
+	}
+
+	query := "INSERT INTO " + partition + " (bike_id, recorded_at, received_at, latitude, longitude, accuracy, battery_level, lock_state, error_codes) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	for _, reading := range readings {
+		var errorCodes interface{}
+		if len(reading.ErrorCodes) > 0 {
+			errorCodes = string(reading.ErrorCodes)
+		}
+		res, err := conn(ctx, r.db).ExecContext(ctx, query, reading.BikeId, reading.RecordedAt, receivedAt, reading.Latitude, reading.Longitude, reading.Accuracy, reading.BatteryLevel, reading.LockState, errorCodes)
+		if err != nil {
+			return err
+		}
+		if reading.Id, err = res.LastInsertId(); err != nil {
+			return err
+		}
+		reading.ReceivedAt = receivedAt
+	}
+	return nil
+}
+
+// GetPage: Lecturas de todas las particiones según el QuerySpec
+func (r *TelemetryRepository) GetPage(ctx context.Context, spec *utils.QuerySpec) (*utils.Page[models.TelemetryReading], error) {
+	partitions, err := r.Partitions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(partitions) == 0 {
+		return &utils.Page[models.TelemetryReading]{Items: []*models.TelemetryReading{}}, nil
+	}
+
+	selects := make([]string, len(partitions))
+	for i, partition := range partitions {
+		selects[i] = "SELECT * FROM " + partition
+	}
+	table := "(" + strings.Join(selects, " UNION ALL ") + ")"
+	return utils.GenericScanPage[models.TelemetryReading](ctx, conn(ctx, r.db), table, spec)
+}
+
+// DropBefore: Elimina las particiones de los meses que terminaron antes de cutoff
+func (r *TelemetryRepository) DropBefore(ctx context.Context, cutoff time.Time) ([]string, error) {
+	partitions, err := r.Partitions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	dropped := []string{}
+	for _, partition := range partitions {
+		month, err := time.Parse(telemetryPartitionLayout, strings.TrimPrefix(partition, TableNameTelemetry+"_"))
+		if err != nil {
+			continue
+		}
+		if month.AddDate(0, 1, 0).After(cutoff) {
+			break
+		}
+		if _, err := conn(ctx, r.db).ExecContext(ctx, "DROP TABLE "+partition); err != nil {
+			return dropped, err
+		}
+		dropped = append(dropped, partition)
+	}
+	return dropped, nil
+}
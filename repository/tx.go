@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/mbarolo/test_back/utils"
+)
+
+type txKey struct{}
+
+// RunInTx: Ejecuta fn dentro de una transacción. Los repositorios usan la transacción del contexto que reciben,
+// así todas las escrituras de fn se confirman juntas o se descartan si fn retorna un error.
+// Si el contexto ya tiene una transacción fn se ejecuta dentro de ella
+func RunInTx(ctx context.Context, db *sql.DB, fn func(ctx context.Context) error) (err error) {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err = fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// conn: Transacción del contexto, o la base de datos si no hay una en curso
+func conn(ctx context.Context, db *sql.DB) utils.DBTX {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return db
+}
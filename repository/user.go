@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"time"
 
@@ -16,11 +17,11 @@ func NewUserRepository(db *sql.DB) *UserRepository {
 	return &UserRepository{db}
 }
 
-func (r *UserRepository) ExistsByEmail(email string) (bool, error) {
+func (r *UserRepository) ExistsByEmail(ctx context.Context, email string) (bool, error) {
 	var count int
 	query := "SELECT COUNT(*) FROM " + TableNameUser + " WHERE email = ?"
 
-	err := r.db.QueryRow(query, email).Scan(&count)
+	err := conn(ctx, r.db).QueryRowContext(ctx, query, email).Scan(&count)
 	if err != nil {
 		return false, err
 	}
@@ -28,9 +29,8 @@ func (r *UserRepository) ExistsByEmail(email string) (bool, error) {
 	return count > 0, nil
 }
 
-func (r *UserRepository) GetAll() ([]*models.User, error) {
-	query := "SELECT * FROM  " + TableNameUser
-	users, err := utils.GenericScanAll[models.User](r.db, query)
+func (r *UserRepository) GetAll(ctx context.Context, spec *utils.QuerySpec) (*utils.Page[models.User], error) {
+	users, err := utils.GenericScanPage[models.User](ctx, conn(ctx, r.db), TableNameUser, spec)
 	if err != nil {
 		return nil, err
 	}
@@ -38,9 +38,9 @@ func (r *UserRepository) GetAll() ([]*models.User, error) {
 	return users, nil
 }
 
-func (r *UserRepository) GetById(id int64) (*models.User, error) {
+func (r *UserRepository) GetById(ctx context.Context, id int64) (*models.User, error) {
 	query := "SELECT * FROM " + TableNameUser + " WHERE id = ?"
-	user, err := utils.GenericScanAll[models.User](r.db, query, id)
+	user, err := utils.GenericScanAll[models.User](ctx, conn(ctx, r.db), query, id)
 	if err != nil {
 		return nil, err
 	}
@@ -51,9 +51,9 @@ func (r *UserRepository) GetById(id int64) (*models.User, error) {
 	return user[0], nil
 }
 
-func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
 	query := "SELECT * FROM " + TableNameUser + " WHERE email = ?"
-	user, err := utils.GenericScanAll[models.User](r.db, query, email)
+	user, err := utils.GenericScanAll[models.User](ctx, conn(ctx, r.db), query, email)
 	if err != nil {
 		return nil, err
 	}
@@ -64,9 +64,9 @@ func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
 	return user[0], nil
 }
 
-func (r *UserRepository) Create(user *models.User) (int64, error) {
-	query := "INSERT INTO " + TableNameUser + " (email, hashed_password, first_name, last_name, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)"
-	res, err := r.db.Exec(query, user.Email, user.HashedPassword, user.FirstName, user.LastName, user.CreatedAt, user.UpdatedAt)
+func (r *UserRepository) Create(ctx context.Context, user *models.User) (int64, error) {
+	query := "INSERT INTO " + TableNameUser + " (email, hashed_password, first_name, last_name, language, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)"
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, user.Email, user.HashedPassword, user.FirstName, user.LastName, user.Language, user.CreatedAt, user.UpdatedAt)
 	if err != nil {
 		return -1, err
 	}
@@ -74,9 +74,10 @@ func (r *UserRepository) Create(user *models.User) (int64, error) {
 	return res.LastInsertId()
 }
 
-func (r *UserRepository) Update(user *models.User) (int64, error) {
-	query := "UPDATE " + TableNameUser + " SET email = ?, hashed_password = ?, first_name = ?, last_name = ?, updated_at = ? WHERE id = ?"
-	res, err := r.db.Exec(query, user.Email, user.HashedPassword, user.FirstName, user.LastName, time.Now(), user.Id)
+// Update: Actualiza el usuario solo si no cambió desde que se leyó (misma versión)
+func (r *UserRepository) Update(ctx context.Context, user *models.User) (int64, error) {
+	query := "UPDATE " + TableNameUser + " SET email = ?, hashed_password = ?, first_name = ?, last_name = ?, language = ?, deleted = ?, updated_at = ?, version = version + 1 WHERE id = ? AND version = ?"
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, user.Email, user.HashedPassword, user.FirstName, user.LastName, user.Language, user.Deleted, time.Now(), user.Id, user.Version)
 	if err != nil {
 		return -1, err
 	}
@@ -84,9 +85,9 @@ func (r *UserRepository) Update(user *models.User) (int64, error) {
 	return res.RowsAffected()
 }
 
-func (r *UserRepository) Delete(id string) (int64, error) {
+func (r *UserRepository) Delete(ctx context.Context, id string) (int64, error) {
 	query := "DELETE FROM " + TableNameUser + " WHERE id = ?"
-	res, err := r.db.Exec(query)
+	res, err := conn(ctx, r.db).ExecContext(ctx, query)
 	if err != nil {
 		return -1, err
 	}
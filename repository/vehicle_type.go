@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/mbarolo/test_back/models"
+	"github.com/mbarolo/test_back/utils"
+)
+
+type VehicleTypeRepository struct {
+	db *sql.DB
+}
+
+func NewVehicleTypeRepository(db *sql.DB) *VehicleTypeRepository {
+	return &VehicleTypeRepository{db}
+}
+
+func (r *VehicleTypeRepository) GetAll(ctx context.Context) ([]*models.VehicleType, error) {
+	query := "SELECT * FROM " + TableNameVehicleType + " ORDER BY id"
+	return utils.GenericScanAll[models.VehicleType](ctx, conn(ctx, r.db), query)
+}
+
+func (r *VehicleTypeRepository) GetById(ctx context.Context, id int64) (*models.VehicleType, error) {
+	query := "SELECT * FROM " + TableNameVehicleType + " WHERE id = ?"
+	types, err := utils.GenericScanAll[models.VehicleType](ctx, conn(ctx, r.db), query, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(types) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return types[0], nil
+}
+
+func (r *VehicleTypeRepository) GetByCode(ctx context.Context, code string) (*models.VehicleType, error) {
+	query := "SELECT * FROM " + TableNameVehicleType + " WHERE code = ?"
+	types, err := utils.GenericScanAll[models.VehicleType](ctx, conn(ctx, r.db), query, code)
+	if err != nil {
+		return nil, err
+	}
+	if len(types) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return types[0], nil
+}
+
+// Update: Actualiza el tipo solo si no cambió desde que se leyó (misma versión). El código no se modifica
+func (r *VehicleTypeRepository) Update(ctx context.Context, vehicleType *models.VehicleType) (int64, error) {
+	query := "UPDATE " + TableNameVehicleType + " SET name = ?, cost_per_minute = ?, unlock_fee = ?, range_km = ?, max_speed_kmh = ?, max_load_kg = ?, version = version + 1 WHERE id = ? AND version = ?"
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, vehicleType.Name, vehicleType.CostPerMinute, vehicleType.UnlockFee, vehicleType.RangeKm, vehicleType.MaxSpeedKmh, vehicleType.MaxLoadKg, vehicleType.Id, vehicleType.Version)
+	if err != nil {
+		return -1, err
+	}
+	return res.RowsAffected()
+}
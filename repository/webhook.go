@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/mbarolo/test_back/models"
+	"github.com/mbarolo/test_back/utils"
+)
+
+type WebhookRepository struct {
+	db *sql.DB
+}
+
+func NewWebhookRepository(db *sql.DB) *WebhookRepository {
+	return &WebhookRepository{db}
+}
+
+func (r *WebhookRepository) GetAll(ctx context.Context, spec *utils.QuerySpec) (*utils.Page[models.WebhookSubscription], error) {
+	subscriptions, err := utils.GenericScanPage[models.WebhookSubscription](ctx, conn(ctx, r.db), TableNameWebhook, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+func (r *WebhookRepository) GetById(ctx context.Context, id int64) (*models.WebhookSubscription, error) {
+	query := "SELECT * FROM " + TableNameWebhook + " WHERE id = ?"
+	subscriptions, err := utils.GenericScanAll[models.WebhookSubscription](ctx, conn(ctx, r.db), query, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(subscriptions) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return subscriptions[0], nil
+}
+
+func (r *WebhookRepository) GetActive(ctx context.Context) ([]*models.WebhookSubscription, error) {
+	query := "SELECT * FROM " + TableNameWebhook + " WHERE is_active = 1"
+	return utils.GenericScanAll[models.WebhookSubscription](ctx, conn(ctx, r.db), query)
+}
+
+func (r *WebhookRepository) Create(ctx context.Context, subscription *models.WebhookSubscription) (int64, error) {
+	query := "INSERT INTO " + TableNameWebhook + " (url, description, event_types, secret, is_active, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)"
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, subscription.Url, subscription.Description, string(subscription.EventTypes), subscription.Secret, subscription.IsActive, subscription.CreatedAt, subscription.UpdatedAt)
+	if err != nil {
+		return -1, err
+	}
+
+	return res.LastInsertId()
+}
+
+// Update: Actualiza la suscripción solo si no cambió desde que se leyó (misma versión).
+// Retorna 0 filas afectadas si la versión no coincide
+func (r *WebhookRepository) Update(ctx context.Context, subscription *models.WebhookSubscription) (int64, error) {
+	query := "UPDATE " + TableNameWebhook + " SET url = ?, description = ?, event_types = ?, secret = ?, is_active = ?, updated_at = ?, version = version + 1 WHERE id = ? AND version = ?"
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, subscription.Url, subscription.Description, string(subscription.EventTypes), subscription.Secret, subscription.IsActive, time.Now(), subscription.Id, subscription.Version)
+	if err != nil {
+		return -1, err
+	}
+	return res.RowsAffected()
+}
+
+// Delete: Elimina la suscripción junto con su historial de entregas
+func (r *WebhookRepository) Delete(ctx context.Context, id int64) error {
+	if _, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM "+TableNameDelivery+" WHERE subscription_id = ?", id); err != nil {
+		return err
+	}
+	_, err := conn(ctx, r.db).ExecContext(ctx, "DELETE FROM "+TableNameWebhook+" WHERE id = ?", id)
+	return err
+}
+
+// CreateDelivery: Crea la entrega del evento para la suscripción, si ya existía no hace nada
+func (r *WebhookRepository) CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	query := "INSERT INTO " + TableNameDelivery + " (subscription_id, event_id, event_type, status, next_attempt_at, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?) ON CONFLICT (subscription_id, event_id) DO NOTHING"
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, delivery.SubscriptionId, delivery.EventId, delivery.EventType, delivery.Status, delivery.NextAttemptAt, delivery.CreatedAt, delivery.UpdatedAt)
+	return err
+}
+
+// GetDueDeliveries: Entregas pendientes cuyo próximo intento ya venció, de suscripciones activas
+func (r *WebhookRepository) GetDueDeliveries(ctx context.Context, limit int) ([]*models.WebhookDelivery, error) {
+	query := "SELECT d.* FROM " + TableNameDelivery + " d JOIN " + TableNameWebhook + " s ON s.id = d.subscription_id" +
+		" WHERE d.status = ? AND s.is_active = 1 AND substr(d.next_attempt_at, 1, 19) <= ? ORDER BY d.next_attempt_at, d.id LIMIT ?"
+	return utils.GenericScanAll[models.WebhookDelivery](ctx, conn(ctx, r.db), query, models.DeliveryPending, time.Now().Format(time.DateTime), limit)
+}
+
+func (r *WebhookRepository) GetDeliveries(ctx context.Context, subscriptionId int64, spec *utils.QuerySpec) (*utils.Page[models.WebhookDelivery], error) {
+	spec.AddFilter("subscription_id", "=", subscriptionId)
+	deliveries, err := utils.GenericScanPage[models.WebhookDelivery](ctx, conn(ctx, r.db), TableNameDelivery, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+func (r *WebhookRepository) GetDeliveryById(ctx context.Context, id int64) (*models.WebhookDelivery, error) {
+	query := "SELECT * FROM " + TableNameDelivery + " WHERE id = ?"
+	deliveries, err := utils.GenericScanAll[models.WebhookDelivery](ctx, conn(ctx, r.db), query, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(deliveries) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return deliveries[0], nil
+}
+
+// UpdateDelivery: Guarda el resultado del último intento de la entrega
+func (r *WebhookRepository) UpdateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	query := "UPDATE " + TableNameDelivery + " SET status = ?, attempts = ?, next_attempt_at = ?, last_status_code = ?, last_error = ?, last_duration_ms = ?, updated_at = ?, delivered_at = ? WHERE id = ?"
+	_, err := conn(ctx, r.db).ExecContext(ctx, query, delivery.Status, delivery.Attempts, delivery.NextAttemptAt, delivery.LastStatusCode, delivery.LastError, delivery.LastDurationMs, time.Now(), delivery.DeliveredAt, delivery.Id)
+	return err
+}
+
+func (r *WebhookRepository) CountDeliveries(ctx context.Context, status string) (int64, error) {
+	var count int64
+	query := "SELECT COUNT(*) FROM " + TableNameDelivery + " WHERE status = ?"
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, status).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/mbarolo/test_back/models"
+	"github.com/mbarolo/test_back/utils"
+)
+
+type WorkOrderRepository struct {
+	db *sql.DB
+}
+
+func NewWorkOrderRepository(db *sql.DB) *WorkOrderRepository {
+	return &WorkOrderRepository{db}
+}
+
+func (r *WorkOrderRepository) GetPage(ctx context.Context, spec *utils.QuerySpec) (*utils.Page[models.WorkOrder], error) {
+	return utils.GenericScanPage[models.WorkOrder](ctx, conn(ctx, r.db), TableNameWorkOrder, spec)
+}
+
+func (r *WorkOrderRepository) GetById(ctx context.Context, id int64) (*models.WorkOrder, error) {
+	query := "SELECT * FROM " + TableNameWorkOrder + " WHERE id = ?"
+	orders, err := utils.GenericScanAll[models.WorkOrder](ctx, conn(ctx, r.db), query, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(orders) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return orders[0], nil
+}
+
+// GetUnresolved: Orden sin resolver de la bicicleta para la categoría, nil si no hay
+func (r *WorkOrderRepository) GetUnresolved(ctx context.Context, bikeId int64, category string) (*models.WorkOrder, error) {
+	query := "SELECT * FROM " + TableNameWorkOrder + " WHERE bike_id = ? AND category = ? AND status != ? ORDER BY id LIMIT 1"
+	orders, err := utils.GenericScanAll[models.WorkOrder](ctx, conn(ctx, r.db), query, bikeId, category, models.WorkOrderResolved)
+	if err != nil {
+		return nil, err
+	}
+	if len(orders) == 0 {
+		return nil, nil
+	}
+	return orders[0], nil
+}
+
+func (r *WorkOrderRepository) Create(ctx context.Context, order *models.WorkOrder) (int64, error) {
+	query := "INSERT INTO " + TableNameWorkOrder + " (bike_id, category, priority, status, description, mechanic, created_at, updated_at, assigned_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, order.BikeId, order.Category, order.Priority, order.Status, order.Description, order.Mechanic, order.CreatedAt, order.UpdatedAt, order.AssignedAt)
+	if err != nil {
+		return -1, err
+	}
+	return res.LastInsertId()
+}
+
+// Update: Actualiza la orden solo si no cambió desde que se leyó (misma versión)
+func (r *WorkOrderRepository) Update(ctx context.Context, order *models.WorkOrder) (int64, error) {
+	query := "UPDATE " + TableNameWorkOrder + " SET priority = ?, status = ?, description = ?, mechanic = ?, resolution = ?, updated_at = ?, assigned_at = ?, resolved_at = ?, version = version + 1 WHERE id = ? AND version = ?"
+	res, err := conn(ctx, r.db).ExecContext(ctx, query, order.Priority, order.Status, order.Description, order.Mechanic, order.Resolution, order.UpdatedAt, order.AssignedAt, order.ResolvedAt, order.Id, order.Version)
+	if err != nil {
+		return -1, err
+	}
+	return res.RowsAffected()
+}
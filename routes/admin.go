@@ -4,15 +4,53 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/mbarolo/test_back/controller"
 	"github.com/mbarolo/test_back/middleware"
+	"github.com/mbarolo/test_back/services"
 )
 
 func InitAdminRoutes(r chi.Router) {
 	r.Route("/admin", func(r chi.Router) {
 		r.Use(middleware.AdminMiddleware)
+		r.Use(rateLimit(services.RateLimitAdmin, middleware.KeyByUser))
 
 		r.Post("/bikes", controller.CreateBike)
+		r.Get("/bikes/{id}", controller.GetBikeById)
 		r.Patch("/bikes/{id}", controller.UpdateBike)
+		r.Post("/bikes/{id}/status", controller.ChangeBikeStatus)
+		r.Get("/bikes/{id}/status-history", controller.GetBikeStatusHistory)
 		r.Get("/bikes", controller.GetAllBikes)
+		r.Get("/bikes/{id}/lock-simulator", controller.GetLockSimulatorDevice)
+		r.Put("/bikes/{id}/lock-simulator", controller.UpdateLockSimulatorDevice)
+		r.Get("/bikes/{id}/telemetry", controller.GetBikeTelemetry)
+		r.Post("/bikes/{id}/device", controller.IssueDeviceToken)
+		r.Delete("/bikes/{id}/device", controller.RevokeDevice)
+		r.Get("/vehicle-types", controller.GetVehicleTypes)
+		r.Patch("/vehicle-types/{id}", controller.UpdateVehicleType)
+
+		r.Get("/bikes/{id}/work-orders", controller.GetServiceHistory)
+		r.Post("/bikes/{id}/work-orders", controller.CreateWorkOrder)
+		r.Get("/stations", controller.GetAllStations)
+		r.Post("/stations", controller.CreateStation)
+		r.Get("/stations/{id}", controller.GetStationById)
+		r.Patch("/stations/{id}", controller.UpdateStation)
+		r.Post("/stations/{id}/bikes", controller.DockBike)
+		r.Delete("/stations/{id}/bikes/{bikeId}", controller.UndockBike)
+
+		r.Get("/work-orders", controller.GetWorkOrders)
+		r.Get("/work-orders/{id}", controller.GetWorkOrder)
+		r.Patch("/work-orders/{id}", controller.UpdateWorkOrder)
+		r.Get("/damage-reports/{id}/photo", controller.GetDamagePhoto)
+
+		r.Get("/rebalancing/forecast", controller.GetRebalancingForecast)
+		r.Post("/rebalancing/plan", controller.PlanRebalancing)
+		r.Get("/rebalancing/tasks", controller.GetRebalancingTasks)
+		r.Get("/rebalancing/tasks/{id}", controller.GetRebalancingTask)
+		r.Post("/rebalancing/tasks/{id}/claim", controller.ClaimRebalancingTask)
+		r.Post("/rebalancing/tasks/{id}/complete", controller.CompleteRebalancingTask)
+
+		r.Get("/incidents", controller.GetIncidents)
+		r.Get("/incidents/{id}", controller.GetIncident)
+		r.Post("/incidents/{id}/acknowledge", controller.AcknowledgeIncident)
+		r.Post("/incidents/{id}/resolve", controller.ResolveIncident)
 
 		r.Get("/users", controller.GetAllUsers)
 		r.Get("/users/{id}", controller.GetUserById)
@@ -21,5 +59,18 @@ func InitAdminRoutes(r chi.Router) {
 		r.Get("/rentals", controller.GetAllRentals)
 		r.Get("/rentals/{id}", controller.GetRentalById)
 		r.Patch("/rentals/{id}", controller.UpdateRental)
+		r.Get("/rentals/{id}/route", controller.GetAdminRentalRoute)
+
+		r.Get("/audit", controller.GetAuditLog)
+		r.Get("/audit/export", controller.ExportAuditLog)
+		r.Get("/audit/verify", controller.VerifyAuditLog)
+
+		r.Get("/webhooks", controller.GetAllWebhooks)
+		r.Post("/webhooks", controller.CreateWebhook)
+		r.Get("/webhooks/{id}", controller.GetWebhookById)
+		r.Patch("/webhooks/{id}", controller.UpdateWebhook)
+		r.Delete("/webhooks/{id}", controller.DeleteWebhook)
+		r.Get("/webhooks/{id}/deliveries", controller.GetWebhookDeliveries)
+		r.Post("/webhooks/{id}/deliveries/{deliveryId}/retry", controller.RetryWebhookDelivery)
 	})
 }
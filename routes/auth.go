@@ -3,10 +3,13 @@ package routes
 import (
 	"github.com/go-chi/chi/v5"
 	"github.com/mbarolo/test_back/controller"
+	"github.com/mbarolo/test_back/middleware"
+	"github.com/mbarolo/test_back/services"
 )
 
 func InitAuthRoutes(r chi.Router) {
 	r.Route("/auth", func(r chi.Router) {
+		r.Use(rateLimit(services.RateLimitAuth, middleware.KeyByIP))
 		r.Post("/login", controller.Login)
 		r.Post("/register", controller.Register)
 	})
@@ -4,11 +4,15 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/mbarolo/test_back/controller"
 	"github.com/mbarolo/test_back/middleware"
+	"github.com/mbarolo/test_back/services"
 )
 
 func InitBikeRoutes(r chi.Router) {
 	r.Route("/bikes", func(r chi.Router) {
 		r.Use(middleware.AuthMiddleware)
+		r.Use(rateLimit(services.RateLimitAPI, middleware.KeyByUser))
 		r.Get("/available", controller.GetAvailableBikes)
+		r.Get("/stream", controller.StreamBikes)
+		r.Post("/{id}/reports", controller.ReportDamage)
 	})
 }
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/mbarolo/test_back/controller"
+	"github.com/mbarolo/test_back/middleware"
+	"github.com/mbarolo/test_back/services"
+)
+
+// InitDeviceRoutes: Rutas de los dispositivos IoT de las bicicletas, autenticados con su propio token
+func InitDeviceRoutes(r chi.Router) {
+	r.Route("/devices", func(r chi.Router) {
+		r.Use(middleware.DeviceAuth(services.AuthenticateDevice))
+		r.Use(rateLimit(services.RateLimitDevices, middleware.KeyByDevice))
+		r.Post("/telemetry", controller.IngestTelemetry)
+	})
+}
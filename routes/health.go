@@ -0,0 +1,12 @@
+package routes
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/mbarolo/test_back/controller"
+)
+
+// InitHealthRoutes: Probes de liveness y readiness, fuera de /api/v1 y sin autenticación
+func InitHealthRoutes(r chi.Router) {
+	r.Get("/healthz", controller.Healthz)
+	r.Get("/readyz", controller.Readyz)
+}
@@ -4,13 +4,20 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/mbarolo/test_back/controller"
 	"github.com/mbarolo/test_back/middleware"
+	"github.com/mbarolo/test_back/services"
 )
 
 func InitRentalRoutes(r chi.Router) {
 	r.Route("/rentals", func(r chi.Router) {
 		r.Use(middleware.AuthMiddleware)
+		r.Use(rateLimit(services.RateLimitAPI, middleware.KeyByUser))
 		r.Get("/history", controller.GetUserRentalHistory)
-		r.Post("/start", controller.StartRental)
-		r.Post("/end", controller.EndRental)
+		r.Get("/{id}/route", controller.GetRentalRoute)
+		r.Post("/{id}/route", controller.RecordRoute)
+
+		// los reintentos de inicio y fin de alquiler no deben duplicar el alquiler ni el cobro
+		idempotent := middleware.Idempotency(services.IdempotencyStore(), middleware.IdempotencyTTL)
+		r.With(idempotent).Post("/start", controller.StartRental)
+		r.With(idempotent).Post("/end", controller.EndRental)
 	})
 }
@@ -4,6 +4,9 @@ import (
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/mbarolo/test_back/i18n"
+	"github.com/mbarolo/test_back/middleware"
+	"github.com/mbarolo/test_back/services"
 	"github.com/mbarolo/test_back/utils"
 )
 
@@ -12,15 +15,25 @@ type Rotuer struct {
 }
 
 func InitRoutes(r chi.Router) {
+	InitHealthRoutes(r)
+
 	r.Route("/api/v1", func(r chi.Router) {
 		InitAuthRoutes(r)
 		InitUserRoutes(r)
 		InitBikeRoutes(r)
 		InitRentalRoutes(r)
+		InitDeviceRoutes(r)
+		InitVehicleTypeRoutes(r)
+		InitStationRoutes(r)
 		InitAdminRoutes(r)
 
 		r.Get("/status", func(w http.ResponseWriter, r *http.Request) {
-			utils.JsonResponse(w, http.StatusOK, "ok", nil)
+			utils.JsonResponse(w, http.StatusOK, i18n.T(r, "status.ok"), nil)
 		})
 	})
 }
+
+// rateLimit: Middleware de rate limiting del grupo de rutas, con el límite de la configuración
+func rateLimit(scope string, key middleware.RateLimitKey) func(http.Handler) http.Handler {
+	return middleware.RateLimit(scope, services.RateLimitStore(), services.RateLimit(scope), key)
+}
@@ -4,11 +4,13 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/mbarolo/test_back/controller"
 	"github.com/mbarolo/test_back/middleware"
+	"github.com/mbarolo/test_back/services"
 )
 
 func InitUserRoutes(r chi.Router) {
 	r.Route("/users", func(r chi.Router) {
 		r.Use(middleware.AuthMiddleware)
+		r.Use(rateLimit(services.RateLimitAPI, middleware.KeyByUser))
 		r.Get("/profile", controller.GetProfile)
 		r.Patch("/profile", controller.UpdateProfile)
 	})
@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/mbarolo/test_back/controller"
+	"github.com/mbarolo/test_back/middleware"
+	"github.com/mbarolo/test_back/services"
+)
+
+func InitVehicleTypeRoutes(r chi.Router) {
+	r.Route("/vehicle-types", func(r chi.Router) {
+		r.Use(middleware.AuthMiddleware)
+		r.Use(rateLimit(services.RateLimitAPI, middleware.KeyByUser))
+		r.Get("/", controller.GetVehicleTypes)
+	})
+}
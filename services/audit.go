@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/mbarolo/test_back/logging"
+	"github.com/mbarolo/test_back/middleware"
+	"github.com/mbarolo/test_back/models"
+	"github.com/mbarolo/test_back/utils"
+)
+
+// Tipos de entidad auditados
+const (
+	AuditEntityUser        = "user"
+	AuditEntityBike        = "bike"
+	AuditEntityRental      = "rental"
+	AuditEntityWebhook     = "webhook"
+	AuditEntityDevice      = "device"
+	AuditEntityVehicleType = "vehicle_type"
+	AuditEntityWorkOrder   = "work_order"
+	AuditEntityDamage      = "damage_report"
+	AuditEntityStation     = "station"
+	AuditEntityRebalancing = "rebalancing_task"
+	AuditEntityIncident    = "incident"
+)
+
+// AuditVerification: Resultado de verificar la cadena de hashes del log de auditoría.
+// BrokenAt es el id del primer registro cuyo hash no coincide, nil si la cadena está íntegra
+type AuditVerification struct {
+	Valid    bool   `json:"valid"`
+	Checked  int64  `json:"checked"`
+	BrokenAt *int64 `json:"broken_at"`
+}
+
+// corta el recorrido de la cadena en el primer registro inválido
+var errAuditChainBroken = errors.New("cadena de auditoría rota")
+
+// snapshot: Estado de una entidad en JSON, se toma antes de modificarla para calcular el diff
+func snapshot(v interface{}) []byte {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// recordAudit: Registra la acción con el diff entre before (snapshot previo, nil en una creación) y el estado
+// nuevo de la entidad. El actor, el request id y la IP se toman del contexto de la request.
+// Debe llamarse dentro de la misma transacción que el cambio
+func recordAudit(ctx context.Context, action string, entityType string, entityId int64, before []byte, after interface{}) error {
+	changes, err := utils.JSONDiff(before, snapshot(after))
+	if err != nil {
+		return err
+	}
+
+	entry := &models.AuditEntry{
+		Actor:      auditActor(ctx),
+		Action:     action,
+		EntityType: entityType,
+		EntityId:   entityId,
+		Changes:    changes,
+		RequestId:  logging.RequestID(ctx),
+		Ip:         middleware.ClientIPFromContext(ctx),
+		CreatedAt:  time.Now(),
+	}
+	if _, err := auditRepo.Append(ctx, entry); err != nil {
+		slog.ErrorContext(ctx, "append audit entry failed", "action", action, "error", err)
+		return err
+	}
+	return nil
+}
+
+// auditActor: Usuario autenticado (user:<id>), el administrador o anonymous si la request no está autenticada
+func auditActor(ctx context.Context) string {
+	switch claims := ctx.Value("claims").(type) {
+	case *middleware.Claims:
+		return "user:" + claims.Sub
+	case string:
+		return models.ActorAdmin
+	}
+	if logging.RequestID(ctx) == "" {
+		return models.ActorSystem
+	}
+	return models.ActorAnonymous
+}
+
+func GetAuditLog(ctx context.Context, spec *utils.QuerySpec) (*utils.Page[models.AuditEntry], error) {
+	entries, err := auditRepo.GetAll(ctx, spec)
+	if err != nil {
+		slog.ErrorContext(ctx, "list audit log failed", "error", err)
+		return nil, err
+	}
+	return entries, nil
+}
+
+// ExportAuditLog: Escribe los registros filtrados en w, en orden de id, con el formato de encode
+func ExportAuditLog(ctx context.Context, spec *utils.QuerySpec, w io.Writer, encode func(w io.Writer, entry *models.AuditEntry) error) error {
+	var count int64
+	err := auditRepo.Each(ctx, spec, func(entry *models.AuditEntry) error {
+		count++
+		return encode(w, entry)
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "export audit log failed", "exported", count, "error", err)
+		return err
+	}
+
+	slog.InfoContext(ctx, "audit log exported", "count", count)
+	return nil
+}
+
+// VerifyAuditLog: Recorre la cadena completa y verifica que cada registro apunte al hash del anterior
+// y que su hash corresponda a su contenido
+func VerifyAuditLog(ctx context.Context) (*AuditVerification, error) {
+	result := &AuditVerification{Valid: true}
+	prevHash := ""
+	err := auditRepo.Each(ctx, &utils.QuerySpec{}, func(entry *models.AuditEntry) error {
+		result.Checked++
+		if entry.PrevHash != prevHash || entry.ComputeHash() != entry.Hash {
+			result.Valid = false
+			result.BrokenAt = &entry.Id
+			return errAuditChainBroken
+		}
+		prevHash = entry.Hash
+		return nil
+	})
+	if err != nil && !errors.Is(err, errAuditChainBroken) {
+		slog.ErrorContext(ctx, "verify audit log failed", "error", err)
+		return nil, err
+	}
+
+	if !result.Valid {
+		slog.WarnContext(ctx, "audit log chain broken", "entry_id", *result.BrokenAt)
+	}
+	return result, nil
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mbarolo/test_back/config"
+	"github.com/mbarolo/test_back/logging"
+	"github.com/mbarolo/test_back/models"
+	"github.com/mbarolo/test_back/utils"
+)
+
+// auditEntries: Registros de la entidad en orden de id
+func auditEntries(t *testing.T, entityType string, entityId int64) []*models.AuditEntry {
+	t.Helper()
+	spec := &utils.QuerySpec{}
+	spec.AddFilter("entity_type", "=", entityType)
+	spec.AddFilter("entity_id", "=", entityId)
+	entries := []*models.AuditEntry{}
+	err := auditRepo.Each(context.Background(), spec, func(entry *models.AuditEntry) error {
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+// rewriteAuditEntry: Sobrescribe el contenido y los hashes del registro sin pasar por el trigger que lo impide,
+// como lo haría alguien con acceso directo a la base de datos. El trigger se vuelve a crear al terminar
+func rewriteAuditEntry(t *testing.T, entry *models.AuditEntry) {
+	t.Helper()
+	var trigger string
+	if err := config.DB.QueryRow("SELECT sql FROM sqlite_master WHERE type = 'trigger' AND name = 'audit_log_no_update'").Scan(&trigger); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := config.DB.Exec("DROP TRIGGER audit_log_no_update"); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if _, err := config.DB.Exec(trigger); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	if _, err := config.DB.Exec("UPDATE audit_log SET changes = ?, prev_hash = ?, hash = ? WHERE id = ?", string(entry.Changes), entry.PrevHash, entry.Hash, entry.Id); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func verifyAuditLog(t *testing.T) *AuditVerification {
+	t.Helper()
+	result, err := VerifyAuditLog(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestVerifyAuditLog(t *testing.T) {
+	ctx := context.Background()
+	entityId := time.Now().UnixNano()
+	for i := range 3 {
+		if err := recordAudit(ctx, "test.update", "test", entityId, nil, map[string]int{"step": i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries := auditEntries(t, "test", entityId)
+	if len(entries) != 3 {
+		t.Fatalf("registros = %d, se esperaban 3", len(entries))
+	}
+
+	if result := verifyAuditLog(t); !result.Valid || result.BrokenAt != nil || result.Checked < 3 {
+		t.Fatalf("verificación = %+v, se esperaba la cadena íntegra", result)
+	}
+
+	middle, last := *entries[1], *entries[2]
+	tests := []struct {
+		name   string
+		change func() *models.AuditEntry
+		broken int64
+	}{
+		{
+			// el contenido ya no corresponde al hash guardado
+			name: "changed row",
+			change: func() *models.AuditEntry {
+				entry := middle
+				entry.Changes = json.RawMessage(`{"step":{"from":null,"to":5}}`)
+				return &entry
+			},
+			broken: middle.Id,
+		},
+		{
+			// el hash se recalcula, pero el registro ya no apunta al anterior
+			name: "broken prev_hash",
+			change: func() *models.AuditEntry {
+				entry := last
+				entry.PrevHash = strings.Repeat("0", 64)
+				entry.Hash = entry.ComputeHash()
+				return &entry
+			},
+			broken: last.Id,
+		},
+		{
+			// el registro se rehace con hashes válidos, el siguiente ya no apunta a él
+			name: "rehashed row",
+			change: func() *models.AuditEntry {
+				entry := middle
+				entry.Changes = json.RawMessage(`{"step":{"from":null,"to":5}}`)
+				entry.Hash = entry.ComputeHash()
+				return &entry
+			},
+			broken: last.Id,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tampered := tt.change()
+			original := middle
+			if tampered.Id == last.Id {
+				original = last
+			}
+			rewriteAuditEntry(t, tampered)
+			defer rewriteAuditEntry(t, &original)
+
+			result := verifyAuditLog(t)
+			if result.Valid || result.BrokenAt == nil || *result.BrokenAt != tt.broken {
+				t.Errorf("verificación = %+v, se esperaba rota en %d", result, tt.broken)
+			}
+		})
+	}
+
+	if result := verifyAuditLog(t); !result.Valid {
+		t.Errorf("verificación = %+v, se esperaba íntegra al restaurar los registros", result)
+	}
+}
+
+func TestRecordAuditRedacted(t *testing.T) {
+	ctx := context.Background()
+	entityId := time.Now().UnixNano()
+	before := snapshot(map[string]string{"email": "old@test.com", "hashed_password": "old-hash", "secret": "old-secret"})
+	after := map[string]string{"email": "new@test.com", "hashed_password": "new-hash", "secret": "new-secret", "password": "new-password"}
+	if err := recordAudit(ctx, "test.update", "test", entityId, before, after); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := auditEntries(t, "test", entityId)
+	if len(entries) != 1 {
+		t.Fatalf("registros = %d, se esperaba 1", len(entries))
+	}
+	var changes map[string]struct {
+		From *string `json:"from"`
+		To   *string `json:"to"`
+	}
+	if err := json.Unmarshal(entries[0].Changes, &changes); err != nil {
+		t.Fatal(err)
+	}
+
+	if email := changes["email"]; email.From == nil || *email.From != "old@test.com" || email.To == nil || *email.To != "new@test.com" {
+		t.Errorf("email = %s, se esperaba el valor sin ocultar", entries[0].Changes)
+	}
+	for _, field := range []string{"password", "hashed_password", "secret"} {
+		change, ok := changes[field]
+		if !ok || change.To == nil || *change.To != logging.Redacted {
+			t.Errorf("%s = %s, se esperaba el valor oculto", field, entries[0].Changes)
+		}
+		// sin valor anterior se registra null, para que se vea que el campo se creó
+		if field == "password" && change.From != nil {
+			t.Errorf("%s.from = %q, se esperaba null", field, *change.From)
+		}
+	}
+	for _, value := range []string{"old-hash", "new-hash", "old-secret", "new-secret", "new-password"} {
+		if strings.Contains(string(entries[0].Changes), value) {
+			t.Errorf("el diff guardado contiene %q: %s", value, entries[0].Changes)
+		}
+	}
+}
@@ -1,31 +1,65 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"strconv"
 
+	"github.com/mbarolo/test_back/apperror"
+	"github.com/mbarolo/test_back/i18n"
 	"github.com/mbarolo/test_back/middleware"
 	"github.com/mbarolo/test_back/models"
+	"github.com/mbarolo/test_back/tracing"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func GetCurrentUser(r *http.Request) (*models.User, error) {
 	// Se obtienen los claims del token
 	claims, ok := r.Context().Value("claims").(*middleware.Claims)
 	if !ok {
-		return nil, errors.New("Error al validar los claims del token")
+		return nil, apperror.Unauthorized("auth.claims_invalid")
 	}
 
 	// Sacamos el id del user logeado
 	userID, err := strconv.ParseInt(claims.Sub, 10, 64)
 	if err != nil {
-		return nil, err
+		return nil, apperror.Wrap(apperror.UNAUTHORIZED, "auth.claims_invalid", err)
 	}
 
-	user, err := GetUserById(userID)
+	user, err := GetUserById(r.Context(), userID)
 	if err != nil {
+		var appErr *apperror.Error
+		if errors.As(err, &appErr) && appErr.Code == apperror.NOT_FOUND {
+			return nil, apperror.Unauthorized("auth.token_user_not_found")
+		}
 		return nil, err
 	}
 
+	// la preferencia de idioma del usuario tiene prioridad sobre el header Accept-Language
+	if user.Language != "" {
+		i18n.SetLang(r.Context(), i18n.Lang(user.Language))
+	}
+
 	return user, nil
 }
+
+// HashPassword: Hashea la contraseña con bcrypt
+func HashPassword(ctx context.Context, password string) (string, error) {
+	_, span := tracing.Start(ctx, "bcrypt.GenerateFromPassword")
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	tracing.End(span, err)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+// ComparePassword: Verifica la contraseña contra su hash bcrypt
+func ComparePassword(ctx context.Context, hash string, password string) error {
+	_, span := tracing.Start(ctx, "bcrypt.CompareHashAndPassword")
+	defer span.End()
+
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+}
@@ -1,95 +1,179 @@
 package services
 
 import (
-	"log"
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"sort"
 	"time"
 
+	"github.com/mbarolo/test_back/apperror"
 	"github.com/mbarolo/test_back/forms"
+	"github.com/mbarolo/test_back/logging"
 	"github.com/mbarolo/test_back/models"
+	"github.com/mbarolo/test_back/stream"
+	"github.com/mbarolo/test_back/utils"
 )
 
-func GetAvailableBikes() ([]*models.Bike, error) {
-	if bikes, err := bikeRepo.GetAllAvailable(); err != nil {
-		log.Printf("Error al obtener las bicicletas: %v", err.Error())
+// GetAvailableBikes: Bicicletas disponibles según los filtros, con query.Near ordenadas por distancia
+func GetAvailableBikes(ctx context.Context, query *forms.AvailableBikesQuery) ([]*models.Bike, error) {
+	var bbox *stream.BBox
+	if query.Near != nil {
+		minLat, minLng, maxLat, maxLng := utils.BoundingBox(query.Near[0], query.Near[1], query.RadiusM)
+		bbox = &stream.BBox{MinLatitude: minLat, MinLongitude: minLng, MaxLatitude: maxLat, MaxLongitude: maxLng}
+	}
+
+	bikes, err := bikeRepo.GetAllAvailable(ctx, query.VehicleType, query.MinBattery, bbox)
+	if err != nil {
+		slog.ErrorContext(ctx, "get available bikes failed", "error", err)
 		return nil, err
-	} else {
-		log.Printf("Bicicletas obtenidas")
-		return bikes, nil
 	}
+
+	if query.Near != nil {
+		bikes = nearestBikes(bikes, query.Near[0], query.Near[1], query.RadiusM)
+	}
+	slog.DebugContext(ctx, "available bikes retrieved", "count", len(bikes))
+	return bikes, nil
 }
 
-func GetAllBikes() ([]*models.Bike, error) {
-	if bikes, err := bikeRepo.GetAll(); err != nil {
-		log.Printf("Error al obtener las bicicletas: %v", err.Error())
+// nearestBikes: Bicicletas a menos de radiusM metros, de la más cercana a la más lejana
+func nearestBikes(bikes []*models.Bike, lat, lng, radiusM float64) []*models.Bike {
+	distances := make(map[int64]float64, len(bikes))
+	nearby := make([]*models.Bike, 0, len(bikes))
+	for _, bike := range bikes {
+		distance := utils.HaversineDistance(lat, lng, bike.Latitude, bike.Longitude)
+		if distance <= radiusM {
+			distances[bike.Id] = distance
+			nearby = append(nearby, bike)
+		}
+	}
+	sort.Slice(nearby, func(i, j int) bool { return distances[nearby[i].Id] < distances[nearby[j].Id] })
+	return nearby
+}
+
+func GetAllBikes(ctx context.Context, spec *utils.QuerySpec) (*utils.Page[models.Bike], error) {
+	if bikes, err := bikeRepo.GetAll(ctx, spec); err != nil {
+		slog.ErrorContext(ctx, "list bikes failed", "error", err)
 		return nil, err
 	} else {
-		log.Printf("Bicicletas obtenidas")
+		slog.DebugContext(ctx, "bikes listed", "count", len(bikes.Items), "total_count", bikes.TotalCount)
 		return bikes, nil
 	}
 }
 
-func GetBikeById(id int64) (*models.Bike, error) {
-	if bike, err := bikeRepo.GetById(id); err != nil {
-		log.Printf("Error al obtener la bicicleta: %v", err.Error())
+func GetBikeById(ctx context.Context, id int64) (*models.Bike, error) {
+	if bike, err := bikeRepo.GetById(ctx, id); errors.Is(err, sql.ErrNoRows) {
+		return nil, apperror.NotFound("bike.not_found")
+	} else if err != nil {
+		slog.ErrorContext(ctx, "get bike failed", "bike_id", id, "error", err)
 		return nil, err
 	} else {
-		log.Printf("Bicicletas obtenidas")
+		slog.DebugContext(ctx, "bike retrieved", "bike_id", id)
 		return bike, nil
 	}
 }
 
-func CreateBike(bike *models.Bike) (*models.Bike, error) {
+// CreateBike: Crea la bicicleta, sin cost_per_minute_override cobra la tarifa de su tipo de vehículo
+func CreateBike(ctx context.Context, bikeForm *forms.BikeForm) (*models.Bike, error) {
+	bike := bikeForm.ToBike()
 	if err := bike.ValidateFields(); err != nil {
 		return nil, err
 	}
 
 	bike.CreatedAt = time.Now()
 	bike.UpdatedAt = time.Now()
-	bike.IsAvailable = true
-
-	id, err := bikeRepo.CreateBike(bike)
+	bike.Status = models.BikeAvailable
+
+	err := inTx(ctx, func(ctx context.Context) error {
+		if _, err := vehicleTypeFor(ctx, bike.VehicleType); err != nil {
+			return err
+		}
+
+		id, err := bikeRepo.CreateBike(ctx, bike)
+		if err != nil {
+			slog.ErrorContext(ctx, "create bike failed", "error", err)
+			return err
+		}
+
+		change := &models.BikeStatusChange{BikeId: id, ToStatus: bike.Status, Reason: models.StatusReasonCreated, Actor: auditActor(ctx), CreatedAt: bike.CreatedAt}
+		if err := recordBikeStatus(ctx, change); err != nil {
+			return err
+		}
+
+		// se vuelve a leer para obtener la tarifa vigente y la autonomía estimada
+		if bike, err = bikeRepo.GetById(ctx, id); err != nil {
+			return err
+		}
+		if err := recordAudit(ctx, "bike.create", AuditEntityBike, bike.Id, nil, bike); err != nil {
+			return err
+		}
+		return publishEvent(ctx, models.EventBikeCreated, AuditEntityBike, bike.Id, bike)
+	})
 	if err != nil {
-		log.Println("Error al crear la bicicleta: ", err.Error())
 		return nil, err
 	}
 
-	bike.Id = id
-
+	logging.AddAttrs(ctx, slog.Int64("bike_id", bike.Id))
+	slog.InfoContext(ctx, "bike created")
+	publishBike(bike, nil)
 	return bike, nil
 }
 
-func UpdateBike(id int64, updatedBike *forms.BikeForm) (*models.Bike, error) {
-	originalBike, err := GetBikeById(id)
-	if err != nil {
-		log.Println("Error al obtener la bicicleta original: ", err.Error())
-		return nil, err
-	}
-
-	if updatedBike.CostPerMinute != nil {
-		originalBike.CostPerMinute = *updatedBike.CostPerMinute
-	}
-	if updatedBike.IsAvailable != nil {
-		originalBike.IsAvailable = *updatedBike.IsAvailable
-	}
-	if updatedBike.Longitude != nil {
-		originalBike.Longitude = *updatedBike.Longitude
-	}
-	if updatedBike.Latitude != nil {
-		originalBike.Latitude = *updatedBike.Latitude
-	}
-
-	if err := originalBike.ValidateFields(); err != nil {
-		log.Println("Error al validar los campos actualizados de la bicicleta: ", err.Error())
-		return nil, err
-	}
-
-	originalBike.UpdatedAt = time.Now()
-
-	_, err = bikeRepo.UpdateBike(originalBike)
+// UpdateBike: Aplica un JSON Merge Patch a la bicicleta. Si ifMatch no es nil debe coincidir con la versión actual
+func UpdateBike(ctx context.Context, id int64, patch []byte, ifMatch *int64) (*models.Bike, error) {
+	logging.AddAttrs(ctx, slog.Int64("bike_id", id))
+
+	var originalBike *models.Bike
+	var previous *stream.Point
+	err := inTx(ctx, func(ctx context.Context) error {
+		var err error
+		originalBike, err = GetBikeById(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := checkVersion(ifMatch, originalBike.Version); err != nil {
+			return err
+		}
+
+		before := snapshot(originalBike)
+		previous = bikePoint(originalBike)
+		if err := utils.MergePatch(originalBike, patch); err != nil {
+			return err
+		}
+
+		if err := originalBike.ValidateFields(); err != nil {
+			return err
+		}
+		if _, err := vehicleTypeFor(ctx, originalBike.VehicleType); err != nil {
+			return err
+		}
+
+		originalBike.UpdatedAt = time.Now()
+
+		rows, err := bikeRepo.UpdateBike(ctx, originalBike)
+		if err != nil {
+			slog.ErrorContext(ctx, "update bike failed", "error", err)
+			return err
+		}
+		if rows == 0 {
+			return apperror.New(apperror.PRECONDITION, "error.version_mismatch")
+		}
+		if originalBike, err = bikeRepo.GetById(ctx, id); err != nil {
+			return err
+		}
+
+		if err := recordAudit(ctx, "bike.update", AuditEntityBike, id, before, originalBike); err != nil {
+			return err
+		}
+		return publishEvent(ctx, models.EventBikeUpdated, AuditEntityBike, id, originalBike)
+	})
 	if err != nil {
-		log.Println("Error al actualizar la bicicleta: ", err.Error())
 		return nil, err
 	}
 
+	slog.InfoContext(ctx, "bike updated", "version", originalBike.Version)
+	publishBike(originalBike, previous)
 	return originalBike, nil
 }
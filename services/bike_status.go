@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/mbarolo/test_back/apperror"
+	"github.com/mbarolo/test_back/forms"
+	"github.com/mbarolo/test_back/logging"
+	"github.com/mbarolo/test_back/models"
+	"github.com/mbarolo/test_back/utils"
+)
+
+// ChangeBikeStatus: Cambia el estado de la bicicleta desde el admin. Si ifMatch no es nil debe coincidir con la versión actual.
+// Una bicicleta alquilada solo puede marcarse como perdida, vuelve a estar disponible al terminar el alquiler
+func ChangeBikeStatus(ctx context.Context, id int64, form *forms.BikeStatusForm, ifMatch *int64) (*models.Bike, error) {
+	logging.AddAttrs(ctx, slog.Int64("bike_id", id))
+
+	to := models.BikeStatus(form.Status)
+	var bike *models.Bike
+	var from models.BikeStatus
+	err := inTx(ctx, func(ctx context.Context) error {
+		var err error
+		bike, err = GetBikeById(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(ifMatch, bike.Version); err != nil {
+			return err
+		}
+		if bike.Status == models.BikeRented && to != models.BikeMissing {
+			return apperror.Conflict("bike.in_rental")
+		}
+
+		before := snapshot(bike)
+		from = bike.Status
+		if err := changeBikeStatus(ctx, bike, to, form.Reason); err != nil {
+			return err
+		}
+
+		rows, err := bikeRepo.UpdateBike(ctx, bike)
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return apperror.New(apperror.PRECONDITION, "error.version_mismatch")
+		}
+		bike.Version++
+
+		return recordAudit(ctx, "bike.status", AuditEntityBike, id, before, bike)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "bike status changed", "from", from, "to", to)
+	publishBike(bike, nil)
+	return bike, nil
+}
+
+// changeBikeStatus: Valida la transición, la registra en el historial y asigna el estado nuevo a la bicicleta.
+// Debe llamarse dentro de la transacción que guarda la bicicleta
+func changeBikeStatus(ctx context.Context, bike *models.Bike, to models.BikeStatus, reason string) error {
+	if !bike.Status.CanTransition(to) {
+		return apperror.Conflict("bike.invalid_status_transition").WithParams(map[string]interface{}{"from": bike.Status, "to": to})
+	}
+
+	from := bike.Status
+	change := &models.BikeStatusChange{BikeId: bike.Id, FromStatus: &from, ToStatus: to, Reason: reason, Actor: auditActor(ctx), CreatedAt: time.Now()}
+	if err := recordBikeStatus(ctx, change); err != nil {
+		return err
+	}
+	bike.Status = to
+	// una bicicleta perdida no ocupa el anclaje donde estaba
+	if to == models.BikeMissing {
+		bike.StationId, bike.Dock = nil, nil
+	}
+	return nil
+}
+
+// recordBikeStatus: Guarda el cambio de estado y su evento. Debe llamarse dentro de una transacción
+func recordBikeStatus(ctx context.Context, change *models.BikeStatusChange) error {
+	id, err := bikeStatusRepo.Create(ctx, change)
+	if err != nil {
+		return err
+	}
+	change.Id = id
+	return publishEvent(ctx, models.EventBikeStatusChanged, AuditEntityBike, change.BikeId, change)
+}
+
+// GetBikeStatusHistory: Cambios de estado de la bicicleta
+func GetBikeStatusHistory(ctx context.Context, bikeId int64, spec *utils.QuerySpec) (*utils.Page[models.BikeStatusChange], error) {
+	if _, err := GetBikeById(ctx, bikeId); err != nil {
+		return nil, err
+	}
+
+	spec.AddFilter("bike_id", "=", bikeId)
+	return bikeStatusRepo.GetPage(ctx, spec)
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/mbarolo/test_back/apperror"
+	"github.com/mbarolo/test_back/config"
+)
+
+// tiempo máximo que espera la verificación de la base de datos
+const readinessTimeout = 2 * time.Second
+
+// se activa al recibir la señal de apagado, para que el balanceador deje de enviar tráfico
+var draining atomic.Bool
+
+// StartDraining: Marca el servicio como no listo mientras se completan las requests en curso
+func StartDraining() {
+	draining.Store(true)
+	// los streams no terminan solos, se cierran para que el apagado no espere al timeout
+	bikeHub.Close()
+}
+
+// WaitForStreams: Espera a que terminen los streams cerrados por StartDraining. Las conexiones WebSocket
+// no las espera server.Shutdown porque ya no pertenecen al servidor HTTP
+func WaitForStreams(ctx context.Context) error {
+	return bikeHub.Wait(ctx)
+}
+
+// CheckReadiness: Verifica que el servicio pueda atender requests: que no se esté apagando,
+// que la base de datos responda y que no queden migraciones pendientes
+func CheckReadiness(ctx context.Context) error {
+	if draining.Load() {
+		return apperror.Unavailable("health.shutting_down", nil)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+
+	if err := sqliteConnection.DB.PingContext(ctx); err != nil {
+		return apperror.Unavailable("health.db_unavailable", err)
+	}
+
+	pending, err := config.PendingMigrations(ctx)
+	if err != nil {
+		return apperror.Unavailable("health.db_unavailable", err)
+	}
+	if len(pending) > 0 {
+		return apperror.Unavailable("health.migrations_pending", nil).
+			WithParams(map[string]interface{}{"columns": strings.Join(pending, ", ")})
+	}
+
+	return nil
+}
@@ -0,0 +1,37 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/mbarolo/test_back/middleware"
+)
+
+// IdempotencyStore: Almacenamiento de las claves de idempotencia usado por middleware.Idempotency
+func IdempotencyStore() middleware.IdempotencyStore {
+	return idempotencyRepo
+}
+
+// CleanExpiredIdempotencyKeys: Elimina periódicamente las claves de idempotencia vencidas, hasta que se cancele el contexto
+func CleanExpiredIdempotencyKeys(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		deleted, err := idempotencyRepo.DeleteExpired(ctx)
+		if err != nil {
+			slog.Error("delete expired idempotency keys failed", "error", err)
+			continue
+		}
+		if deleted > 0 {
+			slog.Info("expired idempotency keys deleted", "count", deleted)
+		}
+	}
+}
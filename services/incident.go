@@ -0,0 +1,500 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/mbarolo/test_back/apperror"
+	"github.com/mbarolo/test_back/config"
+	"github.com/mbarolo/test_back/forms"
+	"github.com/mbarolo/test_back/i18n"
+	"github.com/mbarolo/test_back/lock"
+	"github.com/mbarolo/test_back/logging"
+	"github.com/mbarolo/test_back/models"
+	"github.com/mbarolo/test_back/notify"
+	"github.com/mbarolo/test_back/stream"
+	"github.com/mbarolo/test_back/utils"
+)
+
+// incidentNotifier: Destino de los avisos de incidentes, con el driver de incidents.notifier
+var incidentNotifier notify.Notifier = notify.LogNotifier{}
+
+// alertsInFlight: Avisos que se están enviando en segundo plano
+var alertsInFlight sync.WaitGroup
+
+// InitIncidents: Crea el notificador de los avisos de incidentes
+func InitIncidents(cfg config.IncidentsConfig) error {
+	notifier, err := notify.New(cfg.Notifier, cfg.NotifierURL, cfg.NotifierTimeout)
+	if err != nil {
+		return err
+	}
+	incidentNotifier = notifier
+	return nil
+}
+
+// ruleInput: Estado de la bicicleta sobre el que se evalúan las reglas. Previous es la posición anterior y solo
+// se conoce al recibir telemetría, Rental es el alquiler en curso
+type ruleInput struct {
+	Bike     *models.Bike
+	Previous *stream.Point
+	Rental   *models.Rental
+	Now      time.Time
+	Config   config.IncidentsConfig
+	Area     [][2]float64
+}
+
+// finding: Coincidencia de una regla
+type finding struct {
+	Severity string
+	RentalId *int64
+	Details  map[string]interface{}
+}
+
+// incidentRule: Regla de detección. Con AutoResolve el incidente se resuelve solo cuando la regla deja de cumplirse,
+// sin AutoResolve (eventos puntuales como un movimiento) lo tiene que resolver el admin
+type incidentRule struct {
+	Name        string
+	AutoResolve bool
+	Check       func(in *ruleInput) *finding
+}
+
+var incidentRules = []incidentRule{
+	{Name: models.RuleMovementWithoutRental, Check: checkMovementWithoutRental},
+	{Name: models.RuleNoSignal, AutoResolve: true, Check: checkNoSignal},
+	{Name: models.RuleOutsideServiceArea, AutoResolve: true, Check: checkOutsideServiceArea},
+	{Name: models.RuleRentalOverdue, AutoResolve: true, Check: checkRentalOverdue},
+}
+
+// checkMovementWithoutRental: La bicicleta se movió más de incidents.movement_threshold metros sin un alquiler en curso.
+// En mantenimiento los mecánicos la mueven, y si el candado sigue cerrado la están cargando
+func checkMovementWithoutRental(in *ruleInput) *finding {
+	if in.Previous == nil || in.Rental != nil {
+		return nil
+	}
+	switch in.Bike.Status {
+	case models.BikeAvailable, models.BikeReserved, models.BikeMissing:
+	default:
+		return nil
+	}
+
+	distance := utils.HaversineDistance(in.Previous.Latitude, in.Previous.Longitude, in.Bike.Latitude, in.Bike.Longitude)
+	if distance <= float64(in.Config.MovementThreshold) {
+		return nil
+	}
+	severity := models.SeverityHigh
+	if in.Bike.LockState != nil && *in.Bike.LockState == lock.StateLocked {
+		severity = models.SeverityCritical
+	}
+	return &finding{Severity: severity, Details: map[string]interface{}{
+		"distance_m":     math.Round(distance),
+		"from_latitude":  in.Previous.Latitude,
+		"from_longitude": in.Previous.Longitude,
+		"lock_state":     in.Bike.LockState,
+	}}
+}
+
+// checkNoSignal: El dispositivo no envía telemetría hace más de incidents.no_signal_after. Las bicicletas sin
+// dispositivo no se controlan
+func checkNoSignal(in *ruleInput) *finding {
+	if in.Bike.LastSeenAt == nil {
+		return nil
+	}
+	silence := in.Now.Sub(*in.Bike.LastSeenAt)
+	if silence <= in.Config.NoSignalAfter {
+		return nil
+	}
+	severity := models.SeverityMedium
+	if in.Rental != nil || in.Bike.Status == models.BikeMissing {
+		severity = models.SeverityHigh
+	}
+	return &finding{Severity: severity, RentalId: rentalId(in.Rental), Details: map[string]interface{}{
+		"last_seen_at": in.Bike.LastSeenAt,
+		"hours":        math.Round(silence.Hours()*10) / 10,
+	}}
+}
+
+// checkOutsideServiceArea: La bicicleta está fuera del polígono de incidents.service_area. Si nadie la alquila
+// es probable que se la hayan llevado
+func checkOutsideServiceArea(in *ruleInput) *finding {
+	if in.Area == nil || utils.PointInPolygon(in.Bike.Latitude, in.Bike.Longitude, in.Area) {
+		return nil
+	}
+	severity := models.SeverityCritical
+	if in.Rental != nil {
+		severity = models.SeverityMedium
+	}
+	return &finding{Severity: severity, RentalId: rentalId(in.Rental), Details: map[string]interface{}{
+		"latitude":  in.Bike.Latitude,
+		"longitude": in.Bike.Longitude,
+	}}
+}
+
+// checkRentalOverdue: El alquiler en curso lleva más de incidents.max_rental, el doble es severidad alta
+func checkRentalOverdue(in *ruleInput) *finding {
+	if in.Rental == nil {
+		return nil
+	}
+	elapsed := in.Now.Sub(in.Rental.StartTime)
+	if elapsed <= in.Config.MaxRental {
+		return nil
+	}
+	severity := models.SeverityMedium
+	if elapsed > 2*in.Config.MaxRental {
+		severity = models.SeverityHigh
+	}
+	return &finding{Severity: severity, RentalId: &in.Rental.Id, Details: map[string]interface{}{
+		"user_id":    in.Rental.UserId,
+		"started_at": in.Rental.StartTime,
+		"hours":      math.Round(elapsed.Hours()*10) / 10,
+	}}
+}
+
+// applyRules: Evalúa las reglas sobre la bicicleta. Abre los incidentes nuevos, suma las coincidencias a los que
+// siguen sin resolver (unresolved, por regla) y resuelve los de las reglas con AutoResolve que dejaron de cumplirse.
+// Retorna los avisos de los incidentes abiertos o cuya severidad aumentó. Debe llamarse dentro de una transacción
+func applyRules(ctx context.Context, in *ruleInput, unresolved map[string]*models.Incident) ([]notify.Alert, error) {
+	var alerts []notify.Alert
+	for _, rule := range incidentRules {
+		found := rule.Check(in)
+		incident := unresolved[rule.Name]
+
+		switch {
+		case found == nil && incident != nil && rule.AutoResolve:
+			resolvedBy, resolution := models.ActorSystem, i18n.Translate(i18n.DefaultLang, "incident.auto_resolved")
+			incident.Status = models.IncidentResolved
+			incident.ResolvedBy = &resolvedBy
+			incident.ResolvedAt = &in.Now
+			incident.Resolution = &resolution
+			if err := saveIncident(ctx, incident, in.Now); err != nil {
+				return nil, err
+			}
+			slog.InfoContext(ctx, "incident resolved", "incident_id", incident.Id, "bike_id", incident.BikeId, "rule", incident.Rule)
+
+		case found != nil && incident != nil:
+			escalated := models.SeverityAbove(found.Severity, incident.Severity)
+			if escalated {
+				incident.Severity = found.Severity
+			}
+			incident.Occurrences++
+			incident.LastSeenAt = in.Now
+			incident.Latitude, incident.Longitude = in.Bike.Latitude, in.Bike.Longitude
+			incident.Details, _ = json.Marshal(found.Details)
+			if found.RentalId != nil {
+				incident.RentalId = found.RentalId
+			}
+			if err := saveIncident(ctx, incident, in.Now); err != nil {
+				return nil, err
+			}
+			if escalated {
+				alerts = append(alerts, incidentAlert(incident))
+			}
+
+		case found != nil:
+			details, _ := json.Marshal(found.Details)
+			incident = &models.Incident{
+				BikeId:      in.Bike.Id,
+				RentalId:    found.RentalId,
+				Rule:        rule.Name,
+				Severity:    found.Severity,
+				Status:      models.IncidentOpen,
+				Details:     details,
+				Latitude:    in.Bike.Latitude,
+				Longitude:   in.Bike.Longitude,
+				Occurrences: 1,
+				FirstSeenAt: in.Now,
+				LastSeenAt:  in.Now,
+				UpdatedAt:   in.Now,
+				Version:     1,
+			}
+			id, err := incidentRepo.Create(ctx, incident)
+			if err != nil {
+				return nil, err
+			}
+			incident.Id = id
+			slog.WarnContext(ctx, "incident opened", "incident_id", id, "bike_id", incident.BikeId, "rule", incident.Rule, "severity", incident.Severity)
+			alerts = append(alerts, incidentAlert(incident))
+		}
+	}
+	return alerts, nil
+}
+
+// checkBikeIncidents: Evalúa las reglas sobre la bicicleta después de guardar su telemetría, con la posición anterior
+// para detectar movimientos. Los errores solo se registran, la telemetría ya se guardó
+func checkBikeIncidents(ctx context.Context, bike *models.Bike, previous *stream.Point) {
+	cfg := config.Current().Incidents
+	alerts, err := evaluateBike(ctx, bike, previous, cfg, serviceArea(cfg), time.Now())
+	if err != nil {
+		slog.ErrorContext(ctx, "incident check failed", "error", err)
+		return
+	}
+
+	// el dispositivo no espera a que se envíen los avisos
+	sendAlertsInBackground(ctx, alerts)
+}
+
+// evaluateBike: Evalúa las reglas sobre la bicicleta en su propia transacción, con su alquiler en curso y sus
+// incidentes sin resolver. Retorna los avisos a enviar
+func evaluateBike(ctx context.Context, bike *models.Bike, previous *stream.Point, cfg config.IncidentsConfig, area [][2]float64, now time.Time) ([]notify.Alert, error) {
+	var alerts []notify.Alert
+	err := inTx(ctx, func(ctx context.Context) error {
+		rental, err := rentalRepo.GetRunningByBike(ctx, bike.Id)
+		if err != nil {
+			return err
+		}
+		incidents, err := incidentRepo.GetUnresolved(ctx, bike.Id)
+		if err != nil {
+			return err
+		}
+
+		in := &ruleInput{Bike: bike, Previous: previous, Rental: rental, Now: now, Config: cfg, Area: area}
+		alerts, err = applyRules(ctx, in, incidentsByRule(incidents)[bike.Id])
+		return err
+	})
+	return alerts, err
+}
+
+// WatchIncidents: Evalúa cada interval las reglas sobre toda la flota, hasta que se cancele ctx
+func WatchIncidents(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := sweepIncidents(ctx); err != nil {
+			slog.Error("incident sweep failed", "error", err)
+		}
+	}
+}
+
+// sweepIncidents: Evalúa las reglas sobre todas las bicicletas que no están retiradas. Cada bicicleta se evalúa en
+// su propia transacción para no retener el lock de escritura durante toda la revisión; la que falla se vuelve a
+// evaluar en la próxima
+func sweepIncidents(ctx context.Context) error {
+	cfg := config.Current().Incidents
+	bikes, err := bikeRepo.GetInCirculation(ctx)
+	if err != nil {
+		return err
+	}
+
+	var alerts []notify.Alert
+	area := serviceArea(cfg)
+	now := time.Now()
+	for _, bike := range bikes {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		bikeAlerts, err := evaluateBike(ctx, bike, nil, cfg, area, now)
+		if err != nil {
+			slog.ErrorContext(ctx, "incident check failed", "bike_id", bike.Id, "error", err)
+			continue
+		}
+		alerts = append(alerts, bikeAlerts...)
+	}
+
+	sendAlerts(ctx, alerts)
+	return nil
+}
+
+// WaitForAlerts: Espera a que terminen de enviarse los avisos de los incidentes detectados al recibir telemetría
+func WaitForAlerts(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		alertsInFlight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// sendAlertsInBackground: Envía los avisos sin esperar al notificador, el apagado los espera con WaitForAlerts
+func sendAlertsInBackground(ctx context.Context, alerts []notify.Alert) {
+	if len(alerts) == 0 {
+		return
+	}
+	alertsInFlight.Add(1)
+	go func() {
+		defer alertsInFlight.Done()
+		sendAlerts(context.WithoutCancel(ctx), alerts)
+	}()
+}
+
+func sendAlerts(ctx context.Context, alerts []notify.Alert) {
+	for _, alert := range alerts {
+		if err := incidentNotifier.Notify(ctx, alert); err != nil {
+			slog.ErrorContext(ctx, "incident alert failed", "incident_id", alert.IncidentId, "error", err)
+		}
+	}
+}
+
+func GetIncidents(ctx context.Context, spec *utils.QuerySpec) (*utils.Page[models.Incident], error) {
+	incidents, err := incidentRepo.GetPage(ctx, spec)
+	if err != nil {
+		slog.ErrorContext(ctx, "list incidents failed", "error", err)
+		return nil, err
+	}
+	return incidents, nil
+}
+
+func GetIncident(ctx context.Context, id int64) (*models.Incident, error) {
+	incident, err := incidentRepo.GetById(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperror.NotFound("incident.not_found")
+	}
+	return incident, err
+}
+
+// AcknowledgeIncident: Indica quién se ocupa del incidente. Las coincidencias nuevas se siguen sumando pero no
+// se vuelve a avisar salvo que aumente la severidad
+func AcknowledgeIncident(ctx context.Context, id int64, form *forms.IncidentAckForm, ifMatch *int64) (*models.Incident, error) {
+	incident, err := changeIncident(ctx, id, ifMatch, "incident.acknowledge", func(incident *models.Incident, now time.Time) (bool, error) {
+		if incident.Status == models.IncidentAcknowledged && *incident.AcknowledgedBy == form.AcknowledgedBy {
+			return false, nil
+		}
+		if incident.Status != models.IncidentOpen {
+			return false, apperror.Conflict("incident.invalid_transition").WithParams(map[string]interface{}{"from": incident.Status, "to": models.IncidentAcknowledged})
+		}
+
+		incident.Status = models.IncidentAcknowledged
+		incident.AcknowledgedBy = &form.AcknowledgedBy
+		incident.AcknowledgedAt = &now
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "incident acknowledged", "acknowledged_by", form.AcknowledgedBy)
+	return incident, nil
+}
+
+// ResolveIncident: Cierra el incidente. Si la regla vuelve a cumplirse se abre uno nuevo
+func ResolveIncident(ctx context.Context, id int64, form *forms.IncidentResolveForm, ifMatch *int64) (*models.Incident, error) {
+	incident, err := changeIncident(ctx, id, ifMatch, "incident.resolve", func(incident *models.Incident, now time.Time) (bool, error) {
+		if incident.Status == models.IncidentResolved {
+			return false, apperror.Conflict("incident.invalid_transition").WithParams(map[string]interface{}{"from": incident.Status, "to": models.IncidentResolved})
+		}
+
+		incident.Status = models.IncidentResolved
+		incident.ResolvedBy = &form.ResolvedBy
+		incident.ResolvedAt = &now
+		incident.Resolution = &form.Resolution
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "incident resolved", "resolved_by", form.ResolvedBy)
+	return incident, nil
+}
+
+// changeIncident: Aplica change al incidente con control de versión y lo audita. Si change retorna false el
+// incidente queda como estaba
+func changeIncident(ctx context.Context, id int64, ifMatch *int64, action string, change func(incident *models.Incident, now time.Time) (bool, error)) (*models.Incident, error) {
+	logging.AddAttrs(ctx, slog.Int64("incident_id", id))
+
+	var incident *models.Incident
+	err := inTx(ctx, func(ctx context.Context) error {
+		var err error
+		if incident, err = GetIncident(ctx, id); err != nil {
+			return err
+		}
+		if err := checkVersion(ifMatch, incident.Version); err != nil {
+			return err
+		}
+
+		before := snapshot(incident)
+		now := time.Now()
+		changed, err := change(incident, now)
+		if err != nil || !changed {
+			return err
+		}
+
+		incident.UpdatedAt = now
+		rows, err := incidentRepo.Update(ctx, incident)
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return apperror.New(apperror.PRECONDITION, "error.version_mismatch")
+		}
+		incident.Version++
+
+		return recordAudit(ctx, action, AuditEntityIncident, id, before, incident)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return incident, nil
+}
+
+// saveIncident: Guarda los cambios de las reglas sobre el incidente. Debe llamarse dentro de una transacción
+func saveIncident(ctx context.Context, incident *models.Incident, now time.Time) error {
+	incident.UpdatedAt = now
+	rows, err := incidentRepo.Update(ctx, incident)
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return apperror.Conflict("error.concurrent_update")
+	}
+	incident.Version++
+	return nil
+}
+
+func incidentAlert(incident *models.Incident) notify.Alert {
+	return notify.Alert{
+		IncidentId: incident.Id,
+		BikeId:     incident.BikeId,
+		Rule:       incident.Rule,
+		Severity:   incident.Severity,
+		Summary:    i18n.Translate(i18n.DefaultLang, "incident.rule."+incident.Rule, i18n.Params{"bike_id": incident.BikeId}),
+		Latitude:   incident.Latitude,
+		Longitude:  incident.Longitude,
+		OpenedAt:   incident.FirstSeenAt,
+	}
+}
+
+// incidentsByRule: Incidentes sin resolver agrupados por bicicleta y regla
+func incidentsByRule(incidents []*models.Incident) map[int64]map[string]*models.Incident {
+	byBike := make(map[int64]map[string]*models.Incident)
+	for _, incident := range incidents {
+		if byBike[incident.BikeId] == nil {
+			byBike[incident.BikeId] = make(map[string]*models.Incident)
+		}
+		byBike[incident.BikeId][incident.Rule] = incident
+	}
+	return byBike
+}
+
+// serviceArea: Polígono de incidents.service_area, nil si no se configuró. Ya se validó al cargar la configuración
+func serviceArea(cfg config.IncidentsConfig) [][2]float64 {
+	if cfg.ServiceArea == "" {
+		return nil
+	}
+	area, _ := utils.ParsePolygon(cfg.ServiceArea)
+	return area
+}
+
+func rentalId(rental *models.Rental) *int64 {
+	if rental == nil {
+		return nil
+	}
+	return &rental.Id
+}
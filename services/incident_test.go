@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mbarolo/test_back/models"
+	"github.com/mbarolo/test_back/notify"
+	"github.com/mbarolo/test_back/stream"
+)
+
+// blockingNotifier: Retiene los avisos hasta que se cierra release
+type blockingNotifier struct {
+	release chan struct{}
+	sent    chan notify.Alert
+}
+
+func (n *blockingNotifier) Notify(ctx context.Context, alert notify.Alert) error {
+	<-n.release
+	n.sent <- alert
+	return nil
+}
+
+// setNotifier: Cambia el destino de los avisos durante el test y lo restaura al terminar
+func setNotifier(t *testing.T, notifier notify.Notifier) {
+	t.Helper()
+	previous := incidentNotifier
+	incidentNotifier = notifier
+	t.Cleanup(func() { incidentNotifier = previous })
+}
+
+func TestWaitForAlerts(t *testing.T) {
+	ctx := context.Background()
+	notifier := &blockingNotifier{release: make(chan struct{}), sent: make(chan notify.Alert, 1)}
+	setNotifier(t, notifier)
+	bike := newTestBike(t)
+
+	// la bicicleta apareció a ~1 km sin un alquiler en curso
+	previous := &stream.Point{Latitude: bike.Latitude + 0.01, Longitude: bike.Longitude}
+	checkBikeIncidents(ctx, bike, previous)
+
+	waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if err := WaitForAlerts(waitCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("error = %v, se esperaba que el apagado espere el aviso en curso", err)
+	}
+
+	close(notifier.release)
+	if err := WaitForAlerts(ctx); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case alert := <-notifier.sent:
+		if alert.BikeId != bike.Id || alert.Rule != models.RuleMovementWithoutRental {
+			t.Errorf("aviso = %+v, se esperaba movement_without_rental de la bicicleta %d", alert, bike.Id)
+		}
+	default:
+		t.Error("el aviso no se envió")
+	}
+}
+
+func TestSweepIncidents(t *testing.T) {
+	ctx := context.Background()
+	bike := newTestBike(t)
+	lastSeen := time.Now().Add(-7 * time.Hour)
+	bike.LastSeenAt = &lastSeen
+	if err := bikeRepo.UpdateTelemetry(ctx, bike); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sweepIncidents(ctx); err != nil {
+		t.Fatal(err)
+	}
+	incidents, err := incidentRepo.GetUnresolved(ctx, bike.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(incidents) != 1 || incidents[0].Rule != models.RuleNoSignal {
+		t.Fatalf("incidentes = %+v, se esperaba no_signal", incidents)
+	}
+
+	// la telemetría vuelve y la próxima revisión resuelve el incidente
+	now := time.Now()
+	bike.LastSeenAt = &now
+	if err := bikeRepo.UpdateTelemetry(ctx, bike); err != nil {
+		t.Fatal(err)
+	}
+	if err := sweepIncidents(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if incidents, err := incidentRepo.GetUnresolved(ctx, bike.Id); err != nil || len(incidents) != 0 {
+		t.Errorf("incidentes = %+v (%v), se esperaba resuelto", incidents, err)
+	}
+}
@@ -1,14 +1,61 @@
 package services
 
 import (
+	"context"
+
 	"github.com/mbarolo/test_back/config"
 	"github.com/mbarolo/test_back/repository"
 )
 
-var sqliteConnection = config.NewSQLiteConnection()
+var sqliteConnection *config.SQLiteConnection
 
 var (
-	userRepo   = repository.NewUserRepository(sqliteConnection.DB)
-	bikeRepo   = repository.NewBikeRepository(sqliteConnection.DB)
-	rentalRepo = repository.NewRentalRepository(sqliteConnection.DB)
+	userRepo        *repository.UserRepository
+	bikeRepo        *repository.BikeRepository
+	rentalRepo      *repository.RentalRepository
+	idempotencyRepo *repository.IdempotencyRepository
+	auditRepo       *repository.AuditRepository
+	outboxRepo      *repository.OutboxRepository
+	webhookRepo     *repository.WebhookRepository
+	deviceRepo      *repository.DeviceRepository
+	telemetryRepo   *repository.TelemetryRepository
+	routeRepo       *repository.RouteRepository
+	vehicleTypeRepo *repository.VehicleTypeRepository
+	workOrderRepo   *repository.WorkOrderRepository
+	damageRepo      *repository.DamageReportRepository
+	bikeStatusRepo  *repository.BikeStatusRepository
+	stationRepo     *repository.StationRepository
+	rebalancingRepo *repository.RebalancingRepository
+	incidentRepo    *repository.IncidentRepository
 )
+
+// Init: Abre la base de datos y crea los repositorios, se debe llamar antes de atender requests
+func Init(cfg *config.Config) {
+	sqliteConnection = config.NewSQLiteConnection(cfg.Database)
+
+	userRepo = repository.NewUserRepository(sqliteConnection.DB)
+	bikeRepo = repository.NewBikeRepository(sqliteConnection.DB)
+	rentalRepo = repository.NewRentalRepository(sqliteConnection.DB)
+	idempotencyRepo = repository.NewIdempotencyRepository(sqliteConnection.DB)
+	auditRepo = repository.NewAuditRepository(sqliteConnection.DB)
+	outboxRepo = repository.NewOutboxRepository(sqliteConnection.DB)
+	webhookRepo = repository.NewWebhookRepository(sqliteConnection.DB)
+	deviceRepo = repository.NewDeviceRepository(sqliteConnection.DB)
+	telemetryRepo = repository.NewTelemetryRepository(sqliteConnection.DB)
+	routeRepo = repository.NewRouteRepository(sqliteConnection.DB)
+	vehicleTypeRepo = repository.NewVehicleTypeRepository(sqliteConnection.DB)
+	workOrderRepo = repository.NewWorkOrderRepository(sqliteConnection.DB)
+	damageRepo = repository.NewDamageReportRepository(sqliteConnection.DB)
+	bikeStatusRepo = repository.NewBikeStatusRepository(sqliteConnection.DB)
+	stationRepo = repository.NewStationRepository(sqliteConnection.DB)
+	rebalancingRepo = repository.NewRebalancingRepository(sqliteConnection.DB)
+	incidentRepo = repository.NewIncidentRepository(sqliteConnection.DB)
+
+	registerMetrics()
+}
+
+// inTx: Ejecuta fn en una transacción, las escrituras de los repositorios que reciben el ctx de fn
+// se confirman juntas (ej: el cambio y su registro de auditoría)
+func inTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return repository.RunInTx(ctx, sqliteConnection.DB, fn)
+}
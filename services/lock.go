@@ -0,0 +1,223 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/mbarolo/test_back/apperror"
+	"github.com/mbarolo/test_back/config"
+	"github.com/mbarolo/test_back/lock"
+	"github.com/mbarolo/test_back/metrics"
+	"github.com/mbarolo/test_back/models"
+	"github.com/mbarolo/test_back/notify"
+	"github.com/mbarolo/test_back/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var (
+	locks lock.Controller
+	// lockSimulator: Candados simulados, nil si el driver es mqtt con un broker externo
+	lockSimulator *lock.Simulator
+	lockBroker    *lock.Broker
+)
+
+// InitLocks: Crea el controlador de candados de locks.driver. Con locks.mqtt.embedded_broker inicia el broker
+// embebido, la flota simulada se conecta con ServeSimulatedLocks
+func InitLocks(cfg config.LocksConfig) error {
+	if cfg.Driver == lock.DriverSimulator {
+		lockSimulator = lock.NewSimulator(cfg.SimulatorLatency)
+		locks = lockSimulator
+		return nil
+	}
+
+	opts := lockMQTTOptions(cfg)
+	if cfg.MQTT.EmbeddedBroker != "" {
+		broker, err := lock.StartBroker(cfg.MQTT.EmbeddedBroker)
+		if err != nil {
+			return err
+		}
+		lockBroker = broker
+		lockSimulator = lock.NewSimulator(cfg.SimulatorLatency)
+		slog.Warn("embedded mqtt broker started, locks are simulated", "addr", cfg.MQTT.EmbeddedBroker)
+	}
+
+	controller, err := lock.NewMQTTController(opts)
+	if err != nil {
+		return err
+	}
+	locks = controller
+	return nil
+}
+
+// ServeSimulatedLocks: Conecta la flota simulada al broker embebido hasta que se cancele ctx
+func ServeSimulatedLocks(ctx context.Context, cfg config.LocksConfig) {
+	if err := lock.ServeDevices(ctx, lockMQTTOptions(cfg), lockSimulator); err != nil {
+		slog.Error("simulated locks failed", "error", err)
+	}
+}
+
+// CloseLocks: Cierra la conexión con los candados y el broker embebido
+func CloseLocks() error {
+	err := locks.Close()
+	if lockBroker != nil {
+		err = errors.Join(err, lockBroker.Close())
+	}
+	return err
+}
+
+func lockMQTTOptions(cfg config.LocksConfig) lock.MQTTOptions {
+	broker := cfg.MQTT.Broker
+	if cfg.MQTT.EmbeddedBroker != "" {
+		broker = "tcp://" + cfg.MQTT.EmbeddedBroker
+	}
+	return lock.MQTTOptions{
+		Broker:         broker,
+		ClientId:       cfg.MQTT.ClientId,
+		Username:       cfg.MQTT.Username,
+		Password:       cfg.MQTT.Password,
+		TopicPrefix:    cfg.MQTT.TopicPrefix,
+		ConnectTimeout: cfg.MQTT.ConnectTimeout,
+	}
+}
+
+// GetLockSimulatorDevice: Estado del candado simulado de la bicicleta
+func GetLockSimulatorDevice(ctx context.Context, bikeId int64) (*lock.Device, error) {
+	if lockSimulator == nil {
+		return nil, apperror.NotFound("lock.simulator_disabled")
+	}
+	if _, err := GetBikeById(ctx, bikeId); err != nil {
+		return nil, err
+	}
+
+	device := lockSimulator.Device(bikeId)
+	return &device, nil
+}
+
+// SetLockSimulatorDevice: Cambia el estado del candado simulado, para probar los casos de error
+func SetLockSimulatorDevice(ctx context.Context, bikeId int64, device lock.Device) (*lock.Device, error) {
+	if lockSimulator == nil {
+		return nil, apperror.NotFound("lock.simulator_disabled")
+	}
+	if _, err := GetBikeById(ctx, bikeId); err != nil {
+		return nil, err
+	}
+
+	lockSimulator.SetDevice(bikeId, device)
+	slog.InfoContext(ctx, "simulated lock updated", "bike_id", bikeId, "offline", device.Offline, "silent", device.Silent, "jammed", device.Jammed)
+	device = lockSimulator.Device(bikeId)
+	return &device, nil
+}
+
+// unlockBike: Abre el candado y espera la confirmación hasta locks.timeout
+func unlockBike(ctx context.Context, bikeId int64) error {
+	return commandLock(ctx, bikeId, lock.ActionUnlock)
+}
+
+// lockBike: Cierra el candado y espera la confirmación hasta locks.timeout
+func lockBike(ctx context.Context, bikeId int64) error {
+	return commandLock(ctx, bikeId, lock.ActionLock)
+}
+
+// lockFailed: Registra que el candado no confirmó que quedó cerrado. La bicicleta disponible o reservada pasa a
+// mantenimiento para que nadie la alquile sin candado y se abre un incidente lock_failed, que resuelve el admin.
+// Si ya tiene uno sin resolver se le suma la ocurrencia, como en applyRules. Debe llamarse dentro de una transacción
+func lockFailed(ctx context.Context, bike *models.Bike, rentalId *int64, lockErr error, details map[string]interface{}) ([]notify.Alert, error) {
+	if bike.Status == models.BikeAvailable || bike.Status == models.BikeReserved {
+		if err := changeBikeStatus(ctx, bike, models.BikeMaintenance, models.StatusReasonLockFailed); err != nil {
+			return nil, err
+		}
+		if err := saveBike(ctx, bike); err != nil {
+			return nil, err
+		}
+	}
+
+	unresolved, err := incidentRepo.GetUnresolved(ctx, bike.Id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if details == nil {
+		details = make(map[string]interface{})
+	}
+	details["error"] = lockErr.Error()
+	detailsJson, _ := json.Marshal(details)
+	if incident := incidentsByRule(unresolved)[bike.Id][models.RuleLockFailed]; incident != nil {
+		incident.Occurrences++
+		incident.LastSeenAt = now
+		incident.RentalId = rentalId
+		incident.Latitude, incident.Longitude = bike.Latitude, bike.Longitude
+		incident.Details = detailsJson
+		return nil, saveIncident(ctx, incident, now)
+	}
+
+	incident := &models.Incident{
+		BikeId:      bike.Id,
+		RentalId:    rentalId,
+		Rule:        models.RuleLockFailed,
+		Severity:    models.SeverityHigh,
+		Status:      models.IncidentOpen,
+		Details:     detailsJson,
+		Latitude:    bike.Latitude,
+		Longitude:   bike.Longitude,
+		Occurrences: 1,
+		FirstSeenAt: now,
+		LastSeenAt:  now,
+		UpdatedAt:   now,
+		Version:     1,
+	}
+	id, err := incidentRepo.Create(ctx, incident)
+	if err != nil {
+		return nil, err
+	}
+	incident.Id = id
+	slog.WarnContext(ctx, "incident opened", "incident_id", id, "bike_id", incident.BikeId, "rule", incident.Rule, "severity", incident.Severity)
+	return []notify.Alert{incidentAlert(incident)}, nil
+}
+
+// commandLock: Envía el comando al candado y traduce sus errores a errores de dominio: un candado
+// desconectado o que no confirma es 503 (se puede reintentar), uno que no quedó en el estado pedido es 409
+func commandLock(ctx context.Context, bikeId int64, action string) (err error) {
+	ctx, span := tracing.Start(ctx, "lock."+action, attribute.Int64("bike_id", bikeId))
+	defer func() { tracing.End(span, err) }()
+
+	ctx, cancel := context.WithTimeout(ctx, config.Current().Locks.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	if action == lock.ActionUnlock {
+		err = locks.Unlock(ctx, bikeId)
+	} else {
+		err = locks.Lock(ctx, bikeId)
+	}
+	duration := time.Since(start)
+	metrics.LockCommandDuration.WithLabelValues(action).Observe(duration.Seconds())
+
+	result, appErr := lockError(err)
+	metrics.LockCommands.WithLabelValues(action, result).Inc()
+	if err != nil {
+		slog.WarnContext(ctx, "lock command failed", "action", action, "result", result, "duration_ms", duration.Milliseconds(), "error", err)
+		return appErr
+	}
+	slog.DebugContext(ctx, "lock command confirmed", "action", action, "duration_ms", duration.Milliseconds())
+	return nil
+}
+
+// lockError: Resultado para las métricas y error de dominio del error del candado
+func lockError(err error) (string, error) {
+	switch {
+	case err == nil:
+		return metrics.LockConfirmed, nil
+	case errors.Is(err, lock.ErrOffline):
+		return metrics.LockOffline, apperror.Unavailable("lock.offline", err)
+	case errors.Is(err, lock.ErrNoAck):
+		return metrics.LockNoAck, apperror.Unavailable("lock.no_ack", err)
+	case errors.Is(err, lock.ErrLockOpen):
+		return metrics.LockOpen, apperror.Wrap(apperror.CONFLICT, "lock.open", err)
+	case errors.Is(err, lock.ErrStillLocked):
+		return metrics.LockStillLocked, apperror.Wrap(apperror.CONFLICT, "lock.still_locked", err)
+	}
+	return metrics.LockFailed, err
+}
@@ -0,0 +1,345 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mbarolo/test_back/apperror"
+	"github.com/mbarolo/test_back/config"
+	"github.com/mbarolo/test_back/forms"
+	"github.com/mbarolo/test_back/logging"
+	"github.com/mbarolo/test_back/models"
+	"github.com/mbarolo/test_back/utils"
+)
+
+// extensiones de las fotos según su tipo, el controller solo acepta estos tipos
+var photoExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+// Photo: Foto adjunta a un reporte de daño
+type Photo struct {
+	Data        []byte
+	ContentType string
+}
+
+// ReportDamage: Registra el daño reportado por el ciclista. Si la bicicleta ya tiene una orden sin resolver de la misma
+// categoría el reporte se suma a ella, si no se abre una nueva con la prioridad de la categoría
+func ReportDamage(ctx context.Context, currentUser *models.User, bikeId int64, form *forms.DamageReportForm, photo *Photo) (*models.DamageReport, error) {
+	logging.AddAttrs(ctx, slog.Int64("bike_id", bikeId))
+
+	report := &models.DamageReport{
+		BikeId:      bikeId,
+		UserId:      currentUser.Id,
+		Category:    form.Category,
+		Description: form.Description,
+		CreatedAt:   time.Now(),
+	}
+
+	// la foto se guarda antes de la transacción para no escribir archivos con la base bloqueada,
+	// si la transacción falla se borra
+	if photo != nil {
+		name, err := savePhoto(photo)
+		if err != nil {
+			slog.ErrorContext(ctx, "save damage photo failed", "error", err)
+			return nil, err
+		}
+		report.Photo = &name
+		report.PhotoType = &photo.ContentType
+	}
+
+	var order *models.WorkOrder
+	var opened bool
+	var bike *models.Bike
+	var wasOutOfService bool
+	err := inTx(ctx, func(ctx context.Context) error {
+		var err error
+		bike, err = GetBikeById(ctx, bikeId)
+		if err != nil {
+			return err
+		}
+		wasOutOfService = bike.OutOfService
+
+		order, err = workOrderRepo.GetUnresolved(ctx, bikeId, form.Category)
+		if err != nil {
+			return err
+		}
+		if order == nil {
+			order, err = createWorkOrder(ctx, &models.WorkOrder{
+				BikeId:      bikeId,
+				Category:    form.Category,
+				Priority:    models.DamagePriority(form.Category),
+				Status:      models.WorkOrderOpen,
+				Description: form.Description,
+			})
+			if err != nil {
+				return err
+			}
+			opened = true
+		}
+
+		report.WorkOrderId = order.Id
+		report.Id, err = damageRepo.Create(ctx, report)
+		if err != nil {
+			return err
+		}
+		if err := recordAudit(ctx, "damage_report.create", AuditEntityDamage, report.Id, nil, report); err != nil {
+			return err
+		}
+
+		bike, err = GetBikeById(ctx, bikeId)
+		return err
+	})
+	if err != nil {
+		if report.Photo != nil {
+			removePhoto(ctx, *report.Photo)
+		}
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "damage reported", "report_id", report.Id, "work_order_id", order.Id, "category", report.Category, "opened", opened)
+	if bike.OutOfService != wasOutOfService {
+		publishBike(bike, nil)
+	}
+	return report, nil
+}
+
+// CreateWorkOrder: Abre una orden de trabajo desde el admin, sin reporte de un ciclista
+func CreateWorkOrder(ctx context.Context, bikeId int64, form *forms.WorkOrderForm) (*models.WorkOrder, error) {
+	logging.AddAttrs(ctx, slog.Int64("bike_id", bikeId))
+
+	order := &models.WorkOrder{
+		BikeId:      bikeId,
+		Category:    form.Category,
+		Priority:    models.DamagePriority(form.Category),
+		Status:      models.WorkOrderOpen,
+		Description: form.Description,
+		Mechanic:    form.Mechanic,
+	}
+	if form.Priority != nil {
+		order.Priority = *form.Priority
+	}
+	if order.Mechanic != nil {
+		order.Status = models.WorkOrderAssigned
+	}
+
+	var bike *models.Bike
+	var wasOutOfService bool
+	err := inTx(ctx, func(ctx context.Context) error {
+		var err error
+		bike, err = GetBikeById(ctx, bikeId)
+		if err != nil {
+			return err
+		}
+		wasOutOfService = bike.OutOfService
+
+		if order, err = createWorkOrder(ctx, order); err != nil {
+			return err
+		}
+		bike, err = GetBikeById(ctx, bikeId)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if bike.OutOfService != wasOutOfService {
+		publishBike(bike, nil)
+	}
+	return order, nil
+}
+
+// createWorkOrder: Guarda la orden nueva con su auditoría y su evento. Debe llamarse dentro de una transacción
+func createWorkOrder(ctx context.Context, order *models.WorkOrder) (*models.WorkOrder, error) {
+	now := time.Now()
+	order.CreatedAt = now
+	order.UpdatedAt = now
+	order.Version = 1
+	if order.Status == models.WorkOrderAssigned {
+		order.AssignedAt = &now
+	}
+
+	id, err := workOrderRepo.Create(ctx, order)
+	if err != nil {
+		return nil, err
+	}
+	order.Id = id
+
+	if err := recordAudit(ctx, "work_order.create", AuditEntityWorkOrder, id, nil, order); err != nil {
+		return nil, err
+	}
+	if err := publishEvent(ctx, models.EventWorkOrderOpened, AuditEntityWorkOrder, id, order); err != nil {
+		return nil, err
+	}
+	slog.InfoContext(ctx, "work order opened", "work_order_id", id, "priority", order.Priority)
+	return order, nil
+}
+
+// UpdateWorkOrder: Aplica un JSON Merge Patch a la orden de trabajo validando el cambio de estado.
+// Si ifMatch no es nil debe coincidir con la versión actual
+func UpdateWorkOrder(ctx context.Context, id int64, patch []byte, ifMatch *int64) (*models.WorkOrder, error) {
+	logging.AddAttrs(ctx, slog.Int64("work_order_id", id))
+
+	var order *models.WorkOrder
+	var bike *models.Bike
+	var wasOutOfService bool
+	err := inTx(ctx, func(ctx context.Context) error {
+		var err error
+		order, err = getWorkOrder(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(ifMatch, order.Version); err != nil {
+			return err
+		}
+		if bike, err = GetBikeById(ctx, order.BikeId); err != nil {
+			return err
+		}
+		wasOutOfService = bike.OutOfService
+
+		before := snapshot(order)
+		previous := order.Status
+		if err := utils.MergePatch(order, patch); err != nil {
+			return err
+		}
+		if !previous.CanTransition(order.Status) {
+			return apperror.Conflict("work_order.invalid_transition").WithParams(map[string]interface{}{"from": previous, "to": order.Status})
+		}
+		if order.Mechanic == nil && (order.Status == models.WorkOrderAssigned || order.Status == models.WorkOrderInProgress) {
+			return apperror.Validation("validation.failed", apperror.FieldError{Field: "mechanic", Message: "work_order.mechanic_required"})
+		}
+
+		now := time.Now()
+		order.UpdatedAt = now
+		if order.Status == models.WorkOrderAssigned && previous != models.WorkOrderAssigned {
+			order.AssignedAt = &now
+		}
+		if order.Status == models.WorkOrderResolved && previous != models.WorkOrderResolved {
+			order.ResolvedAt = &now
+		}
+
+		rows, err := workOrderRepo.Update(ctx, order)
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return apperror.New(apperror.PRECONDITION, "error.version_mismatch")
+		}
+		order.Version++
+
+		if err := recordAudit(ctx, "work_order.update", AuditEntityWorkOrder, id, before, order); err != nil {
+			return err
+		}
+		if err := publishEvent(ctx, models.EventWorkOrderUpdated, AuditEntityWorkOrder, id, order); err != nil {
+			return err
+		}
+
+		bike, err = GetBikeById(ctx, order.BikeId)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "work order updated", "status", order.Status, "version", order.Version)
+	if bike.OutOfService != wasOutOfService {
+		publishBike(bike, nil)
+	}
+	return order, nil
+}
+
+func GetWorkOrders(ctx context.Context, spec *utils.QuerySpec) (*utils.Page[models.WorkOrder], error) {
+	orders, err := workOrderRepo.GetPage(ctx, spec)
+	if err != nil {
+		slog.ErrorContext(ctx, "list work orders failed", "error", err)
+		return nil, err
+	}
+	return orders, nil
+}
+
+// GetWorkOrder: Orden de trabajo con los reportes de daño que la originaron
+func GetWorkOrder(ctx context.Context, id int64) (*models.WorkOrder, error) {
+	order, err := getWorkOrder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if order.Reports, err = damageRepo.GetByWorkOrder(ctx, id); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// GetServiceHistory: Órdenes de trabajo de la bicicleta
+func GetServiceHistory(ctx context.Context, bikeId int64, spec *utils.QuerySpec) (*utils.Page[models.WorkOrder], error) {
+	if _, err := GetBikeById(ctx, bikeId); err != nil {
+		return nil, err
+	}
+
+	spec.AddFilter("bike_id", "=", bikeId)
+	return workOrderRepo.GetPage(ctx, spec)
+}
+
+// GetDamagePhoto: Foto del reporte de daño y su tipo
+func GetDamagePhoto(ctx context.Context, reportId int64) (*Photo, error) {
+	report, err := damageRepo.GetById(ctx, reportId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperror.NotFound("damage_report.not_found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if report.Photo == nil {
+		return nil, apperror.NotFound("damage_report.photo_not_found")
+	}
+
+	data, err := os.ReadFile(filepath.Join(config.Current().Maintenance.PhotoDir, *report.Photo))
+	if errors.Is(err, os.ErrNotExist) {
+		slog.WarnContext(ctx, "damage photo missing", "report_id", reportId, "photo", *report.Photo)
+		return nil, apperror.NotFound("damage_report.photo_not_found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Photo{Data: data, ContentType: *report.PhotoType}, nil
+}
+
+func getWorkOrder(ctx context.Context, id int64) (*models.WorkOrder, error) {
+	order, err := workOrderRepo.GetById(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperror.NotFound("work_order.not_found")
+	}
+	return order, err
+}
+
+// savePhoto: Guarda la foto con un nombre aleatorio en maintenance.photo_dir y retorna el nombre
+func savePhoto(photo *Photo) (string, error) {
+	dir := config.Current().Maintenance.PhotoDir
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	name := hex.EncodeToString(random) + photoExtensions[photo.ContentType]
+	if err := os.WriteFile(filepath.Join(dir, name), photo.Data, 0o644); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+func removePhoto(ctx context.Context, name string) {
+	if err := os.Remove(filepath.Join(config.Current().Maintenance.PhotoDir, name)); err != nil {
+		slog.WarnContext(ctx, "remove damage photo failed", "photo", name, "error", err)
+	}
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/mbarolo/test_back/metrics"
+	"github.com/mbarolo/test_back/models"
+)
+
+// tiempo máximo de las consultas de los gauges durante un scrape
+const gaugeQueryTimeout = 2 * time.Second
+
+// registerMetrics: Registra las métricas de la base de datos y los gauges de negocio
+func registerMetrics() {
+	metrics.RegisterDB(sqliteConnection.DB, "sqlite")
+
+	metrics.RegisterGauge("bikes_available", "Cantidad de bicicletas disponibles para alquilar.", countGauge(bikeRepo.CountAvailable))
+	metrics.RegisterGauge("rentals_running", "Cantidad de alquileres en curso.", countGauge(rentalRepo.CountRunning))
+	metrics.RegisterGauge("stream_clients", "Cantidad de clientes conectados al stream de bicicletas.", func() float64 {
+		return float64(bikeHub.Len())
+	})
+	metrics.RegisterGauge("webhook_deliveries_pending", "Cantidad de entregas a webhooks pendientes.", countGauge(func(ctx context.Context) (int64, error) {
+		return webhookRepo.CountDeliveries(ctx, models.DeliveryPending)
+	}))
+	metrics.RegisterGauge("webhook_deliveries_dead", "Cantidad de entregas a webhooks que agotaron los reintentos.", countGauge(func(ctx context.Context) (int64, error) {
+		return webhookRepo.CountDeliveries(ctx, models.DeliveryDead)
+	}))
+}
+
+// countGauge: Adapta una consulta de conteo al valor de un gauge. Si la consulta falla el gauge se reporta como NaN
+func countGauge(count func(ctx context.Context) (int64, error)) func() float64 {
+	return func() float64 {
+		ctx, cancel := context.WithTimeout(context.Background(), gaugeQueryTimeout)
+		defer cancel()
+
+		n, err := count(ctx)
+		if err != nil {
+			slog.Error("metrics gauge query failed", "error", err)
+			return math.NaN()
+		}
+		return float64(n)
+	}
+}
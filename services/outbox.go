@@ -0,0 +1,55 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/mbarolo/test_back/logging"
+	"github.com/mbarolo/test_back/models"
+)
+
+// userEvent: Datos de un usuario en los eventos, sin la contraseña
+type userEvent struct {
+	Id        int64     `json:"id"`
+	Email     string    `json:"email"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+	Language  string    `json:"language"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func newUserEvent(user *models.User) *userEvent {
+	return &userEvent{
+		Id:        user.Id,
+		Email:     user.Email,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		Language:  user.Language,
+		CreatedAt: user.CreatedAt,
+	}
+}
+
+// publishEvent: Guarda el evento de dominio en el outbox para que el dispatcher lo entregue a los webhooks.
+// Debe llamarse dentro de la misma transacción que el cambio, así el evento existe si y solo si el cambio se confirmó
+func publishEvent(ctx context.Context, eventType string, entityType string, entityId int64, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	event := &models.OutboxEvent{
+		EventType:  eventType,
+		EntityType: entityType,
+		EntityId:   entityId,
+		Payload:    payload,
+		RequestId:  logging.RequestID(ctx),
+		CreatedAt:  time.Now(),
+	}
+	if _, err := outboxRepo.Append(ctx, event); err != nil {
+		slog.ErrorContext(ctx, "append outbox event failed", "event_type", eventType, "error", err)
+		return err
+	}
+	return nil
+}
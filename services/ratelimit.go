@@ -0,0 +1,49 @@
+package services
+
+import (
+	"github.com/mbarolo/test_back/config"
+	"github.com/mbarolo/test_back/middleware"
+	"github.com/mbarolo/test_back/ratelimit"
+)
+
+// Grupos de rutas con límite propio
+const (
+	RateLimitAuth  = "auth"
+	RateLimitAPI   = "api"
+	RateLimitAdmin = "admin"
+	// RateLimitDevices: Telemetría de los dispositivos de las bicicletas
+	RateLimitDevices = "devices"
+)
+
+// buckets en memoria, compartidos por todos los grupos de rutas
+var rateLimitStore = ratelimit.NewMemoryStore()
+
+// RateLimitStore: Almacenamiento de los buckets usado por middleware.RateLimit
+func RateLimitStore() middleware.RateLimitStore {
+	return rateLimitStore
+}
+
+// RateLimit: Límite vigente del grupo de rutas según la configuración. Con el rate limiting
+// desactivado retorna un límite vacío y el middleware deja pasar todas las requests
+func RateLimit(scope string) func() ratelimit.Limit {
+	return func() ratelimit.Limit {
+		cfg := config.Current().RateLimit
+		if !cfg.Enabled {
+			return ratelimit.Limit{}
+		}
+
+		spec := cfg.API
+		switch scope {
+		case RateLimitAuth:
+			spec = cfg.Auth
+		case RateLimitAdmin:
+			spec = cfg.Admin
+		case RateLimitDevices:
+			spec = cfg.Devices
+		}
+
+		// la configuración ya fue validada al cargarse
+		limit, _ := ratelimit.ParseLimit(spec)
+		return limit
+	}
+}
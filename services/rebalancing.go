@@ -0,0 +1,335 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/mbarolo/test_back/apperror"
+	"github.com/mbarolo/test_back/config"
+	"github.com/mbarolo/test_back/forms"
+	"github.com/mbarolo/test_back/logging"
+	"github.com/mbarolo/test_back/models"
+	"github.com/mbarolo/test_back/utils"
+)
+
+// GetForecast: Pronóstico de oferta y demanda de cada celda de la grilla para la hora
+func GetForecast(ctx context.Context, hour time.Time) (*models.Forecast, error) {
+	forecast, err := forecastZones(ctx, hour)
+	if err != nil {
+		slog.ErrorContext(ctx, "rebalancing forecast failed", "error", err)
+		return nil, err
+	}
+	return forecast, nil
+}
+
+// PlanRebalancing: Cancela las tareas abiertas y genera las de la hora según el pronóstico.
+// Las tareas tomadas se respetan y se cuentan en el pronóstico como si ya estuvieran hechas
+func PlanRebalancing(ctx context.Context, hour time.Time) ([]*models.RebalancingTask, error) {
+	forecast, err := forecastZones(ctx, hour)
+	if err != nil {
+		slog.ErrorContext(ctx, "rebalancing forecast failed", "error", err)
+		return nil, err
+	}
+
+	cfg := config.Current().Rebalancing
+	tasks := planMoves(forecast.Zones, cfg.MinBikes, float64(cfg.MaxDistance))
+
+	var cancelled int64
+	err = inTx(ctx, func(ctx context.Context) error {
+		now := time.Now()
+		if cancelled, err = rebalancingRepo.CancelOpen(ctx, now); err != nil {
+			return err
+		}
+		for _, task := range tasks {
+			task.Status = models.RebalancingOpen
+			task.ForecastHour = forecast.Hour
+			task.CreatedAt, task.UpdatedAt = now, now
+			task.Version = 1
+			id, err := rebalancingRepo.Create(ctx, task)
+			if err != nil {
+				return err
+			}
+			task.Id = id
+		}
+		return nil
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "rebalancing plan failed", "error", err)
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "rebalancing planned", "hour", forecast.Hour, "tasks", len(tasks), "cancelled", cancelled)
+	return tasks, nil
+}
+
+// ScheduleRebalancing: Planifica cada interval las tareas de la hora siguiente, hasta que se cancele ctx
+func ScheduleRebalancing(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// los errores ya se registran en PlanRebalancing, se reintenta en el próximo ciclo
+		PlanRebalancing(ctx, time.Now().Truncate(time.Hour).Add(time.Hour))
+	}
+}
+
+func GetRebalancingTasks(ctx context.Context, spec *utils.QuerySpec) (*utils.Page[models.RebalancingTask], error) {
+	tasks, err := rebalancingRepo.GetPage(ctx, spec)
+	if err != nil {
+		slog.ErrorContext(ctx, "list rebalancing tasks failed", "error", err)
+		return nil, err
+	}
+	return tasks, nil
+}
+
+func GetRebalancingTask(ctx context.Context, id int64) (*models.RebalancingTask, error) {
+	task, err := rebalancingRepo.GetById(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperror.NotFound("rebalancing.not_found")
+	}
+	return task, err
+}
+
+// ClaimRebalancingTask: Asigna la tarea abierta a quien la va a hacer. Volver a tomarla con el mismo nombre no cambia nada
+func ClaimRebalancingTask(ctx context.Context, id int64, form *forms.RebalancingClaimForm, ifMatch *int64) (*models.RebalancingTask, error) {
+	task, err := changeRebalancingTask(ctx, id, ifMatch, "rebalancing.claim", func(task *models.RebalancingTask, now time.Time) (bool, error) {
+		if task.Status == models.RebalancingClaimed && task.ClaimedBy != nil && *task.ClaimedBy == form.ClaimedBy {
+			return false, nil
+		}
+		if task.Status == models.RebalancingClaimed {
+			return false, apperror.Conflict("rebalancing.already_claimed").WithParams(map[string]interface{}{"claimed_by": *task.ClaimedBy})
+		}
+		if task.Status != models.RebalancingOpen {
+			return false, apperror.Conflict("rebalancing.invalid_transition").WithParams(map[string]interface{}{"from": task.Status, "to": models.RebalancingClaimed})
+		}
+
+		task.Status = models.RebalancingClaimed
+		task.ClaimedBy = &form.ClaimedBy
+		task.ClaimedAt = &now
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "rebalancing task claimed", "claimed_by", form.ClaimedBy)
+	return task, nil
+}
+
+// CompleteRebalancingTask: Cierra la tarea tomada con las bicicletas que se movieron realmente
+func CompleteRebalancingTask(ctx context.Context, id int64, form *forms.RebalancingCompleteForm, ifMatch *int64) (*models.RebalancingTask, error) {
+	task, err := changeRebalancingTask(ctx, id, ifMatch, "rebalancing.complete", func(task *models.RebalancingTask, now time.Time) (bool, error) {
+		if task.Status != models.RebalancingClaimed {
+			return false, apperror.Conflict("rebalancing.invalid_transition").WithParams(map[string]interface{}{"from": task.Status, "to": models.RebalancingCompleted})
+		}
+
+		moved := task.Bikes
+		if form.Moved != nil {
+			moved = *form.Moved
+		}
+		task.Status = models.RebalancingCompleted
+		task.Moved = &moved
+		task.CompletedAt = &now
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "rebalancing task completed", "bikes", task.Bikes, "moved", *task.Moved)
+	return task, nil
+}
+
+// changeRebalancingTask: Aplica change a la tarea con control de versión y la audita. Si change retorna false la tarea
+// queda como estaba
+func changeRebalancingTask(ctx context.Context, id int64, ifMatch *int64, action string, change func(task *models.RebalancingTask, now time.Time) (bool, error)) (*models.RebalancingTask, error) {
+	logging.AddAttrs(ctx, slog.Int64("rebalancing_task_id", id))
+
+	var task *models.RebalancingTask
+	err := inTx(ctx, func(ctx context.Context) error {
+		var err error
+		if task, err = GetRebalancingTask(ctx, id); err != nil {
+			return err
+		}
+		if err := checkVersion(ifMatch, task.Version); err != nil {
+			return err
+		}
+
+		before := snapshot(task)
+		now := time.Now()
+		changed, err := change(task, now)
+		if err != nil || !changed {
+			return err
+		}
+
+		task.UpdatedAt = now
+		rows, err := rebalancingRepo.Update(ctx, task)
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return apperror.New(apperror.PRECONDITION, "error.version_mismatch")
+		}
+		task.Version++
+
+		return recordAudit(ctx, action, AuditEntityRebalancing, id, before, task)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// forecastZones: Agrupa en celdas de rebalancing.cell_size metros las bicicletas disponibles y los alquileres de los
+// últimos rebalancing.history_days días. La demanda de la hora es el promedio diario de los alquileres que empezaron
+// y terminaron en la celda a la misma hora del día (hora del servidor)
+func forecastZones(ctx context.Context, hour time.Time) (*models.Forecast, error) {
+	cfg := config.Current().Rebalancing
+	size := float64(cfg.CellSize)
+	days := float64(cfg.HistoryDays)
+	hour = hour.Truncate(time.Hour)
+
+	bikes, err := bikeRepo.GetAllAvailable(ctx, "", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	trips, err := rentalRepo.GetStartedSince(ctx, time.Now().AddDate(0, 0, -cfg.HistoryDays))
+	if err != nil {
+		return nil, err
+	}
+	claimed, err := rebalancingRepo.GetByStatus(ctx, models.RebalancingClaimed)
+	if err != nil {
+		return nil, err
+	}
+
+	cells := make(map[[2]int]*models.ZoneForecast)
+	zone := func(lat, lon float64) *models.ZoneForecast {
+		row, col := utils.GridCell(lat, lon, size)
+		key := [2]int{row, col}
+		if z, ok := cells[key]; ok {
+			return z
+		}
+		centerLat, centerLon := utils.GridCellCenter(row, col, size)
+		z := &models.ZoneForecast{Cell: cellName(row, col), Latitude: centerLat, Longitude: centerLon}
+		cells[key] = z
+		return z
+	}
+
+	for _, bike := range bikes {
+		zone(bike.Latitude, bike.Longitude).Supply++
+	}
+	for _, task := range claimed {
+		zone(task.FromLatitude, task.FromLongitude).Supply -= task.Bikes
+		zone(task.ToLatitude, task.ToLongitude).Supply += task.Bikes
+	}
+
+	hourOfDay := hour.Local().Hour()
+	for _, trip := range trips {
+		if trip.StartTime.Local().Hour() == hourOfDay {
+			zone(trip.StartLatitude, trip.StartLongitude).Pickups += 1 / days
+		}
+		if trip.EndTime != nil && trip.EndLatitude != nil && trip.EndLongitude != nil && trip.EndTime.Local().Hour() == hourOfDay {
+			zone(*trip.EndLatitude, *trip.EndLongitude).Dropoffs += 1 / days
+		}
+	}
+
+	keys := make([][2]int, 0, len(cells))
+	for key := range cells {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+
+	forecast := &models.Forecast{Hour: hour, CellSize: cfg.CellSize, Zones: make([]*models.ZoneForecast, 0, len(keys))}
+	for _, key := range keys {
+		z := cells[key]
+		// una tarea tomada puede haberse llevado bicicletas que ya no estaban disponibles
+		z.Supply = max(z.Supply, 0)
+		z.Target = int(math.Ceil(z.Pickups - 1e-9))
+		z.Balance = z.Supply - z.Target
+		z.Expected = round2(float64(z.Supply) + z.Dropoffs - z.Pickups)
+		z.Pickups, z.Dropoffs = round2(z.Pickups), round2(z.Dropoffs)
+		forecast.Zones = append(forecast.Zones, z)
+	}
+	return forecast, nil
+}
+
+// planMoves: Planificador greedy. Atiende primero las celdas con más faltante, cada una con las celdas con sobrante
+// más cercanas a menos de maxDistance metros. No genera traslados de menos de minBikes bicicletas
+func planMoves(zones []*models.ZoneForecast, minBikes int, maxDistance float64) []*models.RebalancingTask {
+	var deficits, donors []*models.ZoneForecast
+	surplus := make(map[string]int)
+	for _, z := range zones {
+		switch {
+		case z.Balance <= -minBikes:
+			deficits = append(deficits, z)
+		case z.Balance >= minBikes:
+			donors = append(donors, z)
+			surplus[z.Cell] = z.Balance
+		}
+	}
+	sort.SliceStable(deficits, func(i, j int) bool { return deficits[i].Balance < deficits[j].Balance })
+
+	var tasks []*models.RebalancingTask
+	for _, to := range deficits {
+		distances := make(map[string]float64, len(donors))
+		nearby := make([]*models.ZoneForecast, 0, len(donors))
+		for _, from := range donors {
+			distance := utils.HaversineDistance(from.Latitude, from.Longitude, to.Latitude, to.Longitude)
+			if distance <= maxDistance {
+				distances[from.Cell] = distance
+				nearby = append(nearby, from)
+			}
+		}
+		sort.SliceStable(nearby, func(i, j int) bool { return distances[nearby[i].Cell] < distances[nearby[j].Cell] })
+
+		need := -to.Balance
+		for _, from := range nearby {
+			if need < minBikes {
+				break
+			}
+			bikes := min(need, surplus[from.Cell])
+			if bikes < minBikes {
+				continue
+			}
+			surplus[from.Cell] -= bikes
+			need -= bikes
+			tasks = append(tasks, &models.RebalancingTask{
+				FromCell:      from.Cell,
+				FromLatitude:  from.Latitude,
+				FromLongitude: from.Longitude,
+				ToCell:        to.Cell,
+				ToLatitude:    to.Latitude,
+				ToLongitude:   to.Longitude,
+				Bikes:         bikes,
+				DistanceM:     int(math.Round(distances[from.Cell])),
+			})
+		}
+	}
+	return tasks
+}
+
+// cellName: Identificador de la celda de la grilla, fila:columna
+func cellName(row, col int) string {
+	return fmt.Sprintf("%d:%d", row, col)
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
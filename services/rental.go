@@ -1,111 +1,373 @@
 package services
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"log/slog"
 	"time"
 
+	"github.com/mbarolo/test_back/apperror"
 	"github.com/mbarolo/test_back/forms"
+	"github.com/mbarolo/test_back/lock"
+	"github.com/mbarolo/test_back/logging"
+	"github.com/mbarolo/test_back/metrics"
 	"github.com/mbarolo/test_back/models"
+	"github.com/mbarolo/test_back/notify"
+	"github.com/mbarolo/test_back/stream"
+	"github.com/mbarolo/test_back/tracing"
 	"github.com/mbarolo/test_back/utils"
+	"go.opentelemetry.io/otel/attribute"
 )
 
-func StartRental(currentUser *models.User, rental *forms.StartEndRentalForm) (*models.Rental, error) {
-	bike, err := bikeRepo.GetById(rental.BikeID)
+func StartRental(ctx context.Context, currentUser *models.User, rental *forms.StartEndRentalForm) (_ *models.Rental, err error) {
+	ctx, span := tracing.Start(ctx, "services.StartRental", attribute.Int64("user_id", currentUser.Id), attribute.Int64("bike_id", rental.BikeID))
+	defer func() { tracing.End(span, err) }()
+	logging.AddAttrs(ctx, slog.Int64("bike_id", rental.BikeID))
+
+	// la bicicleta se reserva en una transacción y el candado se abre después de confirmarla, así la espera
+	// de la confirmación no retiene el lock de escritura de SQLite
+	bike, err := reserveBike(ctx, currentUser, rental.BikeID)
 	if err != nil {
-		return nil, errors.New("error al obtener la bicicleta: " + err.Error())
+		return nil, err
 	}
 
-	if !bike.IsAvailable {
-		return nil, errors.New("bicicleta no disponible")
+	// el alquiler se crea solo cuando el candado abre. Si no responde pudo abrirse igual, se vuelve a cerrar antes
+	// de liberar la bicicleta
+	if err := unlockBike(ctx, bike.Id); err != nil {
+		var lockErr error
+		if errors.Is(err, lock.ErrNoAck) || errors.Is(err, lock.ErrOffline) {
+			lockErr = lockBike(context.WithoutCancel(ctx), bike.Id)
+		}
+		releaseBike(ctx, bike.Id, lockErr)
+		return nil, err
 	}
 
-	running, err := rentalRepo.GetRunningRental(currentUser.Id)
-	if err != nil && err != sql.ErrNoRows {
+	var newRental models.Rental
+	err = inTx(ctx, func(ctx context.Context) error {
+		// otro alquiler del usuario pudo empezar mientras se abría el candado
+		if err := checkNoRunningRental(ctx, currentUser.Id); err != nil {
+			return err
+		}
+
+		if err := changeBikeStatus(ctx, bike, models.BikeRented, models.StatusReasonRentalStart); err != nil {
+			return err
+		}
+		startStation := bike.StationId
+		bike.StationId, bike.Dock = nil, nil
+		rows, err := bikeRepo.UpdateBike(ctx, bike)
+		if err != nil {
+			return fmt.Errorf("error al actualizar la bicicleta: %w", err)
+		}
+		if rows == 0 {
+			return apperror.Conflict("error.concurrent_update")
+		}
+		bike.Version++
+
+		newRental = models.Rental{
+			UserId:         currentUser.Id,
+			BikeId:         bike.Id,
+			RentalStatus:   models.RUNNING,
+			StartTime:      time.Now(),
+			EndTime:        nil,
+			StartLatitude:  bike.Latitude,
+			StartLongitude: bike.Longitude,
+			StartStationId: startStation,
+			Version:        1,
+		}
+
+		newId, err := rentalRepo.Create(ctx, &newRental)
+		if err != nil {
+			slog.ErrorContext(ctx, "create rental failed", "error", err)
+			return err
+		}
+		newRental.Id = newId
+
+		if err := recordAudit(ctx, "rental.start", AuditEntityRental, newRental.Id, nil, &newRental); err != nil {
+			return err
+		}
+		return publishEvent(ctx, models.EventRentalStarted, AuditEntityRental, newRental.Id, &newRental)
+	})
+	if err != nil {
+		// no se pudo crear el alquiler con el candado ya abierto, se vuelve a cerrar para que la bicicleta no quede libre
+		releaseBike(ctx, bike.Id, lockBike(context.WithoutCancel(ctx), bike.Id))
 		return nil, err
 	}
-	if running != nil {
-		return nil, errors.New("usuario ya tiene un alquiler en curso")
-	}
 
-	newRental := models.Rental{
-		UserId:         currentUser.Id,
-		BikeId:         bike.Id,
-		RentalStatus:   models.RUNNING,
-		StartTime:      time.Now(),
-		EndTime:        nil,
-		StartLatitude:  bike.Latitude,
-		StartLongitude: bike.Longitude,
-	}
+	span.SetAttributes(attribute.Int64("rental_id", newRental.Id))
+	logging.AddAttrs(ctx, slog.Int64("rental_id", newRental.Id))
+	slog.InfoContext(ctx, "rental started")
+	metrics.RentalsStarted.Inc()
+	publishBike(bike, nil)
+	publishRental(&newRental)
+	return &newRental, nil
+}
+
+// reserveBike: Valida que el usuario pueda alquilar la bicicleta y la pasa a reservada hasta que abra el candado.
+// Si otra solicitud la tomó primero la versión ya no coincide
+func reserveBike(ctx context.Context, currentUser *models.User, bikeId int64) (*models.Bike, error) {
+	var bike *models.Bike
+	err := inTx(ctx, func(ctx context.Context) error {
+		var err error
+		bike, err = GetBikeById(ctx, bikeId)
+		if err != nil {
+			return err
+		}
+
+		if bike.Status != models.BikeAvailable {
+			return apperror.Conflict("bike.not_available")
+		}
+		if bike.OutOfService {
+			return apperror.Conflict("bike.out_of_service")
+		}
 
-	newId, err := rentalRepo.Create(&newRental)
+		vehicleType, err := vehicleTypeFor(ctx, bike.VehicleType)
+		if err != nil {
+			return err
+		}
+		if err := checkBattery(vehicleType, bike); err != nil {
+			return err
+		}
+		if err := checkNoRunningRental(ctx, currentUser.Id); err != nil {
+			return err
+		}
+
+		if err := changeBikeStatus(ctx, bike, models.BikeReserved, models.StatusReasonRentalStart); err != nil {
+			return err
+		}
+		rows, err := bikeRepo.UpdateBike(ctx, bike)
+		if err != nil {
+			return fmt.Errorf("error al actualizar la bicicleta: %w", err)
+		}
+		if rows == 0 {
+			slog.WarnContext(ctx, "bike claimed by a concurrent rental")
+			return apperror.Conflict("bike.not_available")
+		}
+		bike.Version++
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	newRental.Id = newId
+	return bike, nil
+}
+
+// releaseBike: Devuelve a disponible la bicicleta reservada por reserveBike cuando no se pudo crear el alquiler.
+// Con lockErr no se pudo confirmar que el candado quedó cerrado y pasa a mantenimiento (lockFailed). Si mientras
+// tanto el admin le cambió el estado se deja como está
+func releaseBike(ctx context.Context, bikeId int64, lockErr error) {
+	ctx = context.WithoutCancel(ctx)
+	var alerts []notify.Alert
+	err := inTx(ctx, func(ctx context.Context) error {
+		bike, err := GetBikeById(ctx, bikeId)
+		if err != nil {
+			return err
+		}
+		if bike.Status != models.BikeReserved {
+			return nil
+		}
+		if lockErr != nil {
+			alerts, err = lockFailed(ctx, bike, nil, lockErr, nil)
+			return err
+		}
 
-	// Actualizamos la bicicleta a no disponible
-	bike.IsAvailable = false
-	_, err = bikeRepo.UpdateBike(bike)
+		if err := changeBikeStatus(ctx, bike, models.BikeAvailable, models.StatusReasonUnlockFailed); err != nil {
+			return err
+		}
+		return saveBike(ctx, bike)
+	})
 	if err != nil {
-		return nil, errors.New("error al actualizar la bicicleta: " + err.Error())
+		slog.ErrorContext(ctx, "release reserved bike failed", "error", err)
+		return
 	}
+	sendAlertsInBackground(ctx, alerts)
+}
 
-	return &newRental, nil
+// checkNoRunningRental: Conflicto si el usuario ya tiene un alquiler en curso
+func checkNoRunningRental(ctx context.Context, userId int64) error {
+	running, err := rentalRepo.GetRunningRental(ctx, userId)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if running != nil {
+		return apperror.Conflict("rental.already_running")
+	}
+	return nil
 }
 
-func EndRental(currentUser *models.User, rental *forms.StartEndRentalForm) (*models.Rental, error) {
-	bike, err := bikeRepo.GetById(rental.BikeID)
+func EndRental(ctx context.Context, currentUser *models.User, rental *forms.StartEndRentalForm) (_ *models.Rental, err error) {
+	ctx, span := tracing.Start(ctx, "services.EndRental", attribute.Int64("user_id", currentUser.Id), attribute.Int64("bike_id", rental.BikeID))
+	defer func() { tracing.End(span, err) }()
+	logging.AddAttrs(ctx, slog.Int64("bike_id", rental.BikeID))
+
+	// se valida antes de cerrar el candado y el alquiler se finaliza cuando confirma, en una transacción
+	// aparte para que la espera de la confirmación no retenga el lock de escritura de SQLite
+	_, running, err := riderRental(ctx, currentUser.Id, rental.BikeID)
 	if err != nil {
 		return nil, err
 	}
+	span.SetAttributes(attribute.Int64("rental_id", running.Id))
+	logging.AddAttrs(ctx, slog.Int64("rental_id", running.Id))
 
-	running, err := rentalRepo.GetRunningRental(currentUser.Id)
+	// el alquiler termina solo cuando el candado confirma que quedó cerrado
+	if err := lockBike(ctx, rental.BikeID); err != nil {
+		return nil, err
+	}
+
+	// si no se puede finalizar el candado queda cerrado con el alquiler en curso y el usuario puede reintentar
+	var bike *models.Bike
+	var previous *stream.Point
+	err = inTx(ctx, func(ctx context.Context) error {
+		var err error
+		bike, running, err = riderRental(ctx, currentUser.Id, rental.BikeID)
+		if err != nil {
+			return err
+		}
+		previous, err = closeRental(ctx, running, bike, models.EndReasonRider, 0, "rental.end")
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	if running == nil {
-		return nil, errors.New("el usuario no tiene un alquiler activo")
+	rentalEnded(ctx, running, bike, previous)
+	return running, nil
+}
+
+// riderRental: Bicicleta y alquiler en curso del usuario. Conflicto si no tiene uno y prohibido si es de otra bicicleta
+func riderRental(ctx context.Context, userId int64, bikeId int64) (*models.Bike, *models.Rental, error) {
+	bike, err := GetBikeById(ctx, bikeId)
+	if err != nil {
+		return nil, nil, err
 	}
 
+	running, err := rentalRepo.GetRunningRental(ctx, userId)
+	if err != nil {
+		return nil, nil, err
+	}
+	if running == nil {
+		return nil, nil, apperror.Conflict("rental.none_running")
+	}
 	if running.BikeId != bike.Id {
-		return nil, errors.New("el usuario no está alquilando esta bicicleta")
+		return nil, nil, apperror.Forbidden("rental.wrong_bike")
 	}
+	return bike, running, nil
+}
+
+// closeRental: Finaliza el alquiler: calcula duración y costo (más la multa si penalty > 0), lo termina en el
+// último punto del recorrido o en la estación cercana y libera la bicicleta. El candado se cierra antes, fuera
+// de la transacción. Retorna la ubicación anterior de la bicicleta. Debe llamarse dentro de una transacción
+func closeRental(ctx context.Context, running *models.Rental, bike *models.Bike, reason models.RentalEndReason, penalty int, action string) (*stream.Point, error) {
+	before := snapshot(running)
 
 	// Calculamos duracion del rental
 	endTime := time.Now()
 	running.EndTime = &endTime
 	duration := int(endTime.Sub(running.StartTime).Minutes())
 	running.Duration = &duration
-	cost := bike.CostPerMinute * duration
+	vehicleType, err := vehicleTypeFor(ctx, bike.VehicleType)
+	if err != nil {
+		return nil, err
+	}
+	cost := vehicleType.UnlockFee + bike.CostPerMinute*duration
+	if penalty > 0 {
+		running.PenaltyFee = &penalty
+		cost += penalty
+	}
 	running.Cost = &cost
+	running.EndReason = &reason
 
-	// Asignamos end lat y long ~5km
-	endlatitude, endLongitude := utils.GenerateRandomCoordinatesWithinRadius(running.StartLatitude, running.StartLongitude, 5.0)
+	// Si se registró el recorrido se termina en su último punto, si no end lat y long ~5km
+	points, err := routeRepo.GetByRental(ctx, running.Id)
+	if err != nil {
+		return nil, err
+	}
+	var endlatitude, endLongitude float64
+	if len(points) > 0 {
+		endlatitude, endLongitude = points[len(points)-1].Position()
+	} else {
+		endlatitude, endLongitude = utils.GenerateRandomCoordinatesWithinRadius(running.StartLatitude, running.StartLongitude, 5.0)
+	}
 	running.EndLatitude, running.EndLongitude = &endlatitude, &endLongitude
 
+	// cerca de una estación con anclajes libres la devolución se ancla en ella. Sin recorrido registrado no se sabe
+	// dónde quedó, no se ancla ni se exige la estación de stations.return_required. Un alquiler abandonado se cierra
+	// donde quedó aunque la exija
+	if len(points) > 0 {
+		station, err := returnStation(ctx, endlatitude, endLongitude, reason == models.EndReasonRider)
+		if err != nil {
+			return nil, err
+		}
+		if station != nil {
+			if err := dockAt(ctx, bike, station); err != nil {
+				return nil, err
+			}
+			running.EndLatitude, running.EndLongitude = &station.Latitude, &station.Longitude
+			running.EndStationId = &station.Id
+		}
+	}
+	if err := finishRoute(ctx, running, points); err != nil {
+		return nil, err
+	}
+
 	running.RentalStatus = models.ENDED
 
 	// Se actualiza bike y rental
-	_, err = rentalRepo.Update(running)
+	rows, err := rentalRepo.Update(ctx, running)
 	if err != nil {
-		return nil, errors.New("error al actualizar el alquiler: " + err.Error())
+		return nil, fmt.Errorf("error al actualizar el alquiler: %w", err)
+	}
+	if rows == 0 {
+		return nil, apperror.Conflict("error.concurrent_update")
 	}
+	running.Version++
 
-	bike.IsAvailable = true
+	previous := bikePoint(bike)
+	// si se marcó como perdida durante el alquiler conserva ese estado
+	if bike.Status == models.BikeRented {
+		if err := changeBikeStatus(ctx, bike, models.BikeAvailable, models.StatusReasonRentalEnd); err != nil {
+			return nil, err
+		}
+	}
 	bike.Latitude = *running.EndLatitude
 	bike.Longitude = *running.EndLongitude
-	_, err = bikeRepo.UpdateBike(bike)
+	if running.EndStationId == nil {
+		bike.StationId, bike.Dock = nil, nil
+	}
+	rows, err = bikeRepo.UpdateBike(ctx, bike)
 	if err != nil {
-		return nil, errors.New("error al actualizar la bicicleta: " + err.Error())
+		return nil, fmt.Errorf("error al actualizar la bicicleta: %w", err)
+	}
+	if rows == 0 {
+		return nil, apperror.Conflict("error.concurrent_update")
 	}
+	bike.Version++
 
-	return running, nil
+	if err := recordAudit(ctx, action, AuditEntityRental, running.Id, before, running); err != nil {
+		return nil, err
+	}
+	if err := publishEvent(ctx, models.EventRentalEnded, AuditEntityRental, running.Id, running); err != nil {
+		return nil, err
+	}
+	return previous, nil
 }
 
-func GetRentalHistory(userId int64) ([]*models.Rental, error) {
-	rentals, err := rentalRepo.GetUserHistory(userId)
+// rentalEnded: Registra y publica el alquiler finalizado por closeRental. Debe llamarse después de confirmar la transacción
+func rentalEnded(ctx context.Context, rental *models.Rental, bike *models.Bike, previous *stream.Point) {
+	attrs := []any{"rental_id", rental.Id, "end_reason", *rental.EndReason, "duration_minutes", *rental.Duration, "cost", *rental.Cost}
+	if rental.DistanceM != nil {
+		attrs = append(attrs, "distance_m", *rental.DistanceM)
+	}
+	slog.InfoContext(ctx, "rental ended", attrs...)
+	metrics.RentalsEnded.Inc()
+	metrics.Revenue.Add(float64(*rental.Cost))
+	publishBike(bike, previous)
+	publishRental(rental)
+}
+
+func GetRentalHistory(ctx context.Context, userId int64, spec *utils.QuerySpec) (*utils.Page[models.Rental], error) {
+	rentals, err := rentalRepo.GetUserHistory(ctx, userId, spec)
 	if err != nil {
 		return nil, err
 	}
@@ -113,16 +375,19 @@ func GetRentalHistory(userId int64) ([]*models.Rental, error) {
 	return rentals, nil
 }
 
-func GetAllRentals() ([]*models.Rental, error) {
-	rentals, err := rentalRepo.GetAll()
+func GetAllRentals(ctx context.Context, spec *utils.QuerySpec) (*utils.Page[models.Rental], error) {
+	rentals, err := rentalRepo.GetAll(ctx, spec)
 	if err != nil {
 		return nil, err
 	}
 	return rentals, err
 }
 
-func GetRentalById(id int64) (*models.Rental, error) {
-	rental, err := rentalRepo.GetById(id)
+func GetRentalById(ctx context.Context, id int64) (*models.Rental, error) {
+	rental, err := rentalRepo.GetById(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperror.NotFound("rental.not_found")
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -130,47 +395,54 @@ func GetRentalById(id int64) (*models.Rental, error) {
 	return rental, nil
 }
 
-func UpdateRental(id int64, updatedRental *forms.RentalForm) (*models.Rental, error) {
-	originalRental, err := GetRentalById(id)
-	if err != nil {
+// rentalPatchFields: Campos del alquiler que el admin puede corregir con PATCH. El usuario, la bicicleta, el estado
+// y los tiempos solo cambian con StartRental y EndRental, que además actualizan la bicicleta, calculan el costo y
+// publican los eventos
+var rentalPatchFields = []string{"start_latitude", "start_longitude", "end_latitude", "end_longitude"}
+
+// UpdateRental: Aplica un JSON Merge Patch al alquiler. Si ifMatch no es nil debe coincidir con la versión actual.
+// Los campos fuera de rentalPatchFields se rechazan, incluso con null
+func UpdateRental(ctx context.Context, id int64, patch []byte, ifMatch *int64) (*models.Rental, error) {
+	logging.AddAttrs(ctx, slog.Int64("rental_id", id))
+
+	if err := utils.CheckPatchFields(patch, rentalPatchFields); err != nil {
 		return nil, err
 	}
 
-	if updatedRental.UserID != nil {
-		originalRental.UserId = *updatedRental.UserID
-	}
-	if updatedRental.BikeID != nil {
-		originalRental.BikeId = *updatedRental.BikeID
-	}
-	if updatedRental.Status != nil {
-		originalRental.RentalStatus = *updatedRental.Status
-	}
-	if updatedRental.StartTime != nil {
-		originalRental.StartTime = *updatedRental.StartTime
-	}
-	if updatedRental.EndTime != nil {
-		originalRental.EndTime = updatedRental.EndTime
-	}
-	if updatedRental.StartLatitude != nil {
-		originalRental.StartLatitude = *updatedRental.StartLatitude
-	}
-	if updatedRental.StartLongitude != nil {
-		originalRental.StartLongitude = *updatedRental.StartLongitude
-	}
-	if updatedRental.EndLatitude != nil {
-		originalRental.EndLatitude = updatedRental.EndLatitude
-	}
-	if updatedRental.EndLongitude != nil {
-		originalRental.EndLongitude = updatedRental.EndLongitude
-	}
-	if updatedRental.Duration != nil {
-		originalRental.Duration = updatedRental.Duration
-	}
+	var originalRental *models.Rental
+	err := inTx(ctx, func(ctx context.Context) error {
+		var err error
+		originalRental, err = GetRentalById(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := checkVersion(ifMatch, originalRental.Version); err != nil {
+			return err
+		}
+
+		before := snapshot(originalRental)
+		if err := utils.MergePatch(originalRental, patch); err != nil {
+			return err
+		}
+
+		rows, err := rentalRepo.Update(ctx, originalRental)
+		if err != nil {
+			slog.ErrorContext(ctx, "update rental failed", "error", err)
+			return err
+		}
+		if rows == 0 {
+			return apperror.New(apperror.PRECONDITION, "error.version_mismatch")
+		}
+		originalRental.Version++
 
-	_, err = rentalRepo.Update(originalRental)
+		return recordAudit(ctx, "rental.update", AuditEntityRental, id, before, originalRental)
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	slog.InfoContext(ctx, "rental updated", "version", originalRental.Version)
+	publishRental(originalRental)
 	return originalRental, nil
 }
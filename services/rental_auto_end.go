@@ -0,0 +1,243 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/mbarolo/test_back/apperror"
+	"github.com/mbarolo/test_back/config"
+	"github.com/mbarolo/test_back/i18n"
+	"github.com/mbarolo/test_back/metrics"
+	"github.com/mbarolo/test_back/models"
+	"github.com/mbarolo/test_back/notify"
+	"github.com/mbarolo/test_back/stream"
+)
+
+// rentalEndWarning: Aviso al usuario de que su alquiler se va a cerrar, datos del evento rental.end_warning.
+// Message está en el idioma del usuario para reenviarlo como notificación
+type rentalEndWarning struct {
+	Rental     *models.Rental         `json:"rental"`
+	User       *userEvent             `json:"user"`
+	Reason     models.RentalEndReason `json:"reason"`
+	AutoEndAt  time.Time              `json:"auto_end_at"`
+	PenaltyFee int                    `json:"penalty_fee"`
+	Message    string                 `json:"message"`
+}
+
+// CloseAbandonedRentals: Revisa cada interval los alquileres en curso, hasta que se cancele ctx. Avisa al usuario
+// de los que superan rentals.auto_end.max_duration o están inactivos y los cierra si siguen así al terminar el plazo de gracia
+func CloseAbandonedRentals(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := sweepAbandonedRentals(ctx); err != nil {
+			slog.Error("abandoned rentals sweep failed", "error", err)
+		}
+	}
+}
+
+// sweepAbandonedRentals: Revisa cada alquiler en curso en su propia transacción, así un candado que no responde
+// no impide cerrar los demás. El que falla se vuelve a intentar en la próxima revisión
+func sweepAbandonedRentals(ctx context.Context) error {
+	running, err := rentalRepo.GetAllRunning(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, rental := range running {
+		if err := checkAbandonedRental(ctx, rental.Id); err != nil {
+			slog.ErrorContext(ctx, "abandoned rental check failed", "rental_id", rental.Id, "error", err)
+		}
+	}
+	return nil
+}
+
+// checkAbandonedRental: Avisa al usuario si el alquiler está abandonado, retira el aviso si volvió a usarlo
+// y lo cierra con el motivo y la multa si sigue abandonado después de auto_end_at
+func checkAbandonedRental(ctx context.Context, id int64) error {
+	cfg := config.Current().Rentals.AutoEnd
+	now := time.Now()
+
+	var rental *models.Rental
+	var reason models.RentalEndReason
+	var warned, due bool
+	err := inTx(ctx, func(ctx context.Context) error {
+		var err error
+		rental, err = GetRentalById(ctx, id)
+		if err != nil {
+			return err
+		}
+		// el usuario lo finalizó mientras se revisaban los demás
+		if rental.RentalStatus != models.RUNNING {
+			return nil
+		}
+
+		reason, err = abandonReason(ctx, rental, cfg, now)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case reason == "" && rental.AutoEndAt == nil:
+			return nil
+		case reason == "":
+			warned = true
+			return cancelEndWarning(ctx, rental)
+		case rental.AutoEndAt == nil:
+			warned = true
+			return warnRentalEnd(ctx, rental, reason, cfg, now)
+		case now.Before(*rental.AutoEndAt):
+			return nil
+		}
+		due = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if due {
+		return closeAbandonedRental(ctx, rental, reason, cfg.PenaltyFee)
+	}
+	if warned {
+		publishRental(rental)
+	}
+	return nil
+}
+
+// closeAbandonedRental: Cierra el candado y finaliza el alquiler con el motivo y la multa. El candado se cierra
+// fuera de la transacción; si no confirma el alquiler se finaliza igual, el usuario no sigue pagando por una
+// bicicleta que dejó, y la falla queda registrada con lockFailed (si se marcó como perdida conserva ese estado)
+func closeAbandonedRental(ctx context.Context, rental *models.Rental, reason models.RentalEndReason, penalty int) error {
+	lockErr := lockBike(ctx, rental.BikeId)
+	if lockErr != nil {
+		slog.WarnContext(ctx, "auto end lock failed", "rental_id", rental.Id, "bike_id", rental.BikeId, "error", lockErr)
+	}
+
+	var bike *models.Bike
+	var previous *stream.Point
+	var alerts []notify.Alert
+	err := inTx(ctx, func(ctx context.Context) error {
+		// se vuelve a leer, el usuario pudo finalizarlo mientras se cerraba el candado
+		var err error
+		rental, err = GetRentalById(ctx, rental.Id)
+		if err != nil {
+			return err
+		}
+		if rental.RentalStatus != models.RUNNING {
+			return nil
+		}
+
+		bike, err = GetBikeById(ctx, rental.BikeId)
+		if err != nil {
+			return err
+		}
+		previous, err = closeRental(ctx, rental, bike, reason, penalty, "rental.auto_end")
+		if err != nil || lockErr == nil {
+			return err
+		}
+		alerts, err = lockFailed(ctx, bike, &rental.Id, lockErr, map[string]interface{}{"end_reason": rental.EndReason})
+		return err
+	})
+	if err != nil || bike == nil {
+		return err
+	}
+
+	metrics.RentalsAutoEnded.WithLabelValues(string(reason)).Inc()
+	rentalEnded(ctx, rental, bike, previous)
+	sendAlerts(ctx, alerts)
+	return nil
+}
+
+// abandonReason: Motivo por el que el alquiler se considera abandonado, vacío si no lo está. Está inactivo
+// si no registra puntos de recorrido (o no empezó) hace más de rentals.auto_end.idle_after
+func abandonReason(ctx context.Context, rental *models.Rental, cfg config.RentalsAutoEndConfig, now time.Time) (models.RentalEndReason, error) {
+	if now.Sub(rental.StartTime) > cfg.MaxDuration {
+		return models.EndReasonMaxDuration, nil
+	}
+
+	lastActivity := rental.StartTime
+	last, err := routeRepo.GetLast(ctx, rental.Id)
+	if err != nil {
+		return "", err
+	}
+	if last != nil && last.RecordedAt.After(lastActivity) {
+		lastActivity = last.RecordedAt
+	}
+	if now.Sub(lastActivity) > cfg.IdleAfter {
+		return models.EndReasonIdle, nil
+	}
+	return "", nil
+}
+
+// warnRentalEnd: Fija el cierre del alquiler al final del plazo de gracia y avisa al usuario con el evento
+// rental.end_warning y por el stream de sus alquileres. Debe llamarse dentro de una transacción
+func warnRentalEnd(ctx context.Context, rental *models.Rental, reason models.RentalEndReason, cfg config.RentalsAutoEndConfig, now time.Time) error {
+	user, err := userRepo.GetById(ctx, rental.UserId)
+	if err != nil {
+		return err
+	}
+
+	before := snapshot(rental)
+	deadline := now.Add(cfg.GracePeriod)
+	rental.WarnedAt, rental.AutoEndAt = &now, &deadline
+	if err := saveRental(ctx, rental); err != nil {
+		return err
+	}
+	if err := recordAudit(ctx, "rental.end_warning", AuditEntityRental, rental.Id, before, rental); err != nil {
+		return err
+	}
+
+	warning := &rentalEndWarning{
+		Rental:     rental,
+		User:       newUserEvent(user),
+		Reason:     reason,
+		AutoEndAt:  deadline,
+		PenaltyFee: cfg.PenaltyFee,
+		Message:    i18n.Translate(i18n.Lang(user.Language), "rental.end_warning."+string(reason), i18n.Params{"count": int(math.Ceil(cfg.GracePeriod.Minutes()))}),
+	}
+	if err := publishEvent(ctx, models.EventRentalEndWarning, AuditEntityRental, rental.Id, warning); err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "rental end warning", "rental_id", rental.Id, "reason", reason, "auto_end_at", deadline)
+	return nil
+}
+
+// cancelEndWarning: Retira el aviso de cierre del alquiler que volvió a registrar recorrido. Si vuelve a
+// quedar inactivo se avisa de nuevo con otro plazo de gracia. Debe llamarse dentro de una transacción
+func cancelEndWarning(ctx context.Context, rental *models.Rental) error {
+	before := snapshot(rental)
+	rental.WarnedAt, rental.AutoEndAt = nil, nil
+	if err := saveRental(ctx, rental); err != nil {
+		return err
+	}
+	if err := recordAudit(ctx, "rental.end_warning_cancel", AuditEntityRental, rental.Id, before, rental); err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "rental end warning cancelled", "rental_id", rental.Id)
+	return nil
+}
+
+// saveRental: Guarda el alquiler si no cambió desde que se leyó
+func saveRental(ctx context.Context, rental *models.Rental) error {
+	rows, err := rentalRepo.Update(ctx, rental)
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return apperror.Conflict("error.concurrent_update")
+	}
+	rental.Version++
+	return nil
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/mbarolo/test_back/config"
+	"github.com/mbarolo/test_back/forms"
+	"github.com/mbarolo/test_back/lock"
+	"github.com/mbarolo/test_back/models"
+	"github.com/mbarolo/test_back/notify"
+)
+
+// abandonedRental: Alquiler que superó la duración máxima y cuyo plazo de gracia ya venció
+func abandonedRental(t *testing.T, bike *models.Bike) *models.Rental {
+	t.Helper()
+	ctx := context.Background()
+	rental, err := StartRental(ctx, newTestUser(t), &forms.StartEndRentalForm{BikeID: bike.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	autoEndAt := time.Now().Add(-time.Minute)
+	rental.StartTime = rental.StartTime.Add(-config.Current().Rentals.AutoEnd.MaxDuration - time.Hour)
+	rental.WarnedAt, rental.AutoEndAt = &autoEndAt, &autoEndAt
+	if err := saveRental(ctx, rental); err != nil {
+		t.Fatal(err)
+	}
+	return rental
+}
+
+func TestAutoEndLockFailed(t *testing.T) {
+	ctx := context.Background()
+	notifier := &blockingNotifier{release: make(chan struct{}), sent: make(chan notify.Alert, 1)}
+	close(notifier.release)
+	setNotifier(t, notifier)
+	bike := newTestBike(t)
+	rental := abandonedRental(t, bike)
+
+	// el candado quedó abierto: el alquiler se cierra igual
+	lockSimulator.SetDevice(bike.Id, lock.Device{State: lock.StateUnlocked, Jammed: true})
+	if err := checkAbandonedRental(ctx, rental.Id); err != nil {
+		t.Fatal(err)
+	}
+	ended, err := GetRentalById(ctx, rental.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ended.RentalStatus != models.ENDED || ended.EndReason == nil || *ended.EndReason != models.EndReasonMaxDuration {
+		t.Errorf("alquiler = %s %v, se esperaba ended max_duration", ended.RentalStatus, ended.EndReason)
+	}
+
+	// la bicicleta sin candado no se puede alquilar hasta que la revisen
+	want := []string{"available bike.create", "reserved rental.start", "rented rental.start", "available rental.end", "maintenance rental.lock_failed"}
+	if reasons := statusHistory(t, bike.Id); !slices.Equal(reasons, want) {
+		t.Errorf("historial = %v, se esperaba %v", reasons, want)
+	}
+
+	incident := lockFailedIncident(t, bike.Id, rental.Id, 1)
+	select {
+	case alert := <-notifier.sent:
+		if alert.IncidentId != incident.Id {
+			t.Errorf("aviso = %+v, se esperaba el del incidente %d", alert, incident.Id)
+		}
+	default:
+		t.Error("no se avisó del incidente")
+	}
+}
+
+// lockFailedIncident: Único incidente sin resolver de la bicicleta, lock_failed del alquiler (0 sin alquiler) y con
+// las ocurrencias indicadas
+func lockFailedIncident(t *testing.T, bikeId, rentalId int64, occurrences int) *models.Incident {
+	t.Helper()
+	incidents, err := incidentRepo.GetUnresolved(context.Background(), bikeId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(incidents) != 1 {
+		t.Fatalf("incidentes = %+v, se esperaba uno lock_failed", incidents)
+	}
+	incident := incidents[0]
+	var incidentRental int64
+	if incident.RentalId != nil {
+		incidentRental = *incident.RentalId
+	}
+	if incident.Rule != models.RuleLockFailed || incidentRental != rentalId || incident.Occurrences != occurrences {
+		t.Fatalf("incidente = %+v, se esperaba lock_failed del alquiler %d con %d ocurrencias", incident, rentalId, occurrences)
+	}
+	return incident
+}
+
+// TestAutoEndLockFailedTwice: El admin vuelve a habilitar la bicicleta sin resolver el incidente y el candado
+// vuelve a fallar en otro alquiler: se suma la ocurrencia al mismo incidente y el alquiler se cierra igual
+func TestAutoEndLockFailedTwice(t *testing.T) {
+	ctx := context.Background()
+	bike := newTestBike(t)
+	lockSimulator.SetDevice(bike.Id, lock.Device{State: lock.StateUnlocked, Jammed: true})
+
+	first := abandonedRental(t, bike)
+	if err := checkAbandonedRental(ctx, first.Id); err != nil {
+		t.Fatal(err)
+	}
+	lockFailedIncident(t, bike.Id, first.Id, 1)
+
+	// la bicicleta vuelve a circular con el candado trabado, que se libera al alquilarla
+	if _, err := ChangeBikeStatus(ctx, bike.Id, &forms.BikeStatusForm{Status: string(models.BikeAvailable), Reason: "revisada"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	second := abandonedRental(t, bike)
+	if err := checkAbandonedRental(ctx, second.Id); err != nil {
+		t.Fatal(err)
+	}
+
+	ended, err := GetRentalById(ctx, second.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ended.RentalStatus != models.ENDED {
+		t.Errorf("estado = %s, se esperaba ended", ended.RentalStatus)
+	}
+	lockFailedIncident(t, bike.Id, second.Id, 2)
+	if current, err := GetBikeById(ctx, bike.Id); err != nil || current.Status != models.BikeMaintenance {
+		t.Errorf("bicicleta = %+v (%v), se esperaba en mantenimiento", current, err)
+	}
+}
+
+func TestAutoEndLocked(t *testing.T) {
+	ctx := context.Background()
+	bike := newTestBike(t)
+	rental := abandonedRental(t, bike)
+
+	if err := checkAbandonedRental(ctx, rental.Id); err != nil {
+		t.Fatal(err)
+	}
+	current, err := GetBikeById(ctx, bike.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.Status != models.BikeAvailable {
+		t.Errorf("estado = %s, se esperaba available", current.Status)
+	}
+	if incidents, err := incidentRepo.GetUnresolved(ctx, bike.Id); err != nil || len(incidents) != 0 {
+		t.Errorf("incidentes = %+v (%v), se esperaba ninguno", incidents, err)
+	}
+}
@@ -0,0 +1,371 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/mbarolo/test_back/apperror"
+	"github.com/mbarolo/test_back/forms"
+	"github.com/mbarolo/test_back/lock"
+	"github.com/mbarolo/test_back/models"
+	"github.com/mbarolo/test_back/utils"
+)
+
+// spanInt: Valor del atributo entero del span, false si no lo tiene
+func spanInt(span sdktrace.ReadOnlySpan, key string) (int64, bool) {
+	for _, attr := range span.Attributes() {
+		if attr.Key == attribute.Key(key) {
+			return attr.Value.AsInt64(), true
+		}
+	}
+	return 0, false
+}
+
+// rentalSpan: Último span finalizado con el nombre, del usuario indicado
+func rentalSpan(t *testing.T, name string, userId int64) sdktrace.ReadOnlySpan {
+	t.Helper()
+	var found sdktrace.ReadOnlySpan
+	for _, span := range endedSpans(name) {
+		if id, _ := spanInt(span, "user_id"); id == userId {
+			found = span
+		}
+	}
+	if found == nil {
+		t.Fatalf("no se registró el span %s", name)
+	}
+	return found
+}
+
+func TestRentalSpans(t *testing.T) {
+	ctx := context.Background()
+	user := newTestUser(t)
+	bike := newTestBike(t)
+
+	rental, err := StartRental(ctx, user, &forms.StartEndRentalForm{BikeID: bike.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := EndRental(ctx, user, &forms.StartEndRentalForm{BikeID: bike.Id}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"services.StartRental", "services.EndRental"} {
+		span := rentalSpan(t, name, user.Id)
+		want := map[string]int64{"user_id": user.Id, "bike_id": bike.Id, "rental_id": rental.Id}
+		for key, value := range want {
+			if got, ok := spanInt(span, key); !ok || got != value {
+				t.Errorf("%s: %s = %d, se esperaba %d", name, key, got, value)
+			}
+		}
+		if span.Status().Code == codes.Error {
+			t.Errorf("%s: status = %v, se esperaba sin error", name, span.Status())
+		}
+	}
+}
+
+func TestRentalSpansError(t *testing.T) {
+	ctx := context.Background()
+	user := newTestUser(t)
+	bike := newTestBike(t)
+
+	// sin alquiler en curso el error es del cliente: queda registrado pero no marca el span como fallido
+	if _, err := EndRental(ctx, user, &forms.StartEndRentalForm{BikeID: bike.Id}); err == nil {
+		t.Fatal("se finalizó un alquiler inexistente")
+	}
+
+	span := rentalSpan(t, "services.EndRental", user.Id)
+	if _, ok := spanInt(span, "rental_id"); ok {
+		t.Error("rental_id registrado sin alquiler en curso")
+	}
+	if span.Status().Code == codes.Error {
+		t.Errorf("status = %v, se esperaba sin error", span.Status())
+	}
+	if len(span.Events()) == 0 || span.Events()[0].Name != "exception" {
+		t.Errorf("eventos = %v, se esperaba el error registrado", span.Events())
+	}
+}
+
+// rentalError: Código y mensaje del error de dominio
+func rentalError(t *testing.T, err error) string {
+	t.Helper()
+	var appErr *apperror.Error
+	if !errors.As(err, &appErr) {
+		t.Fatalf("error = %v, se esperaba un apperror", err)
+	}
+	return string(appErr.Code) + " " + appErr.Message
+}
+
+// statusHistory: Cambios de estado de la bicicleta en orden cronológico, como "estado motivo"
+func statusHistory(t *testing.T, bikeId int64) []string {
+	t.Helper()
+	spec, err := utils.ParseQuerySpec(url.Values{"sort": {"created_at"}, "limit": {"100"}}, forms.BikeStatusHistoryQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err := GetBikeStatusHistory(context.Background(), bikeId, spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var changes []string
+	for _, change := range page.Items {
+		changes = append(changes, string(change.ToStatus)+" "+change.Reason)
+	}
+	return changes
+}
+
+func TestStartRentalUnlockFailed(t *testing.T) {
+	ctx := context.Background()
+	user := newTestUser(t)
+	bike := newTestBike(t)
+	lockSimulator.SetDevice(bike.Id, lock.Device{Offline: true})
+
+	_, err := StartRental(ctx, user, &forms.StartEndRentalForm{BikeID: bike.Id})
+	if got := rentalError(t, err); got != "UNAVAILABLE lock.offline" {
+		t.Fatalf("error = %s, se esperaba UNAVAILABLE lock.offline", got)
+	}
+
+	// desconectado tampoco confirma que sigue cerrado: la reserva se deshace pero la bicicleta queda en
+	// mantenimiento y no queda un alquiler en curso
+	running, err := rentalRepo.GetRunningRental(ctx, user.Id)
+	if err != nil || running != nil {
+		t.Errorf("alquiler en curso = %v (%v), se esperaba ninguno", running, err)
+	}
+	want := []string{"available bike.create", "reserved rental.start", "maintenance rental.lock_failed"}
+	if reasons := statusHistory(t, bike.Id); !slices.Equal(reasons, want) {
+		t.Errorf("historial = %v, se esperaba %v", reasons, want)
+	}
+	lockFailedIncident(t, bike.Id, 0, 1)
+
+	lockSimulator.SetDevice(bike.Id, lock.Device{})
+	if _, err := ChangeBikeStatus(ctx, bike.Id, &forms.BikeStatusForm{Status: string(models.BikeAvailable), Reason: "revisada"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := StartRental(ctx, user, &forms.StartEndRentalForm{BikeID: bike.Id}); err != nil {
+		t.Errorf("no se pudo alquilar con el candado conectado: %v", err)
+	}
+}
+
+// TestStartRentalNoAck: El candado abrió pero no lo confirmó y tampoco responde al cerrarlo
+func TestStartRentalNoAck(t *testing.T) {
+	ctx := context.Background()
+	user := newTestUser(t)
+	bike := newTestBike(t)
+	lockSimulator.SetDevice(bike.Id, lock.Device{Silent: true})
+
+	_, err := StartRental(ctx, user, &forms.StartEndRentalForm{BikeID: bike.Id})
+	if got := rentalError(t, err); got != "UNAVAILABLE lock.no_ack" {
+		t.Fatalf("error = %s, se esperaba UNAVAILABLE lock.no_ack", got)
+	}
+
+	// no se sabe si el candado quedó abierto, no se ofrece a otro usuario
+	want := []string{"available bike.create", "reserved rental.start", "maintenance rental.lock_failed"}
+	if reasons := statusHistory(t, bike.Id); !slices.Equal(reasons, want) {
+		t.Errorf("historial = %v, se esperaba %v", reasons, want)
+	}
+	lockFailedIncident(t, bike.Id, 0, 1)
+}
+
+// TestStartRentalNoAckRelocked: El candado abrió sin confirmarlo y vuelve a responder al cerrarlo, la bicicleta
+// queda disponible
+func TestStartRentalNoAckRelocked(t *testing.T) {
+	ctx := context.Background()
+	user := newTestUser(t)
+	bike := newTestBike(t)
+	lockSimulator.SetDevice(bike.Id, lock.Device{Silent: true})
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := StartRental(ctx, user, &forms.StartEndRentalForm{BikeID: bike.Id})
+		done <- err
+	}()
+
+	// el candado abre sin confirmar y mientras se espera la confirmación vuelve a responder
+	deadline := time.Now().Add(time.Second)
+	for lockSimulator.Device(bike.Id).State != lock.StateUnlocked {
+		if time.Now().After(deadline) {
+			t.Fatal("el candado no abrió")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	lockSimulator.SetDevice(bike.Id, lock.Device{State: lock.StateUnlocked})
+
+	if got := rentalError(t, <-done); got != "UNAVAILABLE lock.no_ack" {
+		t.Fatalf("error = %s, se esperaba UNAVAILABLE lock.no_ack", got)
+	}
+	if state := lockSimulator.Device(bike.Id).State; state != lock.StateLocked {
+		t.Errorf("candado = %s, se esperaba cerrado", state)
+	}
+	want := []string{"available bike.create", "reserved rental.start", "available rental.unlock_failed"}
+	if reasons := statusHistory(t, bike.Id); !slices.Equal(reasons, want) {
+		t.Errorf("historial = %v, se esperaba %v", reasons, want)
+	}
+	if incidents, err := incidentRepo.GetUnresolved(ctx, bike.Id); err != nil || len(incidents) != 0 {
+		t.Errorf("incidentes = %+v (%v), se esperaba ninguno", incidents, err)
+	}
+}
+
+func TestEndRentalLockFailed(t *testing.T) {
+	ctx := context.Background()
+	user := newTestUser(t)
+	bike := newTestBike(t)
+	form := &forms.StartEndRentalForm{BikeID: bike.Id}
+
+	rental, err := StartRental(ctx, user, form)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// el usuario no trabó el candado: el alquiler sigue en curso y puede reintentar
+	lockSimulator.SetDevice(bike.Id, lock.Device{State: lock.StateUnlocked, Jammed: true})
+	_, err = EndRental(ctx, user, form)
+	if got := rentalError(t, err); got != "CONFLICT lock.open" {
+		t.Fatalf("error = %s, se esperaba CONFLICT lock.open", got)
+	}
+	if current, err := GetRentalById(ctx, rental.Id); err != nil || current.RentalStatus != models.RUNNING {
+		t.Fatalf("alquiler = %+v (%v), se esperaba en curso", current, err)
+	}
+
+	lockSimulator.SetDevice(bike.Id, lock.Device{State: lock.StateUnlocked})
+	ended, err := EndRental(ctx, user, form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ended.RentalStatus != models.ENDED {
+		t.Errorf("estado = %s, se esperaba ended", ended.RentalStatus)
+	}
+}
+
+// TestLockCommandOutsideTx: Mientras se espera la confirmación del candado las demás escrituras no se bloquean
+func TestLockCommandOutsideTx(t *testing.T) {
+	ctx := context.Background()
+	user := newTestUser(t)
+	bike := newTestBike(t)
+	lockSimulator.SetDevice(bike.Id, lock.Device{Silent: true})
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := StartRental(ctx, user, &forms.StartEndRentalForm{BikeID: bike.Id})
+		done <- err
+	}()
+
+	// se espera a que la bicicleta quede reservada, el comando ya está en curso
+	deadline := time.Now().Add(time.Second)
+	for {
+		current, err := GetBikeById(ctx, bike.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if current.Status == models.BikeReserved {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("la bicicleta no quedó reservada")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	newTestBike(t)
+	select {
+	case err := <-done:
+		t.Errorf("la escritura esperó al candado, StartRental terminó antes: %v", err)
+	default:
+	}
+
+	if got := rentalError(t, <-done); got != "UNAVAILABLE lock.no_ack" {
+		t.Errorf("error = %s, se esperaba UNAVAILABLE lock.no_ack", got)
+	}
+}
+
+func TestEndRentalDistance(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("without route", func(t *testing.T) {
+		user := newTestUser(t)
+		bike := newTestBike(t)
+		form := &forms.StartEndRentalForm{BikeID: bike.Id}
+		if _, err := StartRental(ctx, user, form); err != nil {
+			t.Fatal(err)
+		}
+		ended, err := EndRental(ctx, user, form)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ended.DistanceM != nil {
+			t.Errorf("distance_m = %d, se esperaba nil sin recorrido", *ended.DistanceM)
+		}
+	})
+
+	t.Run("with route", func(t *testing.T) {
+		user := newTestUser(t)
+		bike := newTestBike(t)
+		form := &forms.StartEndRentalForm{BikeID: bike.Id}
+		rental, err := StartRental(ctx, user, form)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// ~1112 m hacia el norte desde el inicio, en dos tramos
+		now := time.Now()
+		points := []*models.RoutePoint{
+			{RentalId: rental.Id, RecordedAt: now, Latitude: rental.StartLatitude + 0.005, Longitude: rental.StartLongitude, Source: models.RouteSourceDevice},
+			{RentalId: rental.Id, RecordedAt: now.Add(time.Second), Latitude: rental.StartLatitude + 0.01, Longitude: rental.StartLongitude, Source: models.RouteSourceDevice},
+		}
+		if err := routeRepo.InsertBatch(ctx, points); err != nil {
+			t.Fatal(err)
+		}
+
+		ended, err := EndRental(ctx, user, form)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ended.DistanceM == nil || *ended.DistanceM < 1100 || *ended.DistanceM > 1125 {
+			t.Errorf("distance_m = %v, se esperaba ~1112", ended.DistanceM)
+		}
+		if *ended.EndLatitude != points[1].Latitude || *ended.EndLongitude != points[1].Longitude {
+			t.Errorf("fin = %f,%f, se esperaba el último punto", *ended.EndLatitude, *ended.EndLongitude)
+		}
+	})
+}
+
+func TestUpdateRentalReadonlyFields(t *testing.T) {
+	ctx := context.Background()
+	user := newTestUser(t)
+	bike := newTestBike(t)
+	form := &forms.StartEndRentalForm{BikeID: bike.Id}
+	rental, err := StartRental(ctx, user, form)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// terminarlo por PATCH dejaría la bicicleta alquilada y sin costo, incluso limpiando campos con null
+	_, err = UpdateRental(ctx, rental.Id, []byte(`{"rental_status": "ended", "end_time": null, "start_latitude": -34.6}`), nil)
+	var appErr *apperror.Error
+	if !errors.As(err, &appErr) || appErr.Code != apperror.VALIDATION {
+		t.Fatalf("error = %v, se esperaba un error de validación", err)
+	}
+	var fields []string
+	for _, field := range appErr.Fields {
+		fields = append(fields, field.Field+" "+field.Message)
+	}
+	if want := []string{"end_time validation.readonly", "rental_status validation.readonly"}; !slices.Equal(fields, want) {
+		t.Errorf("campos = %v, se esperaba %v", fields, want)
+	}
+	if current, err := GetRentalById(ctx, rental.Id); err != nil || current.RentalStatus != models.RUNNING || current.StartLatitude != rental.StartLatitude {
+		t.Errorf("alquiler = %+v (%v), se esperaba sin cambios", current, err)
+	}
+
+	updated, err := UpdateRental(ctx, rental.Id, []byte(`{"start_latitude": -34.6}`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.StartLatitude != -34.6 {
+		t.Errorf("start_latitude = %f, se esperaba -34.6", updated.StartLatitude)
+	}
+}
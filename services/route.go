@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/mbarolo/test_back/apperror"
+	"github.com/mbarolo/test_back/config"
+	"github.com/mbarolo/test_back/logging"
+	"github.com/mbarolo/test_back/models"
+	"github.com/mbarolo/test_back/utils"
+)
+
+// RecordRoute: Agrega al recorrido del alquiler en curso los puntos enviados por la app del ciclista
+func RecordRoute(ctx context.Context, currentUser *models.User, rentalId int64, points []*models.RoutePoint) (int, error) {
+	logging.AddAttrs(ctx, slog.Int64("rental_id", rentalId))
+
+	now := time.Now()
+	err := inTx(ctx, func(ctx context.Context) error {
+		rental, err := getUserRental(ctx, currentUser, rentalId)
+		if err != nil {
+			return err
+		}
+		if rental.RentalStatus != models.RUNNING {
+			return apperror.Conflict("route.rental_not_running")
+		}
+
+		for _, point := range points {
+			if point.RecordedAt.Before(rental.StartTime) || point.RecordedAt.After(now.Add(telemetryClockSkew)) {
+				return apperror.Validation("validation.failed", apperror.FieldError{Field: "recorded_at", Message: "route.point_outside_rental"})
+			}
+			point.RentalId = rental.Id
+			point.Source = models.RouteSourceApp
+		}
+		return routeRepo.InsertBatch(ctx, points)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	slog.DebugContext(ctx, "route points recorded", "points", len(points), "source", models.RouteSourceApp)
+	return len(points), nil
+}
+
+// recordTelemetryRoute: Agrega al recorrido del alquiler en curso de la bicicleta las posiciones de la telemetría.
+// Debe llamarse dentro de la transacción que guarda las lecturas
+func recordTelemetryRoute(ctx context.Context, bikeId int64, readings []*models.TelemetryReading) error {
+	rental, err := rentalRepo.GetRunningByBike(ctx, bikeId)
+	if err != nil || rental == nil {
+		return err
+	}
+
+	var points []*models.RoutePoint
+	for _, reading := range readings {
+		if !reading.HasPosition() || reading.RecordedAt.Before(rental.StartTime) {
+			continue
+		}
+		points = append(points, &models.RoutePoint{
+			RentalId:   rental.Id,
+			RecordedAt: reading.RecordedAt,
+			Latitude:   *reading.Latitude,
+			Longitude:  *reading.Longitude,
+			Accuracy:   reading.Accuracy,
+			Source:     models.RouteSourceDevice,
+		})
+	}
+	return routeRepo.InsertBatch(ctx, points)
+}
+
+// finishRoute: Calcula la distancia del alquiler que termina sobre el recorrido completo y guarda solo la versión
+// simplificada (trips.simplify_tolerance). Debe llamarse dentro de la transacción, con la posición de fin ya asignada
+func finishRoute(ctx context.Context, rental *models.Rental, points []*models.RoutePoint) error {
+	// sin puntos registrados no se conoce el recorrido, la distancia queda sin calcular
+	if len(points) == 0 {
+		return nil
+	}
+
+	path := routePath(rental, points)
+	distance := int(math.Round(utils.PathDistance(path, (*models.RoutePoint).Position)))
+	rental.DistanceM = &distance
+
+	// se simplifica con los extremos del alquiler, que no se guardan como puntos (Id 0)
+	tolerance := float64(config.Current().Trips.SimplifyTolerance)
+	var keep []*models.RoutePoint
+	for _, point := range utils.SimplifyPath(path, (*models.RoutePoint).Position, tolerance) {
+		if point.Id != 0 {
+			keep = append(keep, point)
+		}
+	}
+	if len(keep) == len(points) {
+		return nil
+	}
+	dropped, err := routeRepo.DeleteExcept(ctx, rental.Id, keep)
+	if err != nil {
+		return err
+	}
+	slog.DebugContext(ctx, "route simplified", "points", len(points), "dropped", dropped)
+	return nil
+}
+
+// GetRentalRoute: Recorrido del alquiler. Si currentUser no es nil solo puede ver sus propios alquileres
+func GetRentalRoute(ctx context.Context, currentUser *models.User, rentalId int64) (*models.Route, error) {
+	logging.AddAttrs(ctx, slog.Int64("rental_id", rentalId))
+
+	var rental *models.Rental
+	var err error
+	if currentUser != nil {
+		rental, err = getUserRental(ctx, currentUser, rentalId)
+	} else {
+		rental, err = GetRentalById(ctx, rentalId)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	points, err := routeRepo.GetByRental(ctx, rental.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	// los recorridos terminados ya se guardan simplificados, los que están en curso se simplifican al consultarlos
+	path := routePath(rental, points)
+	if rental.RentalStatus == models.RUNNING {
+		path = utils.SimplifyPath(path, (*models.RoutePoint).Position, float64(config.Current().Trips.SimplifyTolerance))
+	}
+	return &models.Route{Rental: rental, Points: path}, nil
+}
+
+// getUserRental: Alquiler del usuario, uno de otro usuario se informa como inexistente
+func getUserRental(ctx context.Context, currentUser *models.User, rentalId int64) (*models.Rental, error) {
+	rental, err := GetRentalById(ctx, rentalId)
+	if err != nil {
+		return nil, err
+	}
+	if rental.UserId != currentUser.Id {
+		return nil, apperror.NotFound("rental.not_found")
+	}
+	return rental, nil
+}
+
+// routePath: Recorrido completo, con la posición de inicio del alquiler y la de fin si ya terminó.
+// La posición de fin no se repite si coincide con el último punto
+func routePath(rental *models.Rental, points []*models.RoutePoint) []*models.RoutePoint {
+	path := make([]*models.RoutePoint, 0, len(points)+2)
+	path = append(path, &models.RoutePoint{RentalId: rental.Id, RecordedAt: rental.StartTime, Latitude: rental.StartLatitude, Longitude: rental.StartLongitude})
+	path = append(path, points...)
+	if rental.EndTime == nil || rental.EndLatitude == nil || rental.EndLongitude == nil {
+		return path
+	}
+	if last := path[len(path)-1]; last.Latitude == *rental.EndLatitude && last.Longitude == *rental.EndLongitude {
+		return path
+	}
+	return append(path, &models.RoutePoint{RentalId: rental.Id, RecordedAt: *rental.EndTime, Latitude: *rental.EndLatitude, Longitude: *rental.EndLongitude})
+}
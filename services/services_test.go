@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/mbarolo/test_back/config"
+	"github.com/mbarolo/test_back/forms"
+	"github.com/mbarolo/test_back/models"
+)
+
+// spans: Spans finalizados durante los tests. El provider global se configura una sola vez porque el tracer
+// de la aplicación queda asociado al primero
+var spans = tracetest.NewSpanRecorder()
+
+// TestMain: Base de datos en un directorio temporal y candados simulados sin latencia
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "services")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)))
+
+	cfg := config.Default()
+	cfg.Database.Path = filepath.Join(dir, "test.db")
+	cfg.Locks.SimulatorLatency = time.Millisecond
+	cfg.Locks.Timeout = 200 * time.Millisecond
+	config.Set(cfg)
+
+	Init(cfg)
+	if err := InitLocks(cfg.Locks); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+	config.CloseDB()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// setConfig: Cambia la configuración durante el test y la restaura al terminar
+func setConfig(t *testing.T, change func(cfg *config.Config)) {
+	t.Helper()
+	previous := config.Current()
+	cfg := *previous
+	change(&cfg)
+	config.Set(&cfg)
+	t.Cleanup(func() { config.Set(previous) })
+}
+
+func newTestUser(t *testing.T) *models.User {
+	t.Helper()
+	now := time.Now()
+	user := &models.User{
+		Email:     fmt.Sprintf("%s-%d@test.com", t.Name(), now.UnixNano()),
+		FirstName: "Test",
+		LastName:  "User",
+		Language:  "es",
+		Version:   1,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	id, err := userRepo.Create(context.Background(), user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user.Id = id
+	return user
+}
+
+func newTestBike(t *testing.T) *models.Bike {
+	t.Helper()
+	lat, lng := -34.6037, -58.3816
+	bike, err := CreateBike(context.Background(), &forms.BikeForm{Latitude: &lat, Longitude: &lng})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bike
+}
+
+// endedSpans: Spans finalizados con el nombre indicado
+func endedSpans(name string) []sdktrace.ReadOnlySpan {
+	var found []sdktrace.ReadOnlySpan
+	for _, span := range spans.Ended() {
+		if span.Name() == name {
+			found = append(found, span)
+		}
+	}
+	return found
+}
@@ -0,0 +1,304 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/mbarolo/test_back/apperror"
+	"github.com/mbarolo/test_back/config"
+	"github.com/mbarolo/test_back/forms"
+	"github.com/mbarolo/test_back/logging"
+	"github.com/mbarolo/test_back/models"
+	"github.com/mbarolo/test_back/stream"
+	"github.com/mbarolo/test_back/utils"
+)
+
+// GetStations: Estaciones activas con las bicicletas que se pueden alquilar en cada una
+func GetStations(ctx context.Context) ([]*models.Station, error) {
+	stations, err := stationRepo.GetActive(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "list stations failed", "error", err)
+		return nil, err
+	}
+	if err := withDockedBikes(ctx, stations, true); err != nil {
+		return nil, err
+	}
+	return stations, nil
+}
+
+func GetAllStations(ctx context.Context, spec *utils.QuerySpec) (*utils.Page[models.Station], error) {
+	stations, err := stationRepo.GetPage(ctx, spec)
+	if err != nil {
+		slog.ErrorContext(ctx, "list stations failed", "error", err)
+		return nil, err
+	}
+	return stations, nil
+}
+
+// GetStationById: Estación con todas las bicicletas ancladas, cualquiera sea su estado
+func GetStationById(ctx context.Context, id int64) (*models.Station, error) {
+	station, err := getStation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := withDockedBikes(ctx, []*models.Station{station}, false); err != nil {
+		return nil, err
+	}
+	return station, nil
+}
+
+func CreateStation(ctx context.Context, form *forms.StationForm) (*models.Station, error) {
+	station := form.ToStation()
+	station.CreatedAt = time.Now()
+	station.UpdatedAt = station.CreatedAt
+
+	err := inTx(ctx, func(ctx context.Context) error {
+		id, err := stationRepo.Create(ctx, station)
+		if err != nil {
+			return err
+		}
+		if station, err = stationRepo.GetById(ctx, id); err != nil {
+			return err
+		}
+		return recordAudit(ctx, "station.create", AuditEntityStation, id, nil, station)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.AddAttrs(ctx, slog.Int64("station_id", station.Id))
+	slog.InfoContext(ctx, "station created", "capacity", station.Capacity)
+	return station, nil
+}
+
+// UpdateStation: Aplica un JSON Merge Patch a la estación. Si ifMatch no es nil debe coincidir con la versión actual.
+// La capacidad no puede quedar por debajo de los anclajes ocupados
+func UpdateStation(ctx context.Context, id int64, patch []byte, ifMatch *int64) (*models.Station, error) {
+	logging.AddAttrs(ctx, slog.Int64("station_id", id))
+
+	var station *models.Station
+	err := inTx(ctx, func(ctx context.Context) error {
+		var err error
+		station, err = getStation(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(ifMatch, station.Version); err != nil {
+			return err
+		}
+
+		before := snapshot(station)
+		if err := utils.MergePatch(station, patch); err != nil {
+			return err
+		}
+
+		docks, err := stationRepo.GetUsedDocks(ctx, id)
+		if err != nil {
+			return err
+		}
+		if len(docks) > 0 && docks[len(docks)-1] > station.Capacity {
+			return apperror.Conflict("station.capacity_below_docked").WithParams(map[string]interface{}{"dock": docks[len(docks)-1]})
+		}
+
+		station.UpdatedAt = time.Now()
+		rows, err := stationRepo.Update(ctx, station)
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return apperror.New(apperror.PRECONDITION, "error.version_mismatch")
+		}
+		if station, err = stationRepo.GetById(ctx, id); err != nil {
+			return err
+		}
+		return recordAudit(ctx, "station.update", AuditEntityStation, id, before, station)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "station updated", "version", station.Version)
+	return station, nil
+}
+
+// DockBike: Ancla la bicicleta en la estación desde el admin, si estaba en otra estación la mueve
+func DockBike(ctx context.Context, stationId int64, bikeId int64) (*models.Bike, error) {
+	logging.AddAttrs(ctx, slog.Int64("station_id", stationId), slog.Int64("bike_id", bikeId))
+
+	var bike *models.Bike
+	var previous *stream.Point
+	err := inTx(ctx, func(ctx context.Context) error {
+		station, err := getStation(ctx, stationId)
+		if err != nil {
+			return err
+		}
+		if !station.Active {
+			return apperror.Conflict("station.inactive")
+		}
+		if bike, err = GetBikeById(ctx, bikeId); err != nil {
+			return err
+		}
+		previous = bikePoint(bike)
+		if bike.Status == models.BikeRented || bike.Status == models.BikeMissing || bike.Status == models.BikeRetired {
+			return apperror.Conflict("station.bike_not_dockable").WithParams(map[string]interface{}{"status": bike.Status})
+		}
+		if bike.StationId != nil && *bike.StationId == stationId {
+			return nil
+		}
+
+		before := snapshot(bike)
+		if err := dockAt(ctx, bike, station); err != nil {
+			return err
+		}
+		if err := saveBike(ctx, bike); err != nil {
+			return err
+		}
+		return recordAudit(ctx, "bike.dock", AuditEntityBike, bikeId, before, bike)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "bike docked", "dock", bike.Dock)
+	publishBike(bike, previous)
+	return bike, nil
+}
+
+// UndockBike: Libera el anclaje de la bicicleta desde el admin, queda suelta en la ubicación de la estación
+func UndockBike(ctx context.Context, stationId int64, bikeId int64) (*models.Bike, error) {
+	logging.AddAttrs(ctx, slog.Int64("station_id", stationId), slog.Int64("bike_id", bikeId))
+
+	var bike *models.Bike
+	err := inTx(ctx, func(ctx context.Context) error {
+		var err error
+		if bike, err = GetBikeById(ctx, bikeId); err != nil {
+			return err
+		}
+		if bike.StationId == nil || *bike.StationId != stationId {
+			return apperror.NotFound("station.bike_not_docked")
+		}
+
+		before := snapshot(bike)
+		bike.StationId, bike.Dock = nil, nil
+		if err := saveBike(ctx, bike); err != nil {
+			return err
+		}
+		return recordAudit(ctx, "bike.undock", AuditEntityBike, bikeId, before, bike)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "bike undocked")
+	publishBike(bike, nil)
+	return bike, nil
+}
+
+// dockAt: Asigna a la bicicleta el primer anclaje libre de la estación y su ubicación.
+// Debe llamarse dentro de la transacción que guarda la bicicleta
+func dockAt(ctx context.Context, bike *models.Bike, station *models.Station) error {
+	docks, err := stationRepo.GetUsedDocks(ctx, station.Id)
+	if err != nil {
+		return err
+	}
+
+	// los anclajes se numeran desde 1, el primero libre es el primer hueco en la lista ordenada
+	dock := 1
+	for _, used := range docks {
+		if used != dock {
+			break
+		}
+		dock++
+	}
+	if dock > station.Capacity {
+		return apperror.Conflict("station.full")
+	}
+
+	bike.StationId, bike.Dock = &station.Id, &dock
+	bike.Latitude, bike.Longitude = station.Latitude, station.Longitude
+	return nil
+}
+
+// returnStation: Estación activa más cercana con anclajes libres a menos de stations.snap_tolerance metros
+// de la posición de devolución, nil si no hay. Con stations.return_required y enforce la devolución fuera de una estación es un error
+func returnStation(ctx context.Context, lat, lng float64, enforce bool) (*models.Station, error) {
+	cfg := config.Current().Stations
+	tolerance := float64(cfg.SnapTolerance)
+	minLat, minLon, maxLat, maxLon := utils.BoundingBox(lat, lng, tolerance)
+	stations, err := stationRepo.GetActiveInBox(ctx, minLat, minLon, maxLat, maxLon)
+	if err != nil {
+		return nil, err
+	}
+
+	distances := make(map[int64]float64, len(stations))
+	nearby := make([]*models.Station, 0, len(stations))
+	for _, station := range stations {
+		distance := utils.HaversineDistance(lat, lng, station.Latitude, station.Longitude)
+		if distance <= tolerance {
+			distances[station.Id] = distance
+			nearby = append(nearby, station)
+		}
+	}
+	sort.Slice(nearby, func(i, j int) bool { return distances[nearby[i].Id] < distances[nearby[j].Id] })
+
+	for _, station := range nearby {
+		if station.FreeDocks > 0 {
+			return station, nil
+		}
+	}
+	if !cfg.ReturnRequired || !enforce {
+		return nil, nil
+	}
+	if len(nearby) > 0 {
+		return nil, apperror.Conflict("station.full")
+	}
+	return nil, apperror.Conflict("station.return_required").WithParams(map[string]interface{}{"meters": cfg.SnapTolerance})
+}
+
+// saveBike: Guarda la bicicleta con control de versión. Debe llamarse dentro de una transacción
+func saveBike(ctx context.Context, bike *models.Bike) error {
+	rows, err := bikeRepo.UpdateBike(ctx, bike)
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return apperror.Conflict("error.concurrent_update")
+	}
+	bike.Version++
+	return nil
+}
+
+// withDockedBikes: Completa las bicicletas ancladas de cada estación, con onlyAvailable solo las que se pueden alquilar
+func withDockedBikes(ctx context.Context, stations []*models.Station, onlyAvailable bool) error {
+	ids := make([]int64, len(stations))
+	byId := make(map[int64]*models.Station, len(stations))
+	for i, station := range stations {
+		ids[i] = station.Id
+		byId[station.Id] = station
+	}
+
+	bikes, err := bikeRepo.GetDocked(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, bike := range bikes {
+		if onlyAvailable && (bike.Status != models.BikeAvailable || bike.OutOfService) {
+			continue
+		}
+		station := byId[*bike.StationId]
+		station.Bikes = append(station.Bikes, bike)
+	}
+	return nil
+}
+
+func getStation(ctx context.Context, id int64) (*models.Station, error) {
+	station, err := stationRepo.GetById(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperror.NotFound("station.not_found")
+	}
+	return station, err
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mbarolo/test_back/apperror"
+	"github.com/mbarolo/test_back/config"
+	"github.com/mbarolo/test_back/forms"
+	"github.com/mbarolo/test_back/models"
+)
+
+// addRoutePoint: Registra un punto del recorrido del alquiler
+func addRoutePoint(t *testing.T, rental *models.Rental, lat, lng float64) {
+	t.Helper()
+	point := &models.RoutePoint{RentalId: rental.Id, RecordedAt: time.Now(), Latitude: lat, Longitude: lng, Source: models.RouteSourceDevice}
+	if err := routeRepo.InsertBatch(context.Background(), []*models.RoutePoint{point}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReturnRequired(t *testing.T) {
+	ctx := context.Background()
+	setConfig(t, func(cfg *config.Config) {
+		cfg.Stations.ReturnRequired = true
+		cfg.Stations.SnapTolerance = 50
+	})
+
+	// estación lejos de donde se crean las bicicletas de prueba
+	name, lat, lng, capacity := "Estación de prueba", -34.5, -58.5, 5
+	station, err := CreateStation(ctx, &forms.StationForm{Name: &name, Latitude: &lat, Longitude: &lng, Capacity: &capacity})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := func(t *testing.T) (*models.User, *forms.StartEndRentalForm, *models.Rental) {
+		t.Helper()
+		user := newTestUser(t)
+		form := &forms.StartEndRentalForm{BikeID: newTestBike(t).Id}
+		rental, err := StartRental(ctx, user, form)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return user, form, rental
+	}
+
+	t.Run("away from a station", func(t *testing.T) {
+		user, form, rental := start(t)
+		addRoutePoint(t, rental, rental.StartLatitude, rental.StartLongitude)
+
+		_, err := EndRental(ctx, user, form)
+		var appErr *apperror.Error
+		if !errors.As(err, &appErr) || appErr.Message != "station.return_required" {
+			t.Fatalf("error = %v, se esperaba station.return_required", err)
+		}
+	})
+
+	t.Run("at a station", func(t *testing.T) {
+		user, form, rental := start(t)
+		addRoutePoint(t, rental, lat+0.0001, lng)
+
+		ended, err := EndRental(ctx, user, form)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ended.EndStationId == nil || *ended.EndStationId != station.Id {
+			t.Errorf("end_station_id = %v, se esperaba %d", ended.EndStationId, station.Id)
+		}
+	})
+
+	t.Run("without route", func(t *testing.T) {
+		user, form, _ := start(t)
+
+		// no se sabe dónde quedó la bicicleta, no se exige ni se ancla en una estación
+		ended, err := EndRental(ctx, user, form)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ended.EndStationId != nil {
+			t.Errorf("end_station_id = %d, se esperaba nil", *ended.EndStationId)
+		}
+		bike, err := GetBikeById(ctx, form.BikeID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bike.StationId != nil {
+			t.Errorf("station_id = %d, se esperaba suelta", *bike.StationId)
+		}
+	})
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/mbarolo/test_back/apperror"
+	"github.com/mbarolo/test_back/metrics"
+	"github.com/mbarolo/test_back/models"
+	"github.com/mbarolo/test_back/stream"
+)
+
+// eventos que puede acumular un cliente del stream antes de ser desconectado por lento
+const streamBuffer = 64
+
+// bikeHub: Cambios de disponibilidad y ubicación de las bicicletas y de estado de los alquileres
+var bikeHub = stream.NewHub(streamBuffer, func() {
+	metrics.StreamSlowClients.Inc()
+})
+
+// SubscribeBikeStream: Suscribe al usuario a los cambios de las bicicletas dentro de bbox (todas si es nil)
+// y a los de sus propios alquileres. Retorna también el snapshot de las bicicletas disponibles en la zona,
+// tomado después de suscribirse para no perder cambios intermedios
+func SubscribeBikeStream(ctx context.Context, user *models.User, bbox *stream.BBox) (*stream.Subscription, []*models.Bike, error) {
+	subscription, err := bikeHub.Subscribe(stream.Filter{UserId: user.Id, BBox: bbox})
+	if err != nil {
+		return nil, nil, apperror.Unavailable("health.shutting_down", err)
+	}
+
+	snapshot, err := bikeRepo.GetAllAvailable(ctx, "", nil, bbox)
+	if err != nil {
+		subscription.Close()
+		return nil, nil, err
+	}
+
+	slog.DebugContext(ctx, "bike stream subscribed", "snapshot_count", len(snapshot), "clients", bikeHub.Len())
+	return subscription, snapshot, nil
+}
+
+// publishBike: Publica el estado de la bicicleta, con su ubicación anterior si cambió.
+// Debe llamarse después de confirmar la transacción
+func publishBike(bike *models.Bike, previous *stream.Point) {
+	points := []stream.Point{{Latitude: bike.Latitude, Longitude: bike.Longitude}}
+	if previous != nil && *previous != points[0] {
+		points = append(points, *previous)
+	}
+	bikeHub.Publish(stream.Event{Type: stream.EventBike, Points: points, Data: bike})
+}
+
+// publishRental: Publica el alquiler solo a su usuario. Debe llamarse después de confirmar la transacción
+func publishRental(rental *models.Rental) {
+	bikeHub.Publish(stream.Event{Type: stream.EventRental, UserId: rental.UserId, Data: rental})
+}
+
+func bikePoint(bike *models.Bike) *stream.Point {
+	return &stream.Point{Latitude: bike.Latitude, Longitude: bike.Longitude}
+}
@@ -0,0 +1,231 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/mbarolo/test_back/apperror"
+	"github.com/mbarolo/test_back/config"
+	"github.com/mbarolo/test_back/logging"
+	"github.com/mbarolo/test_back/metrics"
+	"github.com/mbarolo/test_back/models"
+	"github.com/mbarolo/test_back/stream"
+	"github.com/mbarolo/test_back/utils"
+)
+
+// diferencia de reloj tolerada en las fechas de las lecturas
+const telemetryClockSkew = 5 * time.Minute
+
+// AuthenticateDevice: Valida el token de un dispositivo y retorna el id de su bicicleta, usado por middleware.DeviceAuth
+func AuthenticateDevice(ctx context.Context, token string) (int64, error) {
+	device, err := deviceRepo.GetByTokenHash(ctx, hashDeviceToken(token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, apperror.Unauthorized("device.invalid_token")
+	}
+	if err != nil {
+		return 0, err
+	}
+	return device.BikeId, nil
+}
+
+// IssueDeviceToken: Emite el token del dispositivo de la bicicleta, si ya tenía uno lo reemplaza.
+// El token solo se retorna en esta respuesta, se guarda su hash
+func IssueDeviceToken(ctx context.Context, bikeId int64) (*models.Device, error) {
+	logging.AddAttrs(ctx, slog.Int64("bike_id", bikeId))
+
+	token, err := newDeviceToken()
+	if err != nil {
+		return nil, err
+	}
+
+	var device *models.Device
+	err = inTx(ctx, func(ctx context.Context) error {
+		if _, err := GetBikeById(ctx, bikeId); err != nil {
+			return err
+		}
+
+		device = &models.Device{BikeId: bikeId, TokenHash: hashDeviceToken(token), CreatedAt: time.Now()}
+		device.Id, err = deviceRepo.Upsert(ctx, device)
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, "device.issue_token", AuditEntityDevice, device.Id, nil, device)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "device token issued", "device_id", device.Id)
+	device.Token = token
+	return device, nil
+}
+
+// RevokeDevice: Elimina el dispositivo de la bicicleta, su token deja de ser válido
+func RevokeDevice(ctx context.Context, bikeId int64) error {
+	logging.AddAttrs(ctx, slog.Int64("bike_id", bikeId))
+
+	return inTx(ctx, func(ctx context.Context) error {
+		device, err := deviceRepo.GetByBikeId(ctx, bikeId)
+		if errors.Is(err, sql.ErrNoRows) {
+			return apperror.NotFound("device.not_found")
+		}
+		if err != nil {
+			return err
+		}
+
+		if _, err := deviceRepo.DeleteByBikeId(ctx, bikeId); err != nil {
+			return err
+		}
+		return recordAudit(ctx, "device.revoke", AuditEntityDevice, device.Id, snapshot(device), nil)
+	})
+}
+
+// IngestTelemetry: Guarda las lecturas del dispositivo en el historial y actualiza el último estado conocido
+// de la bicicleta con el dato más reciente de cada tipo. Si el lote es más viejo que lo último recibido
+// (ej: el dispositivo reenvía lecturas guardadas sin conexión) solo se agrega al historial
+func IngestTelemetry(ctx context.Context, bikeId int64, readings []*models.TelemetryReading) (int, error) {
+	receivedAt := time.Now()
+	for _, reading := range readings {
+		if reading.RecordedAt.After(receivedAt.Add(telemetryClockSkew)) {
+			return 0, apperror.Validation("validation.failed", apperror.FieldError{Field: "recorded_at", Message: "telemetry.recorded_in_future"})
+		}
+		reading.BikeId = bikeId
+	}
+
+	var bike *models.Bike
+	var previous *stream.Point
+	var updated bool
+	err := inTx(ctx, func(ctx context.Context) error {
+		var err error
+		bike, err = GetBikeById(ctx, bikeId)
+		if err != nil {
+			return err
+		}
+
+		if err := telemetryRepo.InsertBatch(ctx, receivedAt, readings); err != nil {
+			return err
+		}
+		if err := deviceRepo.Touch(ctx, bikeId, receivedAt); err != nil {
+			return err
+		}
+		if err := recordTelemetryRoute(ctx, bikeId, readings); err != nil {
+			return err
+		}
+
+		previous = bikePoint(bike)
+		if updated = applyTelemetry(bike, readings); !updated {
+			return nil
+		}
+		if err := bikeRepo.UpdateTelemetry(ctx, bike); err != nil {
+			return err
+		}
+		// se vuelve a leer para actualizar la autonomía estimada
+		bike, err = bikeRepo.GetById(ctx, bikeId)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	metrics.TelemetryReadings.Add(float64(len(readings)))
+	for _, reading := range readings {
+		if len(reading.ErrorCodes) > 0 && string(reading.ErrorCodes) != "[]" {
+			slog.WarnContext(ctx, "device reported errors", "error_codes", string(reading.ErrorCodes), "recorded_at", reading.RecordedAt)
+		}
+	}
+	slog.DebugContext(ctx, "telemetry ingested", "readings", len(readings), "bike_updated", updated)
+	if updated {
+		publishBike(bike, previous)
+		checkBikeIncidents(ctx, bike, previous)
+	}
+	return len(readings), nil
+}
+
+// applyTelemetry: Aplica a la bicicleta el dato más reciente de cada tipo (posición, batería, candado).
+// Retorna false si el lote no es más nuevo que el último recibido
+func applyTelemetry(bike *models.Bike, readings []*models.TelemetryReading) bool {
+	var latest, position, battery, lockState *models.TelemetryReading
+	for _, reading := range readings {
+		if latest == nil || reading.RecordedAt.After(latest.RecordedAt) {
+			latest = reading
+		}
+		if reading.HasPosition() && (position == nil || reading.RecordedAt.After(position.RecordedAt)) {
+			position = reading
+		}
+		if reading.BatteryLevel != nil && (battery == nil || reading.RecordedAt.After(battery.RecordedAt)) {
+			battery = reading
+		}
+		if reading.LockState != nil && (lockState == nil || reading.RecordedAt.After(lockState.RecordedAt)) {
+			lockState = reading
+		}
+	}
+	if latest == nil || (bike.LastSeenAt != nil && !latest.RecordedAt.After(*bike.LastSeenAt)) {
+		return false
+	}
+
+	if position != nil {
+		bike.Latitude, bike.Longitude = *position.Latitude, *position.Longitude
+	}
+	if battery != nil {
+		bike.BatteryLevel = battery.BatteryLevel
+	}
+	if lockState != nil {
+		bike.LockState = lockState.LockState
+	}
+	lastSeenAt := latest.RecordedAt
+	bike.LastSeenAt = &lastSeenAt
+	return true
+}
+
+// GetBikeTelemetry: Historial de telemetría de la bicicleta
+func GetBikeTelemetry(ctx context.Context, bikeId int64, spec *utils.QuerySpec) (*utils.Page[models.TelemetryReading], error) {
+	if _, err := GetBikeById(ctx, bikeId); err != nil {
+		return nil, err
+	}
+
+	spec.AddFilter("bike_id", "=", bikeId)
+	return telemetryRepo.GetPage(ctx, spec)
+}
+
+// DropExpiredTelemetry: Elimina periódicamente los meses de telemetría más antiguos que telemetry.retention
+func DropExpiredTelemetry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		cutoff := time.Now().Add(-config.Current().Telemetry.Retention)
+		dropped, err := telemetryRepo.DropBefore(ctx, cutoff)
+		if len(dropped) > 0 {
+			slog.Info("telemetry partitions dropped", "partitions", dropped)
+		}
+		if err != nil {
+			slog.Error("drop telemetry partitions failed", "error", err)
+		}
+	}
+}
+
+func newDeviceToken() (string, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return "dev_" + hex.EncodeToString(token), nil
+}
+
+// hashDeviceToken: Los tokens son aleatorios de 256 bits, alcanza con SHA-256 para guardarlos
+func hashDeviceToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
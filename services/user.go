@@ -1,118 +1,172 @@
 package services
 
 import (
+	"context"
+	"database/sql"
 	"errors"
-	"log"
+	"log/slog"
 	"time"
 
+	"github.com/mbarolo/test_back/apperror"
 	"github.com/mbarolo/test_back/forms"
 	"github.com/mbarolo/test_back/models"
+	"github.com/mbarolo/test_back/utils"
 )
 
-func GetAllUsers() ([]*models.User, error) {
-	if users, err := userRepo.GetAll(); err != nil {
-		log.Printf("Error al obtener los usuarios: %v", err.Error())
+func GetAllUsers(ctx context.Context, spec *utils.QuerySpec) (*utils.Page[models.User], error) {
+	if users, err := userRepo.GetAll(ctx, spec); err != nil {
+		slog.ErrorContext(ctx, "list users failed", "error", err)
 		return nil, err
 	} else {
-		log.Println("Usuarios obtenidos")
+		slog.DebugContext(ctx, "users listed", "count", len(users.Items), "total_count", users.TotalCount)
 		return users, nil
 	}
 }
 
-func GetUserById(id int64) (*models.User, error) {
-	if user, err := userRepo.GetById(id); err != nil {
-		log.Printf("Error al obtener el usuario: %v", err.Error())
+func GetUserById(ctx context.Context, id int64) (*models.User, error) {
+	if user, err := userRepo.GetById(ctx, id); errors.Is(err, sql.ErrNoRows) {
+		return nil, apperror.NotFound("user.not_found")
+	} else if err != nil {
+		slog.ErrorContext(ctx, "get user failed", "target_user_id", id, "error", err)
 		return nil, err
 	} else {
-		log.Println("Usuario obtenido")
+		slog.DebugContext(ctx, "user retrieved", "target_user_id", id)
 		return user, nil
 	}
 }
 
-func GetUserByEmail(email string) (*models.User, error) {
-	if user, err := userRepo.GetByEmail(email); err != nil {
-		log.Printf("Error al obtener el usuario: %v", err.Error())
+func GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	if user, err := userRepo.GetByEmail(ctx, email); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			slog.ErrorContext(ctx, "get user by email failed", "error", err)
+		}
 		return nil, err
 	} else {
-		log.Println("Usuario obtenido")
+		slog.DebugContext(ctx, "user retrieved by email", "target_user_id", user.Id)
 		return user, nil
 	}
 }
 
-func CreateUser(user *models.User) (*models.User, error) {
+func CreateUser(ctx context.Context, user *models.User) (*models.User, error) {
 	if err := user.ValidateFields(); err != nil {
 		return nil, err
 	}
 
-	exists, err := userRepo.ExistsByEmail(user.Email)
+	err := inTx(ctx, func(ctx context.Context) error {
+		exists, err := userRepo.ExistsByEmail(ctx, user.Email)
+		if err != nil {
+			return err
+		}
+
+		if exists {
+			return apperror.Conflict("user.email_taken")
+		}
+
+		user.CreatedAt = time.Now()
+		user.UpdatedAt = time.Now()
+
+		id, err := userRepo.Create(ctx, user)
+		if err != nil {
+			slog.ErrorContext(ctx, "create user failed", "error", err)
+			return err
+		}
+
+		user.Id = id
+		user.Version = 1
+		if err := recordAudit(ctx, "user.create", AuditEntityUser, user.Id, nil, user); err != nil {
+			return err
+		}
+		return publishEvent(ctx, models.EventUserRegistered, AuditEntityUser, user.Id, newUserEvent(user))
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	if exists {
-		return nil, errors.New("correo electrónico ya registrado en la base de datos")
-
-	}
-
-	user.CreatedAt = time.Now()
-	user.UpdatedAt = time.Now()
-
-	id, err := userRepo.Create(user)
-	if err != nil {
-		log.Printf("Error al crear el usuario: %v", err.Error())
-		return nil, err
-	}
-
-	user.Id = id
-
-	log.Println("Usuario creado exitosamente")
+	slog.InfoContext(ctx, "user created", "target_user_id", user.Id)
 	return user, nil
 }
 
-func UpdateUser(id int64, updatedUser *forms.UserForm) (*models.User, error) {
-	originalUser, err := GetUserById(id)
-	if err != nil {
-		return nil, err
-	}
-
-	if updatedUser.Email != "" {
-		originalUser.Email = updatedUser.Email
-	}
-	if updatedUser.FirstName != "" {
-		originalUser.FirstName = updatedUser.FirstName
-	}
-	if updatedUser.LastName != "" {
-		originalUser.LastName = updatedUser.LastName
-	}
-	if updatedUser.HashedPassword != "" {
-		originalUser.HashedPassword = updatedUser.HashedPassword
-	}
-
-	if err := originalUser.ValidateFields(); err != nil {
-		return nil, err
-	}
-
-	originalUser.UpdatedAt = time.Now()
-
-	_, err = userRepo.Update(originalUser)
+// UpdateUser: Aplica un JSON Merge Patch al usuario. La contraseña se toma del form ya que se guarda hasheada.
+// Si ifMatch no es nil debe coincidir con la versión actual
+func UpdateUser(ctx context.Context, id int64, updatedUser *forms.UserForm, patch []byte, ifMatch *int64) (*models.User, error) {
+	// el hash se calcula fuera de la transacción para no retener el lock de escritura
+	hashedPassword := ""
+	if updatedUser.Password != "" {
+		var err error
+		if hashedPassword, err = HashPassword(ctx, updatedUser.Password); err != nil {
+			return nil, err
+		}
+	}
+
+	var originalUser *models.User
+	err := inTx(ctx, func(ctx context.Context) error {
+		var err error
+		originalUser, err = GetUserById(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := checkVersion(ifMatch, originalUser.Version); err != nil {
+			return err
+		}
+
+		before := snapshot(originalUser)
+		if err := utils.MergePatch(originalUser, patch); err != nil {
+			return err
+		}
+		if hashedPassword != "" {
+			originalUser.HashedPassword = hashedPassword
+		}
+
+		if err := originalUser.ValidateFields(); err != nil {
+			return err
+		}
+
+		originalUser.UpdatedAt = time.Now()
+
+		rows, err := userRepo.Update(ctx, originalUser)
+		if err != nil {
+			slog.ErrorContext(ctx, "update user failed", "target_user_id", id, "error", err)
+			return err
+		}
+		if rows == 0 {
+			return apperror.New(apperror.PRECONDITION, "error.version_mismatch")
+		}
+		originalUser.Version++
+
+		return recordAudit(ctx, "user.update", AuditEntityUser, id, before, originalUser)
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	slog.InfoContext(ctx, "user updated", "target_user_id", id, "version", originalUser.Version)
 	return originalUser, nil
 }
 
-func DeleteUser(id int64) error {
-	originalUser, err := GetUserById(id)
+func DeleteUser(ctx context.Context, id int64) error {
+	err := inTx(ctx, func(ctx context.Context) error {
+		originalUser, err := GetUserById(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		before := snapshot(originalUser)
+		originalUser.Deleted = true
+
+		if rows, err := userRepo.Update(ctx, originalUser); err != nil {
+			return err
+		} else if rows == 0 {
+			return apperror.Conflict("error.concurrent_update")
+		}
+		originalUser.Version++
+
+		return recordAudit(ctx, "user.delete", AuditEntityUser, id, before, originalUser)
+	})
 	if err != nil {
 		return err
 	}
 
-	originalUser.Deleted = true
-
-	if _, err := userRepo.Update(originalUser); err != nil {
-		return err
-	}
-
+	slog.InfoContext(ctx, "user deleted", "target_user_id", id)
 	return nil
 }
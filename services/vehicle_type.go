@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+
+	"github.com/mbarolo/test_back/apperror"
+	"github.com/mbarolo/test_back/config"
+	"github.com/mbarolo/test_back/logging"
+	"github.com/mbarolo/test_back/models"
+	"github.com/mbarolo/test_back/utils"
+)
+
+func GetVehicleTypes(ctx context.Context) ([]*models.VehicleType, error) {
+	return vehicleTypeRepo.GetAll(ctx)
+}
+
+// UpdateVehicleType: Aplica un JSON Merge Patch al tipo de vehículo. Si ifMatch no es nil debe coincidir con la versión actual.
+// La tarifa y el cargo de desbloqueo nuevos se cobran en los alquileres que terminen después, la tarifa solo en las
+// bicicletas del tipo sin tarifa propia
+func UpdateVehicleType(ctx context.Context, id int64, patch []byte, ifMatch *int64) (*models.VehicleType, error) {
+	logging.AddAttrs(ctx, slog.Int64("vehicle_type_id", id))
+
+	var vehicleType *models.VehicleType
+	err := inTx(ctx, func(ctx context.Context) error {
+		var err error
+		vehicleType, err = vehicleTypeRepo.GetById(ctx, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return apperror.NotFound("vehicle_type.not_found")
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := checkVersion(ifMatch, vehicleType.Version); err != nil {
+			return err
+		}
+
+		before := snapshot(vehicleType)
+		if err := utils.MergePatch(vehicleType, patch); err != nil {
+			return err
+		}
+		if vehicleType.Electric && vehicleType.RangeKm == nil {
+			return apperror.Validation("validation.failed", apperror.FieldError{Field: "range_km", Message: "vehicle_type.range_required"})
+		}
+
+		rows, err := vehicleTypeRepo.Update(ctx, vehicleType)
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return apperror.New(apperror.PRECONDITION, "error.version_mismatch")
+		}
+		vehicleType.Version++
+
+		return recordAudit(ctx, "vehicle_type.update", AuditEntityVehicleType, id, before, vehicleType)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "vehicle type updated", "code", vehicleType.Code, "version", vehicleType.Version)
+	return vehicleType, nil
+}
+
+// vehicleTypeFor: Tipo de vehículo de la bicicleta, un código que no está en el catálogo es un error de validación
+func vehicleTypeFor(ctx context.Context, code string) (*models.VehicleType, error) {
+	vehicleType, err := vehicleTypeRepo.GetByCode(ctx, code)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperror.Validation("bike.invalid_fields", apperror.FieldError{Field: "vehicle_type", Message: "bike.invalid_vehicle_type"})
+	}
+	return vehicleType, err
+}
+
+// checkBattery: Un vehículo eléctrico solo se alquila con al menos rentals.min_battery de carga,
+// si no se conoce su batería tampoco
+func checkBattery(vehicleType *models.VehicleType, bike *models.Bike) error {
+	if !vehicleType.Electric {
+		return nil
+	}
+	minBattery := config.Current().Rentals.MinBattery
+	if bike.BatteryLevel == nil || *bike.BatteryLevel < minBattery {
+		return apperror.Conflict("bike.battery_low").WithParams(map[string]interface{}{"min": minBattery})
+	}
+	return nil
+}
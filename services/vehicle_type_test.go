@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mbarolo/test_back/forms"
+	"github.com/mbarolo/test_back/models"
+)
+
+// setVehicleTypeCost: Cambia la tarifa del tipo de vehículo y la restaura al terminar el test
+func setVehicleTypeCost(t *testing.T, code string, cost int) {
+	t.Helper()
+	ctx := context.Background()
+	vehicleType, err := vehicleTypeFor(ctx, code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := vehicleType.CostPerMinute
+	if _, err := UpdateVehicleType(ctx, vehicleType.Id, fmt.Appendf(nil, `{"cost_per_minute": %d}`, cost), nil); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if _, err := UpdateVehicleType(ctx, vehicleType.Id, fmt.Appendf(nil, `{"cost_per_minute": %d}`, previous), nil); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func newCargoBike(t *testing.T, override *int) *models.Bike {
+	t.Helper()
+	lat, lng, vehicleType := -34.6037, -58.3816, models.VehicleCargo
+	bike, err := CreateBike(context.Background(), &forms.BikeForm{Latitude: &lat, Longitude: &lng, VehicleType: &vehicleType, CostPerMinuteOverride: override})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bike
+}
+
+func currentCost(t *testing.T, bikeId int64) int {
+	t.Helper()
+	bike, err := GetBikeById(context.Background(), bikeId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bike.CostPerMinute
+}
+
+func TestVehicleTypePricing(t *testing.T) {
+	ctx := context.Background()
+	setVehicleTypeCost(t, models.VehicleCargo, 20)
+
+	own := 3
+	typed := newCargoBike(t, nil)
+	overridden := newCargoBike(t, &own)
+	if typed.CostPerMinute != 20 || typed.CostPerMinuteOverride != nil {
+		t.Errorf("sin tarifa propia: cost_per_minute = %d, override = %v, se esperaba la del tipo", typed.CostPerMinute, typed.CostPerMinuteOverride)
+	}
+	if overridden.CostPerMinute != 3 {
+		t.Errorf("con tarifa propia: cost_per_minute = %d, se esperaba 3", overridden.CostPerMinute)
+	}
+
+	// el cambio de tarifa del tipo alcanza a las bicicletas sin tarifa propia
+	setVehicleTypeCost(t, models.VehicleCargo, 25)
+	if cost := currentCost(t, typed.Id); cost != 25 {
+		t.Errorf("sin tarifa propia: cost_per_minute = %d, se esperaba 25", cost)
+	}
+	if cost := currentCost(t, overridden.Id); cost != 3 {
+		t.Errorf("con tarifa propia: cost_per_minute = %d, se esperaba 3", cost)
+	}
+
+	// null vuelve a la tarifa del tipo
+	bike, err := UpdateBike(ctx, overridden.Id, []byte(`{"cost_per_minute_override": null}`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bike.CostPerMinute != 25 || bike.CostPerMinuteOverride != nil {
+		t.Errorf("override null: cost_per_minute = %d, override = %v, se esperaba la del tipo", bike.CostPerMinute, bike.CostPerMinuteOverride)
+	}
+}
+
+func TestRentalCostUsesVehicleTypePrice(t *testing.T) {
+	ctx := context.Background()
+	setVehicleTypeCost(t, models.VehicleCargo, 20)
+	user := newTestUser(t)
+	bike := newCargoBike(t, nil)
+	form := &forms.StartEndRentalForm{BikeID: bike.Id}
+
+	rental, err := StartRental(ctx, user, form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rental.StartTime = rental.StartTime.Add(-10 * time.Minute)
+	if err := saveRental(ctx, rental); err != nil {
+		t.Fatal(err)
+	}
+
+	// la tarifa cambia durante el alquiler, se cobra la vigente al finalizar
+	setVehicleTypeCost(t, models.VehicleCargo, 30)
+	ended, err := EndRental(ctx, user, form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vehicleType, err := vehicleTypeFor(ctx, models.VehicleCargo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := vehicleType.UnlockFee + 30*10; *ended.Cost != want {
+		t.Errorf("cost = %d, se esperaba %d", *ended.Cost, want)
+	}
+}
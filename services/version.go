@@ -0,0 +1,11 @@
+package services
+
+import "github.com/mbarolo/test_back/apperror"
+
+// checkVersion: Compara la versión enviada en If-Match con la versión actual del recurso
+func checkVersion(ifMatch *int64, current int64) error {
+	if ifMatch != nil && *ifMatch != current {
+		return apperror.New(apperror.PRECONDITION, "error.version_mismatch")
+	}
+	return nil
+}
@@ -0,0 +1,478 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	mathrand "math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/mbarolo/test_back/apperror"
+	"github.com/mbarolo/test_back/config"
+	"github.com/mbarolo/test_back/metrics"
+	"github.com/mbarolo/test_back/models"
+	"github.com/mbarolo/test_back/tracing"
+	"github.com/mbarolo/test_back/utils"
+)
+
+// Headers de las entregas. La firma es un HMAC-SHA256 con el secreto de la suscripción de "<timestamp>.<cuerpo>",
+// con el formato t=<timestamp>,v1=<firma en hex>. El receptor debe rechazar timestamps demasiado viejos
+const (
+	WebhookIdHeader        = "Webhook-Id"
+	WebhookEventHeader     = "Webhook-Event"
+	WebhookDeliveryHeader  = "Webhook-Delivery"
+	WebhookTimestampHeader = "Webhook-Timestamp"
+	WebhookSignatureHeader = "Webhook-Signature"
+)
+
+const (
+	// eventos y entregas procesados en cada ciclo del dispatcher
+	webhookBatchSize = 100
+	// largo máximo del cuerpo de la respuesta guardado como error del intento
+	webhookErrorBodyBytes = 512
+)
+
+// webhookEnvelope: Cuerpo de cada entrega
+type webhookEnvelope struct {
+	Id         int64           `json:"id"`
+	Type       string          `json:"type"`
+	EntityType string          `json:"entity_type"`
+	EntityId   int64           `json:"entity_id"`
+	CreatedAt  time.Time       `json:"created_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+func GetAllWebhooks(ctx context.Context, spec *utils.QuerySpec) (*utils.Page[models.WebhookSubscription], error) {
+	subscriptions, err := webhookRepo.GetAll(ctx, spec)
+	if err != nil {
+		slog.ErrorContext(ctx, "list webhooks failed", "error", err)
+		return nil, err
+	}
+
+	for _, subscription := range subscriptions.Items {
+		subscription.Secret = ""
+	}
+	return subscriptions, nil
+}
+
+func GetWebhookById(ctx context.Context, id int64) (*models.WebhookSubscription, error) {
+	subscription, err := getWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	subscription.Secret = ""
+	return subscription, nil
+}
+
+// getWebhook: Suscripción con su secreto
+func getWebhook(ctx context.Context, id int64) (*models.WebhookSubscription, error) {
+	if subscription, err := webhookRepo.GetById(ctx, id); errors.Is(err, sql.ErrNoRows) {
+		return nil, apperror.NotFound("webhook.not_found")
+	} else if err != nil {
+		slog.ErrorContext(ctx, "get webhook failed", "webhook_id", id, "error", err)
+		return nil, err
+	} else {
+		return subscription, nil
+	}
+}
+
+// CreateWebhook: Registra la suscripción. Si no se indica un secreto se genera uno, y solo se retorna en esta respuesta
+func CreateWebhook(ctx context.Context, subscription *models.WebhookSubscription) (*models.WebhookSubscription, error) {
+	if subscription.Secret == "" {
+		secret, err := newWebhookSecret()
+		if err != nil {
+			return nil, err
+		}
+		subscription.Secret = secret
+	}
+	if err := subscription.ValidateFields(); err != nil {
+		return nil, err
+	}
+
+	subscription.CreatedAt = time.Now()
+	subscription.UpdatedAt = time.Now()
+
+	err := inTx(ctx, func(ctx context.Context) error {
+		id, err := webhookRepo.Create(ctx, subscription)
+		if err != nil {
+			slog.ErrorContext(ctx, "create webhook failed", "error", err)
+			return err
+		}
+
+		subscription.Id = id
+		subscription.Version = 1
+		return recordAudit(ctx, "webhook.create", AuditEntityWebhook, subscription.Id, nil, subscription)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "webhook created", "webhook_id", subscription.Id)
+	return subscription, nil
+}
+
+// UpdateWebhook: Aplica un JSON Merge Patch a la suscripción. Si ifMatch no es nil debe coincidir con la versión actual
+func UpdateWebhook(ctx context.Context, id int64, patch []byte, ifMatch *int64) (*models.WebhookSubscription, error) {
+	var subscription *models.WebhookSubscription
+	err := inTx(ctx, func(ctx context.Context) error {
+		var err error
+		subscription, err = getWebhook(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := checkVersion(ifMatch, subscription.Version); err != nil {
+			return err
+		}
+
+		before := snapshot(subscription)
+		if err := utils.MergePatch(subscription, patch); err != nil {
+			return err
+		}
+
+		if err := subscription.ValidateFields(); err != nil {
+			return err
+		}
+
+		subscription.UpdatedAt = time.Now()
+
+		rows, err := webhookRepo.Update(ctx, subscription)
+		if err != nil {
+			slog.ErrorContext(ctx, "update webhook failed", "webhook_id", id, "error", err)
+			return err
+		}
+		if rows == 0 {
+			return apperror.New(apperror.PRECONDITION, "error.version_mismatch")
+		}
+		subscription.Version++
+
+		return recordAudit(ctx, "webhook.update", AuditEntityWebhook, id, before, subscription)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "webhook updated", "webhook_id", id, "version", subscription.Version)
+	subscription.Secret = ""
+	return subscription, nil
+}
+
+// DeleteWebhook: Elimina la suscripción y su historial de entregas
+func DeleteWebhook(ctx context.Context, id int64) error {
+	err := inTx(ctx, func(ctx context.Context) error {
+		subscription, err := getWebhook(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := webhookRepo.Delete(ctx, id); err != nil {
+			slog.ErrorContext(ctx, "delete webhook failed", "webhook_id", id, "error", err)
+			return err
+		}
+
+		return recordAudit(ctx, "webhook.delete", AuditEntityWebhook, id, snapshot(subscription), nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "webhook deleted", "webhook_id", id)
+	return nil
+}
+
+// GetWebhookDeliveries: Historial de entregas de la suscripción
+func GetWebhookDeliveries(ctx context.Context, id int64, spec *utils.QuerySpec) (*utils.Page[models.WebhookDelivery], error) {
+	if _, err := getWebhook(ctx, id); err != nil {
+		return nil, err
+	}
+
+	deliveries, err := webhookRepo.GetDeliveries(ctx, id, spec)
+	if err != nil {
+		slog.ErrorContext(ctx, "list webhook deliveries failed", "webhook_id", id, "error", err)
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// RetryWebhookDelivery: Vuelve a poner en cola una entrega que agotó los reintentos, con los intentos en 0
+func RetryWebhookDelivery(ctx context.Context, id int64, deliveryId int64) (*models.WebhookDelivery, error) {
+	var delivery *models.WebhookDelivery
+	err := inTx(ctx, func(ctx context.Context) error {
+		var err error
+		delivery, err = webhookRepo.GetDeliveryById(ctx, deliveryId)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && delivery.SubscriptionId != id) {
+			return apperror.NotFound("webhook.delivery_not_found")
+		}
+		if err != nil {
+			return err
+		}
+
+		if delivery.Status != models.DeliveryDead {
+			return apperror.Conflict("webhook.delivery_not_dead")
+		}
+
+		before := snapshot(delivery)
+		delivery.Status = models.DeliveryPending
+		delivery.Attempts = 0
+		delivery.NextAttemptAt = time.Now()
+		if err := webhookRepo.UpdateDelivery(ctx, delivery); err != nil {
+			slog.ErrorContext(ctx, "retry webhook delivery failed", "delivery_id", deliveryId, "error", err)
+			return err
+		}
+
+		return recordAudit(ctx, "webhook.delivery_retry", AuditEntityWebhook, id, before, delivery)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "webhook delivery requeued", "webhook_id", id, "delivery_id", deliveryId)
+	return delivery, nil
+}
+
+// DispatchWebhooks: Crea las entregas de los eventos nuevos del outbox y envía las entregas pendientes,
+// cada interval, hasta que se cancele el contexto
+func DispatchWebhooks(ctx context.Context, interval time.Duration) {
+	client := &http.Client{
+		// las redirecciones no se siguen, el endpoint registrado debe responder directamente
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := fanOutEvents(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("dispatch outbox events failed", "error", err)
+		}
+		if err := deliverDueWebhooks(ctx, client); err != nil && ctx.Err() == nil {
+			slog.Error("deliver webhooks failed", "error", err)
+		}
+	}
+}
+
+// fanOutEvents: Crea una entrega por cada suscripción activa interesada en cada evento nuevo del outbox
+func fanOutEvents(ctx context.Context) error {
+	events, err := outboxRepo.GetUndispatched(ctx, webhookBatchSize)
+	if err != nil || len(events) == 0 {
+		return err
+	}
+
+	subscriptions, err := webhookRepo.GetActive(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		deliveries := 0
+		err := inTx(ctx, func(ctx context.Context) error {
+			now := time.Now()
+			for _, subscription := range subscriptions {
+				if !subscription.Subscribed(event.EventType) {
+					continue
+				}
+				delivery := &models.WebhookDelivery{
+					SubscriptionId: subscription.Id,
+					EventId:        event.Id,
+					EventType:      event.EventType,
+					Status:         models.DeliveryPending,
+					NextAttemptAt:  now,
+					CreatedAt:      now,
+					UpdatedAt:      now,
+				}
+				if err := webhookRepo.CreateDelivery(ctx, delivery); err != nil {
+					return err
+				}
+				deliveries++
+			}
+			return outboxRepo.MarkDispatched(ctx, event.Id)
+		})
+		if err != nil {
+			return fmt.Errorf("error al despachar el evento %d: %w", event.Id, err)
+		}
+		slog.Debug("outbox event dispatched", "event_id", event.Id, "event_type", event.EventType, "deliveries", deliveries)
+	}
+
+	return nil
+}
+
+// deliverDueWebhooks: Envía las entregas pendientes cuyo próximo intento ya venció
+func deliverDueWebhooks(ctx context.Context, client *http.Client) error {
+	deliveries, err := webhookRepo.GetDueDeliveries(ctx, webhookBatchSize)
+	if err != nil {
+		return err
+	}
+
+	subscriptions := map[int64]*models.WebhookSubscription{}
+	for _, delivery := range deliveries {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		subscription, ok := subscriptions[delivery.SubscriptionId]
+		if !ok {
+			subscription, err = webhookRepo.GetById(ctx, delivery.SubscriptionId)
+			if err != nil {
+				return err
+			}
+			subscriptions[subscription.Id] = subscription
+		}
+		event, err := outboxRepo.GetById(ctx, delivery.EventId)
+		if err != nil {
+			return err
+		}
+
+		deliverWebhook(ctx, client, subscription, event, delivery)
+	}
+
+	return nil
+}
+
+// deliverWebhook: Realiza un intento de la entrega y guarda su resultado. Si falla se programa el reintento
+// con backoff exponencial, o se marca como dead si se agotaron los intentos
+func deliverWebhook(ctx context.Context, client *http.Client, subscription *models.WebhookSubscription, event *models.OutboxEvent, delivery *models.WebhookDelivery) {
+	ctx, span := tracing.Start(ctx, "services.deliverWebhook",
+		attribute.Int64("webhook_id", subscription.Id),
+		attribute.Int64("delivery_id", delivery.Id),
+		attribute.String("event_type", event.EventType),
+	)
+	log := slog.With("webhook_id", subscription.Id, "delivery_id", delivery.Id, "event_type", event.EventType)
+	cfg := config.Current().Webhooks
+
+	start := time.Now()
+	statusCode, err := sendWebhook(ctx, client, cfg.Timeout, subscription, event, delivery)
+	duration := time.Since(start).Milliseconds()
+	tracing.End(span, err)
+	if ctx.Err() != nil {
+		// apagado del servidor: el intento no cuenta, se repite al reiniciar
+		return
+	}
+
+	delivery.Attempts++
+	delivery.LastDurationMs = &duration
+	delivery.LastStatusCode = nil
+	if statusCode != 0 {
+		delivery.LastStatusCode = &statusCode
+	}
+
+	result := metrics.WebhookSucceeded
+	switch {
+	case err == nil:
+		now := time.Now()
+		delivery.Status = models.DeliverySucceeded
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+		log.InfoContext(ctx, "webhook delivered", "attempt", delivery.Attempts, "status_code", statusCode, "duration_ms", duration)
+	case delivery.Attempts >= cfg.MaxAttempts:
+		result = metrics.WebhookDead
+		delivery.Status = models.DeliveryDead
+		delivery.LastError = err.Error()
+		log.WarnContext(ctx, "webhook delivery dead", "attempts", delivery.Attempts, "error", err)
+	default:
+		result = metrics.WebhookFailed
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = time.Now().Add(webhookBackoff(delivery.Attempts, cfg.BackoffBase, cfg.BackoffMax))
+		log.WarnContext(ctx, "webhook delivery failed", "attempt", delivery.Attempts, "next_attempt_at", delivery.NextAttemptAt, "error", err)
+	}
+	metrics.WebhookDeliveries.WithLabelValues(result).Inc()
+
+	if err := webhookRepo.UpdateDelivery(ctx, delivery); err != nil {
+		log.ErrorContext(ctx, "update webhook delivery failed", "error", err)
+	}
+}
+
+// sendWebhook: Envía el evento firmado. Retorna el código de la respuesta (0 si no hubo respuesta)
+// y un error si la respuesta no fue 2xx
+func sendWebhook(ctx context.Context, client *http.Client, timeout time.Duration, subscription *models.WebhookSubscription, event *models.OutboxEvent, delivery *models.WebhookDelivery) (int, error) {
+	body, err := json.Marshal(webhookEnvelope{
+		Id:         event.Id,
+		Type:       event.EventType,
+		EntityType: event.EntityType,
+		EntityId:   event.EntityId,
+		CreatedAt:  event.CreatedAt,
+		Data:       event.Payload,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "test_back-webhooks")
+	req.Header.Set(WebhookIdHeader, strconv.FormatInt(event.Id, 10))
+	req.Header.Set(WebhookEventHeader, event.EventType)
+	req.Header.Set(WebhookDeliveryHeader, strconv.FormatInt(delivery.Id, 10))
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, "t="+timestamp+",v1="+SignWebhook(subscription.Secret, timestamp, body))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, webhookErrorBodyBytes))
+		return resp.StatusCode, fmt.Errorf("el endpoint respondió %d: %s", resp.StatusCode, bytes.TrimSpace(snippet))
+	}
+	// se descarta el cuerpo para reutilizar la conexión
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	return resp.StatusCode, nil
+}
+
+// SignWebhook: Firma HMAC-SHA256 en hex de "<timestamp>.<cuerpo>" con el secreto de la suscripción
+func SignWebhook(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookBackoff: Espera antes del próximo intento, base * 2^(intentos-1) hasta max,
+// con hasta un 20% de variación para que los reintentos de muchas entregas no coincidan
+func webhookBackoff(attempts int, base time.Duration, max time.Duration) time.Duration {
+	delay := max
+	if attempts-1 < 32 {
+		if d := base << (attempts - 1); d > 0 && d < max {
+			delay = d
+		}
+	}
+	return delay + mathrand.N(delay/5+1)
+}
+
+func newWebhookSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(secret), nil
+}
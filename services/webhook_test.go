@@ -0,0 +1,243 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mbarolo/test_back/config"
+	"github.com/mbarolo/test_back/models"
+	"github.com/mbarolo/test_back/utils"
+)
+
+const testWebhookSecret = "whsec_0123456789abcdef"
+
+// newTestWebhook: Suscripción a todos los eventos con el secreto de prueba
+func newTestWebhook(t *testing.T, url string, active bool) *models.WebhookSubscription {
+	t.Helper()
+	subscription, err := CreateWebhook(context.Background(), &models.WebhookSubscription{
+		Url:        url,
+		EventTypes: json.RawMessage(`["*"]`),
+		Secret:     testWebhookSecret,
+		IsActive:   active,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return subscription
+}
+
+// newTestDelivery: Evento nuevo en el outbox y su entrega pendiente para la suscripción
+func newTestDelivery(t *testing.T, subscription *models.WebhookSubscription) (*models.OutboxEvent, *models.WebhookDelivery) {
+	t.Helper()
+	ctx := context.Background()
+	now := time.Now()
+	event := &models.OutboxEvent{EventType: models.EventBikeCreated, EntityType: "bike", EntityId: 1, Payload: json.RawMessage(`{"id":1}`), CreatedAt: now}
+	id, err := outboxRepo.Append(ctx, event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	event.Id = id
+
+	delivery := &models.WebhookDelivery{SubscriptionId: subscription.Id, EventId: event.Id, EventType: event.EventType, Status: models.DeliveryPending, NextAttemptAt: now, CreatedAt: now, UpdatedAt: now}
+	if err := webhookRepo.CreateDelivery(ctx, delivery); err != nil {
+		t.Fatal(err)
+	}
+	spec, err := utils.ParseQuerySpec(url.Values{}, utils.QueryConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	deliveries, err := webhookRepo.GetDeliveries(ctx, subscription.Id, spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range deliveries.Items {
+		if d.EventId == event.Id {
+			return event, d
+		}
+	}
+	t.Fatalf("no se creó la entrega del evento %d", event.Id)
+	return nil, nil
+}
+
+func TestSignWebhook(t *testing.T) {
+	// HMAC-SHA256 de "1700000000.{"id":1}" calculado fuera de la aplicación
+	want := "22f267bc13c9c3f35f76035954c196f8ad4cf971af76120dcbcbbb84458514d0"
+	if got := SignWebhook(testWebhookSecret, "1700000000", []byte(`{"id":1}`)); got != want {
+		t.Errorf("firma = %s, se esperaba %s", got, want)
+	}
+}
+
+// TestDeliverWebhookSignature: El receptor verifica el header t=…,v1=… con el secreto de la suscripción
+func TestDeliverWebhookSignature(t *testing.T) {
+	ctx := context.Background()
+	received := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- r
+		bodies <- body
+	}))
+	defer server.Close()
+
+	subscription := newTestWebhook(t, server.URL, true)
+	event, delivery := newTestDelivery(t, subscription)
+	deliverWebhook(ctx, server.Client(), subscription, event, delivery)
+
+	r, body := <-received, <-bodies
+	var timestamp, signature string
+	for _, part := range strings.Split(r.Header.Get(WebhookSignatureHeader), ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signature = value
+		}
+	}
+	if timestamp != r.Header.Get(WebhookTimestampHeader) {
+		t.Errorf("t = %q, se esperaba el timestamp %q", timestamp, r.Header.Get(WebhookTimestampHeader))
+	}
+	if ts, err := strconv.ParseInt(timestamp, 10, 64); err != nil || time.Since(time.Unix(ts, 0)) > time.Minute {
+		t.Errorf("t = %q, se esperaba la hora del envío", timestamp)
+	}
+
+	mac := hmac.New(sha256.New, []byte(testWebhookSecret))
+	mac.Write([]byte(timestamp + "." + string(body)))
+	if want := hex.EncodeToString(mac.Sum(nil)); signature != want {
+		t.Errorf("v1 = %s, se esperaba %s", signature, want)
+	}
+
+	stored, err := webhookRepo.GetDeliveryById(ctx, delivery.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != models.DeliverySucceeded || stored.Attempts != 1 || stored.DeliveredAt == nil {
+		t.Errorf("entrega = %+v, se esperaba succeeded en el primer intento", stored)
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	base, max := 30*time.Second, time.Hour
+	tests := []struct {
+		attempts int
+		delay    time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{4, 4 * time.Minute},
+		{7, 32 * time.Minute},
+		{8, time.Hour},
+		{40, time.Hour},
+		{100, time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.attempts), func(t *testing.T) {
+			// la variación es aleatoria, se verifican los límites en varias muestras
+			for range 100 {
+				got := webhookBackoff(tt.attempts, base, max)
+				if got < tt.delay || got > tt.delay+tt.delay/5 {
+					t.Fatalf("espera = %s, se esperaba entre %s y %s", got, tt.delay, tt.delay+tt.delay/5)
+				}
+			}
+		})
+	}
+}
+
+// TestDeliverWebhookDead: Cada intento fallido programa el reintento y al agotar los intentos la entrega queda dead
+func TestDeliverWebhookDead(t *testing.T) {
+	ctx := context.Background()
+	setConfig(t, func(cfg *config.Config) {
+		cfg.Webhooks.MaxAttempts = 3
+		cfg.Webhooks.BackoffBase = time.Minute
+		cfg.Webhooks.BackoffMax = time.Hour
+	})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "caído", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	subscription := newTestWebhook(t, server.URL, true)
+	event, delivery := newTestDelivery(t, subscription)
+
+	for attempt, delay := range []time.Duration{time.Minute, 2 * time.Minute} {
+		before := time.Now()
+		deliverWebhook(ctx, server.Client(), subscription, event, delivery)
+		stored, err := webhookRepo.GetDeliveryById(ctx, delivery.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.Status != models.DeliveryPending || stored.Attempts != attempt+1 {
+			t.Fatalf("entrega = %+v, se esperaba pendiente con %d intentos", stored, attempt+1)
+		}
+		if stored.LastStatusCode == nil || *stored.LastStatusCode != http.StatusInternalServerError {
+			t.Errorf("código = %v, se esperaba 500", stored.LastStatusCode)
+		}
+		// la hora se guarda con precisión de segundos
+		wait := stored.NextAttemptAt.Sub(before)
+		if wait < delay-time.Second || wait > delay+delay/5+time.Second {
+			t.Errorf("intento %d: reintento en %s, se esperaba ~%s", attempt+1, wait, delay)
+		}
+	}
+
+	deliverWebhook(ctx, server.Client(), subscription, event, delivery)
+	stored, err := webhookRepo.GetDeliveryById(ctx, delivery.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != models.DeliveryDead || stored.Attempts != 3 || stored.LastError == "" {
+		t.Errorf("entrega = %+v, se esperaba dead con 3 intentos", stored)
+	}
+	if due := dueDeliveryIds(t); due[delivery.Id] {
+		t.Error("la entrega dead se volvería a enviar")
+	}
+}
+
+// dueDeliveryIds: Entregas que el dispatcher enviaría ahora
+func dueDeliveryIds(t *testing.T) map[int64]bool {
+	t.Helper()
+	deliveries, err := webhookRepo.GetDueDeliveries(context.Background(), 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := map[int64]bool{}
+	for _, delivery := range deliveries {
+		ids[delivery.Id] = true
+	}
+	return ids
+}
+
+func TestGetDueDeliveriesInactive(t *testing.T) {
+	ctx := context.Background()
+	active := newTestWebhook(t, "https://example.com/active", true)
+	inactive := newTestWebhook(t, "https://example.com/inactive", false)
+	_, activeDelivery := newTestDelivery(t, active)
+	_, inactiveDelivery := newTestDelivery(t, inactive)
+
+	due := dueDeliveryIds(t)
+	if !due[activeDelivery.Id] {
+		t.Error("falta la entrega de la suscripción activa")
+	}
+	if due[inactiveDelivery.Id] {
+		t.Error("se enviaría la entrega de la suscripción inactiva")
+	}
+
+	// al reactivar la suscripción se envían sus entregas pendientes
+	if _, err := UpdateWebhook(ctx, inactive.Id, []byte(`{"is_active": true}`), nil); err != nil {
+		t.Fatal(err)
+	}
+	if !dueDeliveryIds(t)[inactiveDelivery.Id] {
+		t.Error("falta la entrega de la suscripción reactivada")
+	}
+}
@@ -0,0 +1,241 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Tipos de eventos del stream
+const (
+	EventSnapshot = "snapshot"
+	EventBike     = "bike"
+	EventRental   = "rental"
+)
+
+var (
+	// ErrSlowConsumer: El cliente no consumió los eventos a tiempo y se llenó su buffer
+	ErrSlowConsumer = errors.New("cliente lento, se descartó la suscripción")
+	// ErrClosed: El hub se cerró porque el servidor se está apagando
+	ErrClosed = errors.New("stream cerrado")
+)
+
+// Point: Ubicación de una bicicleta
+type Point struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// BBox: Rectángulo de coordenadas (min_lat,min_lng,max_lat,max_lng) para recibir solo los eventos de esa zona
+type BBox struct {
+	MinLatitude  float64
+	MinLongitude float64
+	MaxLatitude  float64
+	MaxLongitude float64
+}
+
+// ParseBBox: Interpreta min_lat,min_lng,max_lat,max_lng. Retorna nil si s está vacío
+func ParseBBox(s string) (*BBox, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("bbox inválido %q, se espera min_lat,min_lng,max_lat,max_lng", s)
+	}
+	values := make([]float64, 4)
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, fmt.Errorf("bbox inválido %q: %w", s, err)
+		}
+		values[i] = v
+	}
+
+	box := &BBox{values[0], values[1], values[2], values[3]}
+	if box.MinLatitude < -90 || box.MaxLatitude > 90 || box.MinLongitude < -180 || box.MaxLongitude > 180 ||
+		box.MinLatitude > box.MaxLatitude || box.MinLongitude > box.MaxLongitude {
+		return nil, fmt.Errorf("bbox inválido %q, fuera de rango", s)
+	}
+	return box, nil
+}
+
+func (b *BBox) Contains(p Point) bool {
+	return p.Latitude >= b.MinLatitude && p.Latitude <= b.MaxLatitude &&
+		p.Longitude >= b.MinLongitude && p.Longitude <= b.MaxLongitude
+}
+
+// Event: Mensaje publicado en el hub. Si UserId no es 0 solo lo recibe ese usuario.
+// Points son las ubicaciones afectadas (la nueva y la anterior), así un cliente con bbox también recibe
+// las bicicletas que salen de su zona
+type Event struct {
+	Type   string
+	UserId int64
+	Points []Point
+	Data   interface{}
+}
+
+// Filter: Eventos que recibe una suscripción
+type Filter struct {
+	UserId int64
+	BBox   *BBox
+}
+
+func (f Filter) Match(e Event) bool {
+	if e.UserId != 0 && e.UserId != f.UserId {
+		return false
+	}
+	if f.BBox == nil || len(e.Points) == 0 {
+		return true
+	}
+	for _, p := range e.Points {
+		if f.BBox.Contains(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscription: Suscripción de un cliente. Done se cierra cuando el hub la descarta, Err indica el motivo
+type Subscription struct {
+	hub    *Hub
+	filter Filter
+	events chan Event
+	done   chan struct{}
+	once   sync.Once
+	err    error
+	closed sync.Once
+}
+
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err: Motivo por el que se cerró la suscripción, nil si la cerró el cliente
+func (s *Subscription) Err() error {
+	<-s.done
+	return s.err
+}
+
+// Close: Cancela la suscripción, se debe llamar al desconectarse el cliente
+func (s *Subscription) Close() {
+	s.hub.remove(s, nil)
+	s.closed.Do(s.hub.active.Done)
+}
+
+// Hub: Pub/sub en memoria. Publish nunca bloquea: cada suscripción tiene un buffer y si un cliente
+// no lo consume a tiempo se lo desconecta (ErrSlowConsumer) para que vuelva a conectarse y reciba un snapshot
+type Hub struct {
+	mu     sync.RWMutex
+	subs   map[*Subscription]struct{}
+	buffer int
+	closed bool
+	onSlow func()
+	// clientes que todavía no terminaron, aunque el hub ya haya cerrado su suscripción
+	active sync.WaitGroup
+}
+
+// NewHub: Crea el hub con buffer eventos por suscripción. onSlow, si no es nil, se llama por cada cliente descartado
+func NewHub(buffer int, onSlow func()) *Hub {
+	return &Hub{subs: map[*Subscription]struct{}{}, buffer: buffer, onSlow: onSlow}
+}
+
+func (h *Hub) Subscribe(filter Filter) (*Subscription, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, ErrClosed
+	}
+
+	s := &Subscription{hub: h, filter: filter, events: make(chan Event, h.buffer), done: make(chan struct{})}
+	h.subs[s] = struct{}{}
+	h.active.Add(1)
+	return s, nil
+}
+
+// Publish: Entrega el evento a las suscripciones cuyo filtro coincide
+func (h *Hub) Publish(e Event) {
+	var slow []*Subscription
+	h.mu.RLock()
+	for s := range h.subs {
+		if !s.filter.Match(e) {
+			continue
+		}
+		select {
+		case s.events <- e:
+		default:
+			slow = append(slow, s)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, s := range slow {
+		if h.remove(s, ErrSlowConsumer) && h.onSlow != nil {
+			h.onSlow()
+		}
+	}
+}
+
+// Close: Cierra todas las suscripciones y rechaza las nuevas
+func (h *Hub) Close() {
+	h.mu.Lock()
+	h.closed = true
+	subs := h.subs
+	h.subs = map[*Subscription]struct{}{}
+	h.mu.Unlock()
+
+	for s := range subs {
+		s.close(ErrClosed)
+	}
+}
+
+// Wait: Espera a que todos los clientes llamen a Close, o a que se cancele el contexto
+func (h *Hub) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		h.active.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Len: Cantidad de suscripciones activas
+func (h *Hub) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subs)
+}
+
+// remove: Quita la suscripción del hub. Retorna false si ya no estaba
+func (h *Hub) remove(s *Subscription, err error) bool {
+	h.mu.Lock()
+	_, ok := h.subs[s]
+	delete(h.subs, s)
+	h.mu.Unlock()
+
+	if ok {
+		s.close(err)
+	}
+	return ok
+}
+
+func (s *Subscription) close(err error) {
+	s.once.Do(func() {
+		s.err = err
+		close(s.done)
+	})
+}
@@ -0,0 +1,88 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/mbarolo/test_back/apperror"
+)
+
+const instrumentationName = "github.com/mbarolo/test_back"
+
+// Exportadores soportados por NewExporter
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+// tracer de la aplicación, delega en el TracerProvider global configurado por Init
+var tracer = otel.Tracer(instrumentationName)
+
+// NewExporter: Crea el exportador de spans indicado. El exportador OTLP (HTTP) se configura con las variables
+// de entorno estándar OTEL_EXPORTER_OTLP_*. Con none (o vacío) retorna nil y los spans no se exportan
+func NewExporter(ctx context.Context, kind string) (sdktrace.SpanExporter, error) {
+	switch kind {
+	case "", ExporterNone:
+		return nil, nil
+	case ExporterOTLP:
+		return otlptracehttp.New(ctx)
+	case ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stderr))
+	}
+	return nil, fmt.Errorf("exportador de trazas desconocido: %q", kind)
+}
+
+// Init: Configura el TracerProvider global con el exportador y la propagación W3C (traceparent y baggage).
+// En tests se puede usar un tracetest.InMemoryExporter; la función retornada exporta los spans pendientes y cierra el provider
+func Init(serviceName string, exporter sdktrace.SpanExporter) func(context.Context) error {
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName))),
+	}
+	if exporter != nil {
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	}
+
+	provider := sdktrace.NewTracerProvider(opts...)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return provider.Shutdown
+}
+
+// Tracer: Tracer de la aplicación, para los spans que necesitan opciones propias (ej: spans de servidor)
+func Tracer() trace.Tracer {
+	return tracer
+}
+
+// Start: Inicia un span hijo del span del contexto
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End: Finaliza el span registrando el error, si existe. Los errores de dominio del cliente (4xx)
+// quedan registrados pero no marcan el span como fallido
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+
+		var appErr *apperror.Error
+		if !errors.As(err, &appErr) || appErr.Code.Status() >= 500 {
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+	span.End()
+}
@@ -1,8 +1,11 @@
 package utils
 
 import (
+	"fmt"
 	"math"
 	"math/rand"
+	"strconv"
+	"strings"
 )
 
 // GenerateRandomCoordinatesWithinRadius genera un lat y long tal que la ditancia sea ~5km del inicio
@@ -21,3 +24,151 @@ func GenerateRandomCoordinatesWithinRadius(startLat, startLon, radiusKm float64)
 
 	return endLat, endLon
 }
+
+// radio medio de la Tierra en metros
+const earthRadiusM = 6371000.0
+
+// HaversineDistance: Distancia en metros sobre la superficie de la Tierra entre dos coordenadas
+func HaversineDistance(lat1, lon1, lat2, lon2 float64) float64 {
+	phi1, phi2 := lat1*math.Pi/180, lat2*math.Pi/180
+	dPhi := (lat2 - lat1) * math.Pi / 180
+	dLambda := (lon2 - lon1) * math.Pi / 180
+
+	a := math.Sin(dPhi/2)*math.Sin(dPhi/2) + math.Cos(phi1)*math.Cos(phi2)*math.Sin(dLambda/2)*math.Sin(dLambda/2)
+	return 2 * earthRadiusM * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+// PathDistance: Largo en metros del recorrido, sumando la distancia entre puntos consecutivos
+func PathDistance[T any](points []T, position func(T) (lat, lon float64)) float64 {
+	total := 0.0
+	for i := 1; i < len(points); i++ {
+		lat1, lon1 := position(points[i-1])
+		lat2, lon2 := position(points[i])
+		total += HaversineDistance(lat1, lon1, lat2, lon2)
+	}
+	return total
+}
+
+// SimplifyPath: Simplifica el recorrido con Douglas-Peucker, descarta los puntos que se apartan menos de
+// toleranceM metros del recorrido simplificado. Siempre conserva el primer y el último punto
+func SimplifyPath[T any](points []T, position func(T) (lat, lon float64), toleranceM float64) []T {
+	if len(points) < 3 || toleranceM <= 0 {
+		return points
+	}
+
+	keep := make([]bool, len(points))
+	keep[0], keep[len(points)-1] = true, true
+
+	// se usa una pila en lugar de recursión para no depender del largo del recorrido
+	stack := [][2]int{{0, len(points) - 1}}
+	for len(stack) > 0 {
+		first, last := stack[len(stack)-1][0], stack[len(stack)-1][1]
+		stack = stack[:len(stack)-1]
+
+		farthest, maxDistance := -1, toleranceM
+		for i := first + 1; i < last; i++ {
+			if d := segmentDistance(points[i], points[first], points[last], position); d > maxDistance {
+				farthest, maxDistance = i, d
+			}
+		}
+		if farthest < 0 {
+			continue
+		}
+		keep[farthest] = true
+		stack = append(stack, [2]int{first, farthest}, [2]int{farthest, last})
+	}
+
+	simplified := make([]T, 0, len(points))
+	for i, point := range points {
+		if keep[i] {
+			simplified = append(simplified, point)
+		}
+	}
+	return simplified
+}
+
+// segmentDistance: Distancia en metros del punto p al segmento a-b. Para tramos cortos como los de un viaje
+// alcanza con proyectar en un plano local (equirectangular) centrado en a
+func segmentDistance[T any](p, a, b T, position func(T) (lat, lon float64)) float64 {
+	latA, lonA := position(a)
+	scale := math.Cos(latA*math.Pi/180)
+	project := func(point T) (float64, float64) {
+		lat, lon := position(point)
+		return (lon - lonA) * scale * math.Pi / 180 * earthRadiusM, (lat - latA) * math.Pi / 180 * earthRadiusM
+	}
+
+	px, py := project(p)
+	bx, by := project(b)
+	lengthSq := bx*bx + by*by
+	if lengthSq == 0 {
+		return math.Hypot(px, py)
+	}
+
+	// posición de la proyección de p sobre el segmento, limitada a sus extremos
+	t := math.Max(0, math.Min(1, (px*bx+py*by)/lengthSq))
+	return math.Hypot(px-t*bx, py-t*by)
+}
+
+// BoundingBox: Rectángulo que contiene el círculo de radiusM metros alrededor de la coordenada, para filtrar
+// en la base de datos antes de calcular la distancia exacta
+func BoundingBox(lat, lon, radiusM float64) (minLat, minLon, maxLat, maxLon float64) {
+	dLat := radiusM / earthRadiusM * 180 / math.Pi
+	dLon := dLat / math.Max(math.Cos(lat*math.Pi/180), 1e-6)
+	return math.Max(lat-dLat, -90), math.Max(lon-dLon, -180), math.Min(lat+dLat, 90), math.Min(lon+dLon, 180)
+}
+
+// GridCell: Celda de una grilla de celdas de sizeM metros de lado que contiene la coordenada. Las filas tienen el
+// mismo alto en grados; el ancho en grados de cada fila se calcula en su centro para que las celdas sean casi cuadradas
+func GridCell(lat, lon, sizeM float64) (row, col int) {
+	dLat := sizeM / earthRadiusM * 180 / math.Pi
+	row = int(math.Floor(lat / dLat))
+	dLon := dLat / math.Max(math.Cos((float64(row)+0.5)*dLat*math.Pi/180), 1e-6)
+	return row, int(math.Floor(lon / dLon))
+}
+
+// GridCellCenter: Coordenada del centro de la celda de GridCell
+func GridCellCenter(row, col int, sizeM float64) (lat, lon float64) {
+	dLat := sizeM / earthRadiusM * 180 / math.Pi
+	lat = (float64(row) + 0.5) * dLat
+	dLon := dLat / math.Max(math.Cos(lat*math.Pi/180), 1e-6)
+	return lat, (float64(col) + 0.5) * dLon
+}
+
+// ParsePolygon: Lee un polígono con el formato "lat,lng;lat,lng;...", de al menos 3 vértices. No hace falta
+// repetir el primer vértice al final
+func ParsePolygon(raw string) ([][2]float64, error) {
+	var polygon [][2]float64
+	for _, vertex := range strings.Split(raw, ";") {
+		if strings.TrimSpace(vertex) == "" {
+			continue
+		}
+		parts := strings.Split(vertex, ",")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("vértice inválido %q, se espera lat,lng", vertex)
+		}
+		lat, errLat := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+		lon, errLon := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if errLat != nil || errLon != nil || lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+			return nil, fmt.Errorf("vértice inválido %q, se espera lat,lng", vertex)
+		}
+		polygon = append(polygon, [2]float64{lat, lon})
+	}
+	if len(polygon) < 3 {
+		return nil, fmt.Errorf("el polígono necesita al menos 3 vértices")
+	}
+	return polygon, nil
+}
+
+// PointInPolygon: Indica si la coordenada está dentro del polígono (ray casting sobre lat/lng, alcanza para
+// áreas del tamaño de una ciudad que no cruzan el antimeridiano)
+func PointInPolygon(lat, lon float64, polygon [][2]float64) bool {
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		latI, lonI := polygon[i][0], polygon[i][1]
+		latJ, lonJ := polygon[j][0], polygon[j][1]
+		if (latI > lat) != (latJ > lat) && lon < (lonJ-lonI)*(lat-latI)/(latJ-latI)+lonI {
+			inside = !inside
+		}
+	}
+	return inside
+}
@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+// testPoint: Punto de prueba, latitud y longitud
+type testPoint [2]float64
+
+func testPosition(p testPoint) (float64, float64) {
+	return p[0], p[1]
+}
+
+func TestHaversineDistance(t *testing.T) {
+	tests := []struct {
+		name                   string
+		lat1, lon1, lat2, lon2 float64
+		want                   float64
+	}{
+		{"same point", -34.6037, -58.3816, -34.6037, -58.3816, 0},
+		{"one degree of latitude", 0, 0, 1, 0, 111194.93},
+		{"one degree of longitude at the equator", 0, 0, 0, 1, 111194.93},
+		{"one degree of longitude at 60°", 60, 0, 60, 1, 55596.02},
+		{"antipodes", 0, 0, 0, 180, math.Pi * earthRadiusM},
+		{"pole to pole", 90, 0, -90, 0, math.Pi * earthRadiusM},
+		{"buenos aires to montevideo", -34.6037, -58.3816, -34.9011, -56.1645, 205232},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := HaversineDistance(tt.lat1, tt.lon1, tt.lat2, tt.lon2)
+			// 0,1% de tolerancia (y 1 cm para las distancias nulas)
+			if math.Abs(got-tt.want) > math.Max(tt.want*0.001, 0.01) {
+				t.Errorf("distancia = %.2f, se esperaba %.2f", got, tt.want)
+			}
+			if back := HaversineDistance(tt.lat2, tt.lon2, tt.lat1, tt.lon1); math.Abs(back-got) > 1e-6 {
+				t.Errorf("distancia inversa = %.2f, se esperaba %.2f", back, got)
+			}
+		})
+	}
+}
+
+func TestPathDistance(t *testing.T) {
+	tests := []struct {
+		name   string
+		points []testPoint
+		want   float64
+	}{
+		{"empty", nil, 0},
+		{"single point", []testPoint{{0, 0}}, 0},
+		{"two points", []testPoint{{0, 0}, {0, 1}}, 111194.93},
+		{"there and back", []testPoint{{0, 0}, {0, 1}, {0, 0}}, 2 * 111194.93},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PathDistance(tt.points, testPosition); math.Abs(got-tt.want) > 1 {
+				t.Errorf("distancia = %.2f, se esperaba %.2f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSimplifyPath(t *testing.T) {
+	// ~1,1 m por cada 0,00001° en el ecuador
+	const step = 0.00001
+
+	tests := []struct {
+		name      string
+		points    []testPoint
+		tolerance float64
+		want      []int // índices de los puntos que se conservan
+	}{
+		{"empty", nil, 5, nil},
+		{"two points", []testPoint{{0, 0}, {0, 1}}, 5, []int{0, 1}},
+		{"straight line", []testPoint{{0, 0}, {0, 100 * step}, {0, 200 * step}, {0, 300 * step}}, 5, []int{0, 3}},
+		{"small jitter", []testPoint{{0, 0}, {2 * step, 100 * step}, {-2 * step, 200 * step}, {0, 300 * step}}, 5, []int{0, 3}},
+		{"corner", []testPoint{{0, 0}, {0, 100 * step}, {0, 200 * step}, {100 * step, 200 * step}, {200 * step, 200 * step}}, 5, []int{0, 2, 4}},
+		{"detour over a small tolerance", []testPoint{{0, 0}, {4 * step, 100 * step}, {0, 200 * step}}, 1, []int{0, 1, 2}},
+		{"zigzag", []testPoint{{0, 0}, {50 * step, 100 * step}, {0, 200 * step}, {50 * step, 300 * step}, {0, 400 * step}}, 5, []int{0, 1, 2, 3, 4}},
+		{"no tolerance", []testPoint{{0, 0}, {0, 100 * step}, {0, 200 * step}}, 0, []int{0, 1, 2}},
+		{"back to the start", []testPoint{{0, 0}, {0, 100 * step}, {100 * step, 100 * step}, {0, 0}}, 5, []int{0, 1, 2, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var want []testPoint
+			for _, i := range tt.want {
+				want = append(want, tt.points[i])
+			}
+			if got := SimplifyPath(tt.points, testPosition, tt.tolerance); !slices.Equal(got, want) {
+				t.Errorf("puntos = %v, se esperaba %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strconv"
+
+	"github.com/mbarolo/test_back/logging"
+)
+
+// campos que cambian en toda modificación y no aportan al diff
+var ignoredDiffFields = map[string]bool{
+	"updated_at": true,
+}
+
+// campos cuyo valor nunca se guarda, solo se registra que cambiaron
+var redactedDiffFields = map[string]bool{
+	"password":        true,
+	"hashed_password": true,
+	"secret":          true,
+}
+
+// JSONDiff: Diferencias entre dos estados de una entidad serializados en JSON, como un objeto
+// {"campo": {"from": anterior, "to": nuevo}} con solo los campos modificados. before vacío representa
+// una creación y after vacío una eliminación. Las claves se ordenan para que el resultado sea estable
+func JSONDiff(before, after []byte) (json.RawMessage, error) {
+	from, err := jsonObject(before)
+	if err != nil {
+		return nil, err
+	}
+	to, err := jsonObject(after)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []string{}
+	for key := range from {
+		keys = append(keys, key)
+	}
+	for key := range to {
+		if _, ok := from[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for _, key := range keys {
+		oldValue, newValue := valueOrNull(from[key]), valueOrNull(to[key])
+		if ignoredDiffFields[key] || bytes.Equal(oldValue, newValue) {
+			continue
+		}
+		if redactedDiffFields[key] {
+			oldValue, newValue = redact(oldValue), redact(newValue)
+		}
+
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(strconv.Quote(key))
+		buf.WriteString(`:{"from":`)
+		buf.Write(oldValue)
+		buf.WriteString(`,"to":`)
+		buf.Write(newValue)
+		buf.WriteByte('}')
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+var redactedJSON = []byte(strconv.Quote(logging.Redacted))
+
+// redact: Oculta el valor, salvo null para que se vea si el campo se creó o se eliminó
+func redact(value []byte) []byte {
+	if bytes.Equal(value, []byte("null")) {
+		return value
+	}
+	return redactedJSON
+}
+
+func jsonObject(data []byte) (map[string]json.RawMessage, error) {
+	members := map[string]json.RawMessage{}
+	if len(data) == 0 {
+		return members, nil
+	}
+	if err := json.Unmarshal(data, &members); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func valueOrNull(raw json.RawMessage) []byte {
+	if raw == nil {
+		return []byte("null")
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, raw); err != nil {
+		return raw
+	}
+	return compact.Bytes()
+}
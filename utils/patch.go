@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mbarolo/test_back/apperror"
+)
+
+// MergePatch: Aplica un JSON Merge Patch (RFC 7396) sobre un struct.
+// Las claves ausentes no se modifican, null limpia los campos que admiten nulos (punteros)
+// y los objetos se combinan recursivamente. Las claves que no corresponden a ningún campo se ignoran,
+// por lo que el patch debe validarse antes contra el form del endpoint
+func MergePatch(target interface{}, patch []byte) error {
+	value := reflect.ValueOf(target)
+	if value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Struct {
+		panic("MergePatch requiere un puntero a struct")
+	}
+
+	fields := []apperror.FieldError{}
+	if err := mergeObject(value.Elem(), patch, "", &fields); err != nil {
+		return err
+	}
+	if len(fields) > 0 {
+		return apperror.Validation("validation.failed", fields...)
+	}
+	return nil
+}
+
+// CheckPatchFields: Error de validación con cada campo del patch que no está entre los editables. Complementa
+// al form del endpoint cuando el form no alcanza, ej: null en un campo que no se puede limpiar
+func CheckPatchFields(patch []byte, editable []string) error {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(patch, &members); err != nil || members == nil {
+		return apperror.BadRequest("request.patch_not_object")
+	}
+
+	fields := []apperror.FieldError{}
+	for name := range members {
+		if !slices.Contains(editable, name) {
+			fields = append(fields, apperror.FieldError{Field: name, Message: "validation.readonly"})
+		}
+	}
+	if len(fields) > 0 {
+		slices.SortFunc(fields, func(a, b apperror.FieldError) int { return strings.Compare(a.Field, b.Field) })
+		return apperror.Validation("validation.failed", fields...)
+	}
+	return nil
+}
+
+func mergeObject(target reflect.Value, patch []byte, prefix string, fields *[]apperror.FieldError) error {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(patch, &members); err != nil || members == nil {
+		return apperror.BadRequest("request.patch_not_object")
+	}
+
+	for name, raw := range members {
+		field, ok := fieldByJsonName(target, name)
+		if !ok {
+			continue
+		}
+		path := prefix + name
+
+		if bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+			switch field.Kind() {
+			case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface:
+				field.Set(reflect.Zero(field.Type()))
+			default:
+				*fields = append(*fields, apperror.FieldError{Field: path, Message: "validation.not_nullable"})
+			}
+			continue
+		}
+
+		// los objetos anidados se combinan en lugar de reemplazarse
+		if field.Kind() == reflect.Struct && field.Type() != reflect.TypeOf(time.Time{}) && bytes.HasPrefix(bytes.TrimSpace(raw), []byte("{")) {
+			if err := mergeObject(field, raw, path+".", fields); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := json.Unmarshal(raw, field.Addr().Interface()); err != nil {
+			*fields = append(*fields, apperror.FieldError{Field: path, Message: "validation.invalid_value"})
+		}
+	}
+
+	return nil
+}
+
+// fieldByJsonName: Busca el campo del struct según su tag json
+func fieldByJsonName(value reflect.Value, name string) (reflect.Value, bool) {
+	for i := 0; i < value.NumField(); i++ {
+		structField := value.Type().Field(i)
+		tag := strings.Split(structField.Tag.Get("json"), ",")[0]
+		if tag == "-" || !structField.IsExported() {
+			continue
+		}
+		if tag == name || (tag == "" && structField.Name == name) {
+			return value.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
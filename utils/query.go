@@ -0,0 +1,417 @@
+package utils
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+
+	"github.com/mbarolo/test_back/apperror"
+	"github.com/mbarolo/test_back/i18n"
+	"github.com/mbarolo/test_back/tracing"
+)
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+type FieldKind int
+
+const (
+	KindInt FieldKind = iota
+	KindFloat
+	KindBool
+	KindString
+	KindTime
+)
+
+// QueryField: Columna de la base de datos expuesta como parámetro de consulta
+type QueryField struct {
+	Column string
+	Kind   FieldKind
+}
+
+// QueryConfig: Define qué filtros y ordenamientos acepta un endpoint de listado
+type QueryConfig struct {
+	Filters     map[string]QueryField // filtros de igualdad, ej: status=running
+	Sorts       map[string]QueryField // campos por los que se puede ordenar, ej: sort=-start_time
+	RangeField  *QueryField           // columna sobre la que aplican from= y to=
+	DefaultSort string
+	Params      []string // otros parámetros que lee el propio endpoint, ej: format
+}
+
+// accepts: Indica si el endpoint reconoce el parámetro de consulta
+func (cfg QueryConfig) accepts(name string) bool {
+	switch name {
+	case "limit", "sort", "cursor":
+		return true
+	case "from", "to":
+		return cfg.RangeField != nil
+	}
+	if _, ok := cfg.Filters[name]; ok {
+		return true
+	}
+	return slices.Contains(cfg.Params, name)
+}
+
+type Filter struct {
+	Column string
+	Op     string
+	Value  interface{}
+}
+
+// QuerySpec: Paginación por cursor (keyset), ordenamiento y filtros de un listado
+type QuerySpec struct {
+	Limit   int
+	Sort    QueryField
+	Desc    bool
+	Filters []Filter
+	cursor  *cursor
+}
+
+// Page: Envoltorio de respuesta para los listados paginados
+type Page[T any] struct {
+	Items      []*T    `json:"items"`
+	NextCursor *string `json:"next_cursor"`
+	TotalCount int64   `json:"total_count"`
+}
+
+type cursor struct {
+	Value interface{} `json:"v"`
+	Id    int64       `json:"id"`
+}
+
+var idField = QueryField{Column: "id", Kind: KindInt}
+
+// ParseQuerySpec: Construye un QuerySpec a partir de los parámetros de la url según la configuración del endpoint.
+// Los parámetros que el endpoint no acepta son un error
+func ParseQuerySpec(values url.Values, cfg QueryConfig) (*QuerySpec, error) {
+	spec := &QuerySpec{Limit: DefaultPageLimit, Sort: idField}
+
+	// un parámetro desconocido (ej: un filtro que ya no existe) se rechaza en lugar de listar sin filtrar
+	for name := range values {
+		if !cfg.accepts(name) {
+			return nil, apperror.BadRequest("query.unknown_param").WithParams(i18n.Params{"param": name})
+		}
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			return nil, invalidParam("limit")
+		}
+		spec.Limit = min(n, MaxPageLimit)
+	}
+
+	sort := values.Get("sort")
+	if sort == "" {
+		sort = cfg.DefaultSort
+	}
+	if sort != "" {
+		name := strings.TrimPrefix(sort, "-")
+		field, ok := cfg.Sorts[name]
+		if name == "id" {
+			field, ok = idField, true
+		}
+		if !ok {
+			return nil, apperror.BadRequest("query.invalid_sort").WithParams(i18n.Params{"field": name})
+		}
+		spec.Sort = field
+		spec.Desc = strings.HasPrefix(sort, "-")
+	}
+
+	for name, field := range cfg.Filters {
+		raw := values.Get(name)
+		if raw == "" {
+			continue
+		}
+		value, err := parseValue(raw, field.Kind)
+		if err != nil {
+			return nil, invalidParam(name)
+		}
+		spec.AddFilter(field.expr(), "=", value)
+	}
+
+	if cfg.RangeField != nil {
+		if from := values.Get("from"); from != "" {
+			t, err := parseTime(from)
+			if err != nil {
+				return nil, invalidParam("from")
+			}
+			spec.AddFilter(cfg.RangeField.expr(), ">=", timeArg(t))
+		}
+		if to := values.Get("to"); to != "" {
+			t, err := parseTime(to)
+			if err != nil {
+				return nil, invalidParam("to")
+			}
+			// una fecha sin hora incluye el día completo
+			if len(to) == len(time.DateOnly) {
+				t = t.AddDate(0, 0, 1)
+			}
+			spec.AddFilter(cfg.RangeField.expr(), "<", timeArg(t))
+		}
+	}
+
+	if raw := values.Get("cursor"); raw != "" {
+		c, err := decodeCursor(raw, spec.Sort.Kind)
+		if err != nil {
+			return nil, invalidParam("cursor")
+		}
+		spec.cursor = c
+	}
+
+	return spec, nil
+}
+
+func invalidParam(name string) error {
+	return apperror.BadRequest("query.invalid_param").WithParams(i18n.Params{"param": name})
+}
+
+// AddFilter: Agrega una condición fija al listado, ej: el usuario dueño del historial
+func (s *QuerySpec) AddFilter(column string, op string, value interface{}) {
+	s.Filters = append(s.Filters, Filter{Column: column, Op: op, Value: value})
+}
+
+// where: Arma la cláusula WHERE con los filtros y, si se pide, la condición del cursor
+func (s *QuerySpec) where(withCursor bool) (string, []interface{}) {
+	conditions := []string{}
+	args := []interface{}{}
+	for _, f := range s.Filters {
+		conditions = append(conditions, f.Column+" "+f.Op+" ?")
+		args = append(args, f.Value)
+	}
+
+	if withCursor && s.cursor != nil {
+		op := ">"
+		if s.Desc {
+			op = "<"
+		}
+		if s.Sort.Column == idField.Column {
+			conditions = append(conditions, "id "+op+" ?")
+			args = append(args, s.cursor.Id)
+		} else {
+			col := s.Sort.expr()
+			conditions = append(conditions, "("+col+" "+op+" ? OR ("+col+" = ? AND id "+op+" ?))")
+			args = append(args, s.cursor.Value, s.cursor.Value, s.cursor.Id)
+		}
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+func (s *QuerySpec) orderBy() string {
+	dir := " ASC"
+	if s.Desc {
+		dir = " DESC"
+	}
+	if s.Sort.Column == idField.Column {
+		return " ORDER BY id" + dir
+	}
+	return " ORDER BY " + s.Sort.expr() + dir + ", id" + dir
+}
+
+// GenericScanPage: Obtiene una página de la tabla según el QuerySpec junto al total de filas filtradas
+func GenericScanPage[T any](ctx context.Context, db DBTX, table string, spec *QuerySpec) (_ *Page[T], err error) {
+	ctx, span := tracing.Start(ctx, "utils.GenericScanPage", semconv.DBCollectionName(table))
+	defer func() { tracing.End(span, err) }()
+
+	where, args := spec.where(false)
+	var total int64
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+table+where, args...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("error contando filas: %w", err)
+	}
+
+	where, args = spec.where(true)
+	query := "SELECT * FROM " + table + where + spec.orderBy() + " LIMIT ?"
+	// se pide una fila extra para saber si existe una página siguiente
+	items, err := GenericScanAll[T](ctx, db, query, append(args, spec.Limit+1)...)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &Page[T]{Items: items, TotalCount: total}
+	if len(items) > spec.Limit {
+		page.Items = items[:spec.Limit]
+		next, err := spec.nextCursor(page.Items[spec.Limit-1])
+		if err != nil {
+			return nil, err
+		}
+		page.NextCursor = &next
+	}
+
+	return page, nil
+}
+
+// GenericScanEach: Recorre en orden de id todas las filas que cumplen los filtros del QuerySpec, de a batch filas,
+// sin cargar el resultado completo en memoria. El ordenamiento y el cursor del QuerySpec no se usan
+func GenericScanEach[T any](ctx context.Context, db DBTX, table string, spec *QuerySpec, batch int, fn func(*T) error) error {
+	where, args := spec.where(false)
+	if where == "" {
+		where = " WHERE id > ?"
+	} else {
+		where += " AND id > ?"
+	}
+	query := "SELECT * FROM " + table + where + " ORDER BY id LIMIT ?"
+
+	var lastId int64
+	for {
+		items, err := GenericScanAll[T](ctx, db, query, append(args, lastId, batch)...)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			if err := fn(item); err != nil {
+				return err
+			}
+		}
+		if len(items) < batch {
+			return nil
+		}
+
+		id := reflect.ValueOf(items[len(items)-1]).Elem().FieldByName("Id")
+		if !id.IsValid() {
+			return errors.New("la entidad no tiene campo Id")
+		}
+		lastId = id.Int()
+	}
+}
+
+// nextCursor: Codifica el valor de ordenamiento y el id de la última fila de la página
+func (s *QuerySpec) nextCursor(last interface{}) (string, error) {
+	value := reflect.ValueOf(last).Elem()
+	id := value.FieldByName("Id")
+	if !id.IsValid() {
+		return "", errors.New("la entidad no tiene campo Id")
+	}
+
+	c := cursor{Id: id.Int()}
+	if s.Sort.Column != idField.Column {
+		field := value.FieldByName(ColumnToFieldName(s.Sort.Column))
+		if !field.IsValid() {
+			return "", fmt.Errorf("la entidad no tiene campo para la columna %s", s.Sort.Column)
+		}
+		c.Value = field.Interface()
+	}
+
+	raw, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func decodeCursor(raw string, kind FieldKind) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var c struct {
+		Value json.RawMessage `json:"v"`
+		Id    int64           `json:"id"`
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+
+	res := &cursor{Id: c.Id}
+	if len(c.Value) == 0 || string(c.Value) == "null" {
+		return res, nil
+	}
+
+	switch kind {
+	case KindTime:
+		var t time.Time
+		err = json.Unmarshal(c.Value, &t)
+		res.Value = timeArg(t)
+	case KindInt:
+		var i int64
+		err = json.Unmarshal(c.Value, &i)
+		res.Value = i
+	case KindFloat:
+		var f float64
+		err = json.Unmarshal(c.Value, &f)
+		res.Value = f
+	case KindBool:
+		var b bool
+		err = json.Unmarshal(c.Value, &b)
+		res.Value = boolToInt(b)
+	default:
+		var s string
+		err = json.Unmarshal(c.Value, &s)
+		res.Value = s
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func parseValue(raw string, kind FieldKind) (interface{}, error) {
+	switch kind {
+	case KindInt:
+		return strconv.ParseInt(raw, 10, 64)
+	case KindFloat:
+		return strconv.ParseFloat(raw, 64)
+	case KindBool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, err
+		}
+		return boolToInt(b), nil
+	case KindTime:
+		t, err := parseTime(raw)
+		if err != nil {
+			return nil, err
+		}
+		return timeArg(t), nil
+	default:
+		return raw, nil
+	}
+}
+
+// expr: Expresión SQL con la que se compara y ordena la columna.
+// Las fechas se guardan como texto en más de un formato (con o sin fracción de segundo y
+// reloj monotónico), así que se comparan por sus primeros 19 caracteres: 2006-01-02 15:04:05
+func (f QueryField) expr() string {
+	if f.Kind == KindTime {
+		return "substr(" + f.Column + ", 1, 19)"
+	}
+	return f.Column
+}
+
+// timeArg: Formatea una fecha para compararla contra expr(), en la zona horaria del servidor
+func timeArg(t time.Time) string {
+	return t.In(time.Local).Format(time.DateTime)
+}
+
+// parseTime: Acepta fechas en formato RFC3339 o 2006-01-02
+func parseTime(raw string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation(time.DateOnly, raw, time.Local)
+}
+
+// sqlite guarda los booleanos como 0 y 1
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
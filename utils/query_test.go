@@ -0,0 +1,191 @@
+package utils
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"slices"
+	"testing"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/mbarolo/test_back/apperror"
+)
+
+type queryItem struct {
+	Id    int64
+	Name  string
+	Score int64
+}
+
+var queryItemConfig = QueryConfig{
+	Filters: map[string]QueryField{
+		"name": {Column: "name", Kind: KindString},
+	},
+	Sorts: map[string]QueryField{
+		"score": {Column: "score", Kind: KindInt},
+	},
+	DefaultSort: "-id",
+}
+
+// newQueryDB: Base en memoria con 7 filas, los puntajes se repiten para probar el desempate por id
+func newQueryDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := db.Exec("CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT NOT NULL, score INTEGER NOT NULL)"); err != nil {
+		t.Fatal(err)
+	}
+	scores := []int64{30, 10, 20, 10, 30, 20, 10}
+	for i, score := range scores {
+		name := "a"
+		if i%2 == 1 {
+			name = "b"
+		}
+		if _, err := db.Exec("INSERT INTO items (id, name, score) VALUES (?, ?, ?)", i+1, name, score); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func parseQuery(t *testing.T, raw string) *QuerySpec {
+	t.Helper()
+	values, err := url.ParseQuery(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec, err := ParseQuerySpec(values, queryItemConfig)
+	if err != nil {
+		t.Fatalf("ParseQuerySpec(%q): %v", raw, err)
+	}
+	return spec
+}
+
+// scanAllPages: Recorre el listado siguiendo next_cursor y retorna los ids en el orden recibido
+func scanAllPages(t *testing.T, db *sql.DB, raw string) ([]int64, int) {
+	t.Helper()
+	var ids []int64
+	pages := 0
+	query := raw
+	for {
+		page, err := GenericScanPage[queryItem](context.Background(), db, "items", parseQuery(t, query))
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages++
+		for _, item := range page.Items {
+			ids = append(ids, item.Id)
+		}
+		if page.NextCursor == nil {
+			return ids, pages
+		}
+		query = raw + "&cursor=" + *page.NextCursor
+	}
+}
+
+func TestScanPageCursor(t *testing.T) {
+	db := newQueryDB(t)
+
+	tests := []struct {
+		name  string
+		query string
+		want  []int64
+		pages int
+	}{
+		{"default sort", "limit=3", []int64{7, 6, 5, 4, 3, 2, 1}, 3},
+		{"sort asc with ties", "limit=3&sort=score", []int64{2, 4, 7, 3, 6, 1, 5}, 3},
+		{"sort desc with ties", "limit=2&sort=-score", []int64{5, 1, 6, 3, 7, 4, 2}, 4},
+		{"filter", "limit=2&name=b", []int64{6, 4, 2}, 2},
+		{"single page", "limit=100", []int64{7, 6, 5, 4, 3, 2, 1}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids, pages := scanAllPages(t, db, tt.query)
+			if !slices.Equal(ids, tt.want) {
+				t.Errorf("ids = %v, se esperaba %v", ids, tt.want)
+			}
+			if pages != tt.pages {
+				t.Errorf("páginas = %d, se esperaban %d", pages, tt.pages)
+			}
+		})
+	}
+}
+
+func TestScanPageFirstPage(t *testing.T) {
+	db := newQueryDB(t)
+
+	page, err := GenericScanPage[queryItem](context.Background(), db, "items", parseQuery(t, "limit=3&sort=score&name=a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.TotalCount != 4 {
+		t.Errorf("total_count = %d, se esperaba 4", page.TotalCount)
+	}
+	if len(page.Items) != 3 {
+		t.Fatalf("items = %d, se esperaban 3", len(page.Items))
+	}
+	if page.NextCursor == nil {
+		t.Fatal("next_cursor nil con más filas")
+	}
+
+	c, err := decodeCursor(*page.NextCursor, KindInt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Id != page.Items[2].Id || c.Value != page.Items[2].Score {
+		t.Errorf("cursor = %+v, se esperaba la última fila %+v", c, page.Items[2])
+	}
+}
+
+func TestParseQuerySpecErrors(t *testing.T) {
+	tampered := base64.RawURLEncoding.EncodeToString([]byte(`{"v":"no es un número","id":3}`))
+
+	tests := []struct {
+		name    string
+		query   string
+		message string
+	}{
+		{"unknown filter", "is_available=true", "query.unknown_param"},
+		{"range without range field", "from=2026-01-01", "query.unknown_param"},
+		{"unknown sort", "sort=-created_at", "query.invalid_sort"},
+		{"invalid limit", "limit=0", "query.invalid_param"},
+		{"invalid limit value", "limit=x", "query.invalid_param"},
+		{"cursor not base64", "cursor=!!!", "query.invalid_param"},
+		{"cursor not json", "cursor=" + base64.RawURLEncoding.EncodeToString([]byte("abc")), "query.invalid_param"},
+		{"cursor value of another kind", "sort=score&cursor=" + tampered, "query.invalid_param"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = ParseQuerySpec(values, queryItemConfig)
+			var appErr *apperror.Error
+			if !errors.As(err, &appErr) {
+				t.Fatalf("error = %v, se esperaba un apperror", err)
+			}
+			if appErr.Code != apperror.BAD_REQUEST || appErr.Message != tt.message {
+				t.Errorf("error = %s %s, se esperaba BAD_REQUEST %s", appErr.Code, appErr.Message, tt.message)
+			}
+		})
+	}
+}
+
+func TestParseQuerySpecParams(t *testing.T) {
+	cfg := queryItemConfig
+	cfg.Params = []string{"format"}
+	if _, err := ParseQuerySpec(url.Values{"format": {"csv"}}, cfg); err != nil {
+		t.Errorf("parámetro propio del endpoint rechazado: %v", err)
+	}
+	if _, err := ParseQuerySpec(url.Values{"format": {"csv"}}, queryItemConfig); err == nil {
+		t.Error("se aceptó un parámetro que el endpoint no declara")
+	}
+}
@@ -2,13 +2,14 @@ package utils
 
 import (
 	"encoding/json"
+	"log/slog"
 	"net/http"
+
+	"github.com/mbarolo/test_back/apperror"
+	"github.com/mbarolo/test_back/i18n"
 )
 
 func JsonResponse(w http.ResponseWriter, code int, message string, data interface{}) {
-	w.Header().Set("Content-type", "application/json")
-	w.WriteHeader(code)
-
 	var res map[string]interface{}
 	if code >= http.StatusOK && code < http.StatusMultipleChoices {
 		// Códigos 2xx (éxito)
@@ -27,14 +28,52 @@ func JsonResponse(w http.ResponseWriter, code int, message string, data interfac
 	} else if code >= http.StatusBadRequest && code < http.StatusInternalServerError {
 		res = map[string]interface{}{
 			"status":  "fail",
+			"code":    apperror.CodeFromStatus(code),
 			"message": message,
 		}
 	} else if code >= http.StatusInternalServerError {
 		res = map[string]interface{}{
 			"status":  "error",
+			"code":    apperror.CodeFromStatus(code),
 			"message": message,
 		}
 	}
 
+	writeJson(w, code, res)
+}
+
+// ErrorResponse: Traduce un error de dominio a la respuesta HTTP correspondiente, en el idioma de la request.
+// Los errores internos no exponen su causa al cliente, solo se registran en el log
+func ErrorResponse(w http.ResponseWriter, r *http.Request, messageID string, err error) {
+	appErr := apperror.From(err)
+	status := appErr.Code.Status()
+	message := i18n.T(r, messageID)
+
+	res := map[string]interface{}{
+		"status": "fail",
+		"code":   appErr.Code,
+	}
+	if status >= http.StatusInternalServerError {
+		slog.ErrorContext(r.Context(), "request failed", "message_id", messageID, "error", err)
+		res["status"] = "error"
+		res["message"] = message
+	} else {
+		slog.DebugContext(r.Context(), "request rejected", "message_id", messageID, "code", appErr.Code, "reason", appErr.Message)
+		res["message"] = message + ": " + i18n.T(r, appErr.Message, appErr.Params)
+	}
+	if len(appErr.Fields) > 0 {
+		fields := make([]apperror.FieldError, len(appErr.Fields))
+		for i, f := range appErr.Fields {
+			fields[i] = apperror.FieldError{Field: f.Field, Message: i18n.T(r, f.Message, f.Params)}
+		}
+		res["errors"] = fields
+	}
+
+	writeJson(w, status, res)
+}
+
+func writeJson(w http.ResponseWriter, code int, res map[string]interface{}) {
+	w.Header().Set("Content-type", "application/json")
+	w.WriteHeader(code)
 	json.NewEncoder(w).Encode(res)
 }
@@ -1,17 +1,36 @@
 package utils
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
 	"reflect"
 	"strconv"
 	"strings"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+
+	"github.com/mbarolo/test_back/tracing"
 )
 
-func GenericScanAll[T any](db *sql.DB, query string, args ...interface{}) ([]*T, error) {
-	rows, err := db.Query(query, args...)
+// DBTX: Operaciones comunes a *sql.DB y *sql.Tx, así las consultas funcionan dentro o fuera de una transacción
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// GenericScanAll: Ejecuta la consulta y mapea cada fila al struct T por reflexión.
+// El span registra la cantidad de filas y el tiempo dedicado al mapeo, separado del tiempo de SQLite
+func GenericScanAll[T any](ctx context.Context, db DBTX, query string, args ...interface{}) (_ []*T, err error) {
+	ctx, span := tracing.Start(ctx, "utils.GenericScanAll", semconv.DBQueryText(query))
+	defer func() { tracing.End(span, err) }()
+	var reflection time.Duration
+
+	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("error ejecutando la consulta: %w", err)
 	}
@@ -44,12 +63,9 @@ func GenericScanAll[T any](db *sql.DB, query string, args ...interface{}) ([]*T,
 		}
 
 		// Mapear los valores a los campos de la estructura
+		mapStart := time.Now()
 		for i, column := range columns {
-			column = strings.ReplaceAll(column, "_", " ")
-			column = strings.Title(column)
-			column = strings.ReplaceAll(column, " ", "")
-			field := structValue.FieldByName(column)
-			//quitar Guiones y espacios en blanco
+			field := structValue.FieldByName(ColumnToFieldName(column))
 			if field.IsValid() && field.CanSet() {
 				val := columnValues[i]
 				if val != nil {
@@ -58,6 +74,8 @@ func GenericScanAll[T any](db *sql.DB, query string, args ...interface{}) ([]*T,
 			}
 		}
 
+		reflection += time.Since(mapStart)
+
 		// Agregar la estructura mapeada a los resultados
 		results = append(results, obj)
 	}
@@ -66,9 +84,21 @@ func GenericScanAll[T any](db *sql.DB, query string, args ...interface{}) ([]*T,
 		return nil, fmt.Errorf("error al iterar filas: %w", err)
 	}
 
+	span.SetAttributes(
+		semconv.DBResponseReturnedRows(len(results)),
+		attribute.Float64("scan.reflection_ms", float64(reflection.Microseconds())/1000),
+	)
 	return results, nil
 }
 
+// ColumnToFieldName: Convierte el nombre de una columna al del campo del struct, ej: start_time -> StartTime
+func ColumnToFieldName(column string) string {
+	//quitar Guiones y espacios en blanco
+	column = strings.ReplaceAll(column, "_", " ")
+	column = strings.Title(column)
+	return strings.ReplaceAll(column, " ", "")
+}
+
 func convertValue(value interface{}, field reflect.Value) {
 	if value == nil {
 		// No se asigna nada si el valor es nulo
@@ -103,28 +133,33 @@ func convertValue(value interface{}, field reflect.Value) {
 			if err == nil {
 				field.SetInt(intValue)
 			} else {
-				log.Printf("Error convirtiendo a int: %v\n", err)
+				slog.Warn("column conversion failed", "type", "int", "error", err)
 			}
 		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
 			uintValue, err := strconv.ParseUint(string(data), 10, 64)
 			if err == nil {
 				field.SetUint(uintValue)
 			} else {
-				log.Printf("Error convirtiendo a uint: %v\n", err)
+				slog.Warn("column conversion failed", "type", "uint", "error", err)
 			}
 		case reflect.Float32, reflect.Float64:
 			floatValue, err := strconv.ParseFloat(string(data), 64)
 			if err == nil {
 				field.SetFloat(floatValue)
 			} else {
-				log.Printf("Error convirtiendo a float: %v\n", err)
+				slog.Warn("column conversion failed", "type", "float", "error", err)
 			}
 		case reflect.Bool:
 			boolValue, err := strconv.ParseBool(string(data))
 			if err == nil {
 				field.SetBool(boolValue)
 			} else {
-				log.Printf("Error convirtiendo a bool: %v\n", err)
+				slog.Warn("column conversion failed", "type", "bool", "error", err)
+			}
+		case reflect.Slice:
+			// []byte y tipos derivados, ej: json.RawMessage
+			if field.Type().Elem().Kind() == reflect.Uint8 {
+				field.SetBytes(append([]byte(nil), data...))
 			}
 		case reflect.Struct:
 			if field.Type() == reflect.TypeOf(time.Time{}) {
@@ -135,17 +170,17 @@ func convertValue(value interface{}, field reflect.Value) {
 					if err == nil {
 						field.Set(reflect.ValueOf(parsedTime))
 					} else {
-						log.Printf("Error convirtiendo a time.Time: %v\n", err)
+						slog.Warn("column conversion failed", "type", "time.Time", "error", err)
 					}
 				} else if len(str) == 2 { // str tiene formato 2006-01-02 15:04:05 -> datetime
 					parsedTime, err := time.Parse("2006-01-02 15:04:05", string(data))
 					if err == nil {
 						field.Set(reflect.ValueOf(parsedTime))
 					} else {
-						log.Printf("Error convirtiendo a time.Time: %v\n", err)
+						slog.Warn("column conversion failed", "type", "time.Time", "error", err)
 					}
 				} else {
-					log.Printf("Error convirtiendo a time.Time: %v\n", data)
+					slog.Warn("column conversion failed", "type", "time.Time", "value", string(data))
 				}
 			}
 		}
@@ -169,7 +204,7 @@ func convertValue(value interface{}, field reflect.Value) {
 			if v >= 0 {
 				field.SetUint(uint64(v))
 			} else {
-				log.Printf("No se puede asignar int64 negativo a uint: %v\n", v)
+				slog.Warn("column conversion failed", "type", "uint", "value", v)
 			}
 		}
 	case reflect.Float32, reflect.Float64:
@@ -181,6 +216,10 @@ func convertValue(value interface{}, field reflect.Value) {
 		case int64:
 			field.SetBool(v != 0)
 		}
+	case reflect.Slice:
+		if str, ok := value.(string); ok && field.Type().Elem().Kind() == reflect.Uint8 {
+			field.SetBytes([]byte(str))
+		}
 	case reflect.Struct:
 		if field.Type() == reflect.TypeOf(time.Time{}) {
 			if t, ok := value.(time.Time); ok {